@@ -221,11 +221,12 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 		}
 
 		switch {
-		case entry.SecretIDNumUses == 0:
+		case entry.SecretIDNumUses == 0 && role.SecretIDUsageHistorySize == 0:
 			//
 			// SecretIDNumUses will be zero only if the usage limit was not set at all,
 			// in which case, the SecretID will remain to be valid as long as it is not
-			// expired.
+			// expired. Unless the role records the logins, the storage entry does not
+			// need to be updated.
 			//
 
 			// Ensure that the CIDRs on the secret ID are still a subset of that of
@@ -257,8 +258,8 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 		default:
 			//
 			// If the SecretIDNumUses is non-zero, it means that its use-count should be updated
-			// in the storage. Switch the lock from a `read` to a `write` and update
-			// the storage entry.
+			// in the storage, as should the usage history if the role records it. Switch the
+			// lock from a `read` to a `write` and update the storage entry.
 			//
 
 			secretIDLock.RUnlock()
@@ -288,10 +289,21 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 					return nil, fmt.Errorf("failed to delete secret ID: %w", err)
 				}
 			} else {
-				// If the use count is greater than one, decrement it and update the last updated time.
-				entry.SecretIDNumUses -= 1
+				// If the use count is limited and greater than one, decrement it and
+				// update the last updated time.
+				if entry.SecretIDNumUses > 0 {
+					entry.SecretIDNumUses -= 1
+				}
 				entry.LastUpdatedTime = time.Now()
 
+				if role.SecretIDUsageHistorySize > 0 {
+					var sourceAddress string
+					if req.Connection != nil {
+						sourceAddress = req.Connection.RemoteAddr
+					}
+					entry.recordUsage(sourceAddress, role.SecretIDUsageHistorySize)
+				}
+
 				sEntry, err := logical.StorageEntryJSON(entryIndex, &entry)
 				if err != nil {
 					return nil, err
@@ -330,6 +342,12 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 		}
 
 		metadata = entry.Metadata
+		if entry.DeliveredTo != "" {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata["secret_id_delivered_to"] = entry.DeliveredTo
+		}
 	}
 
 	if len(role.SecretIDBoundCIDRs) != 0 {
@@ -358,3 +358,80 @@ func TestAppRole_RoleDoesNotExist(t *testing.T) {
 		t.Fatalf("Error was not due to invalid role ID. Error: %s", errString)
 	}
 }
+
+func TestAppRole_SecretIDUsageHistory(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	b.requestNoErr(t, &logical.Request{
+		Path:      "role/testrole",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"secret_id_usage_history_size": 2,
+		},
+		Storage: s,
+	})
+
+	resp := b.requestNoErr(t, &logical.Request{
+		Path:      "role/testrole/role-id",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	roleID := resp.Data["role_id"]
+
+	resp = b.requestNoErr(t, &logical.Request{
+		Path:      "role/testrole/secret-id",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"delivered_to": "orchestrator-1",
+		},
+		Storage: s,
+	})
+	secretID := resp.Data["secret_id"]
+
+	for _, remoteAddr := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"} {
+		resp = b.requestNoErr(t, &logical.Request{
+			Path:      "login",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"role_id":   roleID,
+				"secret_id": secretID,
+			},
+			Storage:    s,
+			Connection: &logical.Connection{RemoteAddr: remoteAddr},
+		})
+		if resp.Auth.Metadata["secret_id_delivered_to"] != "orchestrator-1" {
+			t.Fatalf("bad metadata: %#v", resp.Auth.Metadata)
+		}
+	}
+
+	resp = b.requestNoErr(t, &logical.Request{
+		Path:      "role/testrole/secret-id/lookup",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"secret_id": secretID,
+		},
+		Storage: s,
+	})
+	if resp.Data["delivered_to"] != "orchestrator-1" {
+		t.Fatalf("bad delivered_to: %#v", resp.Data["delivered_to"])
+	}
+
+	// Only the two most recent logins are kept
+	usageHistory := resp.Data["usage_history"].([]map[string]interface{})
+	if len(usageHistory) != 2 {
+		t.Fatalf("expected 2 logins in the usage history, got %d", len(usageHistory))
+	}
+	for i, remoteAddr := range []string{"127.0.0.2", "127.0.0.3"} {
+		if usageHistory[i]["source_address"] != remoteAddr {
+			t.Fatalf("bad source address of login %d: %#v", i, usageHistory[i])
+		}
+		if usageHistory[i]["time"].(time.Time).IsZero() {
+			t.Fatalf("missing time of login %d", i)
+		}
+	}
+
+	// The secret ID still has unlimited uses
+	if resp.Data["secret_id_num_uses"].(int) != 0 {
+		t.Fatalf("bad secret_id_num_uses: %#v", resp.Data["secret_id_num_uses"])
+	}
+}
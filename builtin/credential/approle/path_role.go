@@ -55,6 +55,20 @@ type roleStorageEntry struct {
 	// A constraint, if set, requires 'secret_id' credential to be presented during login
 	BindSecretID bool `json:"bind_secret_id" mapstructure:"bind_secret_id"`
 
+	// A constraint, if set, requires the SecretIDs generated against the role
+	// to be response wrapped, so that only the recipient of the wrapping token
+	// ever sees them
+	SecretIDWrappingRequired bool `json:"secret_id_wrapping_required" mapstructure:"secret_id_wrapping_required"`
+
+	// Bounds on the TTL of the wrapping tokens of the SecretIDs generated
+	// against the role
+	SecretIDMinWrappingTTL time.Duration `json:"secret_id_min_wrapping_ttl" mapstructure:"secret_id_min_wrapping_ttl"`
+	SecretIDMaxWrappingTTL time.Duration `json:"secret_id_max_wrapping_ttl" mapstructure:"secret_id_max_wrapping_ttl"`
+
+	// Number of the most recent logins recorded on each SecretID of the role.
+	// Zero disables the recording of logins.
+	SecretIDUsageHistorySize int `json:"secret_id_usage_history_size" mapstructure:"secret_id_usage_history_size"`
+
 	// Deprecated: A constraint, if set, specifies the CIDR blocks from which logins should be allowed,
 	// please use SecretIDBoundCIDRs instead.
 	BoundCIDRListOld string `json:"bound_cidr_list,omitempty"`
@@ -185,6 +199,31 @@ to 0, meaning no expiration.`,
 				Description: `If set, the secret IDs generated using this role will be cluster local. This
 can only be set during role creation and once set, it can't be reset later.`,
 			},
+
+			"secret_id_wrapping_required": {
+				Type: framework.TypeBool,
+				Description: `If set, secret IDs can only be generated against this role with response
+wrapping. Defaults to 'false'.`,
+			},
+
+			"secret_id_min_wrapping_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `Minimum TTL in seconds of the wrapping token when a secret ID is generated
+with response wrapping. Defaults to 0, meaning no minimum.`,
+			},
+
+			"secret_id_max_wrapping_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `Maximum TTL in seconds of the wrapping token when a secret ID is generated
+with response wrapping. Defaults to 0, meaning no maximum.`,
+			},
+
+			"secret_id_usage_history_size": {
+				Type: framework.TypeInt,
+				Description: fmt.Sprintf(`Number of the most recent logins, with their time and source address, to
+record on each secret ID and return when looking it up. Defaults to 0, meaning
+that logins are not recorded. May not be higher than %d.`, maxSecretIDUsageHistorySize),
+			},
 		},
 		ExistenceCheck: b.pathRoleExistenceCheck,
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -227,6 +266,26 @@ can only be set during role creation and once set, it can't be reset later.`,
 								Required:    true,
 								Description: "If true, the secret identifiers generated using this role will be cluster local. This can only be set during role creation and once set, it can't be reset later",
 							},
+							"secret_id_wrapping_required": {
+								Type:        framework.TypeBool,
+								Required:    true,
+								Description: "If true, secret IDs can only be generated against this role with response wrapping.",
+							},
+							"secret_id_min_wrapping_ttl": {
+								Type:        framework.TypeInt64,
+								Required:    true,
+								Description: "Minimum TTL in seconds of the wrapping token of generated secret IDs.",
+							},
+							"secret_id_max_wrapping_ttl": {
+								Type:        framework.TypeInt64,
+								Required:    true,
+								Description: "Maximum TTL in seconds of the wrapping token of generated secret IDs.",
+							},
+							"secret_id_usage_history_size": {
+								Type:        framework.TypeInt,
+								Required:    true,
+								Description: "Number of the most recent logins recorded on each secret ID.",
+							},
 							"token_bound_cidrs": {
 								Type:        framework.TypeCommaStringSlice,
 								Required:    true,
@@ -936,6 +995,12 @@ Overrides secret_id_num_uses role option when supplied. May not be higher than r
 					Description: `Duration in seconds after which this SecretID expires.
 Overrides secret_id_ttl role option when supplied. May not be longer than role's secret_id_ttl.`,
 				},
+				"delivered_to": {
+					Type: framework.TypeString,
+					Description: `Identifier of the recipient the SecretID is delivered to, such as the
+orchestrator or the host it is generated for. It is returned when looking up
+the SecretID and added to the metadata of the tokens issued with it.`,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.UpdateOperation: &framework.PathOperation{
@@ -1043,6 +1108,16 @@ Overrides secret_id_ttl role option when supplied. May not be longer than role's
 									Required:    true,
 									Description: "List of CIDR blocks. If set, specifies the blocks of IP addresses which can use the returned token. Should be a subset of the token CIDR blocks listed on the role, if any.",
 								},
+								"delivered_to": {
+									Type:        framework.TypeString,
+									Required:    false,
+									Description: "Identifier of the recipient the secret ID was delivered to.",
+								},
+								"usage_history": {
+									Type:        framework.TypeSlice,
+									Required:    false,
+									Description: "Time and source address of the most recent logins with the secret ID, if recorded by the role.",
+								},
 							},
 						}},
 					},
@@ -1152,6 +1227,16 @@ Overrides secret_id_ttl role option when supplied. May not be longer than role's
 									Required:    true,
 									Description: "List of CIDR blocks. If set, specifies the blocks of IP addresses which can use the returned token. Should be a subset of the token CIDR blocks listed on the role, if any.",
 								},
+								"delivered_to": {
+									Type:        framework.TypeString,
+									Required:    false,
+									Description: "Identifier of the recipient the secret ID was delivered to.",
+								},
+								"usage_history": {
+									Type:        framework.TypeSlice,
+									Required:    false,
+									Description: "Time and source address of the most recent logins with the secret ID, if recorded by the role.",
+								},
 							},
 						}},
 					},
@@ -1238,6 +1323,12 @@ Overrides secret_id_num_uses role option when supplied. May not be higher than r
 					Description: `Duration in seconds after which this SecretID expires.
 Overrides secret_id_ttl role option when supplied. May not be longer than role's secret_id_ttl.`,
 				},
+				"delivered_to": {
+					Type: framework.TypeString,
+					Description: `Identifier of the recipient the SecretID is delivered to, such as the
+orchestrator or the host it is generated for. It is returned when looking up
+the SecretID and added to the metadata of the tokens issued with it.`,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.UpdateOperation: &framework.PathOperation{
@@ -1648,6 +1739,29 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		role.SecretIDTTL = time.Second * time.Duration(data.Get("secret_id_ttl").(int))
 	}
 
+	if wrappingRequiredRaw, ok := data.GetOk("secret_id_wrapping_required"); ok {
+		role.SecretIDWrappingRequired = wrappingRequiredRaw.(bool)
+	}
+	if minWrappingTTLRaw, ok := data.GetOk("secret_id_min_wrapping_ttl"); ok {
+		role.SecretIDMinWrappingTTL = time.Second * time.Duration(minWrappingTTLRaw.(int))
+	}
+	if maxWrappingTTLRaw, ok := data.GetOk("secret_id_max_wrapping_ttl"); ok {
+		role.SecretIDMaxWrappingTTL = time.Second * time.Duration(maxWrappingTTLRaw.(int))
+	}
+	if role.SecretIDMinWrappingTTL < 0 || role.SecretIDMaxWrappingTTL < 0 {
+		return logical.ErrorResponse("secret_id_min_wrapping_ttl and secret_id_max_wrapping_ttl cannot be negative"), nil
+	}
+	if role.SecretIDMaxWrappingTTL > 0 && role.SecretIDMinWrappingTTL > role.SecretIDMaxWrappingTTL {
+		return logical.ErrorResponse("secret_id_min_wrapping_ttl cannot be greater than secret_id_max_wrapping_ttl"), nil
+	}
+
+	if usageHistorySizeRaw, ok := data.GetOk("secret_id_usage_history_size"); ok {
+		role.SecretIDUsageHistorySize = usageHistorySizeRaw.(int)
+	}
+	if role.SecretIDUsageHistorySize < 0 || role.SecretIDUsageHistorySize > maxSecretIDUsageHistorySize {
+		return logical.ErrorResponse(fmt.Sprintf("secret_id_usage_history_size must be between 0 and %d", maxSecretIDUsageHistorySize)), nil
+	}
+
 	// handle upgrade cases
 	{
 		if err := tokenutil.UpgradeValue(data, "policies", "token_policies", &role.Policies, &role.TokenPolicies); err != nil {
@@ -1702,6 +1816,11 @@ func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *
 		"secret_id_num_uses":    role.SecretIDNumUses,
 		"secret_id_ttl":         role.SecretIDTTL / time.Second,
 		"local_secret_ids":      false,
+
+		"secret_id_wrapping_required":  role.SecretIDWrappingRequired,
+		"secret_id_min_wrapping_ttl":   role.SecretIDMinWrappingTTL / time.Second,
+		"secret_id_max_wrapping_ttl":   role.SecretIDMaxWrappingTTL / time.Second,
+		"secret_id_usage_history_size": role.SecretIDUsageHistorySize,
 	}
 	role.PopulateTokenData(respData)
 
@@ -1886,6 +2005,19 @@ func (entry *secretIDStorageEntry) ToResponseData() map[string]interface{} {
 	if len(entry.TokenBoundCIDRs) == 0 {
 		ret["token_bound_cidrs"] = []string{}
 	}
+	if entry.DeliveredTo != "" {
+		ret["delivered_to"] = entry.DeliveredTo
+	}
+	if len(entry.UsageHistory) > 0 {
+		usageHistory := make([]map[string]interface{}, 0, len(entry.UsageHistory))
+		for _, usage := range entry.UsageHistory {
+			usageHistory = append(usageHistory, map[string]interface{}{
+				"time":           usage.Time,
+				"source_address": usage.SourceAddress,
+			})
+		}
+		ret["usage_history"] = usageHistory
+	}
 	return ret
 }
 
@@ -3008,6 +3140,10 @@ func (b *backend) handleRoleSecretIDCommon(ctx context.Context, req *logical.Req
 		return logical.ErrorResponse("bind_secret_id is not set on the role"), nil
 	}
 
+	if err := role.validateSecretIDWrapping(req.WrapInfo); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	secretIDCIDRs := data.Get("cidr_list").([]string)
 
 	// Validate the list of CIDR blocks
@@ -3079,6 +3215,7 @@ func (b *backend) handleRoleSecretIDCommon(ctx context.Context, req *logical.Req
 		Metadata:        make(map[string]string),
 		CIDRList:        secretIDCIDRs,
 		TokenBoundCIDRs: secretIDTokenCIDRs,
+		DeliveredTo:     data.Get("delivered_to").(string),
 	}
 
 	if err = strutil.ParseArbitraryKeyValues(data.Get("metadata").(string), secretIDStorage.Metadata, ","); err != nil {
@@ -3305,7 +3442,11 @@ this endpoint.`,
 just this role and none else. The properties of this SecretID will be
 based on the options set on the role. It will expire after a period
 defined by the 'ttl' field or 'secret_id_ttl' option on the role,
-and/or the backend mount's maximum TTL value.`,
+and/or the backend mount's maximum TTL value. If 'secret_id_wrapping_required'
+is set on the role, the request must be response wrapped, with a wrapping
+TTL within the 'secret_id_min_wrapping_ttl' and 'secret_id_max_wrapping_ttl'
+options of the role, so that only the recipient of the wrapping token, such
+as a trusted orchestrator identified by 'delivered_to', sees the SecretID.`,
 	},
 	"role-custom-secret-id": {
 		"Assign a SecretID of choice against the role.",
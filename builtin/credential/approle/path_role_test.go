@@ -2132,3 +2132,77 @@ func TestAppRole_RoleSecretIDAccessorCrossDelete(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestAppRole_SecretIDWrapping(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"secret_id_min_wrapping_ttl": 600,
+			"secret_id_max_wrapping_ttl": 60,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error due to min wrapping TTL greater than max: err:%v resp:%#v", err, resp)
+	}
+
+	b.requestNoErr(t, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"secret_id_wrapping_required": true,
+			"secret_id_min_wrapping_ttl":  60,
+			"secret_id_max_wrapping_ttl":  300,
+		},
+	})
+
+	resp = b.requestNoErr(t, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+	})
+	if !resp.Data["secret_id_wrapping_required"].(bool) {
+		t.Fatal("expected secret_id_wrapping_required to be set")
+	}
+	if resp.Data["secret_id_min_wrapping_ttl"].(time.Duration) != 60 || resp.Data["secret_id_max_wrapping_ttl"].(time.Duration) != 300 {
+		t.Fatalf("bad wrapping TTLs: %#v", resp.Data)
+	}
+
+	for _, path := range []string{"role/role1/secret-id", "role/role1/custom-secret-id"} {
+		for _, wrapInfo := range []*logical.RequestWrapInfo{
+			nil,
+			{TTL: 30 * time.Second},
+			{TTL: 10 * time.Minute},
+		} {
+			resp, err = b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      path,
+				Storage:   storage,
+				WrapInfo:  wrapInfo,
+				Data: map[string]interface{}{
+					"secret_id": "custom-secret-id",
+				},
+			})
+			if err != nil || resp == nil || !resp.IsError() {
+				t.Fatalf("expected an error generating a secret ID at %q with wrapping %#v: err:%v resp:%#v", path, wrapInfo, err, resp)
+			}
+		}
+
+		resp = b.requestNoErr(t, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			WrapInfo:  &logical.RequestWrapInfo{TTL: 2 * time.Minute},
+			Data: map[string]interface{}{
+				"secret_id": "custom-secret-id",
+			},
+		})
+		if resp.Data["secret_id"].(string) == "" {
+			t.Fatalf("expected a secret ID to be generated at %q", path)
+		}
+	}
+}
@@ -60,10 +60,58 @@ type secretIDStorageEntry struct {
 	// restrictions on the usage of the token generated by this SecretID
 	TokenBoundCIDRs []string `json:"token_cidr_list" mapstructure:"token_bound_cidrs"`
 
+	// Identifier of the recipient the SecretID was delivered to
+	DeliveredTo string `json:"delivered_to,omitempty" mapstructure:"delivered_to"`
+
+	// UsageHistory holds the most recent logins performed with the SecretID,
+	// if the role records them
+	UsageHistory []secretIDUsage `json:"usage_history,omitempty" mapstructure:"usage_history"`
+
 	// This is a deprecated field
 	SecretIDNumUsesDeprecated int `json:"SecretIDNumUses" mapstructure:"SecretIDNumUses"`
 }
 
+// maxSecretIDUsageHistorySize caps the number of logins recorded on a SecretID,
+// as the whole history is rewritten to storage on every login.
+const maxSecretIDUsageHistorySize = 100
+
+// secretIDUsage records a login performed with a SecretID.
+type secretIDUsage struct {
+	Time          time.Time `json:"time" mapstructure:"time"`
+	SourceAddress string    `json:"source_address" mapstructure:"source_address"`
+}
+
+// recordUsage appends a login from the given source address to the usage
+// history of the SecretID, dropping the oldest ones beyond size.
+func (entry *secretIDStorageEntry) recordUsage(sourceAddress string, size int) {
+	entry.UsageHistory = append(entry.UsageHistory, secretIDUsage{
+		Time:          time.Now(),
+		SourceAddress: sourceAddress,
+	})
+	if len(entry.UsageHistory) > size {
+		entry.UsageHistory = entry.UsageHistory[len(entry.UsageHistory)-size:]
+	}
+}
+
+// validateSecretIDWrapping checks the response wrapping of a request
+// generating a SecretID against the wrapping constraints of the role.
+func (role *roleStorageEntry) validateSecretIDWrapping(wrapInfo *logical.RequestWrapInfo) error {
+	if wrapInfo == nil || wrapInfo.TTL == 0 {
+		if role.SecretIDWrappingRequired {
+			return fmt.Errorf("secret IDs of role %q must be generated with response wrapping", role.name)
+		}
+		return nil
+	}
+
+	if role.SecretIDMinWrappingTTL > 0 && wrapInfo.TTL < role.SecretIDMinWrappingTTL {
+		return fmt.Errorf("wrapping TTL of %q is less than the secret_id_min_wrapping_ttl of %q", wrapInfo.TTL, role.SecretIDMinWrappingTTL)
+	}
+	if role.SecretIDMaxWrappingTTL > 0 && wrapInfo.TTL > role.SecretIDMaxWrappingTTL {
+		return fmt.Errorf("wrapping TTL of %q is greater than the secret_id_max_wrapping_ttl of %q", wrapInfo.TTL, role.SecretIDMaxWrappingTTL)
+	}
+	return nil
+}
+
 // Represents the payload of the storage entry of the accessor that maps to a
 // unique SecretID. Note that SecretIDs should never be stored in plaintext
 // anywhere in the backend. SecretIDHMAC will be used as an index to fetch the
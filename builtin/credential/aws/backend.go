@@ -13,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/hashicorp/cap/jwt"
 	"github.com/hashicorp/go-secure-stdlib/awsutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -107,6 +108,20 @@ type backend struct {
 
 	resolveArnToUniqueIDFunc func(context.Context, logical.Storage, string) (string, error)
 
+	// webIdentityKeySets caches the key sets of the web identity issuers,
+	// keyed by the name of the issuer. webIdentityKeySetsGen is incremented
+	// whenever they are reset, so that key sets fetched meanwhile are not
+	// cached.
+	webIdentityKeySets     map[string]jwt.KeySet
+	webIdentityKeySetsGen  uint64
+	webIdentityKeySetsLock sync.Mutex
+
+	// webIdentityCtx is the context of the key sets of the web identity
+	// issuers, which keep fetching keys after the request which created
+	// them. It is canceled when the backend is cleaned up.
+	webIdentityCtx       context.Context
+	webIdentityCtxCancel context.CancelFunc
+
 	// upgradeCancelFunc is used to cancel the context used in the upgrade
 	// function
 	upgradeCancelFunc context.CancelFunc
@@ -128,6 +143,7 @@ func Backend(_ *logical.BackendConfig) (*backend, error) {
 		tidyDenyListCASGuard:   new(uint32),
 		tidyAccessListCASGuard: new(uint32),
 		roleCache:              cache.New(cache.NoExpiration, cache.NoExpiration),
+		webIdentityKeySets:     make(map[string]jwt.KeySet),
 
 		deprecatedTerms: strings.NewReplacer(
 			"accesslist", "whitelist",
@@ -136,6 +152,7 @@ func Backend(_ *logical.BackendConfig) (*backend, error) {
 			"deny-list", "blacklist",
 		),
 	}
+	b.webIdentityCtx, b.webIdentityCtxCancel = context.WithCancel(context.Background())
 
 	b.resolveArnToUniqueIDFunc = b.resolveArnToRealUniqueId
 
@@ -146,6 +163,7 @@ func Backend(_ *logical.BackendConfig) (*backend, error) {
 		PathsSpecial: &logical.Paths{
 			Unauthenticated: []string{
 				"login",
+				"login/web-identity",
 			},
 			LocalStorage: []string{
 				identityAccessListStorage,
@@ -156,6 +174,7 @@ func Backend(_ *logical.BackendConfig) (*backend, error) {
 		},
 		Paths: []*framework.Path{
 			b.pathLogin(),
+			b.pathLoginWebIdentity(),
 			b.pathListRole(),
 			b.pathListRoles(),
 			b.pathRole(),
@@ -166,6 +185,8 @@ func Backend(_ *logical.BackendConfig) (*backend, error) {
 			b.pathConfigRotateRoot(),
 			b.pathConfigSts(),
 			b.pathListSts(),
+			b.pathConfigWebIdentity(),
+			b.pathListWebIdentity(),
 			b.pathListCertificates(),
 
 			// The following pairs of functions are path aliases. The first is the
@@ -274,6 +295,7 @@ func (b *backend) cleanup(ctx context.Context) {
 	if b.upgradeCancelFunc != nil {
 		b.upgradeCancelFunc()
 	}
+	b.webIdentityCtxCancel()
 }
 
 func (b *backend) invalidate(ctx context.Context, key string) {
@@ -287,6 +309,8 @@ func (b *backend) invalidate(ctx context.Context, key string) {
 	case strings.HasPrefix(key, "role"):
 		// TODO: We could make this better
 		b.roleCache.Flush()
+	case strings.HasPrefix(key, webIdentityConfigStoragePrefix):
+		b.resetWebIdentityKeySet(strings.TrimPrefix(key, webIdentityConfigStoragePrefix))
 	}
 }
 
@@ -426,6 +450,12 @@ specifically signed AWS API request using clients' AWS IAM credentials. IAM
 principals can then be assigned to roles within Vault. This is known as the
 "iam" auth method.
 
+Pods using EKS Pod Identity or IAM roles for service accounts can instead log
+in at 'login/web-identity' with the service account token of the pod. The
+token is verified against an issuer registered at 'config/web-identity/<name>'
+and mapped to the IAM role of its service account, which is then matched
+against the role like an "iam" login.
+
 Authentication of EC2 instances is done using either a signed PKCS#7 document
 or a detached RSA signature of an AWS EC2 instance's identity document along
 with a client-created nonce. This is known as the "ec2" auth method.
@@ -0,0 +1,407 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package awsauth
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/cap/jwt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const webIdentityConfigStoragePrefix = "config/web-identity/"
+
+// defaultWebIdentityAudiences are the audiences of the tokens projected into
+// pods for IRSA and for EKS Pod Identity respectively.
+var defaultWebIdentityAudiences = []string{"sts.amazonaws.com", "pods.eks.amazonaws.com"}
+
+// awsWebIdentityEntry describes a trusted issuer of web identity tokens, such
+// as the OIDC issuer of an EKS cluster, and the IAM roles its service
+// accounts are associated with.
+type awsWebIdentityEntry struct {
+	Issuer         string   `json:"issuer"`
+	JWKSURL        string   `json:"jwks_url,omitempty"`
+	JWKSCAPEM      string   `json:"jwks_ca_pem,omitempty"`
+	BoundAudiences []string `json:"bound_audiences"`
+
+	// ServiceAccountRoleARNs maps "<namespace>/<service account>" to the
+	// ARN of the IAM role associated with that service account.
+	ServiceAccountRoleARNs map[string]string `json:"service_account_role_arns"`
+}
+
+func (b *backend) pathListWebIdentity() *framework.Path {
+	return &framework.Path{
+		Pattern: "config/web-identity/?",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAWS,
+			OperationSuffix: "web-identity-issuers",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.pathWebIdentityList,
+			},
+		},
+
+		HelpSynopsis:    pathListWebIdentityHelpSyn,
+		HelpDescription: pathListWebIdentityHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigWebIdentity() *framework.Path {
+	return &framework.Path{
+		Pattern: "config/web-identity/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAWS,
+			OperationSuffix: "web-identity-issuer",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the web identity issuer, e.g. the name of the EKS cluster.",
+			},
+			"issuer": {
+				Type: framework.TypeString,
+				Description: `OIDC issuer URL of the web identity tokens, e.g. the OIDC
+issuer of the EKS cluster. It must match the "iss" claim of the tokens.`,
+			},
+			"jwks_url": {
+				Type: framework.TypeString,
+				Description: `URL of the JSON Web Key Set used to verify the tokens. If not
+set, the keys are found through OIDC discovery of the issuer.`,
+			},
+			"jwks_ca_pem": {
+				Type:        framework.TypeString,
+				Description: `PEM encoded CA certificates used to verify the TLS connection to the issuer or JWKS URL.`,
+			},
+			"bound_audiences": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Audiences of which the tokens must have at least one. Defaults to "sts.amazonaws.com" and "pods.eks.amazonaws.com".`,
+			},
+			"service_account_role_arns": {
+				Type: framework.TypeKVPairs,
+				Description: `Map of "<namespace>/<service account>" to the ARN of the IAM role
+associated with the service account. Only service accounts listed here may log in.`,
+			},
+		},
+
+		ExistenceCheck: b.pathConfigWebIdentityExistenceCheck,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWebIdentityCreateUpdate,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWebIdentityCreateUpdate,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigWebIdentityRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathConfigWebIdentityDelete,
+			},
+		},
+
+		HelpSynopsis:    pathConfigWebIdentityHelpSyn,
+		HelpDescription: pathConfigWebIdentityHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigWebIdentityExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	entry, err := b.lockedWebIdentityEntry(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// pathWebIdentityList is used to list all the web identity issuers
+func (b *backend) pathWebIdentityList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.configMutex.RLock()
+	defer b.configMutex.RUnlock()
+	names, err := req.Storage.List(ctx, webIdentityConfigStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+// nonLockedWebIdentityEntry returns the web identity issuer with the given
+// name. This method does not acquire the read lock.
+func (b *backend) nonLockedWebIdentityEntry(ctx context.Context, s logical.Storage, name string) (*awsWebIdentityEntry, error) {
+	entry, err := s.Get(ctx, webIdentityConfigStoragePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var webIdentityEntry awsWebIdentityEntry
+	if err := entry.DecodeJSON(&webIdentityEntry); err != nil {
+		return nil, err
+	}
+	return &webIdentityEntry, nil
+}
+
+// lockedWebIdentityEntry returns the web identity issuer with the given name.
+// This method acquires the read lock before reading the entry.
+func (b *backend) lockedWebIdentityEntry(ctx context.Context, s logical.Storage, name string) (*awsWebIdentityEntry, error) {
+	b.configMutex.RLock()
+	defer b.configMutex.RUnlock()
+
+	return b.nonLockedWebIdentityEntry(ctx, s, name)
+}
+
+// lockedWebIdentityEntryByIssuer returns the name and entry of the web
+// identity issuer whose issuer URL is the given one.
+func (b *backend) lockedWebIdentityEntryByIssuer(ctx context.Context, s logical.Storage, issuer string) (string, *awsWebIdentityEntry, error) {
+	b.configMutex.RLock()
+	defer b.configMutex.RUnlock()
+
+	names, err := s.List(ctx, webIdentityConfigStoragePrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, name := range names {
+		entry, err := b.nonLockedWebIdentityEntry(ctx, s, name)
+		if err != nil {
+			return "", nil, err
+		}
+		if entry != nil && entry.Issuer == issuer {
+			return name, entry, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// pathConfigWebIdentityRead is used to return a web identity issuer
+func (b *backend) pathConfigWebIdentityRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.lockedWebIdentityEntry(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer":                    entry.Issuer,
+			"jwks_url":                  entry.JWKSURL,
+			"jwks_ca_pem":               entry.JWKSCAPEM,
+			"bound_audiences":           entry.BoundAudiences,
+			"service_account_role_arns": entry.ServiceAccountRoleARNs,
+		},
+	}, nil
+}
+
+// pathConfigWebIdentityCreateUpdate is used to register a web identity issuer
+func (b *backend) pathConfigWebIdentityCreateUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing name"), nil
+	}
+
+	b.configMutex.Lock()
+	defer b.configMutex.Unlock()
+
+	entry, err := b.nonLockedWebIdentityEntry(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		entry = &awsWebIdentityEntry{}
+	}
+
+	if issuerRaw, ok := data.GetOk("issuer"); ok {
+		entry.Issuer = issuerRaw.(string)
+	}
+	if entry.Issuer == "" {
+		return logical.ErrorResponse("missing issuer"), nil
+	}
+	if _, err := url.ParseRequestURI(entry.Issuer); err != nil {
+		return logical.ErrorResponse("invalid issuer: %s", err), nil
+	}
+
+	if jwksURLRaw, ok := data.GetOk("jwks_url"); ok {
+		entry.JWKSURL = jwksURLRaw.(string)
+	}
+	if entry.JWKSURL != "" {
+		if _, err := url.ParseRequestURI(entry.JWKSURL); err != nil {
+			return logical.ErrorResponse("invalid jwks_url: %s", err), nil
+		}
+	}
+
+	if caPEMRaw, ok := data.GetOk("jwks_ca_pem"); ok {
+		entry.JWKSCAPEM = caPEMRaw.(string)
+	}
+	if entry.JWKSCAPEM != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(entry.JWKSCAPEM)) {
+		return logical.ErrorResponse("jwks_ca_pem does not contain any valid certificates"), nil
+	}
+
+	if audiencesRaw, ok := data.GetOk("bound_audiences"); ok {
+		entry.BoundAudiences = audiencesRaw.([]string)
+	}
+	if len(entry.BoundAudiences) == 0 {
+		entry.BoundAudiences = defaultWebIdentityAudiences
+	}
+
+	if roleARNsRaw, ok := data.GetOk("service_account_role_arns"); ok {
+		entry.ServiceAccountRoleARNs = roleARNsRaw.(map[string]string)
+	}
+	for serviceAccount, roleARN := range entry.ServiceAccountRoleARNs {
+		if _, _, err := splitServiceAccount(serviceAccount); err != nil {
+			return logical.ErrorResponse("invalid service account %q: %s", serviceAccount, err), nil
+		}
+		entity, err := parseIamArn(roleARN)
+		if err != nil {
+			return logical.ErrorResponse("invalid role ARN %q for service account %q: %s", roleARN, serviceAccount, err), nil
+		}
+		if entity.Type != "role" {
+			return logical.ErrorResponse("ARN %q for service account %q is not an IAM role", roleARN, serviceAccount), nil
+		}
+	}
+
+	// No two entries may claim the same issuer, as logins find their entry
+	// by the issuer of the token.
+	names, err := req.Storage.List(ctx, webIdentityConfigStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	for _, other := range names {
+		if other == name {
+			continue
+		}
+		otherEntry, err := b.nonLockedWebIdentityEntry(ctx, req.Storage, other)
+		if err != nil {
+			return nil, err
+		}
+		if otherEntry != nil && otherEntry.Issuer == entry.Issuer {
+			return logical.ErrorResponse("issuer %q is already configured as %q", entry.Issuer, other), nil
+		}
+	}
+
+	storageEntry, err := logical.StorageEntryJSON(webIdentityConfigStoragePrefix+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+	b.resetWebIdentityKeySet(name)
+
+	return nil, nil
+}
+
+// pathConfigWebIdentityDelete is used to delete a web identity issuer
+func (b *backend) pathConfigWebIdentityDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing name"), nil
+	}
+
+	b.configMutex.Lock()
+	defer b.configMutex.Unlock()
+
+	if err := req.Storage.Delete(ctx, webIdentityConfigStoragePrefix+name); err != nil {
+		return nil, err
+	}
+	b.resetWebIdentityKeySet(name)
+
+	return nil, nil
+}
+
+// getWebIdentityKeySet returns the key set used to verify the tokens of the
+// given web identity issuer, creating it on first use. The keys are fetched
+// without holding the lock, so that an unresponsive issuer does not hold up
+// the logins of the others.
+func (b *backend) getWebIdentityKeySet(name string, entry *awsWebIdentityEntry) (jwt.KeySet, error) {
+	b.webIdentityKeySetsLock.Lock()
+	keySet, ok := b.webIdentityKeySets[name]
+	gen := b.webIdentityKeySetsGen
+	b.webIdentityKeySetsLock.Unlock()
+	if ok {
+		return keySet, nil
+	}
+
+	var err error
+	if entry.JWKSURL != "" {
+		keySet, err = jwt.NewJSONWebKeySet(b.webIdentityCtx, entry.JWKSURL, entry.JWKSCAPEM)
+	} else {
+		keySet, err = jwt.NewOIDCDiscoveryKeySet(b.webIdentityCtx, entry.Issuer, entry.JWKSCAPEM)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.webIdentityKeySetsLock.Lock()
+	defer b.webIdentityKeySetsLock.Unlock()
+
+	// Keep the key set of a concurrent login, and don't cache this one if the
+	// issuers changed meanwhile
+	if existing, ok := b.webIdentityKeySets[name]; ok {
+		return existing, nil
+	}
+	if gen == b.webIdentityKeySetsGen {
+		b.webIdentityKeySets[name] = keySet
+	}
+	return keySet, nil
+}
+
+// resetWebIdentityKeySet drops the cached key set of the given web identity
+// issuer, or of all of them if name is empty.
+func (b *backend) resetWebIdentityKeySet(name string) {
+	b.webIdentityKeySetsLock.Lock()
+	defer b.webIdentityKeySetsLock.Unlock()
+
+	b.webIdentityKeySetsGen++
+	if name == "" {
+		b.webIdentityKeySets = make(map[string]jwt.KeySet)
+		return
+	}
+	delete(b.webIdentityKeySets, name)
+}
+
+// splitServiceAccount splits "<namespace>/<service account>" into its parts.
+func splitServiceAccount(serviceAccount string) (string, string, error) {
+	namespace, name, ok := strings.Cut(serviceAccount, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf(`expected "<namespace>/<service account>"`)
+	}
+	return namespace, name, nil
+}
+
+const pathConfigWebIdentityHelpSyn = `
+Configure issuers of web identity tokens for EKS Pod Identity and IRSA logins.
+`
+
+const pathConfigWebIdentityHelpDesc = `
+Pods using EKS Pod Identity or IAM roles for service accounts (IRSA) are given
+a service account token signed by the OIDC issuer of their cluster. This
+endpoint registers such an issuer, the keys used to verify its tokens, and the
+IAM role associated with each service account allowed to log in.
+
+Tokens presented to the "login/web-identity" endpoint are verified against the
+issuer whose "issuer" matches their "iss" claim. The IAM role associated with
+the service account named in their "sub" claim is then matched against the
+bound_iam_principal_arn of the Vault role, as with iam logins.
+`
+
+const pathListWebIdentityHelpSyn = `
+List all the web identity issuers registered with Vault.
+`
+
+const pathListWebIdentityHelpDesc = `
+Web identity issuers will be listed by name.
+`
@@ -1132,8 +1132,10 @@ func (b *backend) handleRoleTagLogin(ctx context.Context, s logical.Storage, rol
 
 // pathLoginRenew is used to renew an authenticated token
 func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	authType, ok := req.Auth.Metadata["auth_type"]
-	if !ok {
+	// web identity logins also record the auth_type internally, so that
+	// renewals don't depend on the configured iam_metadata
+	authType, err := getMetadataValue(req.Auth, "auth_type")
+	if err != nil {
 		// backwards compatibility for clients that have leases from before we added auth_type
 		authType = ec2AuthType
 	}
@@ -1142,6 +1144,8 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, data
 		return b.pathLoginRenewEc2(ctx, req, data)
 	} else if authType == iamAuthType {
 		return b.pathLoginRenewIam(ctx, req, data)
+	} else if authType == webIdentityAuthType {
+		return b.pathLoginRenewWebIdentity(ctx, req, data)
 	} else {
 		return nil, fmt.Errorf("unrecognized auth_type: %q", authType)
 	}
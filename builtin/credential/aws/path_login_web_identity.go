@@ -0,0 +1,378 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package awsauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"github.com/hashicorp/cap/jwt"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	webIdentityAuthType = "web_identity"
+
+	// serviceAccountSubjectPrefix prefixes the "sub" claim of Kubernetes
+	// service account tokens, which is followed by "<namespace>:<name>".
+	serviceAccountSubjectPrefix = "system:serviceaccount:"
+)
+
+// webIdentity is a verified web identity token and the IAM role associated
+// with its service account.
+type webIdentity struct {
+	issuerName     string
+	serviceAccount string
+	roleARN        string
+	entity         *iamEntity
+}
+
+func (b *backend) pathLoginWebIdentity() *framework.Path {
+	return &framework.Path{
+		Pattern: "login/web-identity$",
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixAWS,
+			OperationVerb:   "login",
+			OperationSuffix: "web-identity",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type: framework.TypeString,
+				Description: `Name of the role against which the login is being attempted.
+If 'role' is not specified, then the login endpoint looks for a role
+bearing the name of the IAM role associated with the service account.`,
+			},
+			"web_identity_token": {
+				Type: framework.TypeString,
+				Description: `Service account token of the pod, as projected for EKS Pod
+Identity or IAM roles for service accounts.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathLoginUpdateWebIdentity,
+			},
+			logical.AliasLookaheadOperation: &framework.PathOperation{
+				Callback: b.pathLoginUpdateWebIdentity,
+			},
+			logical.ResolveRoleOperation: &framework.PathOperation{
+				Callback: b.pathLoginResolveRoleWebIdentity,
+			},
+		},
+
+		HelpSynopsis:    pathLoginWebIdentitySyn,
+		HelpDescription: pathLoginWebIdentityDesc,
+	}
+}
+
+func (b *backend) pathLoginWebIdentityGetRoleNameAndIdentity(ctx context.Context, req *logical.Request, data *framework.FieldData) (string, *webIdentity, *logical.Response, error) {
+	token := data.Get("web_identity_token").(string)
+	if token == "" {
+		return "", nil, logical.ErrorResponse("missing web_identity_token"), nil
+	}
+
+	identity, errResp, err := b.verifyWebIdentityToken(ctx, req.Storage, token)
+	if errResp != nil || err != nil {
+		return "", nil, errResp, err
+	}
+
+	roleName := data.Get("role").(string)
+	if roleName == "" {
+		roleName = identity.entity.FriendlyName
+	}
+	return roleName, identity, nil, nil
+}
+
+// verifyWebIdentityToken verifies the token against the issuer named in its
+// "iss" claim, and looks up the IAM role associated with its service account.
+func (b *backend) verifyWebIdentityToken(ctx context.Context, s logical.Storage, token string) (*webIdentity, *logical.Response, error) {
+	// The issuer is only trusted once the token has been verified against
+	// the keys configured for it below.
+	parsed, err := josejwt.ParseSigned(token)
+	if err != nil {
+		return nil, logical.ErrorResponse("error parsing web_identity_token: %s", err), nil
+	}
+	var unverified josejwt.Claims
+	if err := parsed.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, logical.ErrorResponse("error parsing web_identity_token: %s", err), nil
+	}
+
+	issuerName, issuerEntry, err := b.lockedWebIdentityEntryByIssuer(ctx, s, unverified.Issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if issuerEntry == nil {
+		return nil, logical.ErrorResponse("issuer %q of web_identity_token is not configured", unverified.Issuer), nil
+	}
+
+	keySet, err := b.getWebIdentityKeySet(issuerName, issuerEntry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the keys of web identity issuer %q: %w", issuerName, err)
+	}
+	validator, err := jwt.NewValidator(keySet)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims, err := validator.Validate(ctx, token, jwt.Expected{
+		Issuer:            issuerEntry.Issuer,
+		Audiences:         issuerEntry.BoundAudiences,
+		SigningAlgorithms: []jwt.Alg{jwt.RS256, jwt.ES256},
+	})
+	if err != nil {
+		return nil, logical.ErrorResponse("error validating web_identity_token: %s", err), nil
+	}
+
+	subject, _ := claims["sub"].(string)
+	if !strings.HasPrefix(subject, serviceAccountSubjectPrefix) {
+		return nil, logical.ErrorResponse("subject %q of web_identity_token is not a service account", subject), nil
+	}
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(subject, serviceAccountSubjectPrefix), ":")
+	if !ok || namespace == "" || name == "" {
+		return nil, logical.ErrorResponse("subject %q of web_identity_token is not a service account", subject), nil
+	}
+	serviceAccount := namespace + "/" + name
+
+	roleARN, ok := issuerEntry.ServiceAccountRoleARNs[serviceAccount]
+	if !ok {
+		return nil, logical.ErrorResponse("service account %q is not associated with an IAM role", serviceAccount), nil
+	}
+	entity, err := parseIamArn(roleARN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing ARN %q associated with service account %q: %w", roleARN, serviceAccount, err)
+	}
+
+	return &webIdentity{
+		issuerName:     issuerName,
+		serviceAccount: serviceAccount,
+		roleARN:        roleARN,
+		entity:         entity,
+	}, nil, nil
+}
+
+func (b *backend) pathLoginResolveRoleWebIdentity(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, _, resp, err := b.pathLoginWebIdentityGetRoleNameAndIdentity(ctx, req, data)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	return logical.ResolveRoleResponse(role)
+}
+
+func (b *backend) pathLoginUpdateWebIdentity(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName, identity, errResp, err := b.pathLoginWebIdentityGetRoleNameAndIdentity(ctx, req, data)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+	entity := identity.entity
+
+	roleEntry, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if roleEntry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("entry for role %s not found", roleName)), nil
+	}
+
+	// Check for a CIDR match.
+	if len(roleEntry.TokenBoundCIDRs) > 0 {
+		if req.Connection == nil {
+			b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
+			return nil, logical.ErrPermissionDenied
+		}
+		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, roleEntry.TokenBoundCIDRs) {
+			return nil, logical.ErrPermissionDenied
+		}
+	}
+
+	if roleEntry.AuthType != iamAuthType {
+		return logical.ErrorResponse(fmt.Sprintf("auth method iam not allowed for role %s", roleName)), nil
+	}
+	// Pods are not EC2 instances, so a role inferring one cannot be satisfied
+	if roleEntry.InferredEntityType != "" {
+		return logical.ErrorResponse("role %q infers an entity type and cannot be used with web identity logins", roleName), nil
+	}
+
+	identityConfigEntry, err := identityConfigEntry(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unlike iam logins, the unique ID of the role isn't known from the
+	// login itself, so it is only resolved when needed.
+	uniqueID := ""
+	if roleEntry.ResolveAWSUniqueIDs || identityConfigEntry.IAMAlias == identityAliasIAMUniqueID {
+		uniqueID, err = b.resolveArnToUniqueIDFunc(ctx, req.Storage, identity.roleARN)
+		if err != nil {
+			return logical.ErrorResponse("error resolving unique ID of %q: %v", identity.roleARN, err), nil
+		}
+	}
+
+	identityAlias := ""
+	switch identityConfigEntry.IAMAlias {
+	case identityAliasRoleID:
+		identityAlias = roleEntry.RoleID
+	case identityAliasIAMUniqueID:
+		identityAlias = uniqueID
+	case identityAliasIAMFullArn:
+		identityAlias = identity.roleARN
+	case identityAliasIAMCanonicalArn:
+		identityAlias = entity.canonicalArn()
+	}
+
+	// If we're just looking up for MFA, return the Alias info
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: identityAlias,
+				},
+			},
+		}, nil
+	}
+
+	if !webIdentityIsBound(roleEntry, uniqueID, entity, identity.roleARN) {
+		return logical.ErrorResponse("IAM Principal %q of service account %q does not belong to the role %q", identity.roleARN, identity.serviceAccount, roleName), nil
+	}
+
+	auth := &logical.Auth{
+		Metadata: map[string]string{
+			"role_id":             roleEntry.RoleID,
+			"web_identity_issuer": identity.issuerName,
+			"service_account":     identity.serviceAccount,
+		},
+		InternalData: map[string]interface{}{
+			"role_name":           roleName,
+			"role_id":             roleEntry.RoleID,
+			"auth_type":           webIdentityAuthType,
+			"canonical_arn":       entity.canonicalArn(),
+			"client_arn":          identity.roleARN,
+			"client_user_id":      uniqueID,
+			"account_id":          entity.AccountNumber,
+			"web_identity_issuer": identity.issuerName,
+			"service_account":     identity.serviceAccount,
+		},
+		DisplayName: strings.Join([]string{entity.FriendlyName, identity.serviceAccount}, "/"),
+		Alias: &logical.Alias{
+			Name: identityAlias,
+		},
+	}
+
+	roleEntry.PopulateTokenAuth(auth)
+	if err := identityConfigEntry.IAMAuthMetadataHandler.PopulateDesiredMetadata(auth, map[string]string{
+		"client_arn":     identity.roleARN,
+		"canonical_arn":  entity.canonicalArn(),
+		"client_user_id": uniqueID,
+		"auth_type":      webIdentityAuthType,
+		"account_id":     entity.AccountNumber,
+	}); err != nil {
+		b.Logger().Warn(fmt.Sprintf("unable to set alias metadata due to %s", err))
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func (b *backend) pathLoginRenewWebIdentity(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	roleName, err := getMetadataValue(req.Auth, "role_name")
+	if err != nil {
+		return nil, err
+	}
+	issuerName, err := getMetadataValue(req.Auth, "web_identity_issuer")
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount, err := getMetadataValue(req.Auth, "service_account")
+	if err != nil {
+		return nil, err
+	}
+	clientArn, err := getMetadataValue(req.Auth, "client_arn")
+	if err != nil {
+		return nil, err
+	}
+	uniqueID, _ := getMetadataValue(req.Auth, "client_user_id")
+
+	roleEntry, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if roleEntry == nil {
+		return nil, fmt.Errorf("role entry not found")
+	}
+	if roleEntry.AuthType != iamAuthType || roleEntry.InferredEntityType != "" {
+		return nil, fmt.Errorf("role %q no longer allows web identity logins", roleName)
+	}
+
+	// The service account must still be associated with the same IAM role
+	issuerEntry, err := b.lockedWebIdentityEntry(ctx, req.Storage, issuerName)
+	if err != nil {
+		return nil, err
+	}
+	if issuerEntry == nil {
+		return nil, fmt.Errorf("web identity issuer %q not found", issuerName)
+	}
+	if issuerEntry.ServiceAccountRoleARNs[serviceAccount] != clientArn {
+		return nil, fmt.Errorf("service account %q no longer associated with ARN %q", serviceAccount, clientArn)
+	}
+
+	entity, err := parseIamArn(clientArn)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ARN %q when updating login for role %q: %w", clientArn, roleName, err)
+	}
+	if uniqueID == "" && roleEntry.ResolveAWSUniqueIDs {
+		uniqueID, err = b.resolveArnToUniqueIDFunc(ctx, req.Storage, clientArn)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving unique ID of %q: %w", clientArn, err)
+		}
+	}
+	if !webIdentityIsBound(roleEntry, uniqueID, entity, clientArn) {
+		return nil, fmt.Errorf("role %q no longer bound to ARN %q", roleName, clientArn)
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.TTL = roleEntry.TokenTTL
+	resp.Auth.MaxTTL = roleEntry.TokenMaxTTL
+	resp.Auth.Period = roleEntry.TokenPeriod
+	return resp, nil
+}
+
+// webIdentityIsBound reports whether the IAM role associated with a service
+// account satisfies the bound_iam_principal_arn of the role. As with iam
+// logins, the unique ID may be bound, the canonical ARN may be bound when
+// unique IDs aren't resolved, or the full ARN may match a wildcard bind. The
+// full ARN is known from the association, so no lookup is needed for the
+// last check.
+func webIdentityIsBound(roleEntry *awsRoleEntry, uniqueID string, entity *iamEntity, fullArn string) bool {
+	switch {
+	case uniqueID != "" && strutil.StrListContains(roleEntry.BoundIamPrincipalIDs, uniqueID): // check 1 passed
+		return true
+	case !roleEntry.ResolveAWSUniqueIDs && strutil.StrListContains(roleEntry.BoundIamPrincipalARNs, entity.canonicalArn()): // check 2 passed
+		return true
+	}
+	for _, principalARN := range roleEntry.BoundIamPrincipalARNs {
+		if strings.HasSuffix(principalARN, "*") && strutil.GlobbedStringsMatch(principalARN, fullArn) {
+			return true
+		}
+	}
+	return false
+}
+
+const pathLoginWebIdentitySyn = `
+Authenticates a pod using the service account token of EKS Pod Identity or IRSA.
+`
+
+const pathLoginWebIdentityDesc = `
+Pods using EKS Pod Identity or IAM roles for service accounts (IRSA) are given
+a service account token signed by the OIDC issuer of their cluster. This
+endpoint verifies the token against the issuers registered at
+'config/web-identity/<name>', and maps its service account to the associated
+IAM role. The IAM role is then matched against the bound_iam_principal_arn
+of the role as with iam logins, without the need for a signed
+sts:GetCallerIdentity request.
+`
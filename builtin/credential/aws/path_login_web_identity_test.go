@@ -0,0 +1,369 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package awsauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/cap/oidc"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// testWebIdentityToken returns a service account token of default/app signed
+// by the issuer, with the given claims overridden.
+func testWebIdentityToken(t *testing.T, issuer *oidc.TestProvider, overrides map[string]interface{}) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": issuer.Addr(),
+		"aud": []string{"pods.eks.amazonaws.com"},
+		"sub": "system:serviceaccount:default:app",
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range overrides {
+		claims[name] = value
+	}
+
+	key, _, alg, keyID := issuer.SigningKeys()
+	return oidc.TestSignJWT(t, key, string(alg), claims, []byte(keyID))
+}
+
+func testWebIdentityBackend(t *testing.T, issuer *oidc.TestProvider) (*backend, logical.Storage) {
+	t.Helper()
+
+	storage := &logical.InmemStorage{}
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	b, err := Backend(config)
+	require.NoError(t, err)
+	require.NoError(t, b.Setup(context.Background(), config))
+	b.resolveArnToUniqueIDFunc = resolveArnToFakeUniqueId
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/web-identity/cluster",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"issuer":      issuer.Addr(),
+			"jwks_ca_pem": issuer.CACert(),
+			"service_account_role_arns": map[string]interface{}{
+				"default/app":   "arn:aws:iam::123456789012:role/app-role",
+				"default/other": "arn:aws:iam::123456789012:role/pods/other-role",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	return b, storage
+}
+
+func testWebIdentityLogin(t *testing.T, b *backend, s logical.Storage, role, token string) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation:  logical.UpdateOperation,
+		Path:       "login/web-identity",
+		Storage:    s,
+		Connection: &logical.Connection{},
+		Data: map[string]interface{}{
+			"role":               role,
+			"web_identity_token": token,
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	return resp
+}
+
+func TestBackend_pathLoginWebIdentity(t *testing.T) {
+	issuer := oidc.StartTestProvider(t)
+	b, storage := testWebIdentityBackend(t, issuer)
+
+	for name, data := range map[string]map[string]interface{}{
+		"app-role": {
+			"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/app-role",
+			"resolve_aws_unique_ids":  false,
+		},
+		"unique-id": {
+			"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/app-role",
+		},
+		"pods": {
+			"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/pods/*",
+			"resolve_aws_unique_ids":  false,
+		},
+		"ec2": {
+			"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/app-role",
+			"inferred_entity_type":    ec2EntityType,
+			"inferred_aws_region":     "us-east-1",
+			"resolve_aws_unique_ids":  false,
+		},
+	} {
+		data["auth_type"] = iamAuthType
+		data["token_policies"] = "app"
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/" + name,
+			Storage:   storage,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		require.False(t, resp != nil && resp.IsError(), "creating role %q: %#v", name, resp)
+	}
+
+	t.Run("default role", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "", testWebIdentityToken(t, issuer, nil))
+		require.False(t, resp.IsError(), "%#v", resp.Data)
+		require.Equal(t, []string{"app"}, resp.Auth.Policies)
+		require.Equal(t, resp.Auth.InternalData["role_id"], resp.Auth.Alias.Name)
+		require.Equal(t, webIdentityAuthType, resp.Auth.Metadata["auth_type"])
+		require.Equal(t, "123456789012", resp.Auth.Metadata["account_id"])
+		require.Equal(t, "default/app", resp.Auth.Metadata["service_account"])
+		require.Equal(t, "cluster", resp.Auth.Metadata["web_identity_issuer"])
+		require.Equal(t, "app-role", resp.Auth.InternalData["role_name"])
+	})
+
+	t.Run("unique id bind", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "unique-id", testWebIdentityToken(t, issuer, nil))
+		require.False(t, resp.IsError(), "%#v", resp.Data)
+	})
+
+	t.Run("wildcard bind", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "pods", testWebIdentityToken(t, issuer, map[string]interface{}{
+			"sub": "system:serviceaccount:default:other",
+		}))
+		require.False(t, resp.IsError(), "%#v", resp.Data)
+
+		resp = testWebIdentityLogin(t, b, storage, "pods", testWebIdentityToken(t, issuer, nil))
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "does not belong to the role")
+	})
+
+	t.Run("unbound role", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, issuer, map[string]interface{}{
+			"sub": "system:serviceaccount:default:other",
+		}))
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "does not belong to the role")
+	})
+
+	t.Run("inferred entity", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "ec2", testWebIdentityToken(t, issuer, nil))
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("unknown service account", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, issuer, map[string]interface{}{
+			"sub": "system:serviceaccount:kube-system:app",
+		}))
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "not associated with an IAM role")
+	})
+
+	t.Run("bad audience", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, issuer, map[string]interface{}{
+			"aud": []string{"vault"},
+		}))
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		resp := testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, issuer, map[string]interface{}{
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		}))
+		require.True(t, resp.IsError())
+	})
+
+	t.Run("unknown issuer", func(t *testing.T) {
+		other := oidc.StartTestProvider(t)
+		resp := testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, other, nil))
+		require.True(t, resp.IsError())
+		require.Contains(t, resp.Error().Error(), "is not configured")
+	})
+
+	t.Run("forged signature", func(t *testing.T) {
+		other := oidc.StartTestProvider(t)
+		resp := testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, other, map[string]interface{}{
+			"iss": issuer.Addr(),
+		}))
+		require.True(t, resp.IsError())
+	})
+}
+
+func TestBackend_pathLoginWebIdentity_Renew(t *testing.T) {
+	issuer := oidc.StartTestProvider(t)
+	b, storage := testWebIdentityBackend(t, issuer)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/app-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"auth_type":               iamAuthType,
+			"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/app-role",
+			"resolve_aws_unique_ids":  false,
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	// Limit the metadata so that the auth_type is only known internally
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/identity",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"iam_alias":    identityAliasIAMFullArn,
+			"iam_metadata": []string{"account_id"},
+		},
+	})
+	require.NoError(t, err)
+
+	resp = testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, issuer, nil))
+	require.False(t, resp.IsError(), "%#v", resp.Data)
+	require.Equal(t, "arn:aws:iam::123456789012:role/app-role", resp.Auth.Alias.Name)
+	require.NotContains(t, resp.Auth.Metadata, "auth_type")
+	auth := resp.Auth
+
+	emptyLoginFd := &framework.FieldData{
+		Raw:    map[string]interface{}{},
+		Schema: b.pathLoginWebIdentity().Fields,
+	}
+	resp, err = b.pathLoginRenew(context.Background(), generateRenewRequest(storage, auth), emptyLoginFd)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	// Associating the service account with another IAM role revokes the
+	// ability to renew
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/web-identity/cluster",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"service_account_role_arns": map[string]interface{}{
+				"default/app": "arn:aws:iam::123456789012:role/other-role",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	_, err = b.pathLoginRenew(context.Background(), generateRenewRequest(storage, auth), emptyLoginFd)
+	require.ErrorContains(t, err, "no longer associated")
+}
+
+// TestBackend_pathLoginWebIdentity_KeyRotation verifies that the keys of an
+// issuer are fetched again once it rotates them, after the request which
+// first fetched them is done.
+func TestBackend_pathLoginWebIdentity_KeyRotation(t *testing.T) {
+	issuer := oidc.StartTestProvider(t)
+	b, storage := testWebIdentityBackend(t, issuer)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/app-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"auth_type":               iamAuthType,
+			"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/app-role",
+			"resolve_aws_unique_ids":  false,
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation:  logical.UpdateOperation,
+		Path:       "login/web-identity",
+		Storage:    storage,
+		Connection: &logical.Connection{},
+		Data: map[string]interface{}{
+			"role":               "app-role",
+			"web_identity_token": testWebIdentityToken(t, issuer, nil),
+		},
+	})
+	cancel()
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "%#v", resp.Data)
+
+	pub, priv := oidc.TestGenerateKeys(t)
+	issuer.SetSigningKeys(priv, pub, oidc.ES256, "rotated")
+	resp = testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, issuer, nil))
+	require.False(t, resp.IsError(), "%#v", resp.Data)
+}
+
+func TestBackend_pathConfigWebIdentity(t *testing.T) {
+	issuer := oidc.StartTestProvider(t)
+	b, storage := testWebIdentityBackend(t, issuer)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/web-identity/cluster",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Equal(t, issuer.Addr(), resp.Data["issuer"])
+	require.Equal(t, defaultWebIdentityAudiences, resp.Data["bound_audiences"])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "config/web-identity/",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cluster"}, resp.Data["keys"])
+
+	for name, data := range map[string]map[string]interface{}{
+		"missing issuer": {},
+		"duplicate issuer": {
+			"issuer": issuer.Addr(),
+		},
+		"bad service account": {
+			"issuer": "https://oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE",
+			"service_account_role_arns": map[string]interface{}{
+				"app": "arn:aws:iam::123456789012:role/app-role",
+			},
+		},
+		"not a role": {
+			"issuer": "https://oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE",
+			"service_account_role_arns": map[string]interface{}{
+				"default/app": "arn:aws:iam::123456789012:user/app",
+			},
+		},
+		"bad ca": {
+			"issuer":      "https://oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE",
+			"jwks_ca_pem": "not a certificate",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "config/web-identity/other",
+				Storage:   storage,
+				Data:      data,
+			})
+			require.NoError(t, err)
+			require.True(t, resp.IsError())
+		})
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "config/web-identity/cluster",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp = testWebIdentityLogin(t, b, storage, "app-role", testWebIdentityToken(t, issuer, nil))
+	require.True(t, resp.IsError())
+}
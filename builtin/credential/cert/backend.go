@@ -14,6 +14,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-multierror"
 	lru "github.com/hashicorp/golang-lru/v2"
@@ -43,10 +44,15 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 func Backend() *backend {
 	// ignoring the error as it only can occur with <= 0 size
 	cache, _ := lru.New[string, *trusted](defaultRoleCacheSize)
+	cdpCRLCache, _ := lru.New[string, *cdpCRL](defaultCDPCRLCacheSize)
 	b := backend{
 		trustedCache:      cache,
 		trustedCacheLocks: locksutil.CreateLocks(),
+		cdpCRLCache:       cdpCRLCache,
+		cdpCRLLocks:       locksutil.CreateLocks(),
+		cdpHTTPClient:     cleanhttp.DefaultPooledClient(),
 	}
+	b.cdpCRLMaxSize.Store(defaultCDPCRLMaxSize)
 	b.Backend = &framework.Backend{
 		Help: backendHelp,
 		PathsSpecial: &logical.Paths{
@@ -94,6 +100,13 @@ type backend struct {
 	trustedCacheDisabled atomic.Bool
 	trustedCacheLocks    []*locksutil.LockEntry
 	trustedCacheFull     atomic.Pointer[trusted]
+
+	// cdpCRLCache holds the CRLs fetched from the CRL distribution points of
+	// client certificates, keyed by issuer and URL.
+	cdpCRLCache   *lru.Cache[string, *cdpCRL]
+	cdpCRLLocks   []*locksutil.LockEntry
+	cdpCRLMaxSize atomic.Int64
+	cdpHTTPClient *http.Client
 }
 
 func (b *backend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
@@ -150,6 +163,16 @@ func (b *backend) updatedConfig(config *config) {
 		b.trustedCacheDisabled.Store(false)
 	}
 	b.initOCSPClient(config.OcspCacheSize)
+
+	if config.CDPCRLCacheSize == 0 {
+		config.CDPCRLCacheSize = defaultCDPCRLCacheSize
+	}
+	b.cdpCRLCache.Resize(config.CDPCRLCacheSize)
+	if config.CDPCRLMaxSize == 0 {
+		config.CDPCRLMaxSize = defaultCDPCRLMaxSize
+	}
+	b.cdpCRLMaxSize.Store(config.CDPCRLMaxSize)
+
 	b.configUpdated.Store(false)
 }
 
@@ -174,9 +197,13 @@ func (b *backend) fetchCRL(ctx context.Context, storage logical.Storage, name st
 }
 
 func (b *backend) updateCRLs(ctx context.Context, req *logical.Request) error {
+	var errs *multierror.Error
+	if err := b.refreshCDPCRLs(ctx); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
 	b.crlUpdateMutex.Lock()
 	defer b.crlUpdateMutex.Unlock()
-	var errs *multierror.Error
 	for name, crl := range b.crls {
 		if crl.CDP != nil && time.Now().After(crl.CDP.ValidUntil) {
 			if err := b.fetchCRL(ctx, req.Storage, name, &crl); err != nil {
@@ -0,0 +1,222 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cert
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+)
+
+const (
+	defaultCDPCRLCacheSize = 100
+	maxCDPCRLCacheSize     = 10000
+
+	// defaultCDPCRLMaxSize is the default limit, in bytes, of the CRLs fetched
+	// from distribution points.
+	defaultCDPCRLMaxSize = 10 * 1024 * 1024
+
+	// cdpCRLRefreshInterval is how long CRLs without a nextUpdate field are
+	// used before being fetched again.
+	cdpCRLRefreshInterval = time.Hour
+
+	// cdpCRLRefreshWindow is how long before they become stale CRLs are
+	// refreshed in the background, so that logins rarely wait for a fetch.
+	cdpCRLRefreshWindow = 2 * time.Minute
+
+	cdpCRLFetchTimeout = 30 * time.Second
+)
+
+// cdpCRL is a CRL fetched from a CRL distribution point of a certificate, and
+// verified to be signed by the issuer of that certificate.
+type cdpCRL struct {
+	url        string
+	issuer     *x509.Certificate
+	revoked    map[string]struct{}
+	fetchedAt  time.Time
+	nextUpdate time.Time
+}
+
+// staleAt returns the time after which the CRL should no longer be used.
+func (c *cdpCRL) staleAt() time.Time {
+	if c.nextUpdate.IsZero() {
+		return c.fetchedAt.Add(cdpCRLRefreshInterval)
+	}
+	return c.nextUpdate
+}
+
+func cdpCRLCacheKey(crlURL string, issuer *x509.Certificate) string {
+	fingerprint := sha256.Sum256(issuer.Raw)
+	return hex.EncodeToString(fingerprint[:]) + "|" + crlURL
+}
+
+// checkForChainInCDPCRLs checks the certificates of the chain against the CRLs
+// published at their CRL distribution points. It returns false if any of them
+// is revoked. Errors retrieving the CRLs fail the check, unless failOpen is
+// set, in which case the certificate is assumed not to be revoked.
+func (b *backend) checkForChainInCDPCRLs(ctx context.Context, chain []*x509.Certificate, failOpen bool) (bool, error) {
+	for i := 0; i < len(chain)-1; i++ {
+		cert, issuer := chain[i], chain[i+1]
+		if len(cert.CRLDistributionPoints) == 0 {
+			continue
+		}
+		// CRLs can only be verified when the issuer is part of the chain
+		if cert.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+
+		revoked, err := b.cdpCRLRevoked(ctx, cert, issuer)
+		if err != nil {
+			if failOpen {
+				b.Logger().Warn("CDP CRL checking is set to fail-open, and could not retrieve "+
+					"CRL based revocation but proceeding.", "serial", cert.SerialNumber.String(), "detail", err)
+				continue
+			}
+			return false, fmt.Errorf("failed to check the revocation status of certificate %s: %w", cert.SerialNumber.String(), err)
+		}
+		if revoked {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// cdpCRLRevoked returns whether cert is revoked according to the first CRL
+// which can be retrieved from its distribution points.
+func (b *backend) cdpCRLRevoked(ctx context.Context, cert, issuer *x509.Certificate) (bool, error) {
+	var errs *multierror.Error
+	for _, cdp := range cert.CRLDistributionPoints {
+		if !strings.HasPrefix(cdp, "http://") && !strings.HasPrefix(cdp, "https://") {
+			continue
+		}
+
+		crl, err := b.getCDPCRL(ctx, cdp, issuer)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		_, revoked := crl.revoked[cert.SerialNumber.String()]
+		return revoked, nil
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return false, err
+	}
+	// None of the distribution points is supported
+	return false, nil
+}
+
+// getCDPCRL returns the CRL published at crlURL by issuer, from the cache if it
+// is still current.
+func (b *backend) getCDPCRL(ctx context.Context, crlURL string, issuer *x509.Certificate) (*cdpCRL, error) {
+	key := cdpCRLCacheKey(crlURL, issuer)
+	if crl, ok := b.cdpCRLCache.Get(key); ok && time.Now().Before(crl.staleAt()) {
+		return crl, nil
+	}
+
+	lock := locksutil.LockForKey(b.cdpCRLLocks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Another request may have fetched the CRL while we were waiting
+	if crl, ok := b.cdpCRLCache.Get(key); ok && time.Now().Before(crl.staleAt()) {
+		return crl, nil
+	}
+
+	crl, err := b.fetchCDPCRL(ctx, crlURL, issuer)
+	if err != nil {
+		return nil, err
+	}
+	b.cdpCRLCache.Add(key, crl)
+	return crl, nil
+}
+
+// fetchCDPCRL downloads the CRL published at crlURL and verifies it was
+// signed by issuer.
+func (b *backend) fetchCDPCRL(ctx context.Context, crlURL string, issuer *x509.Certificate) (*cdpCRL, error) {
+	ctx, cancel := context.WithTimeout(ctx, cdpCRLFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, crlURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := b.cdpHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code %d fetching CRL from %s", response.StatusCode, crlURL)
+	}
+
+	maxSize := b.cdpCRLMaxSize.Load()
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("CRL fetched from %s exceeds the maximum size of %d bytes", crlURL, maxSize)
+	}
+
+	list, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL fetched from %s: %w", crlURL, err)
+	}
+	if err := list.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("CRL fetched from %s is not signed by the certificate issuer: %w", crlURL, err)
+	}
+
+	crl := &cdpCRL{
+		url:        crlURL,
+		issuer:     issuer,
+		revoked:    make(map[string]struct{}, len(list.RevokedCertificateEntries)),
+		fetchedAt:  time.Now(),
+		nextUpdate: list.NextUpdate,
+	}
+	for _, entry := range list.RevokedCertificateEntries {
+		crl.revoked[entry.SerialNumber.String()] = struct{}{}
+	}
+	return crl, nil
+}
+
+// refreshCDPCRLs fetches the cached CRLs which are about to become stale. CRLs
+// which can't be fetched are kept until they are stale, after which logins try
+// to fetch them again.
+func (b *backend) refreshCDPCRLs(ctx context.Context) error {
+	var errs *multierror.Error
+	for _, key := range b.cdpCRLCache.Keys() {
+		crl, ok := b.cdpCRLCache.Peek(key)
+		if !ok || time.Now().Add(cdpCRLRefreshWindow).Before(crl.staleAt()) {
+			continue
+		}
+
+		lock := locksutil.LockForKey(b.cdpCRLLocks, key)
+		lock.Lock()
+		refreshed, err := b.fetchCDPCRL(ctx, crl.url, crl.issuer)
+		if err == nil {
+			b.cdpCRLCache.Add(key, refreshed)
+		}
+		lock.Unlock()
+
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return fmt.Errorf("failed to refresh CDP CRLs: %w", err)
+	}
+	return nil
+}
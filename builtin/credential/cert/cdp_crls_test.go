@@ -0,0 +1,277 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cert
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// testCDPServer serves a CRL signed by the CA of a client certificate, whose
+// CRL distribution point points to the server.
+type testCDPServer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	caPEM     []byte
+	ca        *x509.Certificate
+	caKey     *certutil.ParsedCertBundle
+	serial    *big.Int
+	connState tls.ConnectionState
+
+	l        sync.Mutex
+	crl      []byte
+	status   int
+	requests int
+}
+
+func newTestCDPServer(t *testing.T) *testCDPServer {
+	t.Helper()
+
+	s := &testCDPServer{t: t, status: http.StatusOK}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.l.Lock()
+		defer s.l.Unlock()
+
+		s.requests++
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		w.Write(s.crl)
+	}))
+	t.Cleanup(s.server.Close)
+
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: "example.com",
+		},
+		DNSNames:    []string{"example.com"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+		SerialNumber:          big.NewInt(mathrand.Int63()),
+		NotBefore:             time.Now().Add(-30 * time.Second),
+		NotAfter:              time.Now().Add(262980 * time.Hour),
+		CRLDistributionPoints: []string{"ldap://ldap.example.com/cn=crl", s.server.URL + "/crl"},
+	}
+	tempDir, connState, err := generateTestCertAndConnState(t, template)
+	if tempDir != "" {
+		defer os.RemoveAll(tempDir)
+	}
+	require.NoError(t, err)
+
+	s.caPEM, err = os.ReadFile(filepath.Join(tempDir, "ca_cert.pem"))
+	require.NoError(t, err)
+	s.ca = parsePEM(s.caPEM)[0]
+	caKeyPEM, err := os.ReadFile(filepath.Join(tempDir, "ca_key.pem"))
+	require.NoError(t, err)
+	s.caKey, err = certutil.ParsePEMBundle(string(caKeyPEM))
+	require.NoError(t, err)
+	s.serial = template.SerialNumber
+	s.connState = connState
+
+	return s
+}
+
+// publish signs and serves a CRL revoking the given serials.
+func (s *testCDPServer) publish(nextUpdate time.Time, revoked ...*big.Int) {
+	s.t.Helper()
+
+	template := &x509.RevocationList{
+		Number:     big.NewInt(time.Now().UnixNano()),
+		ThisUpdate: time.Now(),
+		NextUpdate: nextUpdate,
+	}
+	for _, serial := range revoked {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: time.Now(),
+		})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, template, s.ca, s.caKey.PrivateKey)
+	require.NoError(s.t, err)
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.crl = crl
+	s.status = http.StatusOK
+}
+
+func (s *testCDPServer) fail(status int) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.status = status
+}
+
+func (s *testCDPServer) requestCount() int {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.requests
+}
+
+func testCDPBackend(t *testing.T, s *testCDPServer, failOpen bool) (*backend, logical.Storage) {
+	t.Helper()
+
+	storage := &logical.InmemStorage{}
+	lb, err := Factory(context.Background(), &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: 300 * time.Second,
+			MaxLeaseTTLVal:     1800 * time.Second,
+		},
+		StorageView: storage,
+	})
+	require.NoError(t, err)
+	b := lb.(*backend)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "certs/web",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"certificate":       string(s.caPEM),
+			"policies":          "foo",
+			"cdp_crl_enabled":   true,
+			"cdp_crl_fail_open": failOpen,
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	return b, storage
+}
+
+func testCDPLogin(t *testing.T, b *backend, storage logical.Storage, s *testCDPServer) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   storage,
+		Connection: &logical.Connection{
+			ConnState: &s.connState,
+		},
+		Data: map[string]interface{}{
+			"name": "web",
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	return resp
+}
+
+func TestCert_CDPCRL(t *testing.T) {
+	s := newTestCDPServer(t)
+	b, storage := testCDPBackend(t, s, false)
+
+	s.publish(time.Now().Add(time.Hour), big.NewInt(1))
+	resp := testCDPLogin(t, b, storage, s)
+	require.False(t, resp.IsError(), "%v", resp)
+	require.NotNil(t, resp.Auth)
+	require.Equal(t, 1, s.requestCount())
+
+	// The cached CRL is used until its next update
+	s.publish(time.Now().Add(time.Hour), s.serial)
+	resp = testCDPLogin(t, b, storage, s)
+	require.NotNil(t, resp.Auth)
+	require.Equal(t, 1, s.requestCount())
+
+	b.cdpCRLCache.Purge()
+	resp = testCDPLogin(t, b, storage, s)
+	require.True(t, resp.IsError())
+	require.Nil(t, resp.Auth)
+}
+
+func TestCert_CDPCRLFailureMode(t *testing.T) {
+	s := newTestCDPServer(t)
+	s.fail(http.StatusInternalServerError)
+
+	b, storage := testCDPBackend(t, s, false)
+	resp := testCDPLogin(t, b, storage, s)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "unexpected response code 500")
+
+	b, storage = testCDPBackend(t, s, true)
+	resp = testCDPLogin(t, b, storage, s)
+	require.False(t, resp.IsError(), "%v", resp)
+	require.NotNil(t, resp.Auth)
+
+	// CRLs which are not signed by the issuer are treated as unavailable
+	other := newTestCDPServer(t)
+	other.publish(time.Now().Add(time.Hour))
+	s.l.Lock()
+	s.crl = other.crl
+	s.status = http.StatusOK
+	s.l.Unlock()
+
+	b, storage = testCDPBackend(t, s, false)
+	resp = testCDPLogin(t, b, storage, s)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "not signed by the certificate issuer")
+}
+
+func TestCert_CDPCRLMaxSize(t *testing.T) {
+	s := newTestCDPServer(t)
+	s.publish(time.Now().Add(time.Hour))
+	b, storage := testCDPBackend(t, s, false)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"cdp_crl_max_size": 16,
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp = testCDPLogin(t, b, storage, s)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "exceeds the maximum size of 16 bytes")
+}
+
+func TestCert_CDPCRLBackgroundRefresh(t *testing.T) {
+	s := newTestCDPServer(t)
+	b, storage := testCDPBackend(t, s, false)
+
+	s.publish(time.Now().Add(time.Minute))
+	resp := testCDPLogin(t, b, storage, s)
+	require.NotNil(t, resp.Auth)
+	require.Equal(t, 1, s.requestCount())
+
+	// The CRL is about to become stale, so it is fetched again in the
+	// background and picks up the revocation
+	s.publish(time.Now().Add(time.Hour), s.serial)
+	require.NoError(t, b.PeriodicFunc(context.Background(), &logical.Request{Storage: storage}))
+	require.Equal(t, 2, s.requestCount())
+
+	resp = testCDPLogin(t, b, storage, s)
+	require.True(t, resp.IsError())
+	require.Equal(t, 2, s.requestCount())
+
+	// CRLs which are still current are not fetched again
+	require.NoError(t, b.PeriodicFunc(context.Background(), &logical.Request{Storage: storage}))
+	require.Equal(t, 2, s.requestCount())
+}
@@ -101,6 +101,16 @@ from the AuthorityInformationAccess extension on the certificate being inspected
 				Default:     4,
 				Description: "The number of retries the OCSP client should attempt per query.",
 			},
+			"cdp_crl_enabled": {
+				Type:        framework.TypeBool,
+				Default:     false,
+				Description: "Whether to check certificates at login against the CRLs fetched from their CRL distribution points. The CRLs are cached and refreshed in the background.",
+			},
+			"cdp_crl_fail_open": {
+				Type:        framework.TypeBool,
+				Default:     false,
+				Description: "If set to true, if a CRL can't be fetched from the CRL distribution points of a certificate, login will proceed rather than failing.  If false, failing to get a CRL fails the request.",
+			},
 			"allowed_names": {
 				Type: framework.TypeCommaStringSlice,
 				Description: `A comma-separated list of names.
@@ -321,6 +331,8 @@ func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *fra
 		"ocsp_query_all_servers":       cert.OcspQueryAllServers,
 		"ocsp_this_update_max_age":     int64(cert.OcspThisUpdateMaxAge.Seconds()),
 		"ocsp_max_retries":             cert.OcspMaxRetries,
+		"cdp_crl_enabled":              cert.CDPCRLEnabled,
+		"cdp_crl_fail_open":            cert.CDPCRLFailOpen,
 	}
 	cert.PopulateTokenData(data)
 
@@ -393,6 +405,12 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 			return nil, fmt.Errorf("ocsp_max_retries can not be a negative number")
 		}
 	}
+	if cdpCRLEnabled, ok := d.GetOk("cdp_crl_enabled"); ok {
+		cert.CDPCRLEnabled = cdpCRLEnabled.(bool)
+	}
+	if cdpCRLFailOpen, ok := d.GetOk("cdp_crl_fail_open"); ok {
+		cert.CDPCRLFailOpen = cdpCRLFailOpen.(bool)
+	}
 	if displayNameRaw, ok := d.GetOk("display_name"); ok {
 		cert.DisplayName = displayNameRaw.(string)
 	}
@@ -545,6 +563,9 @@ type CertEntry struct {
 	OcspQueryAllServers  bool
 	OcspThisUpdateMaxAge time.Duration
 	OcspMaxRetries       int
+
+	CDPCRLEnabled  bool
+	CDPCRLFailOpen bool
 }
 
 const pathCertHelpSyn = `
@@ -42,6 +42,16 @@ func pathConfig(b *backend) *framework.Path {
 				Default:     defaultRoleCacheSize,
 				Description: `The size of the in memory role cache`,
 			},
+			"cdp_crl_cache_size": {
+				Type:        framework.TypeInt,
+				Default:     defaultCDPCRLCacheSize,
+				Description: `The number of CRLs fetched from CRL distribution points kept in memory, shared by all configured certs`,
+			},
+			"cdp_crl_max_size": {
+				Type:        framework.TypeInt,
+				Default:     defaultCDPCRLMaxSize,
+				Description: `The maximum size in bytes of a CRL fetched from a CRL distribution point. Larger CRLs are treated as unavailable.`,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -87,6 +97,20 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 		}
 		config.RoleCacheSize = cacheSize
 	}
+	if cacheSizeRaw, ok := data.GetOk("cdp_crl_cache_size"); ok {
+		cacheSize := cacheSizeRaw.(int)
+		if cacheSize < 1 || cacheSize > maxCDPCRLCacheSize {
+			return logical.ErrorResponse("invalid cdp crl cache size, must be >= 1 and <= %d", maxCDPCRLCacheSize), nil
+		}
+		config.CDPCRLCacheSize = cacheSize
+	}
+	if maxSizeRaw, ok := data.GetOk("cdp_crl_max_size"); ok {
+		maxSize := maxSizeRaw.(int)
+		if maxSize < 1 {
+			return logical.ErrorResponse("invalid cdp crl max size, must be >= 1"), nil
+		}
+		config.CDPCRLMaxSize = int64(maxSize)
+	}
 	if err := b.storeConfig(ctx, req.Storage, config); err != nil {
 		return nil, err
 	}
@@ -104,6 +128,8 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *f
 		"enable_identity_alias_metadata": cfg.EnableIdentityAliasMetadata,
 		"ocsp_cache_size":                cfg.OcspCacheSize,
 		"role_cache_size":                cfg.RoleCacheSize,
+		"cdp_crl_cache_size":             cfg.CDPCRLCacheSize,
+		"cdp_crl_max_size":               cfg.CDPCRLMaxSize,
 	}
 
 	return &logical.Response{
@@ -129,8 +155,10 @@ func (b *backend) Config(ctx context.Context, s logical.Storage) (*config, error
 }
 
 type config struct {
-	DisableBinding              bool `json:"disable_binding"`
-	EnableIdentityAliasMetadata bool `json:"enable_identity_alias_metadata"`
-	OcspCacheSize               int  `json:"ocsp_cache_size"`
-	RoleCacheSize               int  `json:"role_cache_size"`
+	DisableBinding              bool  `json:"disable_binding"`
+	EnableIdentityAliasMetadata bool  `json:"enable_identity_alias_metadata"`
+	OcspCacheSize               int   `json:"ocsp_cache_size"`
+	RoleCacheSize               int   `json:"role_cache_size"`
+	CDPCRLCacheSize             int   `json:"cdp_crl_cache_size"`
+	CDPCRLMaxSize               int64 `json:"cdp_crl_max_size"`
 }
@@ -348,4 +348,8 @@ in which none of the serials are revoked by any CRL -- allows authentication.
 This allows authentication to succeed when interim parts of one chain have been
 revoked; for instance, if a certificate is signed by two intermediate CAs due to
 one of them expiring.
+
+Alternatively, setting "cdp_crl_enabled" on a trusted certificate checks client
+certificates against the CRLs published at their own CRL Distribution Points,
+which are fetched, cached and refreshed automatically.
 `
@@ -378,6 +378,13 @@ func (b *backend) matchesConstraints(ctx context.Context, clientCert *x509.Certi
 		}
 		soFar = soFar && ocspGood
 	}
+	if soFar && config.Entry.CDPCRLEnabled {
+		cdpGood, err := b.checkForChainInCDPCRLs(ctx, trustedChain, config.Entry.CDPCRLFailOpen)
+		if err != nil {
+			return false, err
+		}
+		soFar = soFar && cdpGood
+	}
 	return soFar, nil
 }
 
@@ -0,0 +1,209 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/google/go-github/github"
+)
+
+// appInstallationToken is an installation access token of the configured
+// GitHub App, used to look up organization and team memberships.
+type appInstallationToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// parseAppPrivateKey parses the PEM encoded RSA private key of a GitHub App.
+func parseAppPrivateKey(keyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("app_private_key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse app_private_key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("app_private_key must be an RSA private key")
+	}
+	return rsaKey, nil
+}
+
+// appJWT returns a short-lived JWT authenticating as the GitHub App itself.
+func (c *config) appJWT() (string, error) {
+	key, err := parseAppPrivateKey(c.AppPrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// GitHub rejects JWTs valid for more than ten minutes, and recommends
+	// backdating them to allow for clock drift
+	now := time.Now()
+	claims := jwt.Claims{
+		Issuer:   strconv.FormatInt(c.AppID, 10),
+		IssuedAt: jwt.NewNumericDate(now.Add(-time.Minute)),
+		Expiry:   jwt.NewNumericDate(now.Add(9 * time.Minute)),
+	}
+	return jwt.Signed(signer).Claims(claims).CompactSerialize()
+}
+
+// appClient returns a GitHub client authenticated as the installation of the
+// configured GitHub App in the organization.
+func (b *backend) appClient(ctx context.Context, c *config) (*github.Client, error) {
+	token, err := b.appInstallationToken(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a GitHub App installation token: %w", err)
+	}
+	return b.clientWithBaseURL(token, c.BaseURL)
+}
+
+func (b *backend) appInstallationToken(ctx context.Context, c *config) (string, error) {
+	b.appTokenLock.Lock()
+	defer b.appTokenLock.Unlock()
+
+	if b.appToken != nil && time.Now().Add(time.Minute).Before(b.appToken.expiresAt) {
+		return b.appToken.token, nil
+	}
+
+	appJWT, err := c.appJWT()
+	if err != nil {
+		return "", err
+	}
+	client, err := b.clientWithBaseURL(appJWT, c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	installationID := c.AppInstallationID
+	if installationID == 0 {
+		installation, _, err := client.Apps.FindOrganizationInstallation(ctx, c.Organization)
+		if err != nil {
+			return "", fmt.Errorf("failed to find the installation of the app in %q: %w", c.Organization, err)
+		}
+		installationID = installation.GetID()
+	}
+
+	// The go-github client uses the legacy endpoint, which GitHub removed
+	req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("app/installations/%d/access_tokens", installationID), nil)
+	if err != nil {
+		return "", err
+	}
+	token := new(github.InstallationToken)
+	if _, err := client.Do(ctx, req, token); err != nil {
+		return "", err
+	}
+
+	b.appToken = &appInstallationToken{
+		token:     token.GetToken(),
+		expiresAt: token.GetExpiresAt(),
+	}
+	return b.appToken.token, nil
+}
+
+func (b *backend) resetAppToken() {
+	b.appTokenLock.Lock()
+	defer b.appTokenLock.Unlock()
+	b.appToken = nil
+}
+
+// appMembership verifies with the GitHub App that the user is a member of the
+// configured organization, and returns the names of the teams of the
+// organization the user is an active member of.
+func (b *backend) appMembership(ctx context.Context, c *config, username string) (*github.Organization, []string, error) {
+	client, err := b.appClient(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	org, _, err := client.Organizations.GetByID(ctx, c.OrganizationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	isMember, _, err := client.Organizations.IsMember(ctx, org.GetLogin(), username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isMember {
+		return nil, nil, errors.New("user is not part of required org")
+	}
+
+	teamOpt := &github.ListOptions{
+		PerPage: 100,
+	}
+
+	var allTeams []*github.Team
+	for {
+		teams, resp, err := client.Teams.ListTeams(ctx, org.GetLogin(), teamOpt)
+		if err != nil {
+			return nil, nil, err
+		}
+		allTeams = append(allTeams, teams...)
+		if resp.NextPage == 0 {
+			break
+		}
+		teamOpt.Page = resp.NextPage
+	}
+
+	var teamNames []string
+	for _, t := range allTeams {
+		member, err := isActiveTeamMember(ctx, client, org.GetLogin(), t.GetSlug(), username)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !member {
+			continue
+		}
+
+		teamNames = append(teamNames, t.GetName())
+		if t.GetName() != t.GetSlug() {
+			teamNames = append(teamNames, t.GetSlug())
+		}
+	}
+
+	return org, teamNames, nil
+}
+
+// isActiveTeamMember returns whether the user is an active member of the team,
+// either directly or through a child team.
+func isActiveTeamMember(ctx context.Context, client *github.Client, org, teamSlug, username string) (bool, error) {
+	u := fmt.Sprintf("orgs/%s/teams/%s/memberships/%s", url.PathEscape(org), url.PathEscape(teamSlug), url.PathEscape(username))
+	req, err := client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+
+	membership := new(github.Membership)
+	resp, err := client.Do(ctx, req, membership)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return membership.GetState() == "active", nil
+}
@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAppTestServer mocks the GitHub API of an organization where a GitHub
+// App is installed. Organization and team memberships can only be looked up
+// with the installation token, while the user token can only identify the
+// user.
+func setupAppTestServer(t *testing.T, key *rsa.PrivateKey, tokenRequests *int32) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/app/") || r.URL.Path == "/orgs/foo-org/installation":
+			token, err := jwt.ParseSigned(auth)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var claims jwt.Claims
+			if err := token.Claims(&key.PublicKey, &claims); err != nil || claims.Issuer != "1234" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch {
+			case r.URL.Path == "/orgs/foo-org/installation":
+				fmt.Fprintln(w, `{"id": 42}`)
+			case r.Method == http.MethodPost && r.URL.Path == "/app/installations/42/access_tokens":
+				atomic.AddInt32(tokenRequests, 1)
+				fmt.Fprintf(w, `{"token": "installation-token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case auth == "user-token":
+			if r.URL.Path != "/user" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprintln(w, getUserResponse)
+		case auth == "installation-token":
+			switch r.URL.Path {
+			case "/organizations/12345", "/orgs/foo-org":
+				fmt.Fprintln(w, getOrgResponse)
+			case "/orgs/foo-org/members/user-foo":
+				w.WriteHeader(http.StatusNoContent)
+			case "/orgs/foo-org/teams":
+				fmt.Fprintln(w, `[{"id": 1, "name": "Foo team", "slug": "foo-team"}, {"id": 2, "name": "other", "slug": "other"}]`)
+			case "/orgs/foo-org/teams/foo-team/memberships/user-foo":
+				fmt.Fprintln(w, `{"state": "active", "role": "member"}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func testAppPrivateKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	return key, string(keyPEM)
+}
+
+// TestGitHub_AppLogin tests that memberships are looked up as the GitHub App
+// when one is configured
+func TestGitHub_AppLogin(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	key, keyPEM := testAppPrivateKey(t)
+	var tokenRequests int32
+	ts := setupAppTestServer(t, key, &tokenRequests)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "config",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"organization":    "foo-org",
+			"base_url":        ts.URL,
+			"app_id":          1234,
+			"app_private_key": keyPEM,
+		},
+		Storage: s,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "config",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(12345), resp.Data["organization_id"])
+	require.Equal(t, int64(1234), resp.Data["app_id"])
+	require.NotContains(t, resp.Data, "app_private_key")
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "map/teams/foo-team",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"value": "team-policy",
+		},
+		Storage: s,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "login",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"token": "user-token",
+			},
+			Storage: s,
+		})
+		require.NoError(t, err)
+		require.NoError(t, resp.Error())
+		require.Equal(t, map[string]string{"org": "foo-org", "username": "user-foo"}, resp.Auth.Metadata)
+		require.Equal(t, []string{"team-policy"}, resp.Auth.Policies)
+		require.Len(t, resp.Auth.GroupAliases, 2)
+	}
+
+	// The installation token is requested once to look up the organization ID
+	// when writing the config, and cached across logins afterwards
+	require.Equal(t, int32(2), atomic.LoadInt32(&tokenRequests))
+}
+
+func TestGitHub_AppConfigValidation(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	_, keyPEM := testAppPrivateKey(t)
+
+	for _, data := range []map[string]interface{}{
+		{"app_id": 1234},
+		{"app_private_key": keyPEM},
+		{"app_id": 1234, "app_private_key": "not a key"},
+	} {
+		data["organization"] = "foo-org"
+		data["organization_id"] = 12345
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "config",
+			Operation: logical.UpdateOperation,
+			Data:      data,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError(), "%v", data)
+	}
+}
@@ -5,9 +5,12 @@ package github
 
 import (
 	"context"
+	"fmt"
 	"net/url"
+	"sync"
 
 	"github.com/google/go-github/github"
+	"github.com/hashicorp/cap/jwt"
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -99,13 +102,23 @@ func Backend() *backend {
 		PathsSpecial: &logical.Paths{
 			Unauthenticated: []string{
 				"login",
+				"login/actions",
 			},
 		},
 
-		Paths:       append([]*framework.Path{pathConfig(&b), pathLogin(&b)}, allPaths...),
+		Paths: append([]*framework.Path{
+			pathConfig(&b),
+			pathLogin(&b),
+			pathLoginActions(&b),
+			pathActionsRoleList(&b),
+			pathActionsRole(&b),
+		}, allPaths...),
 		AuthRenew:   b.pathLoginRenew,
+		Invalidate:  b.invalidate,
+		Clean:       b.cleanup,
 		BackendType: logical.TypeCredential,
 	}
+	b.actionsCtx, b.actionsCtxCancel = context.WithCancel(context.Background())
 
 	return &b
 }
@@ -116,6 +129,39 @@ type backend struct {
 	TeamMap *framework.PolicyMap
 
 	UserMap *framework.PolicyMap
+
+	// appToken caches the installation token of the configured GitHub App
+	appToken     *appInstallationToken
+	appTokenLock sync.Mutex
+
+	// actionsKeySet caches the keys of the GitHub Actions OIDC issuer.
+	// actionsKeySetGen is incremented whenever it is reset, so that a key set
+	// discovered meanwhile is not cached.
+	actionsKeySet     jwt.KeySet
+	actionsKeySetGen  uint64
+	actionsKeySetLock sync.Mutex
+
+	// actionsCtx is the context of the key set of the GitHub Actions OIDC
+	// issuer, which keeps fetching keys after the request which created it.
+	// It is canceled when the backend is cleaned up.
+	actionsCtx       context.Context
+	actionsCtxCancel context.CancelFunc
+}
+
+func (b *backend) cleanup(_ context.Context) {
+	b.actionsCtxCancel()
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	if key == "config" {
+		b.reset()
+	}
+}
+
+// reset discards the state derived from the configuration.
+func (b *backend) reset() {
+	b.resetAppToken()
+	b.resetActionsKeySet()
 }
 
 // Client returns the GitHub client to communicate to GitHub via the
@@ -137,6 +183,25 @@ func (b *backend) Client(token string) (*github.Client, error) {
 	return client, nil
 }
 
+// clientWithBaseURL returns a GitHub client using the given token and the
+// configured base URL, if any.
+func (b *backend) clientWithBaseURL(token, baseURL string) (*github.Client, error) {
+	client, err := b.Client(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseURL != "" {
+		parsedURL, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("successfully parsed base_url when set but failing to parse now: %w", err)
+		}
+		client.BaseURL = parsedURL
+	}
+
+	return client, nil
+}
+
 // tokenSource is an oauth2.TokenSource implementation.
 type tokenSource struct {
 	Value string
@@ -152,7 +217,12 @@ The GitHub credential provider allows authentication via GitHub.
 Users provide a personal access token to log in, and the credential
 provider verifies they're part of the correct organization and then
 maps the user to a set of Vault policies according to the teams they're
-part of.
+part of. When a GitHub App is configured, the memberships are looked up
+as the App rather than with the token of the user.
+
+GitHub Actions workflows can log in with the OIDC token of their job
+using the "login/actions" endpoint, which matches the claims of the
+token against the roles configured at "actions/role/".
 
 After enabling the credential provider, use the "config" route to
 configure it.
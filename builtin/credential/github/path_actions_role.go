@@ -0,0 +1,230 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/tokenutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const actionsRolePrefix = "actions/role/"
+
+func pathActionsRoleList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "actions/role/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixGithub,
+			OperationSuffix: "actions-roles",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.pathActionsRoleList,
+			},
+		},
+
+		HelpSynopsis:    pathActionsRoleHelpSyn,
+		HelpDescription: pathActionsRoleHelpDesc,
+	}
+}
+
+func pathActionsRole(b *backend) *framework.Path {
+	p := &framework.Path{
+		Pattern: "actions/role/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixGithub,
+			OperationSuffix: "actions-role",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"bound_audiences": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of audiences, one of which the "aud" claim of the token must match.`,
+			},
+			"bound_repositories": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of repositories, in the "owner/name" format, the workflow must run in. Supports globbing.`,
+			},
+			"bound_refs": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of git refs, such as "refs/heads/main", the workflow must run for. Supports globbing. If unset, any ref is allowed.`,
+			},
+			"bound_workflow_refs": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of workflow refs, such as "owner/repo/.github/workflows/deploy.yml@refs/heads/main", matched against the "job_workflow_ref" claim. Supports globbing. If unset, any workflow is allowed.`,
+			},
+			"bound_environments": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of deployment environments the job must target. Supports globbing. If unset, any environment, or none, is allowed.`,
+			},
+		},
+
+		ExistenceCheck: b.pathActionsRoleExistenceCheck,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathActionsRoleWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathActionsRoleWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathActionsRoleRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathActionsRoleDelete,
+			},
+		},
+
+		HelpSynopsis:    pathActionsRoleHelpSyn,
+		HelpDescription: pathActionsRoleHelpDesc,
+	}
+
+	tokenutil.AddTokenFields(p.Fields)
+	return p
+}
+
+type actionsRole struct {
+	tokenutil.TokenParams
+
+	BoundAudiences    []string `json:"bound_audiences"`
+	BoundRepositories []string `json:"bound_repositories"`
+	BoundRefs         []string `json:"bound_refs"`
+	BoundWorkflowRefs []string `json:"bound_workflow_refs"`
+	BoundEnvironments []string `json:"bound_environments"`
+}
+
+func (b *backend) actionsRole(ctx context.Context, s logical.Storage, name string) (*actionsRole, error) {
+	entry, err := s.Get(ctx, actionsRolePrefix+strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role actionsRole
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, fmt.Errorf("error reading role: %w", err)
+	}
+	return &role, nil
+}
+
+func (b *backend) pathActionsRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := b.actionsRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathActionsRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, actionsRolePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathActionsRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.actionsRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"bound_audiences":     role.BoundAudiences,
+		"bound_repositories":  role.BoundRepositories,
+		"bound_refs":          role.BoundRefs,
+		"bound_workflow_refs": role.BoundWorkflowRefs,
+		"bound_environments":  role.BoundEnvironments,
+	}
+	role.PopulateTokenData(data)
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathActionsRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(d.Get("name").(string))
+
+	role, err := b.actionsRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &actionsRole{}
+	}
+
+	if err := role.ParseTokenFields(req, d); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if boundAudiences, ok := d.GetOk("bound_audiences"); ok {
+		role.BoundAudiences = boundAudiences.([]string)
+	}
+	if boundRepositories, ok := d.GetOk("bound_repositories"); ok {
+		role.BoundRepositories = boundRepositories.([]string)
+	}
+	if boundRefs, ok := d.GetOk("bound_refs"); ok {
+		role.BoundRefs = boundRefs.([]string)
+	}
+	if boundWorkflowRefs, ok := d.GetOk("bound_workflow_refs"); ok {
+		role.BoundWorkflowRefs = boundWorkflowRefs.([]string)
+	}
+	if boundEnvironments, ok := d.GetOk("bound_environments"); ok {
+		role.BoundEnvironments = boundEnvironments.([]string)
+	}
+
+	if len(role.BoundAudiences) == 0 {
+		return logical.ErrorResponse("bound_audiences must be set"), nil
+	}
+	if len(role.BoundRepositories) == 0 {
+		return logical.ErrorResponse(`bound_repositories must be set, use "*" to allow all the repositories of the organization`), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(actionsRolePrefix+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathActionsRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(d.Get("name").(string))
+	if err := req.Storage.Delete(ctx, actionsRolePrefix+name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathActionsRoleHelpSyn = `
+Manage the roles GitHub Actions workflows can log in with.
+`
+
+const pathActionsRoleHelpDesc = `
+A role maps the GitHub Actions workflows whose OIDC token claims match its
+bound repositories, refs, workflows and environments to the token settings
+and policies of the role. Only workflows running in repositories of the
+configured organization can log in.
+`
@@ -45,6 +45,39 @@ API-compatible authentication server.`,
 					Group: "GitHub Options",
 				},
 			},
+			"app_id": {
+				Type:        framework.TypeInt64,
+				Description: "The ID of a GitHub App installed in the organization. If set, the organization and team memberships of users are looked up as the App instead of with the token of the user.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "App ID",
+					Group: "GitHub App",
+				},
+			},
+			"app_private_key": {
+				Type:        framework.TypeString,
+				Description: "The PEM encoded private key of the GitHub App.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "App private key",
+					Group:     "GitHub App",
+					Sensitive: true,
+				},
+			},
+			"app_installation_id": {
+				Type:        framework.TypeInt64,
+				Description: "The ID of the installation of the GitHub App in the organization. If unset, it is looked up from the organization.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "App installation ID",
+					Group: "GitHub App",
+				},
+			},
+			"actions_oidc_issuer": {
+				Type:        framework.TypeString,
+				Description: fmt.Sprintf("The issuer of the OIDC tokens of GitHub Actions, used by the login/actions endpoint. Defaults to %q; GitHub Enterprise Server uses \"https://HOSTNAME/_services/token\".", defaultActionsOIDCIssuer),
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "Actions OIDC issuer",
+					Group: "GitHub Actions",
+				},
+			},
 			"ttl": {
 				Type:        framework.TypeDurationSecond,
 				Description: tokenutil.DeprecationText("token_ttl"),
@@ -113,11 +146,50 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 		c.BaseURL = baseURL
 	}
 
+	if appIDRaw, ok := data.GetOk("app_id"); ok {
+		c.AppID = appIDRaw.(int64)
+	}
+	if appPrivateKeyRaw, ok := data.GetOk("app_private_key"); ok {
+		c.AppPrivateKey = appPrivateKeyRaw.(string)
+	}
+	if appInstallationIDRaw, ok := data.GetOk("app_installation_id"); ok {
+		c.AppInstallationID = appInstallationIDRaw.(int64)
+	}
+	if (c.AppID != 0) != (c.AppPrivateKey != "") {
+		return logical.ErrorResponse("app_id and app_private_key must be set together"), nil
+	}
+	if c.AppPrivateKey != "" {
+		if _, err := parseAppPrivateKey(c.AppPrivateKey); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	if issuerRaw, ok := data.GetOk("actions_oidc_issuer"); ok {
+		issuer := issuerRaw.(string)
+		if issuer != "" {
+			if _, err := url.Parse(issuer); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("error parsing given actions_oidc_issuer: %s", err)), nil
+			}
+		}
+		c.ActionsOIDCIssuer = issuer
+	}
+
+	// Discard the App token and keys of the previous configuration
+	b.reset()
+
 	if c.OrganizationID == 0 {
-		githubToken := os.Getenv("VAULT_AUTH_CONFIG_GITHUB_TOKEN")
-		client, err := b.Client(githubToken)
-		if err != nil {
-			return nil, err
+		var client *github.Client
+		if c.AppID != 0 {
+			client, err = b.appClient(ctx, c)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		} else {
+			githubToken := os.Getenv("VAULT_AUTH_CONFIG_GITHUB_TOKEN")
+			client, err = b.Client(githubToken)
+			if err != nil {
+				return nil, err
+			}
 		}
 		// ensure our client has the BaseURL if it was provided
 		if parsedURL != nil {
@@ -157,6 +229,7 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 	if err := req.Storage.Put(ctx, entry); err != nil {
 		return nil, err
 	}
+	b.reset()
 
 	if len(resp.Warnings) == 0 {
 		return nil, nil
@@ -179,6 +252,13 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, data
 		"organization":    config.Organization,
 		"base_url":        config.BaseURL,
 	}
+	if config.AppID != 0 {
+		d["app_id"] = config.AppID
+		d["app_installation_id"] = config.AppInstallationID
+	}
+	if config.ActionsOIDCIssuer != "" {
+		d["actions_oidc_issuer"] = config.ActionsOIDCIssuer
+	}
 	config.PopulateTokenData(d)
 
 	if config.TTL > 0 {
@@ -228,6 +308,11 @@ type config struct {
 	BaseURL        string        `json:"base_url" structs:"base_url" mapstructure:"base_url"`
 	TTL            time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
 	MaxTTL         time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
+
+	AppID             int64  `json:"app_id,omitempty"`
+	AppPrivateKey     string `json:"app_private_key,omitempty"`
+	AppInstallationID int64  `json:"app_installation_id,omitempty"`
+	ActionsOIDCIssuer string `json:"actions_oidc_issuer,omitempty"`
 }
 
 func (c *config) setOrganizationID(ctx context.Context, client *github.Client) error {
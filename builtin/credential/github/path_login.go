@@ -7,7 +7,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
 
 	"github.com/google/go-github/github"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -107,6 +106,10 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 		return nil, fmt.Errorf("request auth was nil")
 	}
 
+	if roleName, ok := req.Auth.InternalData["actions_role"].(string); ok {
+		return b.pathLoginActionsRenew(ctx, req, roleName)
+	}
+
 	tokenRaw, ok := req.Auth.InternalData["token"]
 	if !ok {
 		return nil, fmt.Errorf("token created in previous version of Vault cannot be validated properly at renewal time")
@@ -161,19 +164,11 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 		}
 	}
 
-	client, err := b.Client(token)
+	client, err := b.clientWithBaseURL(token, config.BaseURL)
 	if err != nil {
 		return nil, err
 	}
 
-	if config.BaseURL != "" {
-		parsedURL, err := url.Parse(config.BaseURL)
-		if err != nil {
-			return nil, fmt.Errorf("successfully parsed base_url when set but failing to parse now: %w", err)
-		}
-		client.BaseURL = parsedURL
-	}
-
 	if config.OrganizationID == 0 {
 		// Previously we did not verify using the Org ID. So if the Org ID is
 		// not set, we will trust-on-first-use and set it now.
@@ -200,7 +195,69 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 		return nil, err
 	}
 
-	// Verify that the user is part of the organization
+	// Verify that the user is part of the organization, and get the teams
+	// that this user is part of to determine the policies
+	var org *github.Organization
+	var teamNames []string
+	if config.AppID != 0 {
+		org, teamNames, err = b.appMembership(ctx, config, user.GetLogin())
+	} else {
+		org, teamNames, err = userMembership(ctx, client, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if org.GetLogin() != config.Organization {
+		warningMsg := fmt.Sprintf(
+			"the organization name has changed to %q. It is recommended to verify and update the organization name in the config: %s=%d",
+			org.GetLogin(),
+			"organization_id",
+			config.OrganizationID,
+		)
+		b.Logger().Warn(warningMsg)
+		warnings = append(warnings, warningMsg)
+	}
+
+	groupPoliciesList, err := b.TeamMap.Policies(ctx, req.Storage, teamNames...)
+	if err != nil {
+		return nil, err
+	}
+
+	userPoliciesList, err := b.UserMap.Policies(ctx, req.Storage, []string{*user.Login}...)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyResp := &verifyCredentialsResp{
+		User:      user,
+		Org:       org,
+		Policies:  append(groupPoliciesList, userPoliciesList...),
+		TeamNames: teamNames,
+		Config:    config,
+		Warnings:  warnings,
+	}
+
+	return verifyResp, nil
+}
+
+type verifyCredentialsResp struct {
+	User      *github.User
+	Org       *github.Organization
+	Policies  []string
+	TeamNames []string
+
+	// Warnings to send back to the caller
+	Warnings []string
+
+	// This is just a cache to send back to the caller
+	Config *config
+}
+
+// userMembership verifies with the token of the user that the user is a member
+// of the configured organization, and returns the names of the teams of the
+// organization the user is part of.
+func userMembership(ctx context.Context, client *github.Client, config *config) (*github.Organization, []string, error) {
 	var org *github.Organization
 
 	orgOpt := &github.ListOptions{
@@ -211,7 +268,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 	for {
 		orgs, resp, err := client.Organizations.List(ctx, "", orgOpt)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		allOrgs = append(allOrgs, orgs...)
 		if resp.NextPage == 0 {
@@ -220,30 +277,16 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 		orgOpt.Page = resp.NextPage
 	}
 
-	orgLoginName := ""
 	for _, o := range allOrgs {
 		if o.GetID() == config.OrganizationID {
 			org = o
-			orgLoginName = *o.Login
 			break
 		}
 	}
 	if org == nil {
-		return nil, errors.New("user is not part of required org")
+		return nil, nil, errors.New("user is not part of required org")
 	}
 
-	if orgLoginName != config.Organization {
-		warningMsg := fmt.Sprintf(
-			"the organization name has changed to %q. It is recommended to verify and update the organization name in the config: %s=%d",
-			orgLoginName,
-			"organization_id",
-			config.OrganizationID,
-		)
-		b.Logger().Warn(warningMsg)
-		warnings = append(warnings, warningMsg)
-	}
-
-	// Get the teams that this user is part of to determine the policies
 	var teamNames []string
 
 	teamOpt := &github.ListOptions{
@@ -254,7 +297,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 	for {
 		teams, resp, err := client.Teams.ListUserTeams(ctx, teamOpt)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		allTeams = append(allTeams, teams...)
 		if resp.NextPage == 0 {
@@ -276,37 +319,5 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 		}
 	}
 
-	groupPoliciesList, err := b.TeamMap.Policies(ctx, req.Storage, teamNames...)
-	if err != nil {
-		return nil, err
-	}
-
-	userPoliciesList, err := b.UserMap.Policies(ctx, req.Storage, []string{*user.Login}...)
-	if err != nil {
-		return nil, err
-	}
-
-	verifyResp := &verifyCredentialsResp{
-		User:      user,
-		Org:       org,
-		Policies:  append(groupPoliciesList, userPoliciesList...),
-		TeamNames: teamNames,
-		Config:    config,
-		Warnings:  warnings,
-	}
-
-	return verifyResp, nil
-}
-
-type verifyCredentialsResp struct {
-	User      *github.User
-	Org       *github.Organization
-	Policies  []string
-	TeamNames []string
-
-	// Warnings to send back to the caller
-	Warnings []string
-
-	// This is just a cache to send back to the caller
-	Config *config
+	return org, teamNames, nil
 }
@@ -0,0 +1,305 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/cap/jwt"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/policyutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// defaultActionsOIDCIssuer is the issuer of the OIDC tokens of GitHub Actions
+// on github.com.
+const defaultActionsOIDCIssuer = "https://token.actions.githubusercontent.com"
+
+func pathLoginActions(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login/actions",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixGithub,
+			OperationVerb:   "login",
+			OperationSuffix: "actions",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "The name of the GitHub Actions role to log in with.",
+			},
+			"jwt": {
+				Type:        framework.TypeString,
+				Description: "The OIDC token of the GitHub Actions job.",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathLoginActions,
+			},
+			logical.AliasLookaheadOperation: &framework.PathOperation{
+				Callback: b.pathLoginActionsAliasLookahead,
+			},
+		},
+
+		HelpSynopsis:    pathLoginActionsHelpSyn,
+		HelpDescription: pathLoginActionsHelpDesc,
+	}
+}
+
+func (c *config) actionsIssuer() string {
+	if c.ActionsOIDCIssuer != "" {
+		return c.ActionsOIDCIssuer
+	}
+	return defaultActionsOIDCIssuer
+}
+
+// getActionsKeySet returns the key set of the GitHub Actions OIDC issuer,
+// discovering it on first use. The discovery happens without holding the
+// lock, so that an unresponsive issuer does not serialize the logins.
+func (b *backend) getActionsKeySet(c *config) (jwt.KeySet, error) {
+	b.actionsKeySetLock.Lock()
+	keySet := b.actionsKeySet
+	gen := b.actionsKeySetGen
+	b.actionsKeySetLock.Unlock()
+	if keySet != nil {
+		return keySet, nil
+	}
+
+	keySet, err := jwt.NewOIDCDiscoveryKeySet(b.actionsCtx, c.actionsIssuer(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	b.actionsKeySetLock.Lock()
+	defer b.actionsKeySetLock.Unlock()
+
+	// Keep the key set of a concurrent login, and don't cache this one if the
+	// configuration changed meanwhile
+	if b.actionsKeySet != nil {
+		return b.actionsKeySet, nil
+	}
+	if gen == b.actionsKeySetGen {
+		b.actionsKeySet = keySet
+	}
+	return keySet, nil
+}
+
+func (b *backend) resetActionsKeySet() {
+	b.actionsKeySetLock.Lock()
+	defer b.actionsKeySetLock.Unlock()
+	b.actionsKeySetGen++
+	b.actionsKeySet = nil
+}
+
+func (b *backend) pathLoginActionsAliasLookahead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	claims, _, err := b.verifyActionsToken(ctx, req, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Alias: &logical.Alias{
+				Name: claims.repository,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathLoginActions(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	claims, role, err := b.verifyActionsToken(ctx, req, d)
+	if err != nil {
+		return nil, err
+	}
+
+	roleName := strings.ToLower(d.Get("role").(string))
+	metadata := map[string]string{
+		"role":             roleName,
+		"repository":       claims.repository,
+		"ref":              claims.ref,
+		"workflow":         claims.workflow,
+		"job_workflow_ref": claims.jobWorkflowRef,
+		"run_id":           claims.runID,
+		"actor":            claims.actor,
+	}
+	if claims.environment != "" {
+		metadata["environment"] = claims.environment
+	}
+
+	auth := &logical.Auth{
+		InternalData: map[string]interface{}{
+			"actions_role": roleName,
+		},
+		Metadata:    metadata,
+		DisplayName: claims.repository,
+		Alias: &logical.Alias{
+			Name:     claims.repository,
+			Metadata: metadata,
+		},
+	}
+	role.PopulateTokenAuth(auth)
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+// pathLoginActionsRenew renews tokens issued by GitHub Actions logins. The
+// OIDC token of the job is short-lived and can't be verified again, so only the
+// role is checked.
+func (b *backend) pathLoginActionsRenew(ctx context.Context, req *logical.Request, roleName string) (*logical.Response, error) {
+	role, err := b.actionsRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q no longer exists", roleName)
+	}
+
+	if !policyutil.EquivalentPolicies(role.TokenPolicies, req.Auth.TokenPolicies) {
+		return nil, fmt.Errorf("policies do not match")
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.Period = role.TokenPeriod
+	resp.Auth.TTL = role.TokenTTL
+	resp.Auth.MaxTTL = role.TokenMaxTTL
+	return resp, nil
+}
+
+// actionsClaims are the claims of a GitHub Actions OIDC token used to match
+// roles.
+type actionsClaims struct {
+	repository        string
+	repositoryOwner   string
+	repositoryOwnerID string
+	ref               string
+	workflow          string
+	jobWorkflowRef    string
+	environment       string
+	runID             string
+	actor             string
+}
+
+func parseActionsClaims(claims map[string]interface{}) *actionsClaims {
+	get := func(name string) string {
+		// GitHub encodes all the custom claims as strings
+		value, _ := claims[name].(string)
+		return value
+	}
+
+	return &actionsClaims{
+		repository:        get("repository"),
+		repositoryOwner:   get("repository_owner"),
+		repositoryOwnerID: get("repository_owner_id"),
+		ref:               get("ref"),
+		workflow:          get("workflow"),
+		jobWorkflowRef:    get("job_workflow_ref"),
+		environment:       get("environment"),
+		runID:             get("run_id"),
+		actor:             get("actor"),
+	}
+}
+
+// verifyActionsToken verifies the signature and the standard claims of the
+// OIDC token, and matches its GitHub claims against the role.
+func (b *backend) verifyActionsToken(ctx context.Context, req *logical.Request, d *framework.FieldData) (*actionsClaims, *actionsRole, error) {
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		return nil, nil, logical.CodedError(400, "missing role")
+	}
+	token := d.Get("jwt").(string)
+	if token == "" {
+		return nil, nil, logical.CodedError(400, "missing jwt")
+	}
+
+	config, err := b.Config(ctx, req.Storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config == nil {
+		return nil, nil, errors.New("configuration has not been set")
+	}
+
+	role, err := b.actionsRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role == nil {
+		return nil, nil, logical.CodedError(400, fmt.Sprintf("role %q could not be found", roleName))
+	}
+
+	if len(role.TokenBoundCIDRs) > 0 {
+		if req.Connection == nil {
+			b.Logger().Error("token bound CIDRs found but no connection information available for validation")
+			return nil, nil, logical.ErrPermissionDenied
+		}
+		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, role.TokenBoundCIDRs) {
+			return nil, nil, logical.ErrPermissionDenied
+		}
+	}
+
+	keySet, err := b.getActionsKeySet(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the keys of the GitHub Actions OIDC issuer: %w", err)
+	}
+	validator, err := jwt.NewValidator(keySet)
+	if err != nil {
+		return nil, nil, err
+	}
+	allClaims, err := validator.Validate(ctx, token, jwt.Expected{
+		Issuer:            config.actionsIssuer(),
+		Audiences:         role.BoundAudiences,
+		SigningAlgorithms: []jwt.Alg{jwt.RS256},
+	})
+	if err != nil {
+		return nil, nil, logical.CodedError(403, fmt.Sprintf("error validating token: %s", err))
+	}
+	claims := parseActionsClaims(allClaims)
+
+	// Only workflows of the configured organization may log in
+	if config.OrganizationID != 0 {
+		if claims.repositoryOwnerID != strconv.FormatInt(config.OrganizationID, 10) {
+			return nil, nil, logical.CodedError(403, "repository is not part of required org")
+		}
+	} else if !strings.EqualFold(claims.repositoryOwner, config.Organization) {
+		return nil, nil, logical.CodedError(403, "repository is not part of required org")
+	}
+
+	if !strutil.StrListContainsGlob(role.BoundRepositories, claims.repository) {
+		return nil, nil, logical.CodedError(403, fmt.Sprintf("repository %q is not bound to the role", claims.repository))
+	}
+	if len(role.BoundRefs) > 0 && !strutil.StrListContainsGlob(role.BoundRefs, claims.ref) {
+		return nil, nil, logical.CodedError(403, fmt.Sprintf("ref %q is not bound to the role", claims.ref))
+	}
+	if len(role.BoundWorkflowRefs) > 0 && !strutil.StrListContainsGlob(role.BoundWorkflowRefs, claims.jobWorkflowRef) {
+		return nil, nil, logical.CodedError(403, fmt.Sprintf("workflow %q is not bound to the role", claims.jobWorkflowRef))
+	}
+	if len(role.BoundEnvironments) > 0 && !strutil.StrListContainsGlob(role.BoundEnvironments, claims.environment) {
+		return nil, nil, logical.CodedError(403, fmt.Sprintf("environment %q is not bound to the role", claims.environment))
+	}
+
+	return claims, role, nil
+}
+
+const pathLoginActionsHelpSyn = `
+Log in with the OIDC token of a GitHub Actions job.
+`
+
+const pathLoginActionsHelpDesc = `
+GitHub Actions jobs with the "id-token: write" permission can request an
+OIDC token from GitHub. This endpoint verifies the token against the keys
+published by the GitHub Actions OIDC issuer, and matches its repository,
+ref, workflow and environment claims against the given role.
+`
@@ -0,0 +1,220 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/cap/oidc"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// testActionsIssuer starts an OIDC issuer signing tokens with RS256, like
+// GitHub Actions does.
+func testActionsIssuer(t *testing.T) *oidc.TestProvider {
+	t.Helper()
+
+	issuer := oidc.StartTestProvider(t, oidc.WithNoTLS())
+	testActionsRotateKey(t, issuer, "test")
+	return issuer
+}
+
+// testActionsRotateKey replaces the signing key of the issuer.
+func testActionsRotateKey(t *testing.T, issuer *oidc.TestProvider, keyID string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer.SetSigningKeys(key, &key.PublicKey, oidc.RS256, keyID)
+}
+
+// testActionsToken returns a token for a workflow of foo-org/foo-repo signed
+// by the issuer, with the given claims overridden.
+func testActionsToken(t *testing.T, issuer *oidc.TestProvider, overrides map[string]interface{}) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":                 issuer.Addr(),
+		"aud":                 "https://github.com/foo-org",
+		"sub":                 "repo:foo-org/foo-repo:environment:production",
+		"iat":                 now.Unix(),
+		"nbf":                 now.Unix(),
+		"exp":                 now.Add(5 * time.Minute).Unix(),
+		"repository":          "foo-org/foo-repo",
+		"repository_owner":    "foo-org",
+		"repository_owner_id": "12345",
+		"ref":                 "refs/heads/main",
+		"workflow":            "deploy",
+		"job_workflow_ref":    "foo-org/foo-repo/.github/workflows/deploy.yml@refs/heads/main",
+		"environment":         "production",
+		"run_id":              "42",
+		"actor":               "user-foo",
+	}
+	for name, value := range overrides {
+		claims[name] = value
+	}
+
+	key, _, alg, keyID := issuer.SigningKeys()
+	return oidc.TestSignJWT(t, key, string(alg), claims, []byte(keyID))
+}
+
+func testActionsBackend(t *testing.T, issuer *oidc.TestProvider) (*backend, logical.Storage) {
+	t.Helper()
+
+	b, s := createBackendWithStorage(t)
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "config",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"organization":        "foo-org",
+			"organization_id":     12345,
+			"actions_oidc_issuer": issuer.Addr(),
+		},
+		Storage: s,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "actions/role/deploy",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"bound_audiences":     "https://github.com/foo-org",
+			"bound_repositories":  "foo-org/foo-*",
+			"bound_refs":          "refs/heads/main",
+			"bound_workflow_refs": "foo-org/foo-repo/.github/workflows/deploy.yml@*",
+			"bound_environments":  "production",
+			"token_policies":      "deploy",
+		},
+		Storage: s,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	return b, s
+}
+
+func testActionsLogin(b *backend, s logical.Storage, role, token string) (*logical.Response, error) {
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "login/actions",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"role": role,
+			"jwt":  token,
+		},
+		Storage:    s,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+}
+
+func TestGitHub_ActionsLogin(t *testing.T) {
+	issuer := testActionsIssuer(t)
+	b, s := testActionsBackend(t, issuer)
+
+	resp, err := testActionsLogin(b, s, "deploy", testActionsToken(t, issuer, nil))
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	require.Equal(t, []string{"deploy"}, resp.Auth.Policies)
+	require.Equal(t, "foo-org/foo-repo", resp.Auth.Alias.Name)
+	require.Equal(t, "production", resp.Auth.Metadata["environment"])
+	require.Equal(t, "deploy", resp.Auth.InternalData["actions_role"])
+
+	// Tokens of the role can be renewed without the OIDC token
+	resp.Auth.TokenPolicies = resp.Auth.Policies
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "login",
+		Operation: logical.RenewOperation,
+		Auth:      resp.Auth,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "actions/role/deploy",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo-org/foo-*"}, resp.Data["bound_repositories"])
+}
+
+// TestGitHub_ActionsLoginKeyRotation verifies that the keys of the issuer are
+// still fetched once the request which discovered them completed.
+func TestGitHub_ActionsLoginKeyRotation(t *testing.T) {
+	issuer := testActionsIssuer(t)
+	b, s := testActionsBackend(t, issuer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Path:      "login/actions",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"role": "deploy",
+			"jwt":  testActionsToken(t, issuer, nil),
+		},
+		Storage:    s,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+	cancel()
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+
+	testActionsRotateKey(t, issuer, "rotated")
+	resp, err = testActionsLogin(b, s, "deploy", testActionsToken(t, issuer, nil))
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+}
+
+func TestGitHub_ActionsLoginClaims(t *testing.T) {
+	issuer := testActionsIssuer(t)
+	b, s := testActionsBackend(t, issuer)
+	other := testActionsIssuer(t)
+
+	for name, token := range map[string]string{
+		"audience":    testActionsToken(t, issuer, map[string]interface{}{"aud": "sts.amazonaws.com"}),
+		"expired":     testActionsToken(t, issuer, map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}),
+		"signature":   testActionsToken(t, other, map[string]interface{}{"iss": issuer.Addr()}),
+		"org":         testActionsToken(t, issuer, map[string]interface{}{"repository_owner_id": "999", "repository": "foo-org/foo-fork"}),
+		"repository":  testActionsToken(t, issuer, map[string]interface{}{"repository": "foo-org/bar"}),
+		"ref":         testActionsToken(t, issuer, map[string]interface{}{"ref": "refs/heads/feature"}),
+		"workflow":    testActionsToken(t, issuer, map[string]interface{}{"job_workflow_ref": "foo-org/foo-repo/.github/workflows/other.yml@refs/heads/main"}),
+		"environment": testActionsToken(t, issuer, map[string]interface{}{"environment": "staging"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := testActionsLogin(b, s, "deploy", token)
+			require.Error(t, err)
+			if resp != nil {
+				require.Nil(t, resp.Auth)
+			}
+		})
+	}
+
+	_, err := testActionsLogin(b, s, "missing", testActionsToken(t, issuer, nil))
+	require.Error(t, err)
+}
+
+func TestGitHub_ActionsRoleValidation(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	for _, data := range []map[string]interface{}{
+		{"bound_repositories": "foo-org/foo-repo"},
+		{"bound_audiences": "https://github.com/foo-org"},
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "actions/role/invalid",
+			Operation: logical.CreateOperation,
+			Data:      data,
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError(), "%v", data)
+	}
+}
@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
+	goldap "github.com/go-ldap/ldap/v3"
 	"github.com/hashicorp/cap/ldap"
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/ldaputil"
@@ -57,6 +60,8 @@ func Backend() *backend {
 
 		AuthRenew:   b.pathLoginRenew,
 		BackendType: logical.TypeCredential,
+		Invalidate:  b.invalidate,
+		Clean:       b.cleanup,
 	}
 
 	return &b
@@ -66,6 +71,45 @@ type backend struct {
 	*framework.Backend
 
 	mu sync.RWMutex
+
+	// serverPool tracks the health of the configured servers. It is created
+	// on first use and reset when the configuration changes.
+	serverPool     *ldaputil.ServerPool
+	serverPoolLock sync.Mutex
+}
+
+func (b *backend) cleanup(_ context.Context) {
+	b.resetServerPool()
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	if key == "config" {
+		b.resetServerPool()
+	}
+}
+
+// getServerPool returns the server pool of the configuration.
+func (b *backend) getServerPool(cfg *ldapConfigEntry) *ldaputil.ServerPool {
+	b.serverPoolLock.Lock()
+	defer b.serverPoolLock.Unlock()
+
+	if b.serverPool == nil {
+		b.serverPool = ldaputil.NewServerPool(b.Logger(), time.Duration(cfg.ServerReprobeInterval)*time.Second)
+	}
+	return b.serverPool
+}
+
+func (b *backend) resetServerPool() {
+	b.serverPoolLock.Lock()
+	defer b.serverPoolLock.Unlock()
+
+	b.serverPool = nil
+}
+
+// isServerUnreachable returns whether the LDAP operation failed because of the
+// connection to the server, rather than because of the server's answer.
+func isServerUnreachable(err error) bool {
+	return goldap.IsErrorWithCode(err, goldap.ErrorNetwork)
 }
 
 func (b *backend) Login(ctx context.Context, req *logical.Request, username string, password string, usernameAsAlias bool) (string, []string, *logical.Response, []string, error) {
@@ -89,7 +133,28 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 	// Clean connection
 	defer ldapClient.Close(ctx)
 
-	c, err := ldapClient.Authenticate(ctx, username, password, ldap.WithGroups(), ldap.WithUserAttributes())
+	// Authenticate against one server at a time, so that the health of each
+	// server is tracked. Servers which can't be reached are skipped, but any
+	// other error is final.
+	pool := b.getServerPool(cfg)
+	var c *ldap.AuthResult
+	var connErr *multierror.Error
+	for _, url := range pool.Order(strings.Split(cfg.Url, ",")) {
+		start := time.Now()
+		c, err = ldapClient.Authenticate(ctx, username, password, ldap.WithGroups(), ldap.WithUserAttributes(), ldap.WithURLs(url))
+		if isServerUnreachable(err) {
+			pool.RecordFailure(url, err)
+			connErr = multierror.Append(connErr, err)
+			continue
+		}
+		pool.RecordSuccess(url, start)
+		connErr = nil
+		break
+	}
+	if connErr != nil {
+		// None of the servers could be reached
+		err = connErr
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "discovery of user bind DN failed") ||
 			strings.Contains(err.Error(), "unable to bind user") {
@@ -6,8 +6,10 @@ package ldap
 import (
 	"context"
 	"fmt"
+	"net"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -1313,6 +1315,7 @@ func TestLdapAuthBackend_ConfigUpgrade(t *testing.T) {
 			UsernameAsAlias:          false,
 			DerefAliases:             "never",
 			MaximumPageSize:          1000,
+			ServerReprobeInterval:    defParams.ServerReprobeInterval,
 		},
 	}
 
@@ -1344,3 +1347,104 @@ func TestLdapAuthBackend_ConfigUpgrade(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+// TestBackend_LoginServerFailover verifies that servers which can't be reached
+// are recorded as failing, and that the health of the servers is reset when
+// the configuration changes.
+func TestBackend_LoginServerFailover(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	var urls []string
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, "ldap://"+listener.Addr().String())
+		listener.Close()
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"url":                     strings.Join(urls, ","),
+			"userdn":                  "ou=users,dc=example,dc=org",
+			"request_timeout":         2,
+			"server_reprobe_interval": "1h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login/user",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"password": "password",
+		},
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+	if err == nil && !resp.IsError() {
+		t.Fatalf("expected login to fail: %#v", resp)
+	}
+	for _, url := range urls {
+		if !strings.Contains(resp.Error().Error(), url) {
+			t.Fatalf("expected the error to report %q: %v", url, resp.Error())
+		}
+	}
+
+	// Both servers were tried and failed, so the one which recovers comes first
+	if b.serverPool == nil {
+		t.Fatal("expected the server pool to be created")
+	}
+	b.serverPool.RecordSuccess(urls[1], time.Now())
+	if order := b.serverPool.Order(urls); !reflect.DeepEqual(order, []string{urls[1], urls[0]}) {
+		t.Fatalf("bad order: %v", order)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"url": urls[0],
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if b.serverPool != nil {
+		t.Fatal("expected the server pool to be reset")
+	}
+}
+
+// TestBackend_ConfigServerReprobeIntervalDefault verifies that configurations
+// stored before server_reprobe_interval was introduced get the same default
+// as new ones, while an interval of 0 is kept.
+func TestBackend_ConfigServerReprobeIntervalDefault(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	ctx := context.Background()
+	req := &logical.Request{Storage: storage}
+
+	for stored, expected := range map[string]int{
+		`{"url": "ldap://127.0.0.1"}`:                                 ldaputil.DefaultServerReprobeInterval,
+		`{"url": "ldap://127.0.0.1", "server_reprobe_interval": 0}`:   0,
+		`{"url": "ldap://127.0.0.1", "server_reprobe_interval": 120}`: 120,
+	} {
+		if err := storage.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte(stored)}); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := b.Config(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ServerReprobeInterval != expected {
+			t.Fatalf("expected server_reprobe_interval %d for %s, got %d", expected, stored, cfg.ServerReprobeInterval)
+		}
+	}
+}
@@ -91,7 +91,9 @@ func (b *backend) Config(ctx context.Context, req *logical.Request) (*ldapConfig
 	// Deserialize stored configuration.
 	// Fields not specified in storedConfig will retain their defaults.
 	result := new(ldapConfigEntry)
-	result.ConfigEntry = new(ldaputil.ConfigEntry)
+	result.ConfigEntry = &ldaputil.ConfigEntry{
+		ServerReprobeInterval: ldaputil.DefaultServerReprobeInterval,
+	}
 	if err := storedConfig.DecodeJSON(result); err != nil {
 		return nil, err
 	}
@@ -218,6 +220,7 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 	if err := req.Storage.Put(ctx, entry); err != nil {
 		return nil, err
 	}
+	b.resetServerPool()
 
 	if warnings := b.checkConfigUserFilter(cfg); len(warnings) > 0 {
 		return &logical.Response{
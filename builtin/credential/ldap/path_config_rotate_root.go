@@ -58,12 +58,14 @@ func (b *backend) pathConfigRotateRootUpdate(ctx context.Context, req *logical.R
 	client := ldaputil.Client{
 		Logger: b.Logger(),
 		LDAP:   ldaputil.NewLDAP(),
+		Pool:   b.getServerPool(cfg),
 	}
 
 	conn, err := client.DialLDAP(cfg.ConfigEntry)
 	if err != nil {
 		return nil, err
 	}
+	defer conn.Close()
 
 	err = conn.Bind(u, p)
 	if err != nil {
@@ -6,9 +6,11 @@ package okta
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/textproto"
 	"time"
 
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
@@ -60,6 +62,7 @@ func Backend() *backend {
 		BackendType: logical.TypeCredential,
 	}
 	b.verifyCache = cache.New(5*time.Minute, time.Minute)
+	b.idxClient = cleanhttp.DefaultPooledClient()
 
 	return &b
 }
@@ -67,6 +70,9 @@ func Backend() *backend {
 type backend struct {
 	*framework.Backend
 	verifyCache *cache.Cache
+
+	// idxClient is the HTTP client of logins through the interaction API
+	idxClient *http.Client
 }
 
 func (b *backend) Login(ctx context.Context, req *logical.Request, username, password, totp, nonce, preferredProvider string) ([]string, *logical.Response, []string, error) {
@@ -94,6 +100,77 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 		return nil, nil, nil, err
 	}
 
+	oktaResponse := &logical.Response{
+		Data: map[string]interface{}{},
+	}
+
+	var oktaUser *okta.User
+	var errResp *logical.Response
+	switch cfg.LoginFlow {
+	case loginFlowIDX:
+		oktaUser, errResp, err = b.loginIDX(ctx, req, cfg, username, password, totp, nonce, preferredProvider, oktaResponse)
+	default:
+		oktaUser, errResp, err = b.loginAuthn(ctx, req, cfg, shim, username, password, totp, nonce, preferredProvider, oktaResponse)
+	}
+	if err != nil || errResp != nil {
+		return nil, errResp, nil, err
+	}
+
+	var allGroups []string
+	// Only query the Okta API for group membership if we have a token
+	client, oktactx := shim.Client()
+	if client != nil {
+		oktaGroups, err := b.getOktaGroups(oktactx, client, oktaUser)
+		if err != nil {
+			return nil, logical.ErrorResponse(fmt.Sprintf("okta failure retrieving groups: %v", err)), nil, nil
+		}
+		if len(oktaGroups) == 0 {
+			errString := fmt.Sprintf(
+				"no Okta groups found; only policies from locally-defined groups available")
+			oktaResponse.AddWarning(errString)
+		}
+		allGroups = append(allGroups, oktaGroups...)
+	}
+
+	// Import the custom added groups from okta backend
+	user, err := b.User(ctx, req.Storage, username)
+	if err != nil {
+		if b.Logger().IsDebug() {
+			b.Logger().Debug("error looking up user", "error", err)
+		}
+	}
+	if err == nil && user != nil && user.Groups != nil {
+		if b.Logger().IsDebug() {
+			b.Logger().Debug("adding local groups", "num_local_groups", len(user.Groups), "local_groups", user.Groups)
+		}
+		allGroups = append(allGroups, user.Groups...)
+	}
+
+	// Retrieve policies
+	var policies []string
+	for _, groupName := range allGroups {
+		entry, _, err := b.Group(ctx, req.Storage, groupName)
+		if err != nil {
+			if b.Logger().IsDebug() {
+				b.Logger().Debug("error looking up group policies", "error", err)
+			}
+		}
+		if err == nil && entry != nil && entry.Policies != nil {
+			policies = append(policies, entry.Policies...)
+		}
+	}
+
+	// Merge local Policies into Okta Policies
+	if user != nil && user.Policies != nil {
+		policies = append(policies, user.Policies...)
+	}
+
+	return policies, oktaResponse, allGroups, nil
+}
+
+// loginAuthn authenticates the user through the classic authentication API
+// of Okta, returning either the authenticated user or an error response.
+func (b *backend) loginAuthn(ctx context.Context, req *logical.Request, cfg *ConfigEntry, shim oktaShim, username, password, totp, nonce, preferredProvider string, oktaResponse *logical.Response) (*okta.User, *logical.Response, error) {
 	type mfaFactor struct {
 		Id       string `json:"id"`
 		Type     string `json:"factorType"`
@@ -123,23 +200,19 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 		"password": password,
 	})
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
 
 	var result authResult
 	rsp, err := shim.Do(authReq, &result)
 	if err != nil {
 		if oe, ok := err.(*okta.Error); ok {
-			return nil, logical.ErrorResponse("Okta auth failed: %v (code=%v)", err, oe.ErrorCode), nil, nil
+			return nil, logical.ErrorResponse("Okta auth failed: %v (code=%v)", err, oe.ErrorCode), nil
 		}
-		return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil, nil
+		return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
 	}
 	if rsp == nil {
-		return nil, logical.ErrorResponse("okta auth method unexpected failure"), nil, nil
-	}
-
-	oktaResponse := &logical.Response{
-		Data: map[string]interface{}{},
+		return nil, logical.ErrorResponse("okta auth method unexpected failure"), nil
 	}
 
 	// More about Okta's Auth transaction state here:
@@ -153,13 +226,13 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("user is locked out", "user", username)
 		}
-		return nil, logical.ErrorResponse("okta authentication failed"), nil, nil
+		return nil, logical.ErrorResponse("okta authentication failed"), nil
 
 	case "PASSWORD_EXPIRED":
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("password is expired", "user", username)
 		}
-		return nil, logical.ErrorResponse("okta authentication failed"), nil, nil
+		return nil, logical.ErrorResponse("okta authentication failed"), nil
 
 	case "PASSWORD_WARN":
 		oktaResponse.AddWarning("Your Okta password is in warning state and needs to be changed soon.")
@@ -169,7 +242,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 			if b.Logger().IsDebug() {
 				b.Logger().Debug("user must enroll or complete mfa enrollment", "user", username)
 			}
-			return nil, logical.ErrorResponse("okta authentication failed: you must complete MFA enrollment to continue"), nil, nil
+			return nil, logical.ErrorResponse("okta authentication failed: you must complete MFA enrollment to continue"), nil
 		}
 
 	case "MFA_REQUIRED":
@@ -216,9 +289,9 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 		case pushFactor != nil && pushFactor.Provider == oktaProvider:
 			selectedFactor = pushFactor
 		case totpFactor != nil && totp == "":
-			return nil, logical.ErrorResponse("'totp' passcode parameter is required to perform MFA"), nil, nil
+			return nil, logical.ErrorResponse("'totp' passcode parameter is required to perform MFA"), nil
 		default:
-			return nil, logical.ErrorResponse("Okta Verify Push or TOTP or Google TOTP factor is required in order to perform MFA"), nil, nil
+			return nil, logical.ErrorResponse("Okta Verify Push or TOTP or Google TOTP factor is required in order to perform MFA"), nil
 		}
 
 		requestPath := fmt.Sprintf("authn/factors/%s/verify", selectedFactor.Id)
@@ -232,7 +305,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 
 		verifyReq, err := shim.NewRequest("POST", requestPath, payload)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, err
 		}
 		if len(req.Headers["X-Forwarded-For"]) > 0 {
 			verifyReq.Header.Set("X-Forwarded-For", req.Headers[textproto.CanonicalMIMEHeaderKey("X-Forwarded-For")][0])
@@ -240,17 +313,17 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 
 		rsp, err := shim.Do(verifyReq, &result)
 		if err != nil {
-			return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil, nil
+			return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
 		}
 		if rsp == nil {
-			return nil, logical.ErrorResponse("okta auth backend unexpected failure"), nil, nil
+			return nil, logical.ErrorResponse("okta auth backend unexpected failure"), nil
 		}
 		for result.Status == "MFA_CHALLENGE" {
 			switch result.FactorResult {
 			case "WAITING":
 				verifyReq, err := shim.NewRequest("POST", requestPath, payload)
 				if err != nil {
-					return nil, logical.ErrorResponse(fmt.Sprintf("okta auth failed creating verify request: %v", err)), nil, nil
+					return nil, logical.ErrorResponse(fmt.Sprintf("okta auth failed creating verify request: %v", err)), nil
 				}
 				rsp, err := shim.Do(verifyReq, &result)
 
@@ -258,17 +331,17 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 				numberChallenge := result.Embedded.Factor.Embedded.Challenge.CorrectAnswer
 				if numberChallenge != nil {
 					if nonce == "" {
-						return nil, logical.ErrorResponse("nonce must be provided during login request when presented with number challenge"), nil, nil
+						return nil, logical.ErrorResponse("nonce must be provided during login request when presented with number challenge"), nil
 					}
 
 					b.verifyCache.SetDefault(nonce, *numberChallenge)
 				}
 
 				if err != nil {
-					return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed checking loop: %v", err)), nil, nil
+					return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed checking loop: %v", err)), nil
 				}
 				if rsp == nil {
-					return nil, logical.ErrorResponse("okta auth backend unexpected failure"), nil, nil
+					return nil, logical.ErrorResponse("okta auth backend unexpected failure"), nil
 				}
 
 				timer := time.NewTimer(1 * time.Second)
@@ -277,19 +350,19 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 					// Continue
 				case <-ctx.Done():
 					timer.Stop()
-					return nil, logical.ErrorResponse("exiting pending mfa challenge"), nil, nil
+					return nil, logical.ErrorResponse("exiting pending mfa challenge"), nil
 				}
 			case "REJECTED":
-				return nil, logical.ErrorResponse("multi-factor authentication denied"), nil, nil
+				return nil, logical.ErrorResponse("multi-factor authentication denied"), nil
 			case "TIMEOUT":
-				return nil, logical.ErrorResponse("failed to complete multi-factor authentication"), nil, nil
+				return nil, logical.ErrorResponse("failed to complete multi-factor authentication"), nil
 			case "SUCCESS":
 				// Allowed
 			default:
 				if b.Logger().IsDebug() {
 					b.Logger().Debug("unhandled result status", "status", result.Status, "factorstatus", result.FactorResult)
 				}
-				return nil, logical.ErrorResponse("okta authentication failed"), nil, nil
+				return nil, logical.ErrorResponse("okta authentication failed"), nil
 			}
 		}
 
@@ -300,7 +373,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("unhandled result status", "status", result.Status)
 		}
-		return nil, logical.ErrorResponse("okta authentication failed"), nil, nil
+		return nil, logical.ErrorResponse("okta authentication failed"), nil
 	}
 
 	// Verify result status again in case a switch case above modifies result
@@ -315,59 +388,10 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("authentication returned a non-success status", "status", result.Status)
 		}
-		return nil, logical.ErrorResponse("okta authentication failed"), nil, nil
+		return nil, logical.ErrorResponse("okta authentication failed"), nil
 	}
 
-	var allGroups []string
-	// Only query the Okta API for group membership if we have a token
-	client, oktactx := shim.Client()
-	if client != nil {
-		oktaGroups, err := b.getOktaGroups(oktactx, client, &result.Embedded.User)
-		if err != nil {
-			return nil, logical.ErrorResponse(fmt.Sprintf("okta failure retrieving groups: %v", err)), nil, nil
-		}
-		if len(oktaGroups) == 0 {
-			errString := fmt.Sprintf(
-				"no Okta groups found; only policies from locally-defined groups available")
-			oktaResponse.AddWarning(errString)
-		}
-		allGroups = append(allGroups, oktaGroups...)
-	}
-
-	// Import the custom added groups from okta backend
-	user, err := b.User(ctx, req.Storage, username)
-	if err != nil {
-		if b.Logger().IsDebug() {
-			b.Logger().Debug("error looking up user", "error", err)
-		}
-	}
-	if err == nil && user != nil && user.Groups != nil {
-		if b.Logger().IsDebug() {
-			b.Logger().Debug("adding local groups", "num_local_groups", len(user.Groups), "local_groups", user.Groups)
-		}
-		allGroups = append(allGroups, user.Groups...)
-	}
-
-	// Retrieve policies
-	var policies []string
-	for _, groupName := range allGroups {
-		entry, _, err := b.Group(ctx, req.Storage, groupName)
-		if err != nil {
-			if b.Logger().IsDebug() {
-				b.Logger().Debug("error looking up group policies", "error", err)
-			}
-		}
-		if err == nil && entry != nil && entry.Policies != nil {
-			policies = append(policies, entry.Policies...)
-		}
-	}
-
-	// Merge local Policies into Okta Policies
-	if user != nil && user.Policies != nil {
-		policies = append(policies, user.Policies...)
-	}
-
-	return policies, oktaResponse, allGroups, nil
+	return &result.Embedded.User, nil, nil
 }
 
 func (b *backend) getOktaGroups(ctx context.Context, client *okta.Client, user *okta.User) ([]string, error) {
@@ -402,5 +426,7 @@ configured groups are pulled down from Okta.
 
 Configuration of the connection is done through the "config" and "policies"
 endpoints by a user with root access. Authentication is then done
-by supplying the two fields for "login".
+by supplying the two fields for "login". Users are authenticated through
+the classic authentication API of Okta, or through the interaction API of
+Okta Identity Engine when "login_flow" is set to "idx".
 `
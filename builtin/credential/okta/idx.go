@@ -0,0 +1,548 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package okta
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+const (
+	idxContentType = "application/ion+json; okta-version=1.0.0"
+
+	// maxIDXRemediations bounds the number of steps of a login through the
+	// interaction API, push notification polls excluded.
+	maxIDXRemediations = 10
+
+	// maxIDXResponseSize bounds the size of the responses of the interaction
+	// API read.
+	maxIDXResponseSize = 1 << 20
+
+	idxAuthenticatorPassword   = "okta_password"
+	idxAuthenticatorOktaVerify = "okta_verify"
+	idxAuthenticatorGoogleOTP  = "google_otp"
+)
+
+// idxResponse holds the parts of the responses of the Okta Identity Engine
+// interaction API used to log users in.
+//
+// API reference: https://developer.okta.com/docs/guides/oie-embedded-common-org-setup/
+type idxResponse struct {
+	StateHandle string `json:"stateHandle"`
+	Remediation struct {
+		Value []*idxRemediation `json:"value"`
+	} `json:"remediation"`
+	Authenticators struct {
+		Value []*idxAuthenticator `json:"value"`
+	} `json:"authenticators"`
+	CurrentAuthenticator struct {
+		Value idxAuthenticator `json:"value"`
+	} `json:"currentAuthenticator"`
+	CurrentAuthenticatorEnrollment struct {
+		Value idxAuthenticator `json:"value"`
+	} `json:"currentAuthenticatorEnrollment"`
+	Messages struct {
+		Value []struct {
+			Message string `json:"message"`
+			Class   string `json:"class"`
+		} `json:"value"`
+	} `json:"messages"`
+	SuccessWithInteractionCode *idxRemediation `json:"successWithInteractionCode"`
+}
+
+// idxRemediation is a step the interaction API expects next.
+type idxRemediation struct {
+	Name string `json:"name"`
+	Href string `json:"href"`
+	// Refresh is the interval of polls, in milliseconds
+	Refresh int `json:"refresh"`
+	Value   []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	} `json:"value"`
+}
+
+func (r *idxRemediation) hasField(name string) bool {
+	for _, v := range r.Value {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *idxRemediation) fieldValue(name string) string {
+	for _, v := range r.Value {
+		if v.Name == name {
+			value, _ := v.Value.(string)
+			return value
+		}
+	}
+	return ""
+}
+
+type idxAuthenticator struct {
+	ID      string `json:"id"`
+	Key     string `json:"key"`
+	Methods []struct {
+		Type string `json:"type"`
+	} `json:"methods"`
+	ContextualData struct {
+		CorrectAnswer *int `json:"correctAnswer"`
+	} `json:"contextualData"`
+}
+
+func (a *idxAuthenticator) hasMethod(methodType string) bool {
+	for _, m := range a.Methods {
+		if m.Type == methodType {
+			return true
+		}
+	}
+	return false
+}
+
+func (resp *idxResponse) remediation(name string) *idxRemediation {
+	for _, r := range resp.Remediation.Value {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (resp *idxResponse) errorMessages() []string {
+	var messages []string
+	for _, m := range resp.Messages.Value {
+		if m.Class == "ERROR" {
+			messages = append(messages, m.Message)
+		}
+	}
+	return messages
+}
+
+// idxLogin holds the state of a login through the interaction API.
+type idxLogin struct {
+	client       *http.Client
+	cfg          *ConfigEntry
+	req          *logical.Request
+	codeVerifier string
+}
+
+// loginIDX authenticates the user through the interaction API of Okta
+// Identity Engine, returning either the authenticated user or an error
+// response. The remediations Okta asks for are followed until the policies of
+// the organization are satisfied, the same authenticators as the classic
+// authentication API being supported.
+func (b *backend) loginIDX(ctx context.Context, req *logical.Request, cfg *ConfigEntry, username, password, totp, nonce, preferredProvider string, oktaResponse *logical.Response) (*okta.User, *logical.Response, error) {
+	login := &idxLogin{
+		client: b.idxClient,
+		cfg:    cfg,
+		req:    req,
+	}
+
+	interactionHandle, err := login.interact(ctx)
+	if err != nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
+	}
+
+	result, err := login.post(ctx, cfg.orgURL()+"/idp/idx/introspect", map[string]interface{}{
+		"interactionHandle": interactionHandle,
+	})
+	if err != nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
+	}
+
+	var passwordUsed, totpUsed bool
+	for steps := 0; ; steps++ {
+		if messages := result.errorMessages(); len(messages) > 0 {
+			return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %s", strings.Join(messages, "; "))), nil
+		}
+
+		if result.SuccessWithInteractionCode != nil {
+			accessToken, err := login.exchange(ctx, result.SuccessWithInteractionCode)
+			if err != nil {
+				return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
+			}
+			// The user is the one the authorization server issued the token
+			// to, rather than the one the interaction API reports.
+			userID, err := login.introspect(ctx, accessToken)
+			if err != nil {
+				return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
+			}
+			return &okta.User{Id: userID}, nil, nil
+		}
+
+		if steps >= maxIDXRemediations {
+			return nil, logical.ErrorResponse("okta authentication failed"), nil
+		}
+
+		payload := map[string]interface{}{
+			"stateHandle": result.StateHandle,
+		}
+
+		var remediation *idxRemediation
+		switch {
+		case result.remediation("identify") != nil:
+			remediation = result.remediation("identify")
+			payload["identifier"] = username
+			// Orgs not using identifier first authentication expect the
+			// password along with the username
+			if remediation.hasField("credentials") {
+				payload["credentials"] = map[string]interface{}{
+					"passcode": password,
+				}
+				passwordUsed = true
+			}
+
+		case result.remediation("challenge-authenticator") != nil:
+			remediation = result.remediation("challenge-authenticator")
+			var passcode string
+			switch result.CurrentAuthenticatorEnrollment.Value.Key {
+			case idxAuthenticatorPassword:
+				if passwordUsed {
+					return nil, logical.ErrorResponse("okta authentication failed"), nil
+				}
+				passcode = password
+				passwordUsed = true
+			default:
+				if totp == "" {
+					return nil, logical.ErrorResponse("'totp' passcode parameter is required to perform MFA"), nil
+				}
+				if totpUsed {
+					return nil, logical.ErrorResponse("okta authentication failed"), nil
+				}
+				passcode = totp
+				totpUsed = true
+			}
+			payload["credentials"] = map[string]interface{}{
+				"passcode": passcode,
+			}
+
+		case result.remediation("challenge-poll") != nil:
+			remediation = result.remediation("challenge-poll")
+
+			// Store number challenge if found
+			numberChallenge := result.CurrentAuthenticator.Value.ContextualData.CorrectAnswer
+			if numberChallenge != nil {
+				if nonce == "" {
+					return nil, logical.ErrorResponse("nonce must be provided during login request when presented with number challenge"), nil
+				}
+
+				b.verifyCache.SetDefault(nonce, *numberChallenge)
+			}
+
+			refresh := time.Duration(remediation.Refresh) * time.Millisecond
+			if refresh < time.Second {
+				refresh = time.Second
+			}
+			timer := time.NewTimer(refresh)
+			select {
+			case <-timer.C:
+				// Continue
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, logical.ErrorResponse("exiting pending mfa challenge"), nil
+			}
+
+			// Polls are not remediations of their own
+			steps--
+
+		case result.remediation("select-authenticator-authenticate") != nil:
+			remediation = result.remediation("select-authenticator-authenticate")
+			authenticator, methodType, errResp := b.selectIDXAuthenticator(result.Authenticators.Value, passwordUsed, totp, preferredProvider)
+			if errResp != nil {
+				return nil, errResp, nil
+			}
+			payload["authenticator"] = map[string]interface{}{
+				"id":         authenticator.ID,
+				"methodType": methodType,
+			}
+
+		case result.remediation("reenroll-authenticator-warning") != nil && result.remediation("skip") != nil:
+			remediation = result.remediation("skip")
+			oktaResponse.AddWarning("Your Okta password is in warning state and needs to be changed soon.")
+
+		case result.remediation("select-authenticator-enroll") != nil,
+			result.remediation("enroll-authenticator") != nil:
+			if remediation = result.remediation("skip"); remediation == nil {
+				if b.Logger().IsDebug() {
+					b.Logger().Debug("user must enroll or complete mfa enrollment", "user", username)
+				}
+				return nil, logical.ErrorResponse("okta authentication failed: you must complete MFA enrollment to continue"), nil
+			}
+
+		default:
+			if b.Logger().IsDebug() {
+				var names []string
+				for _, r := range result.Remediation.Value {
+					names = append(names, r.Name)
+				}
+				b.Logger().Debug("unhandled remediations", "user", username, "remediations", names)
+			}
+			return nil, logical.ErrorResponse("okta authentication failed"), nil
+		}
+
+		result, err = login.post(ctx, remediation.Href, payload)
+		if err != nil {
+			return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
+		}
+	}
+}
+
+// selectIDXAuthenticator selects the authenticator to verify the user with,
+// along with its method. As with the classic authentication API, a TOTP
+// passcode is preferred when one is provided, then an Okta Verify push.
+func (b *backend) selectIDXAuthenticator(authenticators []*idxAuthenticator, passwordUsed bool, totp, preferredProvider string) (*idxAuthenticator, string, *logical.Response) {
+	var passwordAuthenticator, totpAuthenticator, pushAuthenticator *idxAuthenticator
+	var totpMethod string
+
+	for _, a := range authenticators {
+		switch a.Key {
+		case idxAuthenticatorPassword:
+			passwordAuthenticator = a
+		case idxAuthenticatorOktaVerify:
+			if preferredProvider != "" && preferredProvider != oktaProvider {
+				continue
+			}
+			if a.hasMethod("totp") {
+				totpAuthenticator, totpMethod = a, "totp"
+			}
+			if a.hasMethod("push") {
+				pushAuthenticator = a
+			}
+		case idxAuthenticatorGoogleOTP:
+			if preferredProvider != "" && preferredProvider != googleProvider {
+				continue
+			}
+			totpAuthenticator, totpMethod = a, "otp"
+		}
+	}
+
+	switch {
+	case passwordAuthenticator != nil && !passwordUsed:
+		return passwordAuthenticator, "password", nil
+	case totpAuthenticator != nil && totp != "":
+		return totpAuthenticator, totpMethod, nil
+	case pushAuthenticator != nil:
+		return pushAuthenticator, "push", nil
+	case totpAuthenticator != nil:
+		return nil, "", logical.ErrorResponse("'totp' passcode parameter is required to perform MFA")
+	default:
+		return nil, "", logical.ErrorResponse("Okta Verify Push or TOTP or Google TOTP factor is required in order to perform MFA")
+	}
+}
+
+// issuerURL returns the URL of the authorization server to interact with.
+func (l *idxLogin) issuerURL() string {
+	if l.cfg.AuthorizationServerID != "" {
+		return l.cfg.orgURL() + "/oauth2/" + url.PathEscape(l.cfg.AuthorizationServerID)
+	}
+	return l.cfg.orgURL() + "/oauth2"
+}
+
+// interact starts a new interaction with the authorization server, using
+// PKCE to bind the interaction code it issues on success to this login.
+func (l *idxLogin) interact(ctx context.Context) (string, error) {
+	verifier, err := randomURLSafeString()
+	if err != nil {
+		return "", err
+	}
+	state, err := randomURLSafeString()
+	if err != nil {
+		return "", err
+	}
+	l.codeVerifier = verifier
+	challenge := sha256.Sum256([]byte(verifier))
+
+	form := url.Values{
+		"client_id":             {l.cfg.ClientID},
+		"scope":                 {"openid"},
+		"redirect_uri":          {l.cfg.RedirectURI},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if l.cfg.ClientSecret != "" {
+		form.Set("client_secret", l.cfg.ClientSecret)
+	}
+
+	var result struct {
+		InteractionHandle string `json:"interaction_handle"`
+	}
+	if err := l.postForm(ctx, l.issuerURL()+"/v1/interact", form, &result); err != nil {
+		return "", err
+	}
+	if result.InteractionHandle == "" {
+		return "", errors.New("no interaction handle returned")
+	}
+	return result.InteractionHandle, nil
+}
+
+// exchange redeems the interaction code issued once the user is
+// authenticated, which completes the interaction, and returns the access
+// token issued for it.
+func (l *idxLogin) exchange(ctx context.Context, success *idxRemediation) (string, error) {
+	form := url.Values{
+		"grant_type":       {"interaction_code"},
+		"client_id":        {l.cfg.ClientID},
+		"interaction_code": {success.fieldValue("interaction_code")},
+		"code_verifier":    {l.codeVerifier},
+	}
+	if l.cfg.ClientSecret != "" {
+		form.Set("client_secret", l.cfg.ClientSecret)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := l.postForm(ctx, success.Href, form, &result); err != nil {
+		return "", err
+	}
+	if result.AccessToken == "" {
+		return "", errors.New("no access token returned for the interaction code")
+	}
+	return result.AccessToken, nil
+}
+
+// introspect returns the ID of the user the access token was issued to.
+func (l *idxLogin) introspect(ctx context.Context, accessToken string) (string, error) {
+	form := url.Values{
+		"client_id":       {l.cfg.ClientID},
+		"token":           {accessToken},
+		"token_type_hint": {"access_token"},
+	}
+	if l.cfg.ClientSecret != "" {
+		form.Set("client_secret", l.cfg.ClientSecret)
+	}
+
+	var result struct {
+		Active bool   `json:"active"`
+		UID    string `json:"uid"`
+	}
+	if err := l.postForm(ctx, l.issuerURL()+"/v1/introspect", form, &result); err != nil {
+		return "", err
+	}
+	if !result.Active {
+		return "", errors.New("the access token issued for the interaction code is not active")
+	}
+	if result.UID == "" {
+		return "", errors.New("no user ID returned for the access token")
+	}
+	return result.UID, nil
+}
+
+// checkHref returns an error unless href is served by the org, so that the
+// responses of the interaction API cannot send the credentials of the user
+// anywhere else.
+func (l *idxLogin) checkHref(href string) error {
+	u, err := url.Parse(href)
+	if err != nil {
+		return fmt.Errorf("invalid href: %w", err)
+	}
+	org, err := url.Parse(l.cfg.orgURL())
+	if err != nil {
+		return err
+	}
+	if u.Scheme != org.Scheme || !strings.EqualFold(u.Host, org.Host) {
+		return fmt.Errorf("href %q is not served by %q", href, l.cfg.orgURL())
+	}
+	return nil
+}
+
+func (l *idxLogin) post(ctx context.Context, href string, body map[string]interface{}) (*idxResponse, error) {
+	if err := l.checkHref(href); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, href, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", idxContentType)
+	httpReq.Header.Set("Accept", idxContentType)
+	l.setForwardedFor(httpReq)
+
+	resp, err := l.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Failed remediations are described by the messages of the response
+	var result idxResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxIDXResponseSize)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response with status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest && len(result.errorMessages()) == 0 {
+		return nil, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return &result, nil
+}
+
+func (l *idxLogin) postForm(ctx context.Context, href string, form url.Values, v interface{}) error {
+	if err := l.checkHref(href); err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, href, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	l.setForwardedFor(httpReq)
+
+	resp, err := l.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIDXResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			return fmt.Errorf("%s: %s", oauthErr.Error, oauthErr.ErrorDescription)
+		}
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (l *idxLogin) setForwardedFor(httpReq *http.Request) {
+	if forwardedFor := l.req.Headers[textproto.CanonicalMIMEHeaderKey("X-Forwarded-For")]; len(forwardedFor) > 0 {
+		httpReq.Header.Set("X-Forwarded-For", forwardedFor[0])
+	}
+}
+
+func randomURLSafeString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
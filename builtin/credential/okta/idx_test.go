@@ -0,0 +1,371 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package okta
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+const testIDXOrgURL = "https://foo-org.okta.com"
+
+// testIDXServer stubs the interaction API of an Okta Identity Engine org
+// using identifier first authentication, where the user verifies with a
+// password, then with the given second authenticator.
+type testIDXServer struct {
+	t             *testing.T
+	server        *httptest.Server
+	password      string
+	authenticator map[string]interface{}
+	codeChallenge string
+	polls         int
+
+	// foreignHref points the identify remediation outside of the org
+	foreignHref bool
+	identified  int
+	// inactiveToken has the access token introspected as inactive
+	inactiveToken bool
+}
+
+var (
+	testIDXPasswordAuthenticator = map[string]interface{}{
+		"id": "aut-password", "key": idxAuthenticatorPassword, "methods": []map[string]string{{"type": "password"}},
+	}
+	testIDXOktaVerifyAuthenticator = map[string]interface{}{
+		"id": "aut-okta-verify", "key": idxAuthenticatorOktaVerify, "methods": []map[string]string{{"type": "push"}, {"type": "totp"}},
+	}
+	testIDXGoogleAuthenticator = map[string]interface{}{
+		"id": "aut-google", "key": idxAuthenticatorGoogleOTP, "methods": []map[string]string{{"type": "otp"}},
+	}
+)
+
+func newTestIDXServer(t *testing.T, authenticator map[string]interface{}) *testIDXServer {
+	t.Helper()
+
+	s := &testIDXServer{t: t, password: "secret", authenticator: authenticator}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/default/v1/interact", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client-id", r.PostForm.Get("client_id"))
+		require.Equal(t, "S256", r.PostForm.Get("code_challenge_method"))
+		s.codeChallenge = r.PostForm.Get("code_challenge")
+		json.NewEncoder(w).Encode(map[string]string{"interaction_handle": "interaction-handle"})
+	})
+	mux.HandleFunc("/idp/idx/introspect", func(w http.ResponseWriter, r *http.Request) {
+		body := s.decode(r)
+		require.Equal(t, "interaction-handle", body["interactionHandle"])
+		s.respond(w, http.StatusOK, map[string]interface{}{
+			"remediation": s.remediations(s.remediation("identify", "identifier")),
+		})
+	})
+	mux.HandleFunc("/idp/idx/identify", func(w http.ResponseWriter, r *http.Request) {
+		body := s.decode(r)
+		require.Equal(t, "user-foo", body["identifier"])
+		s.identified++
+		s.respond(w, http.StatusOK, map[string]interface{}{
+			"remediation":    s.remediations(s.remediation("select-authenticator-authenticate", "authenticator")),
+			"authenticators": map[string]interface{}{"value": []interface{}{testIDXPasswordAuthenticator}},
+		})
+	})
+	mux.HandleFunc("/idp/idx/challenge", func(w http.ResponseWriter, r *http.Request) {
+		body := s.decode(r)
+		authenticator := body["authenticator"].(map[string]interface{})
+		switch authenticator["id"] {
+		case "aut-password":
+			s.respond(w, http.StatusOK, map[string]interface{}{
+				"remediation":                    s.remediations(s.remediation("challenge-authenticator", "credentials")),
+				"currentAuthenticatorEnrollment": map[string]interface{}{"value": testIDXPasswordAuthenticator},
+			})
+		case "aut-okta-verify":
+			if authenticator["methodType"] == "push" {
+				poll := s.remediation("challenge-poll")
+				poll["href"] = testIDXOrgURL + "/idp/idx/authenticators/poll"
+				s.respond(w, http.StatusOK, map[string]interface{}{
+					"remediation": s.remediations(poll),
+					"currentAuthenticator": map[string]interface{}{"value": map[string]interface{}{
+						"id": "aut-okta-verify", "key": idxAuthenticatorOktaVerify,
+						"contextualData": map[string]interface{}{"correctAnswer": 42},
+					}},
+				})
+				return
+			}
+			fallthrough
+		default:
+			require.Equal(t, s.authenticator["id"], authenticator["id"])
+			s.respond(w, http.StatusOK, map[string]interface{}{
+				"remediation":                    s.remediations(s.remediation("challenge-authenticator", "credentials")),
+				"currentAuthenticatorEnrollment": map[string]interface{}{"value": s.authenticator},
+			})
+		}
+	})
+	mux.HandleFunc("/idp/idx/challenge/answer", func(w http.ResponseWriter, r *http.Request) {
+		body := s.decode(r)
+		passcode := body["credentials"].(map[string]interface{})["passcode"]
+		switch {
+		case passcode == s.password:
+			s.respond(w, http.StatusOK, map[string]interface{}{
+				"remediation":    s.remediations(s.remediation("select-authenticator-authenticate", "authenticator")),
+				"authenticators": map[string]interface{}{"value": []interface{}{s.authenticator}},
+			})
+		case passcode == "123456" && s.authenticator["id"] != "aut-password":
+			s.success(w)
+		default:
+			s.respond(w, http.StatusUnauthorized, map[string]interface{}{
+				"messages": map[string]interface{}{"value": []interface{}{
+					map[string]string{"message": "Invalid code. Try again.", "class": "ERROR"},
+				}},
+			})
+		}
+	})
+	mux.HandleFunc("/idp/idx/authenticators/poll", func(w http.ResponseWriter, r *http.Request) {
+		s.decode(r)
+		s.polls++
+		s.success(w)
+	})
+	mux.HandleFunc("/oauth2/default/v1/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "interaction_code", r.PostForm.Get("grant_type"))
+		require.Equal(t, "interaction-code", r.PostForm.Get("interaction_code"))
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(challenge[:]) != s.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed."})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access-token", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/oauth2/default/v1/introspect", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "access-token", r.PostForm.Get("token"))
+		if s.inactiveToken {
+			json.NewEncoder(w).Encode(map[string]interface{}{"active": false})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "uid": "00u-foo", "sub": "user-foo"})
+	})
+
+	s.server = httptest.NewTLSServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// client returns an HTTP client sending the requests to the org to the stub.
+func (s *testIDXServer) client() *http.Client {
+	transport := s.server.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, s.server.Listener.Addr().String())
+	}
+	transport.TLSClientConfig.ServerName = "example.com"
+	return &http.Client{Transport: transport}
+}
+
+func (s *testIDXServer) decode(r *http.Request) map[string]interface{} {
+	s.t.Helper()
+	require.Equal(s.t, idxContentType, r.Header.Get("Content-Type"))
+	var body map[string]interface{}
+	require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+	if _, ok := body["interactionHandle"]; !ok {
+		require.Equal(s.t, "state-handle", body["stateHandle"])
+	}
+	return body
+}
+
+func (s *testIDXServer) respond(w http.ResponseWriter, status int, body map[string]interface{}) {
+	body["version"] = "1.0.0"
+	body["stateHandle"] = "state-handle"
+	w.Header().Set("Content-Type", idxContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *testIDXServer) remediation(name string, fields ...string) map[string]interface{} {
+	hrefs := map[string]string{
+		"identify":                          "/idp/idx/identify",
+		"select-authenticator-authenticate": "/idp/idx/challenge",
+		"challenge-authenticator":           "/idp/idx/challenge/answer",
+	}
+	values := []map[string]interface{}{{"name": "stateHandle", "value": "state-handle"}}
+	for _, field := range fields {
+		values = append(values, map[string]interface{}{"name": field})
+	}
+	orgURL := testIDXOrgURL
+	if name == "identify" && s.foreignHref {
+		orgURL = "https://foo-org.example.com"
+	}
+	return map[string]interface{}{
+		"name":    name,
+		"href":    orgURL + hrefs[name],
+		"method":  "POST",
+		"refresh": 10,
+		"value":   values,
+	}
+}
+
+func (s *testIDXServer) remediations(remediations ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "value": remediations}
+}
+
+func (s *testIDXServer) success(w http.ResponseWriter) {
+	s.respond(w, http.StatusOK, map[string]interface{}{
+		"user": map[string]interface{}{"value": map[string]string{"id": "00u-foo", "identifier": "user-foo"}},
+		"successWithInteractionCode": map[string]interface{}{
+			"name": "issue",
+			"href": testIDXOrgURL + "/oauth2/default/v1/token",
+			"value": []map[string]interface{}{
+				{"name": "grant_type", "value": "interaction_code"},
+				{"name": "interaction_code", "value": "interaction-code"},
+				{"name": "client_id", "value": "client-id"},
+				{"name": "code_verifier"},
+			},
+		},
+	})
+}
+
+func testIDXBackend(t *testing.T, s *testIDXServer) (*backend, logical.Storage) {
+	t.Helper()
+
+	lb, storage := getBackend(t)
+	b := lb.(*backend)
+	b.idxClient = s.client()
+
+	for _, req := range []*logical.Request{
+		{
+			Path: "config",
+			Data: map[string]interface{}{
+				"org_name":                "foo-org",
+				"login_flow":              "idx",
+				"client_id":               "client-id",
+				"authorization_server_id": "default",
+				"redirect_uri":            "http://localhost:8250/callback",
+			},
+		},
+		{Path: "users/user-foo", Data: map[string]interface{}{"groups": "local-group"}},
+		{Path: "groups/local-group", Data: map[string]interface{}{"policies": "local-policy"}},
+	} {
+		req.Operation = logical.UpdateOperation
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Error())
+	}
+	return b, storage
+}
+
+func testIDXLogin(b *backend, storage logical.Storage, data map[string]interface{}) (*logical.Response, error) {
+	data["password"] = "secret"
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Path:       "login/user-foo",
+		Operation:  logical.UpdateOperation,
+		Data:       data,
+		Storage:    storage,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+}
+
+func TestIDX_LoginPush(t *testing.T) {
+	s := newTestIDXServer(t, testIDXOktaVerifyAuthenticator)
+	b, storage := testIDXBackend(t, s)
+
+	// The number challenge of the push can only be retrieved with a nonce
+	resp, err := testIDXLogin(b, storage, map[string]interface{}{})
+	require.NoError(t, err)
+	require.ErrorContains(t, resp.Error(), "nonce must be provided")
+
+	resp, err = testIDXLogin(b, storage, map[string]interface{}{"nonce": "nonce"})
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	require.Equal(t, []string{"local-policy"}, resp.Auth.Policies)
+	require.Equal(t, "user-foo", resp.Auth.Alias.Name)
+	require.Equal(t, 1, s.polls)
+}
+
+func TestIDX_LoginTOTP(t *testing.T) {
+	for _, authenticator := range []map[string]interface{}{testIDXOktaVerifyAuthenticator, testIDXGoogleAuthenticator} {
+		t.Run(fmt.Sprint(authenticator["key"]), func(t *testing.T) {
+			s := newTestIDXServer(t, authenticator)
+			b, storage := testIDXBackend(t, s)
+
+			resp, err := testIDXLogin(b, storage, map[string]interface{}{"totp": "654321"})
+			require.NoError(t, err)
+			require.ErrorContains(t, resp.Error(), "Invalid code")
+
+			resp, err = testIDXLogin(b, storage, map[string]interface{}{"totp": "123456"})
+			require.NoError(t, err)
+			require.NoError(t, resp.Error())
+			require.Equal(t, []string{"local-policy"}, resp.Auth.Policies)
+			require.Zero(t, s.polls)
+		})
+	}
+
+	// Without a push authenticator, a passcode is required
+	s := newTestIDXServer(t, testIDXGoogleAuthenticator)
+	b, storage := testIDXBackend(t, s)
+	resp, err := testIDXLogin(b, storage, map[string]interface{}{})
+	require.NoError(t, err)
+	require.ErrorContains(t, resp.Error(), "'totp' passcode parameter is required")
+}
+
+func TestIDX_LoginInvalidPassword(t *testing.T) {
+	s := newTestIDXServer(t, testIDXOktaVerifyAuthenticator)
+	b, storage := testIDXBackend(t, s)
+	s.password = "other"
+
+	resp, err := testIDXLogin(b, storage, map[string]interface{}{"totp": "123456"})
+	require.NoError(t, err)
+	require.ErrorContains(t, resp.Error(), "Okta auth failed")
+	require.Nil(t, resp.Auth)
+}
+
+func TestIDX_LoginForeignHref(t *testing.T) {
+	s := newTestIDXServer(t, testIDXOktaVerifyAuthenticator)
+	b, storage := testIDXBackend(t, s)
+	s.foreignHref = true
+
+	resp, err := testIDXLogin(b, storage, map[string]interface{}{"totp": "123456"})
+	require.NoError(t, err)
+	require.ErrorContains(t, resp.Error(), "is not served by")
+	require.Nil(t, resp.Auth)
+	require.Zero(t, s.identified)
+}
+
+func TestIDX_LoginInactiveToken(t *testing.T) {
+	s := newTestIDXServer(t, testIDXOktaVerifyAuthenticator)
+	b, storage := testIDXBackend(t, s)
+	s.inactiveToken = true
+
+	resp, err := testIDXLogin(b, storage, map[string]interface{}{"totp": "123456"})
+	require.NoError(t, err)
+	require.ErrorContains(t, resp.Error(), "not active")
+	require.Nil(t, resp.Auth)
+}
+
+func TestIDX_ConfigValidation(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for _, data := range []map[string]interface{}{
+		{"login_flow": "idx", "redirect_uri": "http://localhost:8250/callback"},
+		{"login_flow": "idx", "client_id": "client-id"},
+		{"login_flow": "idx", "client_id": "client-id", "redirect_uri": "http://localhost:8250/callback", "bypass_okta_mfa": true},
+	} {
+		data["org_name"] = "foo-org"
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "config",
+			Operation: logical.UpdateOperation,
+			Data:      data,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError(), "%v", data)
+	}
+}
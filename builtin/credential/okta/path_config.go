@@ -22,6 +22,9 @@ import (
 const (
 	defaultBaseURL = "okta.com"
 	previewBaseURL = "oktapreview.com"
+
+	loginFlowAuthn = "authn"
+	loginFlowIDX   = "idx"
 )
 
 func pathConfig(b *backend) *framework.Path {
@@ -87,6 +90,44 @@ func pathConfig(b *backend) *framework.Path {
 					Name: "Bypass Okta MFA",
 				},
 			},
+			"login_flow": {
+				Type:          framework.TypeString,
+				Default:       loginFlowAuthn,
+				AllowedValues: []interface{}{loginFlowAuthn, loginFlowIDX},
+				Description:   `The Okta API to log users in with, "authn" for the classic authentication API or "idx" for the Identity Engine interaction API. Defaults to "authn".`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Login Flow",
+				},
+			},
+			"client_id": {
+				Type:        framework.TypeString,
+				Description: `Client ID of the Okta application with the Interaction Code grant enabled. Required when login_flow is "idx".`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Client ID",
+				},
+			},
+			"client_secret": {
+				Type:        framework.TypeString,
+				Description: `Client secret of the Okta application, if it is a confidential client. Only used when login_flow is "idx".`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Client Secret",
+					Sensitive: true,
+				},
+			},
+			"authorization_server_id": {
+				Type:        framework.TypeString,
+				Description: `ID of the custom authorization server to interact with, such as "default". When unset, the org authorization server is used. Only used when login_flow is "idx".`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Authorization Server ID",
+				},
+			},
+			"redirect_uri": {
+				Type:        framework.TypeString,
+				Description: `Sign-in redirect URI of the Okta application. It is never redirected to, but Okta requires it to match the application. Required when login_flow is "idx".`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Redirect URI",
+				},
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -160,9 +201,16 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *f
 		"organization":    cfg.Org,
 		"org_name":        cfg.Org,
 		"bypass_okta_mfa": cfg.BypassOktaMFA,
+		"login_flow":      cfg.loginFlow(),
 	}
 	cfg.PopulateTokenData(data)
 
+	if cfg.LoginFlow == loginFlowIDX {
+		data["client_id"] = cfg.ClientID
+		data["authorization_server_id"] = cfg.AuthorizationServerID
+		data["redirect_uri"] = cfg.RedirectURI
+	}
+
 	if cfg.BaseURL != "" {
 		data["base_url"] = cfg.BaseURL
 	}
@@ -248,6 +296,32 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		cfg.BypassOktaMFA = bypass.(bool)
 	}
 
+	if loginFlow, ok := d.GetOk("login_flow"); ok {
+		cfg.LoginFlow = loginFlow.(string)
+	}
+	if clientID, ok := d.GetOk("client_id"); ok {
+		cfg.ClientID = clientID.(string)
+	}
+	if clientSecret, ok := d.GetOk("client_secret"); ok {
+		cfg.ClientSecret = clientSecret.(string)
+	}
+	if authorizationServerID, ok := d.GetOk("authorization_server_id"); ok {
+		cfg.AuthorizationServerID = authorizationServerID.(string)
+	}
+	if redirectURI, ok := d.GetOk("redirect_uri"); ok {
+		cfg.RedirectURI = redirectURI.(string)
+	}
+	if cfg.LoginFlow == loginFlowIDX {
+		switch {
+		case cfg.ClientID == "":
+			return logical.ErrorResponse(`client_id is required when login_flow is "idx"`), nil
+		case cfg.RedirectURI == "":
+			return logical.ErrorResponse(`redirect_uri is required when login_flow is "idx"`), nil
+		case cfg.BypassOktaMFA:
+			return logical.ErrorResponse(`bypass_okta_mfa is not supported when login_flow is "idx", as Okta Identity Engine enforces the authenticators required by its policies`), nil
+		}
+	}
+
 	if err := cfg.ParseTokenFields(req, d); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
@@ -331,8 +405,7 @@ func (new *oktaShimOld) Do(req *http.Request, v interface{}) (interface{}, error
 	return new.client.Do(req, v)
 }
 
-// OktaClient creates a basic okta client connection
-func (c *ConfigEntry) OktaClient(ctx context.Context) (oktaShim, error) {
+func (c *ConfigEntry) baseURL() string {
 	baseURL := defaultBaseURL
 	if c.Production != nil {
 		if !*c.Production {
@@ -342,10 +415,21 @@ func (c *ConfigEntry) OktaClient(ctx context.Context) (oktaShim, error) {
 	if c.BaseURL != "" {
 		baseURL = c.BaseURL
 	}
+	return baseURL
+}
+
+// orgURL returns the URL of the Okta organization.
+func (c *ConfigEntry) orgURL() string {
+	return "https://" + c.Org + "." + c.baseURL()
+}
+
+// OktaClient creates a basic okta client connection
+func (c *ConfigEntry) OktaClient(ctx context.Context) (oktaShim, error) {
+	baseURL := c.baseURL()
 
 	if c.Token != "" {
 		ctx, client, err := oktanew.NewClient(ctx,
-			oktanew.WithOrgUrl("https://"+c.Org+"."+baseURL),
+			oktanew.WithOrgUrl(c.orgURL()),
 			oktanew.WithToken(c.Token))
 		if err != nil {
 			return nil, err
@@ -370,6 +454,19 @@ type ConfigEntry struct {
 	TTL           time.Duration `json:"ttl"`
 	MaxTTL        time.Duration `json:"max_ttl"`
 	BypassOktaMFA bool          `json:"bypass_okta_mfa"`
+
+	LoginFlow             string `json:"login_flow,omitempty"`
+	ClientID              string `json:"client_id,omitempty"`
+	ClientSecret          string `json:"client_secret,omitempty"`
+	AuthorizationServerID string `json:"authorization_server_id,omitempty"`
+	RedirectURI           string `json:"redirect_uri,omitempty"`
+}
+
+func (c *ConfigEntry) loginFlow() string {
+	if c.LoginFlow == "" {
+		return loginFlowAuthn
+	}
+	return c.LoginFlow
 }
 
 const pathConfigHelp = `
@@ -378,4 +475,11 @@ configuration options.
 
 The Okta organization are the characters at the front of the URL for Okta.
 Example https://ORG.okta.com
+
+Organizations on Okta Identity Engine can set "login_flow" to "idx" to log
+users in through the interaction API, with the client ID and redirect URI of
+an Okta application that has the Interaction Code grant enabled. The user
+logged in is the one the access token issued at the end of the interaction
+belongs to, as reported by the introspection endpoint of the authorization
+server.
 `
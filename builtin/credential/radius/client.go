@@ -0,0 +1,179 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"layeh.com/radius"
+)
+
+const (
+	transportUDP = "udp"
+	transportTLS = "tls"
+
+	// defaultRadSecPort is the port assigned to RADIUS over TLS by RFC 6614.
+	defaultRadSecPort = 2083
+
+	// defaultRadSecSecret is the shared secret mandated by RFC 6614 when the
+	// transport is protected by TLS.
+	defaultRadSecSecret = "radsec"
+)
+
+// servers returns the addresses of the configured RADIUS servers, in the order
+// they should be tried.
+func (c *ConfigEntry) servers() []string {
+	servers := make([]string, 0, 1+len(c.FailoverHosts))
+	servers = append(servers, net.JoinHostPort(c.Host, strconv.Itoa(c.Port)))
+	for _, host := range c.FailoverHosts {
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			servers = append(servers, host)
+			continue
+		}
+		servers = append(servers, net.JoinHostPort(host, strconv.Itoa(c.Port)))
+	}
+	return servers
+}
+
+// hasServer returns whether hostport is one of the configured servers.
+func (c *ConfigEntry) hasServer(hostport string) bool {
+	for _, server := range c.servers() {
+		if server == hostport {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsConfig returns the TLS configuration used to connect to RadSec servers.
+func (c *ConfigEntry) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.TLSServerName,
+	}
+
+	if c.TLSCACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.TLSCACert)) {
+			return nil, errors.New("could not parse tls_ca_cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.TLSClientCert != "" || c.TLSClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(c.TLSClientCert), []byte(c.TLSClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse tls_client_cert and tls_client_key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// exchange sends the packet to the configured servers in order, until one of
+// them answers. Servers which cannot be reached are skipped, but an answer,
+// even a rejection, is final. The address of the server which answered is
+// returned along with its answer.
+func (b *backend) exchange(ctx context.Context, cfg *ConfigEntry, packet *radius.Packet) (*radius.Packet, string, error) {
+	var errs *multierror.Error
+	for _, hostport := range cfg.servers() {
+		received, err := b.exchangeWith(ctx, cfg, packet, hostport)
+		if err == nil {
+			return received, hostport, nil
+		}
+		b.Logger().Warn("radius server unavailable", "server", hostport, "error", err)
+		errs = multierror.Append(errs, fmt.Errorf("%s: %w", hostport, err))
+	}
+
+	return nil, "", errs.ErrorOrNil()
+}
+
+// exchangeWith sends the packet to the given server only.
+func (b *backend) exchangeWith(ctx context.Context, cfg *ConfigEntry, packet *radius.Packet, hostport string) (*radius.Packet, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ReadTimeout)*time.Second)
+	defer cancel()
+
+	dialer := net.Dialer{
+		Timeout: time.Duration(cfg.DialTimeout) * time.Second,
+	}
+
+	if cfg.Transport != transportTLS {
+		client := radius.Client{
+			Dialer: dialer,
+		}
+		return client.Exchange(ctx, packet, hostport)
+	}
+
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	tlsDialer := tls.Dialer{
+		NetDialer: &dialer,
+		Config:    tlsConfig,
+	}
+	conn, err := tlsDialer.DialContext(ctx, "tcp", hostport)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	return exchangeStream(conn, packet)
+}
+
+// exchangeStream sends the packet over a stream connection as described by
+// RFC 6613, and reads the matching response.
+func exchangeStream(conn io.ReadWriter, packet *radius.Packet) (*radius.Packet, error) {
+	wire, err := packet.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(wire); err != nil {
+		return nil, err
+	}
+
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(header[2:4]))
+		if length < 20 || length > radius.MaxPacketLength {
+			return nil, errors.New("radius: invalid packet length")
+		}
+		incoming := make([]byte, length)
+		copy(incoming, header)
+		if _, err := io.ReadFull(conn, incoming[4:]); err != nil {
+			return nil, err
+		}
+
+		// Responses to other requests can't be received on a dedicated
+		// connection, but are skipped like the UDP client does
+		if incoming[1] != packet.Identifier {
+			continue
+		}
+		if !radius.IsAuthenticResponse(incoming, wire, packet.Secret) {
+			return nil, &radius.NonAuthenticResponseError{}
+		}
+
+		return radius.Parse(incoming, packet.Secret)
+	}
+}
@@ -83,6 +83,50 @@ func pathConfig(b *backend) *framework.Path {
 					Name: "NAS Identifier",
 				},
 			},
+			"failover_hosts": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of additional RADIUS servers, as host or host:port, tried in order when the previous servers can't be reached (optional)",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Failover hosts",
+				},
+			},
+			"transport": {
+				Type:        framework.TypeString,
+				Default:     transportUDP,
+				Description: `Transport used to reach the RADIUS servers, "udp" or "tls" for RADIUS over TLS (RadSec) (default: udp)`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Value: transportUDP,
+				},
+			},
+			"tls_ca_cert": {
+				Type:        framework.TypeString,
+				Description: "PEM-encoded CA certificates used to verify RadSec servers. Defaults to the system CAs (optional)",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "TLS CA certificate",
+				},
+			},
+			"tls_client_cert": {
+				Type:        framework.TypeString,
+				Description: "PEM-encoded client certificate presented to RadSec servers (optional)",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "TLS client certificate",
+				},
+			},
+			"tls_client_key": {
+				Type:        framework.TypeString,
+				Description: "PEM-encoded private key of the client certificate (optional)",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "TLS client key",
+					Sensitive: true,
+				},
+			},
+			"tls_server_name": {
+				Type:        framework.TypeString,
+				Description: "Name expected in the certificates of RadSec servers. Defaults to the host (optional)",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "TLS server name",
+				},
+			},
 		},
 
 		ExistenceCheck: b.configExistenceCheck,
@@ -146,6 +190,11 @@ func (b *backend) Config(ctx context.Context, req *logical.Request) (*ConfigEntr
 		return nil, err
 	}
 
+	// Configurations written before the transport could be set use UDP
+	if result.Transport == "" {
+		result.Transport = transportUDP
+	}
+
 	return &result, nil
 }
 
@@ -166,6 +215,11 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *f
 		"read_timeout":               cfg.ReadTimeout,
 		"nas_port":                   cfg.NasPort,
 		"nas_identifier":             cfg.NasIdentifier,
+		"failover_hosts":             cfg.FailoverHosts,
+		"transport":                  cfg.Transport,
+		"tls_ca_cert":                cfg.TLSCACert,
+		"tls_client_cert":            cfg.TLSClientCert,
+		"tls_server_name":            cfg.TLSServerName,
 	}
 	cfg.PopulateTokenData(data)
 
@@ -198,11 +252,24 @@ func (b *backend) pathConfigCreateUpdate(ctx context.Context, req *logical.Reque
 		return logical.ErrorResponse("config parameter `host` cannot be empty"), nil
 	}
 
+	transport, ok := d.GetOk("transport")
+	if ok {
+		cfg.Transport = transport.(string)
+	} else if req.Operation == logical.CreateOperation {
+		cfg.Transport = d.Get("transport").(string)
+	}
+	if cfg.Transport != transportUDP && cfg.Transport != transportTLS {
+		return logical.ErrorResponse("config parameter `transport` must be %q or %q", transportUDP, transportTLS), nil
+	}
+
 	port, ok := d.GetOk("port")
 	if ok {
 		cfg.Port = port.(int)
 	} else if req.Operation == logical.CreateOperation {
 		cfg.Port = d.Get("port").(int)
+		if cfg.Transport == transportTLS {
+			cfg.Port = defaultRadSecPort
+		}
 	}
 
 	secret, ok := d.GetOk("secret")
@@ -211,6 +278,9 @@ func (b *backend) pathConfigCreateUpdate(ctx context.Context, req *logical.Reque
 	} else if req.Operation == logical.CreateOperation {
 		cfg.Secret = d.Get("secret").(string)
 	}
+	if cfg.Secret == "" && cfg.Transport == transportTLS {
+		cfg.Secret = defaultRadSecSecret
+	}
 	if cfg.Secret == "" {
 		return logical.ErrorResponse("config parameter `secret` cannot be empty"), nil
 	}
@@ -260,6 +330,29 @@ func (b *backend) pathConfigCreateUpdate(ctx context.Context, req *logical.Reque
 		cfg.NasIdentifier = d.Get("nas_identifier").(string)
 	}
 
+	failoverHosts, ok := d.GetOk("failover_hosts")
+	if ok {
+		cfg.FailoverHosts = failoverHosts.([]string)
+	}
+
+	if tlsCACert, ok := d.GetOk("tls_ca_cert"); ok {
+		cfg.TLSCACert = tlsCACert.(string)
+	}
+	if tlsClientCert, ok := d.GetOk("tls_client_cert"); ok {
+		cfg.TLSClientCert = tlsClientCert.(string)
+	}
+	if tlsClientKey, ok := d.GetOk("tls_client_key"); ok {
+		cfg.TLSClientKey = tlsClientKey.(string)
+	}
+	if tlsServerName, ok := d.GetOk("tls_server_name"); ok {
+		cfg.TLSServerName = tlsServerName.(string)
+	}
+	if cfg.Transport == transportTLS {
+		if _, err := cfg.tlsConfig(); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
 	entry, err := logical.StorageEntryJSON("config", cfg)
 	if err != nil {
 		return nil, err
@@ -282,6 +375,12 @@ type ConfigEntry struct {
 	ReadTimeout              int      `json:"read_timeout" structs:"read_timeout" mapstructure:"read_timeout"`
 	NasPort                  int      `json:"nas_port" structs:"nas_port" mapstructure:"nas_port"`
 	NasIdentifier            string   `json:"nas_identifier" structs:"nas_identifier" mapstructure:"nas_identifier"`
+	FailoverHosts            []string `json:"failover_hosts" structs:"failover_hosts" mapstructure:"failover_hosts"`
+	Transport                string   `json:"transport" structs:"transport" mapstructure:"transport"`
+	TLSCACert                string   `json:"tls_ca_cert" structs:"tls_ca_cert" mapstructure:"tls_ca_cert"`
+	TLSClientCert            string   `json:"tls_client_cert" structs:"tls_client_cert" mapstructure:"tls_client_cert"`
+	TLSClientKey             string   `json:"tls_client_key" structs:"tls_client_key" mapstructure:"tls_client_key"`
+	TLSServerName            string   `json:"tls_server_name" structs:"tls_server_name" mapstructure:"tls_server_name"`
 }
 
 const pathConfigHelpSyn = `
@@ -291,4 +390,12 @@ Configure the RADIUS server to connect to, along with its options.
 const pathConfigHelpDesc = `
 This endpoint allows you to configure the RADIUS server to connect to and its
 configuration options.
+
+Additional servers can be listed in "failover_hosts". They are tried in order
+when the previous servers can't be reached, but a rejection by any server is
+final.
+
+Setting "transport" to "tls" connects to the servers using RADIUS over TLS
+(RadSec), as described in RFC 6614. The port then defaults to 2083 and the
+secret to "radsec".
 `
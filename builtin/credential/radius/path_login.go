@@ -5,11 +5,10 @@ package radius
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"net"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
@@ -42,7 +41,12 @@ func pathLogin(b *backend) *framework.Path {
 
 			"password": {
 				Type:        framework.TypeString,
-				Description: "Password for this user.",
+				Description: "Password for this user, or the response to the challenge of the authentication server.",
+			},
+
+			"state": {
+				Type:        framework.TypeString,
+				Description: "State returned by a previous login request when the authentication server sent a challenge. Required to answer that challenge.",
 			},
 		},
 
@@ -105,7 +109,9 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		return logical.ErrorResponse("password cannot be empty"), nil
 	}
 
-	policies, resp, err := b.RadiusLogin(ctx, req, username, password)
+	state := d.Get("state").(string)
+
+	policies, resp, err := b.RadiusLogin(ctx, req, username, password, state)
 	// Handle an internal error
 	if err != nil {
 		return nil, err
@@ -115,6 +121,10 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		if resp.IsError() {
 			return resp, nil
 		}
+		// Handle a challenge, which the client must answer
+		if isChallengeResponse(resp) {
+			return resp, nil
+		}
 	}
 
 	auth := &logical.Auth{
@@ -122,14 +132,17 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 			"username": username,
 			"policies": strings.Join(policies, ","),
 		},
-		InternalData: map[string]interface{}{
-			"password": password,
-		},
-		DisplayName: username,
+		InternalData: map[string]interface{}{},
+		DisplayName:  username,
 		Alias: &logical.Alias{
 			Name: username,
 		},
 	}
+	// Responses to challenges are usually one-time codes, which can't be used
+	// again to renew the token
+	if state == "" {
+		auth.InternalData["password"] = password
+	}
 	cfg.PopulateTokenAuth(auth)
 
 	resp.Auth = auth
@@ -150,14 +163,25 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	}
 
 	username := req.Auth.Metadata["username"]
-	password := req.Auth.InternalData["password"].(string)
 
 	var resp *logical.Response
 	var loginPolicies []string
 
-	loginPolicies, resp, err = b.RadiusLogin(ctx, req, username, password)
-	if err != nil || (resp != nil && resp.IsError()) {
-		return resp, err
+	password, ok := req.Auth.InternalData["password"].(string)
+	if ok {
+		loginPolicies, resp, err = b.RadiusLogin(ctx, req, username, password, "")
+		if err != nil || (resp != nil && resp.IsError()) {
+			return resp, err
+		}
+		if isChallengeResponse(resp) {
+			return nil, fmt.Errorf("authentication server requires a response to its challenge, not renewing")
+		}
+	} else {
+		// The login answered a challenge, which can't be answered again
+		loginPolicies, err = b.userPolicies(ctx, req, cfg, username)
+		if err != nil {
+			return nil, err
+		}
 	}
 	finalPolicies := cfg.TokenPolicies
 	if loginPolicies != nil {
@@ -174,7 +198,7 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	return &logical.Response{Auth: req.Auth}, nil
 }
 
-func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, username string, password string) ([]string, *logical.Response, error) {
+func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, username string, password string, state string) ([]string, *logical.Response, error) {
 	cfg, err := b.Config(ctx, req)
 	if err != nil {
 		return nil, nil, err
@@ -183,8 +207,6 @@ func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, usernam
 		return nil, logical.ErrorResponse("radius backend not configured"), nil
 	}
 
-	hostport := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
-
 	packet := radius.New(radius.CodeAccessRequest, []byte(cfg.Secret))
 	UserName_SetString(packet, username)
 	UserPassword_SetString(packet, password)
@@ -193,33 +215,114 @@ func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, usernam
 	}
 	packet.Add(5, radius.NewInteger(uint32(cfg.NasPort)))
 
-	client := radius.Client{
-		Dialer: net.Dialer{
-			Timeout: time.Duration(cfg.DialTimeout) * time.Second,
-		},
+	var received *radius.Packet
+	var hostport string
+	if state != "" {
+		// The response to a challenge must be sent to the server which sent
+		// it, along with its state
+		challenge, parseErr := parseChallengeState(state)
+		if parseErr != nil || !cfg.hasServer(challenge.Server) {
+			return nil, logical.ErrorResponse("invalid state"), nil
+		}
+		if err := State_Set(packet, challenge.State); err != nil {
+			return nil, logical.ErrorResponse("invalid state"), nil
+		}
+		hostport = challenge.Server
+		received, err = b.exchangeWith(ctx, cfg, packet, hostport)
+	} else {
+		received, hostport, err = b.exchange(ctx, cfg, packet)
 	}
-	clientCtx, cancelFunc := context.WithTimeout(ctx, time.Duration(cfg.ReadTimeout)*time.Second)
-	received, err := client.Exchange(clientCtx, packet, hostport)
-	cancelFunc()
 	if err != nil {
 		return nil, logical.ErrorResponse(err.Error()), nil
 	}
-	if received.Code != radius.CodeAccessAccept {
+
+	switch received.Code {
+	case radius.CodeAccessAccept:
+	case radius.CodeAccessChallenge:
+		resp, err := challengeResponse(hostport, received)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, resp, nil
+	default:
 		return nil, logical.ErrorResponse("access denied by the authentication server"), nil
 	}
 
-	policies := cfg.UnregisteredUserPolicies
+	policies, err := b.userPolicies(ctx, req, cfg, username)
+	if err != nil {
+		return nil, logical.ErrorResponse("could not retrieve user entry from storage"), err
+	}
+
+	return policies, &logical.Response{}, nil
+}
 
-	// Retrieve user entry from storage
+// userPolicies returns the policies of the user, or the policies of
+// unregistered users if it has no entry.
+func (b *backend) userPolicies(ctx context.Context, req *logical.Request, cfg *ConfigEntry, username string) ([]string, error) {
 	user, err := b.user(ctx, req.Storage, username)
 	if err != nil {
-		return nil, logical.ErrorResponse("could not retrieve user entry from storage"), err
+		return nil, err
 	}
 	if user != nil {
-		policies = user.Policies
+		return user.Policies, nil
 	}
 
-	return policies, &logical.Response{}, nil
+	return cfg.UnregisteredUserPolicies, nil
+}
+
+// challengeState is handed to the client when a server sends a challenge, so
+// that the response to the challenge can be sent to the same server along
+// with the RADIUS State attribute of the challenge.
+type challengeState struct {
+	Server string `json:"server"`
+	State  []byte `json:"state"`
+}
+
+func parseChallengeState(state string) (*challengeState, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var challenge challengeState
+	if err := json.Unmarshal(raw, &challenge); err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// challengeResponse returns the response to a login request challenged by the
+// server at hostport. It holds the state the client must send back with its
+// response, and the message of the server to show to the user.
+func challengeResponse(hostport string, challenge *radius.Packet) (*logical.Response, error) {
+	raw, err := json.Marshal(&challengeState{
+		Server: hostport,
+		State:  State_Get(challenge),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := ReplyMessage_GetStrings(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"state":         base64.RawURLEncoding.EncodeToString(raw),
+			"reply_message": strings.Join(messages, "\n"),
+		},
+	}, nil
+}
+
+func isChallengeResponse(resp *logical.Response) bool {
+	if resp == nil || resp.Auth != nil {
+		return false
+	}
+	_, ok := resp.Data["state"]
+	return ok
 }
 
 const pathLoginSyn = `
@@ -229,4 +332,13 @@ Log in with a username and password.
 const pathLoginDesc = `
 This endpoint authenticates using a username and password. Please be sure to
 read the note on escaping from the path-help for the 'config' endpoint.
+
+When the authentication server sends a challenge, for instance to ask for a
+second factor, no token is returned. The response holds the message of the
+server in "reply_message" and a "state". The challenge is answered by logging
+in again with the same username, the answer as the password, and the state.
+Servers may send several challenges in a row.
+
+Tokens obtained by answering a challenge are not authenticated against the
+server again when they are renewed.
 `
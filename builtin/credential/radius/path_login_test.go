@@ -0,0 +1,307 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package radius
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/testhelpers/certhelpers"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	"layeh.com/radius"
+	. "layeh.com/radius/rfc2865"
+)
+
+const testSecret = "testing123"
+
+// testRadiusResponse answers requests like a server asking for a one-time code
+// once the password of the user is verified.
+func testRadiusResponse(request *radius.Packet) *radius.Packet {
+	username := UserName_GetString(request)
+	password := UserPassword_GetString(request)
+
+	switch state := State_GetString(request); {
+	case username == "user" && password == "password" && state == "":
+		response := request.Response(radius.CodeAccessChallenge)
+		State_SetString(response, "otp")
+		ReplyMessage_SetString(response, "Enter your code")
+		return response
+	case username == "user" && password == "123456" && state == "otp":
+		return request.Response(radius.CodeAccessAccept)
+	case username == "single" && password == "password" && state == "":
+		return request.Response(radius.CodeAccessAccept)
+	default:
+		return request.Response(radius.CodeAccessReject)
+	}
+}
+
+// testUDPServer starts a RADIUS server, returning its port.
+func testUDPServer(t *testing.T) int {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &radius.PacketServer{
+		SecretSource: radius.StaticSecretSource([]byte(testSecret)),
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, r *radius.Request) {
+			w.Write(testRadiusResponse(r.Packet))
+		}),
+	}
+	go server.Serve(conn)
+	t.Cleanup(func() {
+		server.Shutdown(context.Background())
+	})
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// testRadSecServer starts a RADIUS over TLS server using the RFC 6614 secret,
+// returning its port.
+func testRadSecServer(t *testing.T, tlsConfig *tls.Config) int {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					header := make([]byte, 4)
+					if _, err := io.ReadFull(conn, header); err != nil {
+						return
+					}
+					wire := make([]byte, binary.BigEndian.Uint16(header[2:4]))
+					copy(wire, header)
+					if _, err := io.ReadFull(conn, wire[4:]); err != nil {
+						return
+					}
+					request, err := radius.Parse(wire, []byte(defaultRadSecSecret))
+					if err != nil {
+						return
+					}
+					response, err := testRadiusResponse(request).Encode()
+					if err != nil {
+						return
+					}
+					if _, err := conn.Write(response); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// testClosedPort returns a local UDP port nothing listens on.
+func testClosedPort(t *testing.T) int {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	require.NoError(t, conn.Close())
+
+	return port
+}
+
+func testBackendWithConfig(t *testing.T, config map[string]interface{}) (logical.Backend, logical.Storage) {
+	t.Helper()
+
+	storage := &logical.InmemStorage{}
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: testSysTTL,
+			MaxLeaseTTLVal:     testSysMaxTTL,
+		},
+		StorageView: storage,
+	})
+	require.NoError(t, err)
+
+	config["unregistered_user_policies"] = "unregistered"
+	config["read_timeout"] = 2
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data:      config,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "%v", resp)
+
+	return b, storage
+}
+
+func testLogin(t *testing.T, b logical.Backend, storage logical.Storage, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation:  logical.UpdateOperation,
+		Path:       "login",
+		Storage:    storage,
+		Data:       data,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	return resp
+}
+
+// testChallengeLogin logs in as a user which must answer a challenge.
+func testChallengeLogin(t *testing.T, b logical.Backend, storage logical.Storage) {
+	t.Helper()
+
+	resp := testLogin(t, b, storage, map[string]interface{}{
+		"username": "user",
+		"password": "password",
+	})
+	require.False(t, resp.IsError(), "%v", resp)
+	require.Nil(t, resp.Auth)
+	require.Equal(t, "Enter your code", resp.Data["reply_message"])
+	state := resp.Data["state"].(string)
+	require.NotEmpty(t, state)
+
+	resp = testLogin(t, b, storage, map[string]interface{}{
+		"username": "user",
+		"password": "000000",
+		"state":    state,
+	})
+	require.True(t, resp.IsError())
+
+	resp = testLogin(t, b, storage, map[string]interface{}{
+		"username": "user",
+		"password": "123456",
+		"state":    state,
+	})
+	require.False(t, resp.IsError(), "%v", resp)
+	require.NotNil(t, resp.Auth)
+	require.Contains(t, resp.Auth.Policies, "unregistered")
+	require.NotContains(t, resp.Auth.InternalData, "password")
+}
+
+func TestBackend_LoginChallenge(t *testing.T) {
+	port := testUDPServer(t)
+	b, storage := testBackendWithConfig(t, map[string]interface{}{
+		"host":   "127.0.0.1",
+		"port":   port,
+		"secret": testSecret,
+	})
+
+	testChallengeLogin(t, b, storage)
+
+	// Logins which are not challenged are unchanged
+	resp := testLogin(t, b, storage, map[string]interface{}{
+		"username": "single",
+		"password": "password",
+	})
+	require.False(t, resp.IsError(), "%v", resp)
+	require.NotNil(t, resp.Auth)
+	require.Equal(t, "password", resp.Auth.InternalData["password"])
+
+	// The state can't point to a server which is not configured
+	resp = testLogin(t, b, storage, map[string]interface{}{
+		"username": "user",
+		"password": "123456",
+		"state":    "eyJzZXJ2ZXIiOiIxMC4wLjAuMToxODEyIiwic3RhdGUiOiJiM1J3In0",
+	})
+	require.True(t, resp.IsError())
+	require.Equal(t, "invalid state", resp.Error().Error())
+}
+
+func TestBackend_LoginFailover(t *testing.T) {
+	port := testUDPServer(t)
+	b, storage := testBackendWithConfig(t, map[string]interface{}{
+		"host":           "127.0.0.1",
+		"port":           testClosedPort(t),
+		"secret":         testSecret,
+		"failover_hosts": "127.0.0.1:" + strconv.Itoa(port),
+	})
+
+	testChallengeLogin(t, b, storage)
+
+	// A rejection is final
+	resp := testLogin(t, b, storage, map[string]interface{}{
+		"username": "user",
+		"password": "wrong",
+	})
+	require.True(t, resp.IsError())
+	require.Equal(t, "access denied by the authentication server", resp.Error().Error())
+}
+
+func TestBackend_LoginRadSec(t *testing.T) {
+	ca := certhelpers.NewCert(t,
+		certhelpers.CommonName("ca"),
+		certhelpers.IsCA(true),
+		certhelpers.SelfSign(),
+	)
+	serverCert := certhelpers.NewCert(t,
+		certhelpers.CommonName("radius"),
+		certhelpers.Parent(ca),
+		certhelpers.IP("127.0.0.1"),
+	)
+
+	port := testRadSecServer(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCert.TLSCert},
+	})
+	b, storage := testBackendWithConfig(t, map[string]interface{}{
+		"host":        "127.0.0.1",
+		"port":        port,
+		"transport":   "tls",
+		"tls_ca_cert": string(ca.Pem),
+	})
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "tls", resp.Data["transport"])
+
+	testChallengeLogin(t, b, storage)
+}
+
+func TestBackend_ConfigRadSecDefaults(t *testing.T) {
+	b, storage := testBackendWithConfig(t, map[string]interface{}{
+		"host":      "radius.example.com",
+		"transport": "tls",
+	})
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Equal(t, defaultRadSecPort, resp.Data["port"])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"transport": "tcp",
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+}
@@ -37,6 +37,7 @@ func Backend() *backend {
 			pathUserPolicies(&b),
 			pathUserPassword(&b),
 			pathLogin(&b),
+			pathConfig(&b),
 		},
 
 		AuthRenew:   b.pathLoginRenew,
@@ -0,0 +1,165 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package userpass
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	pathConfigHelpSyn = `
+Configure the password requirements of the backend.
+`
+	pathConfigHelpDesc = `
+This endpoint allows configuring the requirements passwords must meet when
+they are set through the "users/" endpoints.
+
+"password_policy" names a password policy of Vault which new passwords must
+satisfy. The policy must exist, and be able to generate a password, when it
+is configured.
+
+"password_history" rejects new passwords matching any of the given number of
+most recent passwords of the user, including the current one.
+
+Pre-hashed passwords cannot be checked against the policy or the history, so
+"password_hash" is rejected while either of them is configured.
+
+"password_max_age" rejects logins with passwords older than the given
+duration, until the password is reset. Passwords last set by older versions
+of Vault have no known age and are not subject to it.
+`
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixUserpass,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"password_policy": {
+				Type:        framework.TypeString,
+				Description: "Name of the password policy new passwords must satisfy.",
+			},
+			"password_history": {
+				Type:        framework.TypeInt,
+				Description: "Number of previous passwords of a user which cannot be reused, including the current one. Defaults to 0, which allows any password to be reused.",
+			},
+			"password_max_age": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration after which the password of a user expires and must be reset before they can log in again. Defaults to 0, which never expires passwords.",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb: "configure",
+				},
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigRead,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "configuration",
+				},
+			},
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	cfg, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if passwordPolicyRaw, ok := d.GetOk("password_policy"); ok {
+		cfg.PasswordPolicy = passwordPolicyRaw.(string)
+	}
+	if passwordHistoryRaw, ok := d.GetOk("password_history"); ok {
+		passwordHistory := passwordHistoryRaw.(int)
+		if passwordHistory < 0 {
+			return logical.ErrorResponse("password_history must not be negative"), nil
+		}
+		cfg.PasswordHistory = passwordHistory
+	}
+	if passwordMaxAgeRaw, ok := d.GetOk("password_max_age"); ok {
+		passwordMaxAge := time.Duration(passwordMaxAgeRaw.(int)) * time.Second
+		if passwordMaxAge < 0 {
+			return logical.ErrorResponse("password_max_age must not be negative"), nil
+		}
+		cfg.PasswordMaxAge = passwordMaxAge
+	}
+
+	if cfg.PasswordPolicy != "" {
+		if _, ok := b.System().(logical.PasswordPolicyValidatorSystemView); !ok {
+			return logical.ErrorResponse("password policies are not supported by this mount"), nil
+		}
+		// Generating a password checks that the policy exists and can be
+		// satisfied, so that it does not lock out every password change.
+		if _, err := b.System().GeneratePasswordFromPolicy(ctx, cfg.PasswordPolicy); err != nil {
+			return logical.ErrorResponse("unable to use password policy %q: %s", cfg.PasswordPolicy, err), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config", cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	cfg, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"password_policy":  cfg.PasswordPolicy,
+			"password_history": cfg.PasswordHistory,
+			"password_max_age": int64(cfg.PasswordMaxAge.Seconds()),
+		},
+	}, nil
+}
+
+// config returns the configuration of the backend, or the default
+// configuration if none was written.
+func (b *backend) config(ctx context.Context, s logical.Storage) (*passwordConfig, error) {
+	entry, err := s.Get(ctx, "config")
+	if err != nil {
+		return nil, err
+	}
+
+	var result passwordConfig
+	if entry != nil {
+		if err := entry.DecodeJSON(&result); err != nil {
+			return nil, fmt.Errorf("error reading configuration: %w", err)
+		}
+	}
+
+	return &result, nil
+}
+
+type passwordConfig struct {
+	PasswordPolicy  string        `json:"password_policy"`
+	PasswordHistory int           `json:"password_history"`
+	PasswordMaxAge  time.Duration `json:"password_max_age"`
+}
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package userpass
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicySystemView validates passwords against a policy requiring at least
+// eight characters.
+type testPolicySystemView struct {
+	logical.StaticSystemView
+}
+
+func (testPolicySystemView) ValidatePasswordFromPolicy(_ context.Context, policyName, password string) error {
+	if policyName != "test-policy" {
+		return errors.New("no password policy found")
+	}
+	if len(password) < 8 {
+		return errors.New("must be at least 8 characters long")
+	}
+	return nil
+}
+
+func testPasswordConfigBackend(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &testPolicySystemView{
+		StaticSystemView: logical.StaticSystemView{
+			PasswordPolicies: map[string]logical.PasswordGenerator{
+				"test-policy": func() (string, error) {
+					return "generated-password", nil
+				},
+			},
+		},
+	}
+
+	b, err := Factory(context.Background(), config)
+	require.NoError(t, err)
+
+	return b.(*backend), config.StorageView
+}
+
+func testWriteUser(t *testing.T, b *backend, s logical.Storage, data map[string]interface{}) (*logical.Response, error) {
+	t.Helper()
+
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "users/user1",
+		Storage:   s,
+		Data:      data,
+	})
+}
+
+func testLogin(t *testing.T, b *backend, s logical.Storage, password string) (*logical.Response, error) {
+	t.Helper()
+
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login/user1",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password": password,
+		},
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+}
+
+func TestUserPass_ConfigReadWrite(t *testing.T) {
+	b, s := testPasswordConfigBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password_policy":  "test-policy",
+			"password_history": 3,
+			"password_max_age": "24h",
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"password_policy":  "test-policy",
+		"password_history": 3,
+		"password_max_age": int64(86400),
+	}, resp.Data)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password_history": -1,
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password_policy": "missing-policy",
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), `unable to use password policy "missing-policy"`)
+}
+
+func TestUserPass_PasswordPolicy(t *testing.T) {
+	b, s := testPasswordConfigBackend(t)
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password_policy": "test-policy",
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := testWriteUser(t, b, s, map[string]interface{}{"password": "short"})
+	require.ErrorIs(t, err, logical.ErrInvalidRequest)
+	require.Contains(t, resp.Error().Error(), `password does not satisfy the password policy "test-policy"`)
+
+	resp, err = testWriteUser(t, b, s, map[string]interface{}{"password_hash": "$2a$10$5lZmAQGjsRgu.h6aVU8ZteMfqaf5rpZEHGnjU4TxUNt9zSFHoK2eS"})
+	require.ErrorIs(t, err, logical.ErrInvalidRequest)
+	require.True(t, resp.IsError())
+
+	resp, err = testWriteUser(t, b, s, map[string]interface{}{"password": "long-enough"})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testLogin(t, b, s, "long-enough")
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+}
+
+func TestUserPass_PasswordHistory(t *testing.T) {
+	b, s := testPasswordConfigBackend(t)
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password_history": 2,
+		},
+	})
+	require.NoError(t, err)
+
+	for _, password := range []string{"password1", "password2"} {
+		resp, err := testWriteUser(t, b, s, map[string]interface{}{"password": password})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+	}
+
+	// Both the current and the previous passwords are rejected
+	for _, password := range []string{"password1", "password2"} {
+		resp, err := testWriteUser(t, b, s, map[string]interface{}{"password": password})
+		require.ErrorIs(t, err, logical.ErrInvalidRequest)
+		require.Contains(t, resp.Error().Error(), "password was used recently")
+	}
+
+	resp, err := testWriteUser(t, b, s, map[string]interface{}{"password": "password3"})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	user, err := b.user(context.Background(), s, "user1")
+	require.NoError(t, err)
+	require.Len(t, user.PasswordHistory, 1)
+
+	// The oldest password fell out of the history
+	resp, err = testWriteUser(t, b, s, map[string]interface{}{"password": "password1"})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
+
+func TestUserPass_PasswordMaxAge(t *testing.T) {
+	b, s := testPasswordConfigBackend(t)
+	ctx := context.Background()
+
+	_, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password_max_age": "1h",
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := testWriteUser(t, b, s, map[string]interface{}{"password": "password1"})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testLogin(t, b, s, "password1")
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+
+	user, err := b.user(ctx, s, "user1")
+	require.NoError(t, err)
+	user.PasswordLastSet = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, b.setUser(ctx, s, "user1", user))
+
+	resp, err = testLogin(t, b, s, "password1")
+	require.ErrorIs(t, err, logical.ErrPermissionDenied)
+	require.Contains(t, resp.Error().Error(), "password expired")
+
+	// Passwords without a known age never expire
+	user.PasswordLastSet = time.Time{}
+	require.NoError(t, b.setUser(ctx, s, "user1", user))
+
+	resp, err = testLogin(t, b, s, "password1")
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+
+	// Resetting the password allows logging in again
+	user.PasswordLastSet = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, b.setUser(ctx, s, "user1", user))
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "users/user1/password",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"password": "password2",
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testLogin(t, b, s, "password2")
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+}
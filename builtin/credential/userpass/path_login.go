@@ -8,6 +8,7 @@ import (
 	"crypto/subtle"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
@@ -124,6 +125,16 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		return logical.ErrorResponse("invalid username or password"), nil
 	}
 
+	// Check that the password is not expired. Only passwords with a known age
+	// are subject to the maximum age.
+	cfg, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PasswordMaxAge > 0 && !user.PasswordLastSet.IsZero() && time.Since(user.PasswordLastSet) > cfg.PasswordMaxAge {
+		return logical.ErrorResponse("password expired on %s and must be reset", user.PasswordLastSet.Add(cfg.PasswordMaxAge).Format(time.RFC3339)), logical.ErrPermissionDenied
+	}
+
 	// Check for a CIDR match.
 	if len(user.TokenBoundCIDRs) > 0 {
 		if req.Connection == nil {
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -81,9 +82,9 @@ func (b *backend) pathUserPasswordUpdate(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("username does not exist")
 	}
 
-	userErr, intErr := b.updateUserPassword(req, d, userEntry)
+	userErr, intErr := b.updateUserPassword(ctx, req, d, userEntry)
 	if intErr != nil {
-		return nil, err
+		return nil, intErr
 	}
 	if userErr != nil {
 		return logical.ErrorResponse(userErr.Error()), logical.ErrInvalidRequest
@@ -92,12 +93,16 @@ func (b *backend) pathUserPasswordUpdate(ctx context.Context, req *logical.Reque
 	return nil, b.setUser(ctx, req.Storage, username, userEntry)
 }
 
-func (b *backend) updateUserPassword(_ *logical.Request, d *framework.FieldData, userEntry *UserEntry) (error, error) {
+func (b *backend) updateUserPassword(ctx context.Context, req *logical.Request, d *framework.FieldData, userEntry *UserEntry) (error, error) {
 	password := d.Get(paramPassword).(string)
 	passwordHash := d.Get(paramPasswordHash).(string)
 
+	cfg, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	var hash []byte
-	var err error
 
 	switch {
 	case password != "" && passwordHash != "":
@@ -105,8 +110,15 @@ func (b *backend) updateUserPassword(_ *logical.Request, d *framework.FieldData,
 	case password == "" && passwordHash == "":
 		return fmt.Errorf("%q or %q must be supplied", paramPassword, paramPasswordHash), nil
 	case password != "":
+		if userErr, intErr := b.checkPassword(ctx, cfg, password, userEntry); intErr != nil || userErr != nil {
+			return userErr, intErr
+		}
 		hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	case passwordHash != "":
+		// Neither the policy nor the history can be checked against a hash
+		if cfg.PasswordPolicy != "" || cfg.PasswordHistory > 0 {
+			return fmt.Errorf("%q cannot be supplied when a password policy or history is configured", paramPasswordHash), nil
+		}
 		hash, err = parsePasswordHash(passwordHash)
 	}
 
@@ -114,7 +126,57 @@ func (b *backend) updateUserPassword(_ *logical.Request, d *framework.FieldData,
 		return nil, err
 	}
 
+	// Keep the hashes of the previous passwords which can't be reused, the
+	// current one being the first of them
+	if cfg.PasswordHistory > 1 && userEntry.PasswordHash != nil {
+		userEntry.PasswordHistory = append([][]byte{userEntry.PasswordHash}, userEntry.PasswordHistory...)
+	}
+	if keep := cfg.PasswordHistory - 1; len(userEntry.PasswordHistory) > keep {
+		if keep <= 0 {
+			userEntry.PasswordHistory = nil
+		} else {
+			userEntry.PasswordHistory = userEntry.PasswordHistory[:keep]
+		}
+	}
+
 	userEntry.PasswordHash = hash
+	userEntry.PasswordLastSet = time.Now()
+
+	return nil, nil
+}
+
+// checkPassword verifies that a new password satisfies the configured password
+// policy and is not one of the recent passwords of the user.
+func (b *backend) checkPassword(ctx context.Context, cfg *passwordConfig, password string, userEntry *UserEntry) (error, error) {
+	if cfg.PasswordPolicy != "" {
+		validator, ok := b.System().(logical.PasswordPolicyValidatorSystemView)
+		if !ok {
+			return nil, fmt.Errorf("password policies are not supported by this mount")
+		}
+		if err := validator.ValidatePasswordFromPolicy(ctx, cfg.PasswordPolicy, password); err != nil {
+			return fmt.Errorf("password does not satisfy the password policy %q: %w", cfg.PasswordPolicy, err), nil
+		}
+	}
+
+	if cfg.PasswordHistory <= 0 {
+		return nil, nil
+	}
+
+	previous := make([][]byte, 0, cfg.PasswordHistory)
+	if userEntry.PasswordHash != nil {
+		previous = append(previous, userEntry.PasswordHash)
+	}
+	previous = append(previous, userEntry.PasswordHistory...)
+	if len(previous) > cfg.PasswordHistory {
+		previous = previous[:cfg.PasswordHistory]
+	}
+
+	passwordBytes := []byte(password)
+	for _, previousHash := range previous {
+		if bcrypt.CompareHashAndPassword(previousHash, passwordBytes) == nil {
+			return fmt.Errorf("password was used recently, the last %d passwords cannot be reused", cfg.PasswordHistory), nil
+		}
+	}
 
 	return nil, nil
 }
@@ -204,6 +204,9 @@ func (b *backend) pathUserRead(ctx context.Context, req *logical.Request, d *fra
 	if len(user.BoundCIDRs) > 0 {
 		data["bound_cidrs"] = user.BoundCIDRs
 	}
+	if !user.PasswordLastSet.IsZero() {
+		data["password_last_set"] = user.PasswordLastSet.Format(time.RFC3339)
+	}
 
 	return &logical.Response{
 		Data: data,
@@ -226,7 +229,7 @@ func (b *backend) userCreateUpdate(ctx context.Context, req *logical.Request, d
 	}
 
 	if d.Get(paramPassword).(string) != "" || d.Get(paramPasswordHash).(string) != "" {
-		userErr, intErr := b.updateUserPassword(req, d, userEntry)
+		userErr, intErr := b.updateUserPassword(ctx, req, d, userEntry)
 		if intErr != nil {
 			return nil, intErr
 		}
@@ -283,6 +286,14 @@ type UserEntry struct {
 	// used instead of the actual password in Vault 0.2+.
 	PasswordHash []byte
 
+	// PasswordHistory holds the bcrypt hashes of the previous passwords,
+	// most recent first, which cannot be reused.
+	PasswordHistory [][]byte
+
+	// PasswordLastSet is the time the password was last changed. It is zero
+	// for passwords set before it was tracked.
+	PasswordLastSet time.Time
+
 	Policies []string
 
 	// Duration after which the user will be revoked unless renewed
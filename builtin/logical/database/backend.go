@@ -111,15 +111,18 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 				pathConfigurePluginConnection(&b),
 				pathResetConnection(&b),
 				pathReloadPlugin(&b),
+				pathRotationStatus(&b),
 			},
 			pathListRoles(&b),
 			pathRoles(&b),
 			pathCredsCreate(&b),
 			pathRotateRootCredentials(&b),
+			pathLibrary(&b),
 		),
 
 		Secrets: []*framework.Secret{
 			secretCreds(&b),
+			secretLibraryCreds(&b),
 		},
 		Clean:             b.clean,
 		Invalidate:        b.invalidate,
@@ -181,6 +184,10 @@ type databaseBackend struct {
 	// issues with the priority queue.
 	roleLocks []*locksutil.LockEntry
 
+	// libraryLock serializes changes to library sets, so a static role can
+	// not be added to two sets at once.
+	libraryLock sync.Mutex
+
 	// the running gauge collection process
 	gaugeCollectionProcess     *metricsutil.GaugeCollectionProcess
 	gaugeCollectionProcessStop sync.Once
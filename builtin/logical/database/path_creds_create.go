@@ -255,8 +255,24 @@ func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
 			return nil, fmt.Errorf("%q is not an allowed role", name)
 		}
 
+		// The credentials of static roles in a library set are only handed
+		// out to their current borrower.
+		setName, err := b.librarySetOf(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if setName != "" {
+			co, err := b.checkOut(ctx, req.Storage, name)
+			if err != nil {
+				return nil, err
+			}
+			if co == nil || !co.borrowedBy(req) {
+				return logical.ErrorResponse("static role %q is part of library set %q; its credentials are only available to the caller who checked it out", name, setName), nil
+			}
+		}
+
 		respData := map[string]interface{}{
-			"username":            role.StaticAccount.Username,
+			"username":            role.StaticAccount.CurrentUsername(),
 			"ttl":                 role.StaticAccount.CredentialTTL().Seconds(),
 			"last_vault_rotation": role.StaticAccount.LastVaultRotation,
 		}
@@ -270,6 +286,10 @@ func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
 			}
 		}
 
+		if role.StaticAccount.PreviousUsername != "" {
+			respData["previous_username"] = role.StaticAccount.PreviousUsername
+		}
+
 		switch role.CredentialType {
 		case v5.CredentialTypePassword:
 			respData["password"] = role.StaticAccount.Password
@@ -0,0 +1,730 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/go-uuid"
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	libraryStoragePrefix  = "library/"
+	checkOutStoragePrefix = "library-check-out/"
+)
+
+// librarySet is a pool of static roles whose credentials can be checked out
+// for exclusive use.
+type librarySet struct {
+	StaticRoleNames           []string      `json:"static_role_names"`
+	TTL                       time.Duration `json:"ttl"`
+	MaxTTL                    time.Duration `json:"max_ttl"`
+	DisableCheckInEnforcement bool          `json:"disable_check_in_enforcement"`
+}
+
+// checkOut records who currently holds the credentials of a static role in a
+// library set. It is stored per static role and removed on check-in.
+type checkOut struct {
+	// ID ties the check-out to its lease, so that revoking a stale lease
+	// does not check in a later check-out of the same static role.
+	ID                          string    `json:"id"`
+	SetName                     string    `json:"set_name"`
+	BorrowerEntityID            string    `json:"borrower_entity_id"`
+	BorrowerClientTokenAccessor string    `json:"borrower_client_token_accessor"`
+	CheckedOutAt                time.Time `json:"checked_out_at"`
+}
+
+// borrowedBy returns true if the check-out belongs to the caller of req.
+func (c *checkOut) borrowedBy(req *logical.Request) bool {
+	if c.BorrowerEntityID != "" {
+		return c.BorrowerEntityID == req.EntityID
+	}
+	return c.BorrowerClientTokenAccessor == req.ClientTokenAccessor
+}
+
+func pathLibrary(b *databaseBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "library/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "list",
+				OperationSuffix: "library-sets",
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathLibraryList,
+			},
+
+			HelpSynopsis:    pathLibraryHelpSyn,
+			HelpDescription: pathLibraryHelpDesc,
+		},
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationSuffix: "library-set",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"static_role_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "The static roles whose credentials can be checked out from the set.",
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Default lease duration of a check-out. Defaults to the mount's default lease TTL.",
+				},
+				"max_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Maximum lease duration of a check-out, including renewals. Defaults to the mount's max lease TTL.",
+				},
+				"disable_check_in_enforcement": {
+					Type:        framework.TypeBool,
+					Description: "If true, anyone allowed to write to the check-in endpoint may check in any credential, not only the ones they checked out.",
+				},
+			},
+
+			ExistenceCheck: b.pathLibraryExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.pathLibraryRead,
+				logical.CreateOperation: b.pathLibraryCreateUpdate,
+				logical.UpdateOperation: b.pathLibraryCreateUpdate,
+				logical.DeleteOperation: b.pathLibraryDelete,
+			},
+
+			HelpSynopsis:    pathLibraryHelpSyn,
+			HelpDescription: pathLibraryHelpDesc,
+		},
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name") + "/check-out$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "check-out",
+				OperationSuffix: "library-credentials",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Requested lease duration of the check-out. Can not exceed the set's ttl.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathLibraryCheckOut,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+			},
+
+			HelpSynopsis:    pathLibraryCheckOutHelpSyn,
+			HelpDescription: pathLibraryCheckOutHelpDesc,
+		},
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name") + "/check-in$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "check-in",
+				OperationSuffix: "library-credentials",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"static_role_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "The static roles to check in. May be omitted if the caller has only one checked out from the set.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathLibraryCheckIn(false),
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+			},
+
+			HelpSynopsis:    pathLibraryCheckInHelpSyn,
+			HelpDescription: pathLibraryCheckInHelpDesc,
+		},
+		{
+			Pattern: "library/manage/" + framework.GenericNameRegex("name") + "/check-in$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "force-check-in",
+				OperationSuffix: "library-credentials",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"static_role_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "The static roles to check in.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathLibraryCheckIn(true),
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+				},
+			},
+
+			HelpSynopsis:    pathLibraryManageCheckInHelpSyn,
+			HelpDescription: pathLibraryManageCheckInHelpDesc,
+		},
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name") + "/status$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "read",
+				OperationSuffix: "library-status",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.pathLibraryStatus,
+			},
+
+			HelpSynopsis:    pathLibraryStatusHelpSyn,
+			HelpDescription: pathLibraryStatusHelpDesc,
+		},
+	}
+}
+
+func (b *databaseBackend) librarySet(ctx context.Context, s logical.Storage, name string) (*librarySet, error) {
+	entry, err := s.Get(ctx, libraryStoragePrefix+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library set: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var set librarySet
+	if err := entry.DecodeJSON(&set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// librarySetOf returns the name of the library set containing the given static
+// role, if any.
+func (b *databaseBackend) librarySetOf(ctx context.Context, s logical.Storage, roleName string) (string, error) {
+	names, err := s.List(ctx, libraryStoragePrefix)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		set, err := b.librarySet(ctx, s, name)
+		if err != nil {
+			return "", err
+		}
+		if set != nil && strutil.StrListContains(set.StaticRoleNames, roleName) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+func (b *databaseBackend) checkOut(ctx context.Context, s logical.Storage, roleName string) (*checkOut, error) {
+	entry, err := s.Get(ctx, checkOutStoragePrefix+roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read check-out: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var co checkOut
+	if err := entry.DecodeJSON(&co); err != nil {
+		return nil, err
+	}
+	return &co, nil
+}
+
+// checkIn ends the check-out of a static role and rotates its credential so
+// the borrower can no longer use it. If checkOutID is set, only that
+// check-out is ended. The caller must hold the role's lock.
+func (b *databaseBackend) checkIn(ctx context.Context, s logical.Storage, roleName, checkOutID string) error {
+	co, err := b.checkOut(ctx, s, roleName)
+	if err != nil {
+		return err
+	}
+	if co == nil || (checkOutID != "" && co.ID != checkOutID) {
+		return nil
+	}
+
+	role, err := b.StaticRole(ctx, s, roleName)
+	if err != nil {
+		return err
+	}
+	if role != nil {
+		if err := b.rotateStaticRole(ctx, s, roleName, role); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Delete(ctx, checkOutStoragePrefix+roleName); err != nil {
+		return fmt.Errorf("failed to remove check-out: %w", err)
+	}
+	b.dbEvent(ctx, "library-check-in", "", roleName, true, "set", co.SetName)
+	return nil
+}
+
+func (b *databaseBackend) pathLibraryExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	set, err := b.librarySet(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return set != nil, nil
+}
+
+func (b *databaseBackend) pathLibraryList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := req.Storage.List(ctx, libraryStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+func (b *databaseBackend) pathLibraryRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	set, err := b.librarySet(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"static_role_names":            set.StaticRoleNames,
+			"ttl":                          set.TTL.Seconds(),
+			"max_ttl":                      set.MaxTTL.Seconds(),
+			"disable_check_in_enforcement": set.DisableCheckInEnforcement,
+		},
+	}, nil
+}
+
+func (b *databaseBackend) pathLibraryCreateUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	b.libraryLock.Lock()
+	defer b.libraryLock.Unlock()
+
+	set, err := b.librarySet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		set = &librarySet{}
+	}
+
+	if raw, ok := data.GetOk("static_role_names"); ok || req.Operation == logical.CreateOperation {
+		if !ok {
+			raw = data.Get("static_role_names")
+		}
+		roleNames := strutil.RemoveDuplicates(raw.([]string), false)
+		if len(roleNames) == 0 {
+			return logical.ErrorResponse("static_role_names must contain at least one static role"), nil
+		}
+
+		for _, roleName := range roleNames {
+			role, err := b.StaticRole(ctx, req.Storage, roleName)
+			if err != nil {
+				return nil, err
+			}
+			if role == nil {
+				return logical.ErrorResponse("static role %q does not exist", roleName), nil
+			}
+			// Checking in rotates a single credential, which would leave the
+			// borrower of a dual-account role with a still valid one.
+			if role.StaticAccount.IsDualAccount() {
+				return logical.ErrorResponse("static role %q uses dual-account rotation, which is not supported in library sets", roleName), nil
+			}
+
+			setName, err := b.librarySetOf(ctx, req.Storage, roleName)
+			if err != nil {
+				return nil, err
+			}
+			if setName != "" && setName != name {
+				return logical.ErrorResponse("static role %q is already part of library set %q", roleName, setName), nil
+			}
+		}
+
+		for _, roleName := range set.StaticRoleNames {
+			if strutil.StrListContains(roleNames, roleName) {
+				continue
+			}
+			co, err := b.checkOut(ctx, req.Storage, roleName)
+			if err != nil {
+				return nil, err
+			}
+			if co != nil {
+				return logical.ErrorResponse("static role %q is checked out and can not be removed from the set", roleName), nil
+			}
+		}
+		set.StaticRoleNames = roleNames
+	}
+
+	if raw, ok := data.GetOk("ttl"); ok {
+		set.TTL = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := data.GetOk("max_ttl"); ok {
+		set.MaxTTL = time.Duration(raw.(int)) * time.Second
+	}
+	if set.MaxTTL > 0 && set.TTL > set.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+	if raw, ok := data.GetOk("disable_check_in_enforcement"); ok {
+		set.DisableCheckInEnforcement = raw.(bool)
+	}
+
+	entry, err := logical.StorageEntryJSON(libraryStoragePrefix+name, set)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	b.dbEvent(ctx, fmt.Sprintf("library-%s", req.Operation), req.Path, name, true)
+	return nil, nil
+}
+
+func (b *databaseBackend) pathLibraryDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	b.libraryLock.Lock()
+	defer b.libraryLock.Unlock()
+
+	set, err := b.librarySet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+
+	for _, roleName := range set.StaticRoleNames {
+		co, err := b.checkOut(ctx, req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if co != nil {
+			return logical.ErrorResponse("static role %q is checked out; check it in before deleting the set", roleName), nil
+		}
+	}
+
+	if err := req.Storage.Delete(ctx, libraryStoragePrefix+name); err != nil {
+		return nil, err
+	}
+
+	b.dbEvent(ctx, "library-delete", req.Path, name, true)
+	return nil, nil
+}
+
+func (b *databaseBackend) pathLibraryCheckOut(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	setName := data.Get("name").(string)
+
+	set, err := b.librarySet(ctx, req.Storage, setName)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return logical.ErrorResponse("unknown library set: %s", setName), nil
+	}
+
+	ttl := set.TTL
+	if requested := time.Duration(data.Get("ttl").(int)) * time.Second; requested > 0 && (ttl == 0 || requested < ttl) {
+		ttl = requested
+	}
+
+	for _, roleName := range set.StaticRoleNames {
+		resp, err := b.tryCheckOut(ctx, req, setName, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			continue
+		}
+
+		resp.Secret.TTL = ttl
+		resp.Secret.MaxTTL = set.MaxTTL
+		return resp, nil
+	}
+
+	return logical.ErrorResponse("no static roles of library set %q are available for check-out", setName), nil
+}
+
+// tryCheckOut checks out the given static role if it is available, returning
+// its credentials, or nil if it is already checked out.
+func (b *databaseBackend) tryCheckOut(ctx context.Context, req *logical.Request, setName, roleName string) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.roleLocks, roleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	co, err := b.checkOut(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if co != nil {
+		return nil, nil
+	}
+
+	role, err := b.StaticRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		b.Logger().Warn("static role of library set not found", "set", setName, "role", roleName)
+		return nil, nil
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	co = &checkOut{
+		ID:                          id,
+		SetName:                     setName,
+		BorrowerEntityID:            req.EntityID,
+		BorrowerClientTokenAccessor: req.ClientTokenAccessor,
+		CheckedOutAt:                time.Now(),
+	}
+	entry, err := logical.StorageEntryJSON(checkOutStoragePrefix+roleName, co)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	respData := map[string]interface{}{
+		"static_role_name": roleName,
+		"username":         role.StaticAccount.CurrentUsername(),
+	}
+	switch role.CredentialType {
+	case v5.CredentialTypePassword:
+		respData["password"] = role.StaticAccount.Password
+	case v5.CredentialTypeRSAPrivateKey:
+		respData["rsa_private_key"] = string(role.StaticAccount.PrivateKey)
+	}
+
+	internal := map[string]interface{}{
+		"set_name":         setName,
+		"static_role_name": roleName,
+		"check_out_id":     co.ID,
+	}
+
+	b.dbEvent(ctx, "library-check-out", req.Path, roleName, true, "set", setName)
+	return b.Secret(SecretLibraryCredsType).Response(respData, internal), nil
+}
+
+func (b *databaseBackend) pathLibraryCheckIn(force bool) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		setName := data.Get("name").(string)
+
+		set, err := b.librarySet(ctx, req.Storage, setName)
+		if err != nil {
+			return nil, err
+		}
+		if set == nil {
+			return logical.ErrorResponse("unknown library set: %s", setName), nil
+		}
+		enforce := !force && !set.DisableCheckInEnforcement
+
+		roleNames := data.Get("static_role_names").([]string)
+		if len(roleNames) == 0 {
+			if force {
+				return logical.ErrorResponse("static_role_names must be provided"), nil
+			}
+
+			// Default to the only static role the caller has checked out.
+			for _, roleName := range set.StaticRoleNames {
+				co, err := b.checkOut(ctx, req.Storage, roleName)
+				if err != nil {
+					return nil, err
+				}
+				if co != nil && co.borrowedBy(req) {
+					roleNames = append(roleNames, roleName)
+				}
+			}
+			switch len(roleNames) {
+			case 0:
+				return logical.ErrorResponse("no static roles of library set %q are checked out by the caller", setName), nil
+			case 1:
+			default:
+				return logical.ErrorResponse("the caller has multiple static roles checked out; static_role_names must be provided"), nil
+			}
+		}
+
+		var checkedIn []string
+		for _, roleName := range roleNames {
+			if !strutil.StrListContains(set.StaticRoleNames, roleName) {
+				return logical.ErrorResponse("static role %q is not part of library set %q", roleName, setName), nil
+			}
+
+			done, err := func() (bool, error) {
+				lock := locksutil.LockForKey(b.roleLocks, roleName)
+				lock.Lock()
+				defer lock.Unlock()
+
+				co, err := b.checkOut(ctx, req.Storage, roleName)
+				if err != nil {
+					return false, err
+				}
+				if co == nil {
+					return false, nil
+				}
+				if enforce && !co.borrowedBy(req) {
+					return false, logical.ErrPermissionDenied
+				}
+				return true, b.checkIn(ctx, req.Storage, roleName, co.ID)
+			}()
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				checkedIn = append(checkedIn, roleName)
+			}
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"check_ins": checkedIn,
+			},
+		}, nil
+	}
+}
+
+func (b *databaseBackend) pathLibraryStatus(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	set, err := b.librarySet(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+
+	roleNames := append([]string(nil), set.StaticRoleNames...)
+	sort.Strings(roleNames)
+
+	status := make(map[string]interface{}, len(roleNames))
+	for _, roleName := range roleNames {
+		co, err := b.checkOut(ctx, req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if co == nil {
+			status[roleName] = map[string]interface{}{
+				"available": true,
+			}
+			continue
+		}
+		status[roleName] = map[string]interface{}{
+			"available":                      false,
+			"borrower_entity_id":             co.BorrowerEntityID,
+			"borrower_client_token_accessor": co.BorrowerClientTokenAccessor,
+			"checked_out_at":                 co.CheckedOutAt,
+		}
+	}
+
+	return &logical.Response{
+		Data: status,
+	}, nil
+}
+
+const pathLibraryHelpSyn = `
+Manage sets of static roles whose credentials can be checked out.
+`
+
+const pathLibraryHelpDesc = `
+A library set is a pool of static roles, such as shared break-glass accounts.
+The credentials of each static role can be checked out by one requester at a
+time for the duration of a lease, and are rotated when they are checked in or
+the lease expires. While checked out, scheduled rotations of the static role are
+skipped and rotate-role is refused, and static-creds only returns the
+credentials to the borrower.
+
+The "static_role_names" parameter lists the static roles of the set. A static
+role can only be part of one set, and dual-account static roles can not be
+part of any.
+`
+
+const pathLibraryCheckOutHelpSyn = `
+Check out the credentials of an available static role of a library set.
+`
+
+const pathLibraryCheckOutHelpDesc = `
+This path checks out the credentials of the first static role of the set which
+is not already checked out, for the exclusive use of the requester. The
+credentials are checked in, and rotated, when the lease is revoked or expires.
+`
+
+const pathLibraryCheckInHelpSyn = `
+Check in credentials checked out from a library set.
+`
+
+const pathLibraryCheckInHelpDesc = `
+This path checks in and rotates the credentials of the given static roles.
+Unless check-in enforcement is disabled on the set, only credentials checked
+out by the caller can be checked in.
+`
+
+const pathLibraryManageCheckInHelpSyn = `
+Check in any credentials checked out from a library set.
+`
+
+const pathLibraryManageCheckInHelpDesc = `
+This path checks in and rotates the credentials of the given static roles,
+regardless of who checked them out.
+`
+
+const pathLibraryStatusHelpSyn = `
+Read which static roles of a library set are checked out.
+`
+
+const pathLibraryStatusHelpDesc = `
+This path returns, for each static role of the set, whether it is available
+and, if not, who checked it out and when.
+`
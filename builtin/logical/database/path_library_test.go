@@ -0,0 +1,205 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_Library_CheckOutCheckIn(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	createRole(t, b, storage, mockDB, "breakglass-1")
+	createRole(t, b, storage, mockDB, "breakglass-2")
+
+	request := func(op logical.Operation, path, entityID string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+			EntityID:  entityID,
+		})
+	}
+	password := func(roleName string) string {
+		role, err := b.StaticRole(ctx, storage, roleName)
+		require.NoError(t, err)
+		return role.StaticAccount.Password
+	}
+
+	resp, err := request(logical.CreateOperation, "library/dba", "", map[string]interface{}{
+		"static_role_names": "breakglass-1,missing",
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+
+	resp, err = request(logical.CreateOperation, "library/dba", "", map[string]interface{}{
+		"static_role_names": "breakglass-1,breakglass-2",
+		"ttl":               "1h",
+		"max_ttl":           "4h",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = request(logical.CreateOperation, "library/other", "", map[string]interface{}{
+		"static_role_names": "breakglass-2",
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError(), "a static role can only be in one set")
+
+	// Each check-out gets a different account, until none are left.
+	resp, err = request(logical.UpdateOperation, "library/dba/check-out", "alice", map[string]interface{}{
+		"ttl": "10m",
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError())
+	require.Equal(t, "breakglass-1", resp.Data["static_role_name"])
+	require.Equal(t, "breakglass-1", resp.Data["username"])
+	require.Equal(t, password("breakglass-1"), resp.Data["password"])
+	require.Equal(t, 10*time.Minute, resp.Secret.TTL)
+	require.Equal(t, 4*time.Hour, resp.Secret.MaxTTL)
+	aliceLease := &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+	}
+
+	resp, err = request(logical.UpdateOperation, "library/dba/check-out", "bob", nil)
+	require.NoError(t, err)
+	require.Equal(t, "breakglass-2", resp.Data["static_role_name"])
+	require.Equal(t, time.Hour, resp.Secret.TTL)
+
+	resp, err = request(logical.UpdateOperation, "library/dba/check-out", "carol", nil)
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+
+	// Static credentials of the set are only readable by their borrower,
+	// and can not be rotated while checked out.
+	resp, err = request(logical.ReadOperation, "static-creds/breakglass-1", "alice", nil)
+	require.NoError(t, err)
+	require.False(t, resp.IsError())
+	require.Equal(t, password("breakglass-1"), resp.Data["password"])
+	resp, err = request(logical.ReadOperation, "static-creds/breakglass-1", "bob", nil)
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+	resp, err = request(logical.UpdateOperation, "rotate-role/breakglass-1", "alice", nil)
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+
+	resp, err = request(logical.ReadOperation, "library/dba/status", "", nil)
+	require.NoError(t, err)
+	status := resp.Data["breakglass-1"].(map[string]interface{})
+	assert.Equal(t, false, status["available"])
+	assert.Equal(t, "alice", status["borrower_entity_id"])
+
+	// Checked out credentials are not rotated by the queue.
+	item, err := b.popFromRotationQueueByKey("breakglass-1")
+	require.NoError(t, err)
+	item.Priority = time.Now().Unix()
+	require.NoError(t, b.pushItem(item))
+	b.rotateCredentials(ctx, storage)
+	mockDB.AssertNumberOfCalls(t, "UpdateUser", 2)
+
+	// Only the borrower can check in, which rotates the credential.
+	_, err = request(logical.UpdateOperation, "library/dba/check-in", "bob", map[string]interface{}{
+		"static_role_names": "breakglass-1",
+	})
+	require.ErrorIs(t, err, logical.ErrPermissionDenied)
+
+	oldPassword := password("breakglass-2")
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	resp, err = request(logical.UpdateOperation, "library/dba/check-in", "bob", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"breakglass-2"}, resp.Data["check_ins"])
+	require.NotEqual(t, oldPassword, password("breakglass-2"))
+
+	resp, err = request(logical.DeleteOperation, "library/dba", "", nil)
+	require.NoError(t, err)
+	require.True(t, resp.IsError(), "sets with checked out credentials can not be deleted")
+
+	// Revoking the lease checks the credential in.
+	oldPassword = password("breakglass-1")
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	resp, err = b.HandleRequest(ctx, aliceLease)
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	require.NotEqual(t, oldPassword, password("breakglass-1"))
+
+	resp, err = request(logical.ReadOperation, "library/dba/status", "", nil)
+	require.NoError(t, err)
+	for _, roleName := range []string{"breakglass-1", "breakglass-2"} {
+		assert.Equal(t, true, resp.Data[roleName].(map[string]interface{})["available"])
+	}
+	resp, err = request(logical.ReadOperation, "static-creds/breakglass-1", "alice", nil)
+	require.NoError(t, err)
+	require.True(t, resp.IsError(), "credentials which are not checked out are not readable")
+
+	// A later revocation of the same lease leaves new check-outs alone.
+	resp, err = request(logical.UpdateOperation, "library/dba/check-out", "carol", nil)
+	require.NoError(t, err)
+	require.Equal(t, "breakglass-1", resp.Data["static_role_name"])
+	_, err = b.HandleRequest(ctx, aliceLease)
+	require.NoError(t, err)
+
+	resp, err = request(logical.DeleteOperation, "static-roles/breakglass-1", "", nil)
+	require.NoError(t, err)
+	require.True(t, resp.IsError(), "static roles in a set can not be deleted")
+
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	resp, err = request(logical.UpdateOperation, "library/manage/dba/check-in", "", map[string]interface{}{
+		"static_role_names": "breakglass-1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"breakglass-1"}, resp.Data["check_ins"])
+
+	resp, err = request(logical.DeleteOperation, "library/dba", "", nil)
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	mockDB.AssertNumberOfCalls(t, "UpdateUser", 5)
+}
+
+func TestBackend_Library_DualAccountRoles(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	createRoleWithData(t, b, storage, mockDB, "dual", map[string]interface{}{
+		"username":           "blue",
+		"secondary_username": "green",
+		"db_name":            mockv5,
+		"rotation_period":    "3600s",
+	})
+
+	// Check-in rotates a single credential, which would leave the borrower
+	// with the other account's.
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "library/dba",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"static_role_names": "dual",
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "dual-account")
+}
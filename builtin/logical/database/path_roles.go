@@ -222,6 +222,20 @@ func staticFields() map[string]*framework.FieldSchema {
 			Description: `Used to connect to a self-managed static account. Must
 	be provided by the user when root credentials are not provided.`,
 		},
+		"secondary_username": {
+			Type: framework.TypeString,
+			Description: `Name of a second static user account for Vault to
+	manage. If set, each rotation changes the credential of the account not
+	currently in use and then makes it the one returned by static-creds, so
+	the credential of the previously returned account stays valid.`,
+		},
+		"dual_account_overlap": {
+			Type: framework.TypeDurationSecond,
+			Description: `Minimum amount of time the credential of the previously
+	returned account stays valid after a switch. Rotations scheduled or
+	requested earlier are delayed until it has passed. Only valid with
+	"secondary_username".`,
+		},
 	}
 	return fields
 }
@@ -260,13 +274,24 @@ func (b *databaseBackend) pathStaticRoleDelete(ctx context.Context, req *logical
 	lock.Lock()
 	defer lock.Unlock()
 
+	setName, err := b.librarySetOf(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if setName != "" {
+		return logical.ErrorResponse("static role %q is part of library set %q; remove it from the set first", name, setName), nil
+	}
+
 	// Remove the item from the queue
 	_, _ = b.popFromRotationQueueByKey(name)
 
-	err := req.Storage.Delete(ctx, databaseStaticRolePath+name)
+	err = req.Storage.Delete(ctx, databaseStaticRolePath+name)
 	if err != nil {
 		return nil, err
 	}
+	if err := req.Storage.Delete(ctx, rotationStatusStoragePrefix+name); err != nil {
+		return nil, err
+	}
 
 	walIDs, err := framework.ListWAL(ctx, req.Storage)
 	if err != nil {
@@ -312,6 +337,11 @@ func (b *databaseBackend) pathStaticRoleRead(ctx context.Context, req *logical.R
 	if role.StaticAccount != nil {
 		data["username"] = role.StaticAccount.Username
 		data["rotation_statements"] = role.Statements.Rotation
+		if role.StaticAccount.IsDualAccount() {
+			data["secondary_username"] = role.StaticAccount.SecondaryUsername
+			data["dual_account_overlap"] = role.StaticAccount.DualAccountOverlap.Seconds()
+			data["active_username"] = role.StaticAccount.ActiveUsername
+		}
 		if !role.StaticAccount.LastVaultRotation.IsZero() {
 			data["last_vault_rotation"] = role.StaticAccount.LastVaultRotation
 		}
@@ -567,6 +597,24 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 	}
 	role.StaticAccount.Username = username
 
+	if secondaryUsernameRaw, ok := data.GetOk("secondary_username"); ok {
+		secondaryUsername := secondaryUsernameRaw.(string)
+		if !createRole && secondaryUsername != role.StaticAccount.SecondaryUsername {
+			return logical.ErrorResponse("cannot update static account secondary_username"), nil
+		}
+		if secondaryUsername == username {
+			return logical.ErrorResponse("secondary_username must differ from username"), nil
+		}
+		role.StaticAccount.SecondaryUsername = secondaryUsername
+	}
+
+	if overlapRaw, ok := data.GetOk("dual_account_overlap"); ok {
+		if !role.StaticAccount.IsDualAccount() {
+			return logical.ErrorResponse("dual_account_overlap is only valid with secondary_username"), nil
+		}
+		role.StaticAccount.DualAccountOverlap = time.Duration(overlapRaw.(int)) * time.Second
+	}
+
 	rotationPeriodSecondsRaw, rotationPeriodOk := data.GetOk("rotation_period")
 	rotationScheduleRaw, rotationScheduleOk := data.GetOk("rotation_schedule")
 	rotationWindowSecondsRaw, rotationWindowOk := data.GetOk("rotation_window")
@@ -637,6 +685,17 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 		role.StaticAccount.SelfManagedPassword = smPasswordRaw.(string)
 	}
 
+	if role.StaticAccount.IsDualAccount() {
+		// Vault connects as the account being rotated, which differs from the
+		// one the self-managed password belongs to every other rotation.
+		if role.StaticAccount.SelfManagedPassword != "" {
+			return logical.ErrorResponse("self_managed_password is not supported with secondary_username"), nil
+		}
+		if role.StaticAccount.UsesRotationPeriod() && role.StaticAccount.DualAccountOverlap >= role.StaticAccount.RotationPeriod {
+			return logical.ErrorResponse("dual_account_overlap must be less than rotation_period"), nil
+		}
+	}
+
 	var credentialConfig map[string]string
 	if raw, ok := data.GetOk("credential_config"); ok {
 		credentialConfig = raw.(map[string]string)
@@ -661,6 +720,10 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 			Role:     role,
 		})
 		if err != nil {
+			// The role was never stored, so neither should its rotation status
+			if deleteErr := req.Storage.Delete(ctx, rotationStatusStoragePrefix+name); deleteErr != nil {
+				b.Logger().Debug("failed to delete rotation status for failed role creation", "role", name, "error", deleteErr)
+			}
 			if resp != nil && resp.WALID != "" {
 				b.Logger().Debug("deleting WAL for failed role creation", "WAL ID", resp.WALID, "role", name)
 				walDeleteErr := framework.DeleteWAL(ctx, req.Storage, resp.WALID)
@@ -841,6 +904,62 @@ type staticAccount struct {
 	// RevokeUser is a boolean flag to indicate if Vault should revoke the
 	// database user when the role is deleted
 	RevokeUserOnDelete bool `json:"revoke_user_on_delete"`
+
+	// SecondaryUsername, if set, is a second database user managed by the
+	// role. Rotations then alternate between Username and SecondaryUsername,
+	// always changing the credential of the account which is not active.
+	SecondaryUsername string `json:"secondary_username,omitempty"`
+
+	// DualAccountOverlap is the minimum time the previously active account
+	// keeps its credential after the active account changes.
+	DualAccountOverlap time.Duration `json:"dual_account_overlap,omitempty"`
+
+	// ActiveUsername is the account whose credential, held in Password or
+	// PrivateKey, is returned by static-creds. Only set for dual-account
+	// roles.
+	ActiveUsername string `json:"active_username,omitempty"`
+
+	// PreviousUsername is the account which was active before the last
+	// switch, if any. Its credential remains valid until it is rotated again.
+	PreviousUsername string `json:"previous_username,omitempty"`
+
+	// LastAccountSwitch is the time ActiveUsername last changed.
+	LastAccountSwitch time.Time `json:"last_account_switch,omitempty"`
+}
+
+// IsDualAccount returns true if the static account alternates between two
+// database users.
+func (s *staticAccount) IsDualAccount() bool {
+	return s.SecondaryUsername != ""
+}
+
+// CurrentUsername returns the database user whose credential is currently
+// returned for the static account.
+func (s *staticAccount) CurrentUsername() string {
+	if s.IsDualAccount() && s.ActiveUsername != "" {
+		return s.ActiveUsername
+	}
+	return s.Username
+}
+
+// RotationUsername returns the database user whose credential the next
+// rotation changes. For dual-account roles this is the inactive account,
+// which becomes active once its credential is set.
+func (s *staticAccount) RotationUsername() string {
+	if s.IsDualAccount() && s.ActiveUsername == s.Username {
+		return s.SecondaryUsername
+	}
+	return s.Username
+}
+
+// OverlapEnd returns the earliest time the account which was active before
+// the last switch may be rotated, or the zero time if there is no such
+// account.
+func (s *staticAccount) OverlapEnd() time.Time {
+	if !s.IsDualAccount() || s.PreviousUsername == "" {
+		return time.Time{}
+	}
+	return s.LastAccountSwitch.Add(s.DualAccountOverlap)
 }
 
 // NextRotationTime calculates the next rotation for period and schedule-based
@@ -980,6 +1099,11 @@ This path lets you manage the static roles that can be created with this
 backend. Static Roles are associated with a single database user, and manage the
 credential based on a rotation period, automatically rotating the credential.
 
+If "secondary_username" is set, the static role manages two database users and
+each rotation changes the credential of the one not currently returned, then
+switches to it. Applications holding the previous credential keep working until
+the following rotation, and for at least "dual_account_overlap".
+
 The "db_name" parameter is required and configures the name of the database
 connection to use.
 
@@ -1195,6 +1195,157 @@ func TestWALsDeletedOnRoleDeletion(t *testing.T) {
 	requireWALs(t, storage, 1)
 }
 
+func TestBackend_StaticRole_DualAccount(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	expectUpdate := func(username string) {
+		t.Helper()
+		mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(req v5.UpdateUserRequest) bool {
+			return req.Username == username
+		})).Return(v5.UpdateUserResponse{}, nil).Once()
+	}
+	rotate := func() {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/app",
+			Storage:   storage,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatal(resp, err)
+		}
+	}
+	readCreds := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-creds/app",
+			Storage:   storage,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatal(resp, err)
+		}
+		return resp.Data
+	}
+	roleRequest := func(op logical.Operation, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      "static-roles/app",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := roleRequest(logical.CreateOperation, map[string]interface{}{
+		"username":             "blue",
+		"secondary_username":   "blue",
+		"db_name":              mockv5,
+		"rotation_period":      "3600s",
+		"dual_account_overlap": "60s",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatal("expected identical usernames to be rejected")
+	}
+	resp = roleRequest(logical.CreateOperation, map[string]interface{}{
+		"username":             "blue",
+		"secondary_username":   "green",
+		"db_name":              mockv5,
+		"rotation_period":      "3600s",
+		"dual_account_overlap": "3600s",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatal("expected an overlap as long as the rotation period to be rejected")
+	}
+
+	// Creating the role sets the credential of the first account.
+	expectUpdate("blue")
+	resp = roleRequest(logical.CreateOperation, map[string]interface{}{
+		"username":           "blue",
+		"secondary_username": "green",
+		"db_name":            mockv5,
+		"rotation_period":    "3600s",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatal(resp)
+	}
+	blue := readCreds()
+	assert.Equal(t, "blue", blue["username"])
+	assert.Nil(t, blue["previous_username"])
+
+	resp = roleRequest(logical.ReadOperation, nil)
+	assert.Equal(t, "green", resp.Data["secondary_username"])
+	assert.Equal(t, "blue", resp.Data["active_username"])
+
+	// Each rotation sets the inactive account's credential and switches to it,
+	// leaving the previous credential in place.
+	expectUpdate("green")
+	rotate()
+	green := readCreds()
+	assert.Equal(t, "green", green["username"])
+	assert.Equal(t, "blue", green["previous_username"])
+	assert.NotEqual(t, blue["password"], green["password"])
+
+	expectUpdate("blue")
+	rotate()
+	assert.Equal(t, "blue", readCreds()["username"])
+
+	// Within the overlap, the previous account may not be rotated yet.
+	resp = roleRequest(logical.UpdateOperation, map[string]interface{}{
+		"username":             "blue",
+		"dual_account_overlap": "600s",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatal(resp)
+	}
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/app",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "green") {
+		t.Fatal("expected rotation within the overlap to be refused", resp)
+	}
+
+	// The queue holds off as well.
+	item, err := b.popFromRotationQueueByKey("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Priority = time.Now().Unix()
+	if err := b.pushItem(item); err != nil {
+		t.Fatal(err)
+	}
+	b.rotateCredentials(ctx, storage)
+	item, err = b.popFromRotationQueueByKey("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Priority < time.Now().Add(500*time.Second).Unix() {
+		t.Fatal("expected rotation to be delayed until the end of the overlap")
+	}
+	assert.Equal(t, "blue", readCreds()["username"])
+
+	resp = roleRequest(logical.UpdateOperation, map[string]interface{}{
+		"username":           "blue",
+		"secondary_username": "red",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatal("expected secondary_username to be immutable")
+	}
+	mockDB.AssertNumberOfCalls(t, "UpdateUser", 3)
+}
+
 func TestIsInsideRotationWindow(t *testing.T) {
 	for _, tc := range []struct {
 		name         string
@@ -12,7 +12,6 @@ import (
 	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
-	"github.com/hashicorp/vault/sdk/queue"
 )
 
 func pathRotateRootCredentials(b *databaseBackend) []*framework.Path {
@@ -211,54 +210,26 @@ func (b *databaseBackend) pathRotateRoleCredentialsUpdate() framework.OperationF
 			return logical.ErrorResponse("no static role found for role name"), nil
 		}
 
-		// In create/update of static accounts, we only care if the operation
-		// err'd , and this call does not return credentials
-		item, err := b.popFromRotationQueueByKey(name)
+		// Checked out credentials are rotated on check-in instead, so they
+		// keep working for the borrower until then
+		co, err := b.checkOut(ctx, req.Storage, name)
 		if err != nil {
-			item = &queue.Item{
-				Key: name,
-			}
-		}
-
-		input := &setStaticAccountInput{
-			RoleName: name,
-			Role:     role,
+			return nil, err
 		}
-		if walID, ok := item.Value.(string); ok {
-			input.WALID = walID
-		}
-		resp, err := b.setStaticAccount(ctx, req.Storage, input)
-		// if err is not nil, we need to attempt to update the priority and place
-		// this item back on the queue. The err should still be returned at the end
-		// of this method.
-		if err != nil {
-			b.logger.Warn("unable to rotate credentials in rotate-role", "error", err)
-			// Update the priority to re-try this rotation and re-add the item to
-			// the queue
-			item.Priority = time.Now().Add(10 * time.Second).Unix()
-
-			// Preserve the WALID if it was returned
-			if resp != nil && resp.WALID != "" {
-				item.Value = resp.WALID
-			}
-		} else {
-			item.Priority = role.StaticAccount.NextRotationTimeFromInput(resp.RotationTime).Unix()
-			// Clear any stored WAL ID as we must have successfully deleted our WAL to get here.
-			item.Value = ""
-			modified = true
+		if co != nil {
+			return logical.ErrorResponse("static role %q is checked out from library set %q; check it in to rotate its credentials", name, co.SetName), nil
 		}
 
-		// Add their rotation to the queue
-		if err := b.pushItem(item); err != nil {
-			return nil, err
+		if overlapEnd := role.StaticAccount.OverlapEnd(); time.Now().Before(overlapEnd) {
+			return logical.ErrorResponse("the credential of %q must remain valid until %s; rotate again after that",
+				role.StaticAccount.PreviousUsername, overlapEnd.Format(time.RFC3339)), nil
 		}
 
-		if err != nil {
-			return nil, fmt.Errorf("unable to finish rotating credentials; retries will "+
-				"continue in the background but it is also safe to retry manually: %w", err)
+		if err = b.rotateStaticRole(ctx, req.Storage, name, role); err != nil {
+			return nil, err
 		}
+		modified = true
 
-		// return any err from the setStaticAccount call
 		return nil, nil
 	}
 }
@@ -252,6 +252,38 @@ func (b *databaseBackend) rotateCredential(ctx context.Context, s logical.Storag
 		return false
 	}
 
+	// Credentials checked out from a library set are rotated on check-in
+	// instead, so they keep working for the borrower until then
+	co, err := b.checkOut(ctx, s, roleName)
+	if err != nil {
+		logger.Error("unable to load check-out", "error", err)
+
+		item.Priority = time.Now().Add(10 * time.Second).Unix()
+		if err := b.pushItem(item); err != nil {
+			logger.Error("unable to push item on to queue", "error", err)
+		}
+		return true
+	}
+	if co != nil {
+		logger.Debug("skipping rotation of checked out credentials", "set", co.SetName)
+		item.Priority = role.StaticAccount.NextRotationTimeFromInput(now).Unix()
+		if err := b.pushItem(item); err != nil {
+			logger.Error("unable to push item on to queue", "error", err)
+		}
+		return true
+	}
+
+	// Keep the credential of the account which was active before the last
+	// switch of a dual-account role valid for at least the configured overlap
+	if overlapEnd := role.StaticAccount.OverlapEnd(); now.Before(overlapEnd) {
+		logger.Debug("delaying rotation until the dual account overlap has passed", "until", overlapEnd)
+		item.Priority = overlapEnd.Unix()
+		if err := b.pushItem(item); err != nil {
+			logger.Error("unable to push item on to queue", "error", err)
+		}
+		return true
+	}
+
 	// send an event indicating if the rotation was a success or failure
 	rotated := false
 	defer func() {
@@ -305,6 +337,58 @@ func (b *databaseBackend) rotateCredential(ctx context.Context, s logical.Storag
 	return true
 }
 
+// rotateStaticRole rotates the credential of a static role right away and
+// reschedules its next rotation. If the rotation fails, the role is put back
+// on the queue to be retried shortly.
+func (b *databaseBackend) rotateStaticRole(ctx context.Context, s logical.Storage, name string, role *roleEntry) error {
+	// In create/update of static accounts, we only care if the operation
+	// err'd , and this call does not return credentials
+	item, err := b.popFromRotationQueueByKey(name)
+	if err != nil {
+		item = &queue.Item{
+			Key: name,
+		}
+	}
+
+	input := &setStaticAccountInput{
+		RoleName: name,
+		Role:     role,
+	}
+	if walID, ok := item.Value.(string); ok {
+		input.WALID = walID
+	}
+	resp, err := b.setStaticAccount(ctx, s, input)
+	// if err is not nil, we need to attempt to update the priority and place
+	// this item back on the queue. The err should still be returned at the end
+	// of this method.
+	if err != nil {
+		b.logger.Warn("unable to rotate credentials", "role", name, "error", err)
+		// Update the priority to re-try this rotation and re-add the item to
+		// the queue
+		item.Priority = time.Now().Add(10 * time.Second).Unix()
+
+		// Preserve the WALID if it was returned
+		if resp != nil && resp.WALID != "" {
+			item.Value = resp.WALID
+		}
+	} else {
+		item.Priority = role.StaticAccount.NextRotationTimeFromInput(resp.RotationTime).Unix()
+		// Clear any stored WAL ID as we must have successfully deleted our WAL to get here.
+		item.Value = ""
+	}
+
+	// Add their rotation to the queue
+	if err := b.pushItem(item); err != nil {
+		return err
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to finish rotating credentials; retries will "+
+			"continue in the background but it is also safe to retry manually: %w", err)
+	}
+	return nil
+}
+
 // findStaticWAL loads a WAL entry by ID. If found, only return the WAL if it
 // is of type staticWALKey, otherwise return nil
 func (b *databaseBackend) findStaticWAL(ctx context.Context, s logical.Storage, id string) (*setCredentialsWAL, error) {
@@ -367,10 +451,12 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	}
 	modified := false
 	defer func() {
+		status := b.recordRotationAttempt(ctx, s, input.RoleName, input.Role, err)
 		if err == nil {
 			b.dbEvent(ctx, "static-creds-create", "", input.RoleName, modified)
 		} else {
-			b.dbEvent(ctx, "static-creds-create-fail", "", input.RoleName, modified)
+			b.dbEvent(ctx, "static-creds-create-fail", "", input.RoleName, modified,
+				"error", err.Error(), "consecutive_failures", strconv.Itoa(status.ConsecutiveFailures))
 		}
 	}()
 
@@ -406,8 +492,10 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	dbi.RLock()
 	defer dbi.RUnlock()
 
+	// For dual-account roles this is the inactive account, leaving the
+	// credential handed out for the active one untouched.
 	updateReq := v5.UpdateUserRequest{
-		Username: input.Role.StaticAccount.Username,
+		Username: input.Role.StaticAccount.RotationUsername(),
 	}
 	statements := v5.Statements{
 		Commands: input.Role.Statements.Rotation,
@@ -430,6 +518,14 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 		case wal == nil:
 			b.Logger().Error("expected role to have WAL, but WAL not found in storage", "role", input.RoleName, "WAL ID", output.WALID)
 
+			// Generate a new WAL entry and credential
+			output.WALID = ""
+		case wal.Username != "" && wal.Username != updateReq.Username:
+			b.Logger().Warn("WAL is for a different account than the one being rotated", "role", input.RoleName, "WAL ID", output.WALID, "username", wal.Username)
+			if err := framework.DeleteWAL(ctx, s, output.WALID); err != nil {
+				b.Logger().Warn("failed to delete WAL for a different account", "error", err, "WAL ID", output.WALID)
+			}
+
 			// Generate a new WAL entry and credential
 			output.WALID = ""
 		case !wal.credentialIsSet():
@@ -463,7 +559,7 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	if output.WALID == "" {
 		walEntry := &setCredentialsWAL{
 			RoleName:          input.RoleName,
-			Username:          input.Role.StaticAccount.Username,
+			Username:          updateReq.Username,
 			LastVaultRotation: input.Role.StaticAccount.LastVaultRotation,
 		}
 
@@ -545,6 +641,11 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	lvr := time.Now()
 	input.Role.StaticAccount.LastVaultRotation = lvr
 	input.Role.StaticAccount.SetNextVaultRotation(lvr)
+	if input.Role.StaticAccount.IsDualAccount() {
+		input.Role.StaticAccount.PreviousUsername = input.Role.StaticAccount.ActiveUsername
+		input.Role.StaticAccount.ActiveUsername = updateReq.Username
+		input.Role.StaticAccount.LastAccountSwitch = lvr
+	}
 	output.RotationTime = lvr
 
 	entry, err := logical.StorageEntryJSON(databaseStaticRolePath+input.RoleName, input.Role)
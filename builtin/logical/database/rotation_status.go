@@ -0,0 +1,185 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	rotationStatusStoragePrefix = "rotation-status/"
+
+	// rotationHistoryLength is the number of rotation attempts kept per
+	// static role.
+	rotationHistoryLength = 10
+)
+
+type rotationAttempt struct {
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// rotationStatus tracks the most recent rotation attempts of a static role,
+// newest first.
+type rotationStatus struct {
+	History             []rotationAttempt `json:"history"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	LastError           string            `json:"last_error,omitempty"`
+	LastErrorTime       time.Time         `json:"last_error_time,omitempty"`
+}
+
+func pathRotationStatus(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotation-status/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixDatabase,
+			OperationVerb:   "read",
+			OperationSuffix: "static-role-rotation-status",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the static role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathRotationStatusRead,
+		},
+
+		HelpSynopsis:    pathRotationStatusHelpSyn,
+		HelpDescription: pathRotationStatusHelpDesc,
+	}
+}
+
+func (b *databaseBackend) rotationStatus(ctx context.Context, s logical.Storage, roleName string) (*rotationStatus, error) {
+	entry, err := s.Get(ctx, rotationStatusStoragePrefix+roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rotation status: %w", err)
+	}
+	if entry == nil {
+		return &rotationStatus{}, nil
+	}
+
+	var status rotationStatus
+	if err := entry.DecodeJSON(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// recordRotationAttempt adds the outcome of a rotation of the given static
+// role to its history and reports it as a metric. Failing to store the history
+// does not fail the rotation, so errors are only logged.
+func (b *databaseBackend) recordRotationAttempt(ctx context.Context, s logical.Storage, roleName string, role *roleEntry, rotationErr error) *rotationStatus {
+	key := []string{"secrets", "database", "static_role", "rotation"}
+	labels := []metrics.Label{
+		{Name: "role_name", Value: roleName},
+		{Name: "db_name", Value: role.DBName},
+	}
+	if rotationErr != nil {
+		metrics.IncrCounterWithLabels(append(key, "failure"), 1, labels)
+	} else {
+		metrics.IncrCounterWithLabels(key, 1, labels)
+	}
+
+	status, err := b.rotationStatus(ctx, s, roleName)
+	if err != nil {
+		b.Logger().Warn("unable to load rotation status", "role", roleName, "error", err)
+		status = &rotationStatus{}
+	}
+
+	attempt := rotationAttempt{
+		Time:    time.Now(),
+		Success: rotationErr == nil,
+	}
+	if rotationErr != nil {
+		attempt.Error = rotationErr.Error()
+		status.ConsecutiveFailures++
+		status.LastError = attempt.Error
+		status.LastErrorTime = attempt.Time
+	} else {
+		status.ConsecutiveFailures = 0
+	}
+	status.History = append([]rotationAttempt{attempt}, status.History...)
+	if len(status.History) > rotationHistoryLength {
+		status.History = status.History[:rotationHistoryLength]
+	}
+	metrics.SetGaugeWithLabels(append(key, "consecutive_failures"), float32(status.ConsecutiveFailures), labels)
+
+	entry, err := logical.StorageEntryJSON(rotationStatusStoragePrefix+roleName, status)
+	if err == nil {
+		err = s.Put(ctx, entry)
+	}
+	if err != nil {
+		b.Logger().Warn("unable to store rotation status", "role", roleName, "error", err)
+	}
+	return status
+}
+
+func (b *databaseBackend) pathRotationStatusRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	role, err := b.StaticRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("unknown static role: %s", name), nil
+	}
+
+	status, err := b.rotationStatus(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]map[string]interface{}, 0, len(status.History))
+	for _, attempt := range status.History {
+		entry := map[string]interface{}{
+			"time":    attempt.Time,
+			"success": attempt.Success,
+		}
+		if attempt.Error != "" {
+			entry["error"] = attempt.Error
+		}
+		history = append(history, entry)
+	}
+
+	respData := map[string]interface{}{
+		"history":              history,
+		"consecutive_failures": status.ConsecutiveFailures,
+		"next_vault_rotation":  role.StaticAccount.NextRotationTime(),
+	}
+	if !role.StaticAccount.LastVaultRotation.IsZero() {
+		respData["last_vault_rotation"] = role.StaticAccount.LastVaultRotation
+	}
+	if status.LastError != "" {
+		respData["last_error"] = status.LastError
+		respData["last_error_time"] = status.LastErrorTime
+	}
+
+	return &logical.Response{
+		Data: respData,
+	}, nil
+}
+
+const pathRotationStatusHelpSyn = `
+Read the recent rotation attempts of a static role.
+`
+
+const pathRotationStatusHelpDesc = `
+This path returns the most recent credential rotation attempts of the given
+static role, newest first, with their outcome and the error returned by the
+database plugin for failed ones. It also returns the time of the last
+successful rotation, the last error, and when the next rotation is scheduled.
+`
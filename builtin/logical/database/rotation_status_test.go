@@ -0,0 +1,69 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_StaticRole_RotationStatus(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	readStatus := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "rotation-status/hashicorp",
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError(), "unexpected error response: %v", resp)
+		return resp.Data
+	}
+
+	createRole(t, b, storage, mockDB, "hashicorp")
+	status := readStatus()
+	require.Len(t, status["history"], 1)
+	require.Equal(t, 0, status["consecutive_failures"])
+	require.NotContains(t, status, "last_error")
+	require.NotEmpty(t, status["next_vault_rotation"])
+
+	generateWALFromFailedRotation(t, b, storage, mockDB, "hashicorp")
+	generateWALFromFailedRotation(t, b, storage, mockDB, "hashicorp")
+	status = readStatus()
+	require.Equal(t, 2, status["consecutive_failures"])
+	require.Contains(t, status["last_error"], "forced error")
+	history := status["history"].([]map[string]interface{})
+	require.Len(t, history, 3)
+	require.Equal(t, false, history[0]["success"])
+	require.Contains(t, history[0]["error"], "forced error")
+	require.Equal(t, true, history[2]["success"])
+
+	// Only the most recent attempts are kept.
+	for i := 0; i < rotationHistoryLength; i++ {
+		rotateRole(t, b, storage, mockDB, "hashicorp")
+	}
+	status = readStatus()
+	require.Equal(t, 0, status["consecutive_failures"])
+	require.Contains(t, status["last_error"], "forced error")
+	require.Len(t, status["history"], rotationHistoryLength)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	entry, err := storage.Get(ctx, rotationStatusStoragePrefix+"hashicorp")
+	require.NoError(t, err)
+	require.Nil(t, entry)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const SecretLibraryCredsType = "library-creds"
+
+func secretLibraryCreds(b *databaseBackend) *framework.Secret {
+	return &framework.Secret{
+		Type:   SecretLibraryCredsType,
+		Fields: map[string]*framework.FieldSchema{},
+
+		Renew:  b.secretLibraryCredsRenew,
+		Revoke: b.secretLibraryCredsRevoke,
+	}
+}
+
+func libraryCredsInternalData(req *logical.Request) (setName, roleName, checkOutID string, err error) {
+	for key, value := range map[string]*string{
+		"set_name":         &setName,
+		"static_role_name": &roleName,
+		"check_out_id":     &checkOutID,
+	} {
+		raw, ok := req.Secret.InternalData[key]
+		if !ok {
+			return "", "", "", fmt.Errorf("secret is missing %s internal data", key)
+		}
+		if *value, ok = raw.(string); !ok {
+			return "", "", "", fmt.Errorf("%s not a string", key)
+		}
+	}
+	return setName, roleName, checkOutID, nil
+}
+
+func (b *databaseBackend) secretLibraryCredsRenew(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	setName, roleName, checkOutID, err := libraryCredsInternalData(req)
+	if err != nil {
+		return nil, err
+	}
+
+	co, err := b.checkOut(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if co == nil || co.ID != checkOutID {
+		return logical.ErrorResponse("the credentials of static role %q have already been checked in", roleName), nil
+	}
+
+	set, err := b.librarySet(ctx, req.Storage, setName)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, fmt.Errorf("error during renew: could not find library set with name %q", setName)
+	}
+
+	ttl, _, err := framework.CalculateTTL(b.System(), req.Secret.Increment, set.TTL, 0, set.MaxTTL, 0, req.Secret.IssueTime)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{Secret: req.Secret}
+	resp.Secret.TTL = ttl
+	resp.Secret.MaxTTL = set.MaxTTL
+	return resp, nil
+}
+
+func (b *databaseBackend) secretLibraryCredsRevoke(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	_, roleName, checkOutID, err := libraryCredsInternalData(req)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.roleLocks, roleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return nil, b.checkIn(ctx, req.Storage, roleName, checkOutID)
+}
@@ -4,6 +4,7 @@
 package pki
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/sha256"
@@ -12,12 +13,16 @@ import (
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/hashicorp/go-multierror"
 )
 
 const (
@@ -88,34 +93,256 @@ func ValidateRawSHA256KeyAuthorization(keyAuthz []byte, token string, thumbprint
 	return true, nil
 }
 
-func buildResolver(config *acmeConfigEntry) (*net.Resolver, error) {
-	if len(config.DNSResolver) == 0 {
-		return net.DefaultResolver, nil
+// acmeChallengeNetwork performs the DNS lookups and outbound connections
+// needed to validate challenges, honoring the resolvers and proxy from the
+// mount's ACME configuration.
+type acmeChallengeNetwork struct {
+	// resolvers are tried in order; a later resolver is only used when an
+	// earlier one failed to give an answer.
+	resolvers     []*net.Resolver
+	resolverNames []string
+
+	// proxy, when set, is used to reach hosts for http-01 and tls-alpn-01
+	// challenges. The proxy resolves the challenged domain itself.
+	proxy *url.URL
+
+	dialer *net.Dialer
+}
+
+func buildChallengeNetwork(config *acmeConfigEntry) (*acmeChallengeNetwork, error) {
+	network := &acmeChallengeNetwork{
+		dialer: &net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: -1 * time.Second,
+		},
+	}
+
+	var tlsConfig *tls.Config
+	if config.DNSOverTLS {
+		var err error
+		tlsConfig, err = buildDNSTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, address := range config.dnsResolverAddresses() {
+		network.resolvers = append(network.resolvers, buildResolver(address, tlsConfig))
+		network.resolverNames = append(network.resolverNames, address)
+	}
+	if len(network.resolvers) == 0 {
+		network.resolvers = []*net.Resolver{net.DefaultResolver}
+		network.resolverNames = []string{"system resolver"}
+	}
+
+	if config.ChallengeProxy != "" {
+		proxy, err := parseChallengeProxy(config.ChallengeProxy)
+		if err != nil {
+			return nil, err
+		}
+		network.proxy = proxy
+	}
+
+	return network, nil
+}
+
+func buildResolver(address string, tlsConfig *tls.Config) *net.Resolver {
+	if tlsConfig != nil && tlsConfig.ServerName == "" {
+		// Without an explicit server name, verify the resolver's
+		// certificate against the address we connect to.
+		host, _, _ := net.SplitHostPort(address)
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
 	}
 
 	return &net.Resolver{
 		PreferGo:     true,
 		StrictErrors: false,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
 			d := net.Dialer{
 				Timeout: 10 * time.Second,
 			}
-			return d.DialContext(ctx, network, config.DNSResolver)
+			if tlsConfig != nil {
+				// DNS over TLS (RFC 7858) always runs over TCP; the Go
+				// resolver switches to TCP framing for non-packet conns.
+				td := tls.Dialer{
+					NetDialer: &d,
+					Config:    tlsConfig,
+				}
+				return td.DialContext(ctx, "tcp", address)
+			}
+			return d.DialContext(ctx, network, address)
 		},
-	}, nil
+	}
 }
 
-func buildDialerConfig(config *acmeConfigEntry) (*net.Dialer, error) {
-	resolver, err := buildResolver(config)
+func buildDNSTLSConfig(config *acmeConfigEntry) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: config.DNSTLSServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.DNSTLSCABundle != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(config.DNSTLSCABundle)) {
+			return nil, fmt.Errorf("failed to parse any certificates from dns_tls_ca_bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func parseChallengeProxy(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build resolver: %w", err)
+		return nil, fmt.Errorf("failed to parse challenge proxy URL: %w", err)
 	}
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" {
+		return nil, fmt.Errorf("challenge proxy URL must use the http or https scheme, got %q", proxyURL.Scheme)
+	}
+	if proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("challenge proxy URL is missing a host")
+	}
+
+	return proxyURL, nil
+}
 
-	return &net.Dialer{
-		Timeout:   10 * time.Second,
-		KeepAlive: -1 * time.Second,
-		Resolver:  resolver,
-	}, nil
+// isAuthoritativeDNSError reports whether err is an answer from the resolver
+// (such as NXDOMAIN) rather than a failure to reach it, in which case the
+// remaining resolvers are not consulted.
+func isAuthoritativeDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+func (n *acmeChallengeNetwork) lookupTXT(ctx context.Context, name string) ([]string, error) {
+	var errs *multierror.Error
+	for index, resolver := range n.resolvers {
+		results, err := resolver.LookupTXT(ctx, name)
+		if err == nil || isAuthoritativeDNSError(err) {
+			return results, err
+		}
+		errs = multierror.Append(errs, fmt.Errorf("via resolver %v: %w", n.resolverNames[index], err))
+	}
+
+	return nil, errs.ErrorOrNil()
+}
+
+func (n *acmeChallengeNetwork) lookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var errs *multierror.Error
+	for index, resolver := range n.resolvers {
+		results, err := resolver.LookupIPAddr(ctx, host)
+		if err == nil || isAuthoritativeDNSError(err) {
+			return results, err
+		}
+		errs = multierror.Append(errs, fmt.Errorf("via resolver %v: %w", n.resolverNames[index], err))
+	}
+
+	return nil, errs.ErrorOrNil()
+}
+
+// DialContext resolves the host of address with the configured resolvers and
+// connects to the first address that accepts the connection.
+func (n *acmeChallengeNetwork) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return n.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := n.lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %v: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("failed to resolve %v: no addresses found", host)
+	}
+
+	var errs *multierror.Error
+	for _, addr := range addrs {
+		conn, err := n.dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = multierror.Append(errs, err)
+	}
+
+	return nil, errs.ErrorOrNil()
+}
+
+// dialTarget connects to the challenged host at address, tunneling through
+// the proxy with HTTP CONNECT if one is configured.
+func (n *acmeChallengeNetwork) dialTarget(ctx context.Context, address string) (net.Conn, error) {
+	if n.proxy == nil {
+		return n.DialContext(ctx, "tcp", address)
+	}
+
+	proxyAddress := n.proxy.Host
+	if n.proxy.Port() == "" {
+		port := "80"
+		if n.proxy.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(n.proxy.Hostname(), port)
+	}
+
+	conn, err := n.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial proxy: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if n.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName: n.proxy.Hostname(),
+			MinVersion: tls.VersionTLS12,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to perform handshake with proxy: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if n.proxy.User != nil {
+		password, _ := n.proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(n.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT request to proxy: %w", err)
+	}
+
+	// The challenged server only speaks after our ClientHello, so nothing
+	// beyond the proxy's response can be buffered here.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT to %v: %v", address, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
 }
 
 // Validates a given ACME http-01 challenge against the specified domain,
@@ -124,9 +351,14 @@ func buildDialerConfig(config *acmeConfigEntry) (*net.Dialer, error) {
 // We attempt to be defensive here against timeouts, extra redirects, &c.
 func ValidateHTTP01Challenge(domain string, token string, thumbprint string, config *acmeConfigEntry) (bool, error) {
 	path := "http://" + domain + "/.well-known/acme-challenge/" + token
-	dialer, err := buildDialerConfig(config)
+	network, err := buildChallengeNetwork(config)
 	if err != nil {
-		return false, fmt.Errorf("failed to build dialer: %w", err)
+		return false, fmt.Errorf("failed to build challenge network: %w", err)
+	}
+
+	var proxy func(*http.Request) (*url.URL, error)
+	if network.proxy != nil {
+		proxy = http.ProxyURL(network.proxy)
 	}
 
 	transport := &http.Transport{
@@ -142,8 +374,12 @@ func ValidateHTTP01Challenge(domain string, token string, thumbprint string, con
 
 		// We'd rather timeout and re-attempt validation later than hang
 		// too many validators waiting for slow hosts.
-		DialContext:           dialer.DialContext,
+		DialContext:           network.DialContext,
 		ResponseHeaderTimeout: 10 * time.Second,
+
+		// Requests are sent through the configured proxy if any; the
+		// proxy environment variables of the server are never used.
+		Proxy: proxy,
 	}
 
 	maxRedirects := 10
@@ -207,24 +443,21 @@ func ValidateDNS01Challenge(domain string, token string, thumbprint string, conf
 	// Per RFC 8555, no difference in validation occurs if a wildcard entry
 	// is requested or if a non-wildcard entry is requested.
 	//
-	// XXX: In this case the DNS server is operator controlled and is assumed
-	// to be less malicious so the default resolver is used. In the future,
-	// we'll want to use net.Resolver for two reasons:
-	//
-	// 1. To control the actual resolver via ACME configuration,
-	// 2. To use a context to set stricter timeout limits.
-	resolver, err := buildResolver(config)
+	// The DNS servers are operator controlled and are assumed to be less
+	// malicious; they are queried in the configured order until one of
+	// them answers.
+	network, err := buildChallengeNetwork(config)
 	if err != nil {
-		return false, fmt.Errorf("failed to build resolver: %w", err)
+		return false, fmt.Errorf("failed to build challenge network: %w", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	name := DNSChallengePrefix + domain
-	results, err := resolver.LookupTXT(ctx, name)
+	results, err := network.lookupTXT(ctx, name)
 	if err != nil {
-		return false, fmt.Errorf("dns-01: failed to lookup TXT records for domain (%v) via resolver %v: %w", name, config.DNSResolver, err)
+		return false, fmt.Errorf("dns-01: failed to lookup TXT records for domain (%v): %w", name, err)
 	}
 
 	for _, keyAuthz := range results {
@@ -452,11 +685,11 @@ func ValidateTLSALPN01Challenge(domain string, token string, thumbprint string,
 		},
 	}
 
-	// Build a dialer using our custom DNS resolver, to ensure domains get
-	// resolved according to configuration.
-	dialer, err := buildDialerConfig(config)
+	// Build a dialer using our custom DNS resolvers and proxy, to ensure
+	// domains get resolved and reached according to configuration.
+	network, err := buildChallengeNetwork(config)
 	if err != nil {
-		return false, fmt.Errorf("failed to build dialer: %w", err)
+		return false, fmt.Errorf("failed to build challenge network: %w", err)
 	}
 
 	// Per RFC 8737 Section 3. TLS with Application-Layer Protocol
@@ -468,8 +701,14 @@ func ValidateTLSALPN01Challenge(domain string, token string, thumbprint string,
 	// >    one is returned).
 	// > 3. The ACME server initiates a TLS connection to the chosen IP
 	// >    address. This connection MUST use TCP port 443.
+	//
+	// We wish to put time bounds on the total time the connection and
+	// handshake can stall for, so build a connection context here.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
 	address := fmt.Sprintf("%v:"+ALPNPort, domain)
-	conn, err := dialer.Dial("tcp", address)
+	conn, err := network.dialTarget(ctx, address)
 	if err != nil {
 		return false, fmt.Errorf("tls-alpn-01: failed to dial host: %w", err)
 	}
@@ -488,11 +727,6 @@ func ValidateTLSALPN01Challenge(domain string, token string, thumbprint string,
 	// > invalid or the handshake doesn't fully complete.
 	defer client.Close()
 
-	// We wish to put time bounds on the total time the handshake can
-	// stall for, so build a connection context here.
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
 	// See note above about why we can allow Handshake to complete
 	// successfully.
 	if err := client.HandshakeContext(ctx); err != nil {
@@ -15,17 +15,21 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/builtin/logical/pki/dnstest"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/require"
 )
 
@@ -756,3 +760,155 @@ func TestAcmeValidateHttp01TLSRedirect(t *testing.T) {
 		})
 	}
 }
+
+// TestAcmeValidateHTTP01ChallengeViaProxy verifies that http-01 challenges are
+// fetched through the configured proxy, which resolves the domain itself.
+func TestAcmeValidateHTTP01ChallengeViaProxy(t *testing.T) {
+	t.Parallel()
+
+	// This domain is only resolvable by the proxy.
+	host := "acme.dadgarcorp.internal"
+	token := "my-token"
+	thumbprint := "my-thumbprint"
+
+	var proxied atomic.Bool
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host != host || r.URL.Path != "/.well-known/acme-challenge/"+token {
+			http.Error(w, "unexpected target", http.StatusBadGateway)
+			return
+		}
+		proxied.Store(true)
+		w.Write([]byte(token + "." + thumbprint))
+	}))
+	defer proxy.Close()
+
+	config := &acmeConfigEntry{
+		ChallengeProxy: proxy.URL,
+	}
+	isValid, err := ValidateHTTP01Challenge(host, token, thumbprint, config)
+	require.NoError(t, err)
+	require.True(t, isValid)
+	require.True(t, proxied.Load(), "expected challenge to be fetched through the proxy")
+
+	_, err = ValidateHTTP01Challenge(host, token, thumbprint, &acmeConfigEntry{})
+	require.Error(t, err, "expected direct validation of an unresolvable domain to fail")
+}
+
+// setupTestTXTResolver starts an in-process DNS server answering TXT queries
+// for name with value, and NXDOMAIN otherwise. If tlsConfig is set, the server
+// speaks DNS over TLS.
+func setupTestTXTResolver(t *testing.T, name string, value string, tlsConfig *tls.Config) string {
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if len(r.Question) == 1 && r.Question[0].Qtype == dns.TypeTXT && r.Question[0].Name == dns.Fqdn(name) {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{value},
+			})
+		} else {
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})
+
+	server := &dns.Server{Handler: handler}
+	var address string
+	if tlsConfig != nil {
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+		require.NoError(t, err)
+		server.Listener = ln
+		server.Net = "tcp-tls"
+		address = ln.Addr().String()
+	} else {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server.PacketConn = pc
+		address = pc.LocalAddr().String()
+	}
+
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	return address
+}
+
+func TestAcmeValidateDNS01ChallengeResolverFailover(t *testing.T) {
+	t.Parallel()
+
+	host := "dadgarcorp.com"
+	token := "my-token"
+	thumbprint := "my-thumbprint"
+	checksum := sha256.Sum256([]byte(token + "." + thumbprint))
+	authz := base64.RawURLEncoding.EncodeToString(checksum[:])
+
+	working := setupTestTXTResolver(t, DNSChallengePrefix+host, authz, nil)
+
+	// Grab a port nothing is listening on, to act as a dead resolver.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dead := pc.LocalAddr().String()
+	require.NoError(t, pc.Close())
+
+	config := &acmeConfigEntry{
+		DNSResolver:  dead,
+		DNSResolvers: []string{working},
+	}
+	isValid, err := ValidateDNS01Challenge(host, token, thumbprint, config)
+	require.NoError(t, err)
+	require.True(t, isValid)
+
+	// An authoritative negative answer must not fall through to the
+	// remaining resolvers.
+	config = &acmeConfigEntry{
+		DNSResolvers: []string{working, dead},
+	}
+	isValid, err = ValidateDNS01Challenge("vaultproject.io", token, thumbprint, config)
+	require.Error(t, err)
+	require.False(t, isValid)
+	require.NotContains(t, err.Error(), dead)
+}
+
+func TestAcmeValidateDNS01ChallengeOverTLS(t *testing.T) {
+	t.Parallel()
+
+	host := "dadgarcorp.com"
+	token := "my-token"
+	thumbprint := "my-thumbprint"
+	checksum := sha256.Sum256([]byte(token + "." + thumbprint))
+	authz := base64.RawURLEncoding.EncodeToString(checksum[:])
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "resolver.dadgarcorp.internal"},
+		DNSNames:     []string{"resolver.dadgarcorp.internal"},
+		NotBefore:    time.Now().Add(-1 * time.Minute),
+		NotAfter:     time.Now().Add(1 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	resolver := setupTestTXTResolver(t, DNSChallengePrefix+host, authz, &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+	})
+
+	config := &acmeConfigEntry{
+		DNSResolvers:     []string{resolver},
+		DNSOverTLS:       true,
+		DNSTLSServerName: "resolver.dadgarcorp.internal",
+		DNSTLSCABundle:   string(certPEM),
+	}
+	isValid, err := ValidateDNS01Challenge(host, token, thumbprint, config)
+	require.NoError(t, err)
+	require.True(t, isValid)
+
+	// Without trusting the resolver's certificate, the lookup must fail.
+	config.DNSTLSCABundle = ""
+	isValid, err = ValidateDNS01Challenge(host, token, thumbprint, config)
+	require.Error(t, err)
+	require.False(t, isValid)
+}
@@ -119,6 +119,10 @@ func Backend(conf *logical.BackendConfig) *backend {
 				"unified-ocsp",   // Unified OCSP POST
 				"unified-ocsp/*", // Unified OCSP GET
 
+				// SCEP carries its own authentication through challenge passwords
+				"roles/+/scep",
+				"roles/+/scep/pkiclient.exe",
+
 				// ACME paths are added below
 			},
 
@@ -130,7 +134,9 @@ func Backend(conf *logical.BackendConfig) *backend {
 				issuing.PathCrls,
 				issuing.PathCerts,
 				issuing.PathCertMetadata,
+				issuanceLogPrefix,
 				acmePathPrefix,
+				scepChallengePrefix,
 			},
 
 			Root: []string{
@@ -160,6 +166,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 				"ocsp/*",         // OCSP GET
 				"unified-ocsp",   // Unified OCSP POST
 				"unified-ocsp/*", // Unified OCSP GET
+				"roles/+/scep",   // SCEP PKIOperation POST
+				"roles/+/scep/pkiclient.exe",
 			},
 		},
 
@@ -184,6 +192,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathRevoke(&b),
 			pathRevokeWithKey(&b),
 			pathListCertsRevoked(&b),
+			pathSearchCerts(&b),
+			pathConfigIssuanceLog(&b),
 			pathTidy(&b),
 			pathTidyCancel(&b),
 			pathTidyStatus(&b),
@@ -236,6 +246,15 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathAcmeConfig(&b),
 			pathAcmeEabList(&b),
 			pathAcmeEabDelete(&b),
+
+			// EST
+			pathEstConfig(&b),
+
+			// SCEP
+			pathScepConfig(&b),
+			pathScepChallenge(&b),
+			pathScep(&b, "roles/"+framework.GenericNameRegex("role")+"/scep"),
+			pathScep(&b, "roles/"+framework.GenericNameRegex("role")+"/scep/pkiclient\\.exe"),
 		},
 
 		Secrets: []*framework.Secret{
@@ -279,6 +298,9 @@ func Backend(conf *logical.BackendConfig) *backend {
 		setupAcmeDirectory(&b, prefix.acmePrefix, prefix.unauthPrefix, prefix.opts)
 	}
 
+	// Add EST paths to backend
+	setupEstPaths(&b)
+
 	b.tidyCASGuard = new(uint32)
 	b.tidyCancelCAS = new(uint32)
 	b.tidyStatus = &tidyStatus{state: tidyStatusInactive}
@@ -335,6 +357,15 @@ type backend struct {
 	// Context around ACME operations
 	acmeState       *acmeState
 	acmeAccountLock sync.RWMutex // (Write) Locked on Tidy, (Read) Locked on Account Creation
+
+	// Serializes the consumption of one-time SCEP challenge passwords
+	scepChallengeLock sync.Mutex
+
+	// Cached issuance log configuration, nil until loaded. The generation is
+	// bumped on invalidation so that a load racing with it is not cached.
+	issuanceLogConfigLock sync.RWMutex
+	issuanceLogConfig     *issuanceLogConfig
+	issuanceLogConfigGen  uint64
 }
 
 // BackendOps a bridge/legacy interface until we can further
@@ -440,6 +471,8 @@ func (b *backend) initialize(ctx context.Context, ir *logical.InitializationRequ
 		return err
 	}
 
+	b.initializeEst(sc)
+
 	// Initialize also needs to populate our certificate and revoked certificate count
 	err = b.initializeStoredCertificateCounts(ctx)
 	if err != nil {
@@ -593,6 +626,8 @@ func (b *backend) invalidate(ctx context.Context, key string) {
 		b.CrlBuilder().markConfigDirty()
 	case key == storageAcmeConfig:
 		b.GetAcmeState().markConfigDirty()
+	case key == issuanceLogConfigPath:
+		b.invalidateIssuanceLogConfig()
 	case key == storageIssuerConfig:
 		b.CrlBuilder().invalidateCRLBuildTime()
 	case strings.HasPrefix(key, crossRevocationPrefix):
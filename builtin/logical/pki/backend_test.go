@@ -6759,6 +6759,30 @@ func pathShouldBeUnauthedWriteOnly(t *testing.T, client *api.Client, path string
 	}
 }
 
+func pathShouldBeUnauthedReadWriteOnly(t *testing.T, client *api.Client, path string, token string) {
+	for _, tok := range []string{"", token} {
+		client.SetToken(tok)
+		resp, err := client.Logical().ReadWithContext(ctx, path)
+		if err != nil && isPermDenied(err) {
+			t.Fatalf("unexpected failure to read %v (token: %v): %v / %v", path, tok != "", err, resp)
+		}
+		resp, err = client.Logical().WriteWithContext(ctx, path, map[string]interface{}{})
+		if err != nil && isPermDenied(err) {
+			t.Fatalf("unexpected failure to write %v (token: %v): %v / %v", path, tok != "", err, resp)
+		}
+
+		// These should all be denied.
+		resp, err = client.Logical().ListWithContext(ctx, path)
+		if (err == nil && resp != nil) || (err != nil && !isDeniedOp(err)) {
+			t.Fatalf("unexpected failure during list on read-write-only path %v (token: %v): %v / %v", path, tok != "", err, resp)
+		}
+		resp, err = client.Logical().DeleteWithContext(ctx, path)
+		if (err == nil && resp != nil) || (err != nil && !isDeniedOp(err)) {
+			t.Fatalf("unexpected failure during delete on read-write-only path %v (token: %v): %v / %v", path, tok != "", err, resp)
+		}
+	}
+}
+
 type pathAuthChecker int
 
 const (
@@ -6772,7 +6796,7 @@ var pathAuthChckerMap = map[pathAuthChecker]pathAuthCheckerFunc{
 	shouldBeAuthed:                pathShouldBeAuthed,
 	shouldBeUnauthedReadList:      pathShouldBeUnauthedReadList,
 	shouldBeUnauthedWriteOnly:     pathShouldBeUnauthedWriteOnly,
-	shouldBeUnauthedReadWriteOnly: pathShouldBeUnauthedWriteOnly,
+	shouldBeUnauthedReadWriteOnly: pathShouldBeUnauthedReadWriteOnly,
 }
 
 func TestProperAuthing(t *testing.T) {
@@ -6852,10 +6876,14 @@ func TestProperAuthing(t *testing.T) {
 		"certs/revocation-queue/":                shouldBeAuthed,
 		"certs/unified-revoked/":                 shouldBeAuthed,
 		"config/acme":                            shouldBeAuthed,
+		"config/est":                             shouldBeAuthed,
+		"config/scep":                            shouldBeAuthed,
+		"certs/search":                           shouldBeAuthed,
 		"config/auto-tidy":                       shouldBeAuthed,
 		"config/ca":                              shouldBeAuthed,
 		"config/cluster":                         shouldBeAuthed,
 		"config/crl":                             shouldBeAuthed,
+		"config/issuance-log":                    shouldBeAuthed,
 		"config/issuers":                         shouldBeAuthed,
 		"config/keys":                            shouldBeAuthed,
 		"config/urls":                            shouldBeAuthed,
@@ -6972,6 +7000,19 @@ func TestProperAuthing(t *testing.T) {
 		paths[acmePrefix+"new-eab"] = shouldBeAuthed
 	}
 
+	// Add EST based paths to the test suite
+	for _, estPrefix := range []string{"est/", "est/test/"} {
+		paths[estPrefix+"cacerts"] = shouldBeUnauthedReadList
+		paths[estPrefix+"csrattrs"] = shouldBeUnauthedReadList
+		paths[estPrefix+"simpleenroll"] = shouldBeUnauthedWriteOnly
+		paths[estPrefix+"simplereenroll"] = shouldBeUnauthedWriteOnly
+	}
+
+	// Add SCEP based paths to the test suite
+	paths["roles/test/scep"] = shouldBeUnauthedReadWriteOnly
+	paths["roles/test/scep/pkiclient.exe"] = shouldBeUnauthedReadWriteOnly
+	paths["roles/test/scep/challenge"] = shouldBeAuthed
+
 	for path, checkerType := range paths {
 		checker := pathAuthChckerMap[checkerType]
 		checker(t, client, "pki/"+path, token)
@@ -7030,6 +7071,9 @@ func TestProperAuthing(t *testing.T) {
 		if strings.Contains(raw_path, "eab") && strings.Contains(raw_path, "{key_id}") {
 			raw_path = strings.ReplaceAll(raw_path, "{key_id}", eabKid)
 		}
+		if strings.Contains(raw_path, "est/") && strings.Contains(raw_path, "{label}") {
+			raw_path = strings.ReplaceAll(raw_path, "{label}", "test")
+		}
 		if strings.Contains(raw_path, "external-policy/") && strings.Contains(raw_path, "{policy}") {
 			raw_path = strings.ReplaceAll(raw_path, "{policy}", "a-policy")
 		}
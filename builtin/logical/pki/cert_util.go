@@ -789,3 +789,42 @@ func (i CertNotAfterInputFromFieldData) GetTTL() int {
 func (i CertNotAfterInputFromFieldData) GetOptionalNotAfter() (interface{}, bool) {
 	return i.data.GetOk("not_after")
 }
+
+// signAndStoreCsr signs a CSR received over an enrollment protocol such as
+// EST or SCEP according to the role, storing the certificate unless the role
+// disables it.
+func (b *backend) signAndStoreCsr(sc *storageContext, req *logical.Request, role *issuing.RoleEntry, signingBundle *certutil.CAInfoBundle, issuerId issuing.IssuerID, csr *x509.CertificateRequest) (*certutil.ParsedCertBundle, error) {
+	pemCsr := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE REQUEST",
+		Bytes: csr.Raw,
+	}))
+
+	data := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr": pemCsr,
+		},
+		Schema: getCsrSignVerbatimSchemaFields(),
+	}
+
+	input := &inputBundle{
+		req:     req,
+		apiData: data,
+		role:    role,
+	}
+
+	// As with the sign-verbatim endpoint, the CSR's values are used as-is
+	// when no role restricts them; only stored roles carry a name.
+	useCSRValues := role.Name == ""
+	parsedBundle, _, err := signCert(sc.System(), input, signingBundle, false, useCSRValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if !role.NoStore {
+		if err := sc.storeCertificate(parsedBundle, role.Name, issuerId); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsedBundle, nil
+}
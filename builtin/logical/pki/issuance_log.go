@@ -0,0 +1,338 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/pki/issuing"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// The issuance log records, for every stored certificate, the fields the
+// search API filters on, so searches don't need to load and parse each
+// certificate. Entries live next to the certificates they describe:
+//
+//	issuance-log/serial/<serial>              the record itself
+//	issuance-log/expiry/<YYYY-MM-DD>/<serial> an index by expiration day
+//	issuance-log/role/<role>/<serial>         an index by role
+//	issuance-log/issuer/<issuer id>/<serial>  an index by issuer
+//
+// All are kept in step with certs/ by storeCertificate and by tidy, as long as
+// the log is enabled through config/issuance-log. The
+// common name and SAN filters are globs, which can't be served by an index;
+// they are evaluated against the records selected through the other filters.
+const (
+	issuanceLogPrefix       = "issuance-log/"
+	issuanceLogSerialPrefix = issuanceLogPrefix + "serial/"
+	issuanceLogExpiryPrefix = issuanceLogPrefix + "expiry/"
+	issuanceLogRolePrefix   = issuanceLogPrefix + "role/"
+	issuanceLogIssuerPrefix = issuanceLogPrefix + "issuer/"
+	issuanceLogExpiryLayout = "2006-01-02"
+
+	issuanceLogConfigPath = "config/issuance-log"
+)
+
+type issuanceLogConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// getIssuanceLogConfig returns the issuance log configuration, which is
+// cached as it is consulted on every issuance. The log is enabled unless
+// configured otherwise.
+func (sc *storageContext) getIssuanceLogConfig() (*issuanceLogConfig, error) {
+	b := sc.Backend
+	b.issuanceLogConfigLock.RLock()
+	config, gen := b.issuanceLogConfig, b.issuanceLogConfigGen
+	b.issuanceLogConfigLock.RUnlock()
+	if config != nil {
+		return config, nil
+	}
+
+	entry, err := sc.Storage.Get(sc.Context, issuanceLogConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching issuance log configuration: %w", err)
+	}
+	config = &issuanceLogConfig{Enabled: true}
+	if entry != nil {
+		if err := entry.DecodeJSON(config); err != nil {
+			return nil, fmt.Errorf("failed decoding issuance log configuration: %w", err)
+		}
+	}
+
+	b.issuanceLogConfigLock.Lock()
+	if b.issuanceLogConfigGen == gen {
+		b.issuanceLogConfig = config
+	}
+	b.issuanceLogConfigLock.Unlock()
+	return config, nil
+}
+
+func (sc *storageContext) writeIssuanceLogConfig(config *issuanceLogConfig) error {
+	entry, err := logical.StorageEntryJSON(issuanceLogConfigPath, config)
+	if err != nil {
+		return err
+	}
+	if err := sc.Storage.Put(sc.Context, entry); err != nil {
+		return err
+	}
+
+	sc.Backend.invalidateIssuanceLogConfig()
+	return nil
+}
+
+func (b *backend) invalidateIssuanceLogConfig() {
+	b.issuanceLogConfigLock.Lock()
+	defer b.issuanceLogConfigLock.Unlock()
+	b.issuanceLogConfig = nil
+	b.issuanceLogConfigGen++
+}
+
+type issuanceLogEntry struct {
+	SerialNumber   string           `json:"serial_number"`
+	CommonName     string           `json:"common_name"`
+	Subject        string           `json:"subject"`
+	DNSNames       []string         `json:"dns_names,omitempty"`
+	IPAddresses    []string         `json:"ip_addresses,omitempty"`
+	EmailAddresses []string         `json:"email_addresses,omitempty"`
+	URIs           []string         `json:"uris,omitempty"`
+	Role           string           `json:"role,omitempty"`
+	IssuerId       issuing.IssuerID `json:"issuer_id,omitempty"`
+	NotBefore      time.Time        `json:"not_before"`
+	NotAfter       time.Time        `json:"not_after"`
+}
+
+func newIssuanceLogEntry(cert *x509.Certificate, role string, issuerId issuing.IssuerID) *issuanceLogEntry {
+	entry := &issuanceLogEntry{
+		SerialNumber:   serialFromCert(cert),
+		CommonName:     cert.Subject.CommonName,
+		Subject:        cert.Subject.String(),
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Role:           role,
+		IssuerId:       issuerId,
+		NotBefore:      cert.NotBefore.UTC(),
+		NotAfter:       cert.NotAfter.UTC(),
+	}
+	for _, ip := range cert.IPAddresses {
+		entry.IPAddresses = append(entry.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		entry.URIs = append(entry.URIs, uri.String())
+	}
+	return entry
+}
+
+// subjectAltNames returns all SANs of the certificate, regardless of type.
+func (e *issuanceLogEntry) subjectAltNames() []string {
+	var sans []string
+	sans = append(sans, e.DNSNames...)
+	sans = append(sans, e.IPAddresses...)
+	sans = append(sans, e.EmailAddresses...)
+	sans = append(sans, e.URIs...)
+	return sans
+}
+
+func issuanceLogExpiryDay(notAfter time.Time) string {
+	return notAfter.UTC().Format(issuanceLogExpiryLayout)
+}
+
+// storeCertificate stores the certificate by serial, as
+// issuing.StoreCertificate does, and records it within the issuance log
+// unless it is disabled.
+func (sc *storageContext) storeCertificate(certBundle *certutil.ParsedCertBundle, role string, issuerId issuing.IssuerID) error {
+	if err := issuing.StoreCertificate(sc.Context, sc.Storage, sc.GetCertificateCounter(), certBundle); err != nil {
+		return err
+	}
+
+	config, err := sc.getIssuanceLogConfig()
+	if err != nil {
+		return err
+	}
+	if !config.Enabled {
+		return nil
+	}
+
+	return sc.writeIssuanceLogEntry(newIssuanceLogEntry(certBundle.Certificate, role, issuerId))
+}
+
+func (sc *storageContext) writeIssuanceLogEntry(entry *issuanceLogEntry) error {
+	serial := normalizeSerial(entry.SerialNumber)
+	json, err := logical.StorageEntryJSON(issuanceLogSerialPrefix+serial, entry)
+	if err != nil {
+		return fmt.Errorf("failed creating issuance log entry: %w", err)
+	}
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return fmt.Errorf("failed writing issuance log entry: %w", err)
+	}
+
+	for _, key := range entry.indexKeys() {
+		index := &logical.StorageEntry{
+			Key:   key,
+			Value: []byte{},
+		}
+		if err := sc.Storage.Put(sc.Context, index); err != nil {
+			return fmt.Errorf("failed writing issuance log index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// indexKeys returns the storage keys of the index entries of the record.
+func (e *issuanceLogEntry) indexKeys() []string {
+	serial := normalizeSerial(e.SerialNumber)
+	keys := []string{issuanceLogExpiryPrefix + issuanceLogExpiryDay(e.NotAfter) + "/" + serial}
+	if e.Role != "" {
+		keys = append(keys, issuanceLogRolePrefix+e.Role+"/"+serial)
+	}
+	if e.IssuerId != "" {
+		keys = append(keys, issuanceLogIssuerPrefix+e.IssuerId.String()+"/"+serial)
+	}
+	return keys
+}
+
+func (sc *storageContext) fetchIssuanceLogEntry(serial string) (*issuanceLogEntry, error) {
+	serial = normalizeSerial(serial)
+	entry, err := sc.Storage.Get(sc.Context, issuanceLogSerialPrefix+serial)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching issuance log entry %v: %w", serial, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var logEntry issuanceLogEntry
+	if err := entry.DecodeJSON(&logEntry); err != nil {
+		return nil, fmt.Errorf("failed decoding issuance log entry %v: %w", serial, err)
+	}
+	return &logEntry, nil
+}
+
+// deleteIssuanceLogEntry removes the record of the given serial along with
+// its index entries; it is a no-op for serials that were never recorded.
+func (sc *storageContext) deleteIssuanceLogEntry(serial string) error {
+	serial = normalizeSerial(serial)
+	entry, err := sc.fetchIssuanceLogEntry(serial)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+
+	for _, key := range entry.indexKeys() {
+		if err := sc.Storage.Delete(sc.Context, key); err != nil {
+			return fmt.Errorf("failed deleting issuance log index of %v: %w", serial, err)
+		}
+	}
+	if err := sc.Storage.Delete(sc.Context, issuanceLogSerialPrefix+serial); err != nil {
+		return fmt.Errorf("failed deleting issuance log entry %v: %w", serial, err)
+	}
+	return nil
+}
+
+// listIssuanceLogCandidates returns, sorted, the serials of the records that
+// may match the query, using the index of its role, issuer or expiration
+// bounds in that order of preference. When none of these is set, every
+// recorded serial is returned.
+func (sc *storageContext) listIssuanceLogCandidates(query *certSearchQuery) ([]string, error) {
+	var prefix string
+	switch {
+	case query.role != "":
+		prefix = issuanceLogRolePrefix + query.role + "/"
+	case query.issuerId != "":
+		prefix = issuanceLogIssuerPrefix + query.issuerId.String() + "/"
+	case !query.expiresAfter.IsZero() || !query.expiresBefore.IsZero():
+		return sc.listIssuanceLogExpiring(query.expiresAfter, query.expiresBefore)
+	default:
+		prefix = issuanceLogSerialPrefix
+	}
+
+	serials, err := sc.Storage.List(sc.Context, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing issuance log: %w", err)
+	}
+	sort.Strings(serials)
+	return serials, nil
+}
+
+// listIssuanceLogExpiring returns, sorted, the serials of the records that
+// may expire within the given bounds; a zero bound is unbounded.
+func (sc *storageContext) listIssuanceLogExpiring(expiresAfter, expiresBefore time.Time) ([]string, error) {
+	days, err := sc.Storage.List(sc.Context, issuanceLogExpiryPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing issuance log index: %w", err)
+	}
+
+	var serials []string
+	for _, day := range days {
+		day = strings.TrimSuffix(day, "/")
+		if !expiresAfter.IsZero() && day < issuanceLogExpiryDay(expiresAfter) {
+			continue
+		}
+		if !expiresBefore.IsZero() && day > issuanceLogExpiryDay(expiresBefore) {
+			continue
+		}
+
+		daySerials, err := sc.Storage.List(sc.Context, issuanceLogExpiryPrefix+day+"/")
+		if err != nil {
+			return nil, fmt.Errorf("failed listing issuance log index: %w", err)
+		}
+		serials = append(serials, daySerials...)
+	}
+
+	sort.Strings(serials)
+	return serials, nil
+}
+
+// recordExistingIssuance records a stored certificate issued before the
+// issuance log existed, attributing it to the issuer which signed it.
+func (sc *storageContext) recordExistingIssuance(cert *x509.Certificate, issuers map[issuing.IssuerID]*x509.Certificate) error {
+	var issuerId issuing.IssuerID
+	for id, issuer := range issuers {
+		if cert.CheckSignatureFrom(issuer) == nil {
+			issuerId = id
+			break
+		}
+	}
+
+	return sc.writeIssuanceLogEntry(newIssuanceLogEntry(cert, "", issuerId))
+}
+
+// tidyIssuanceLogOrphans removes the records of certificates which are no
+// longer stored, given the normalized serials still present in certs/.
+func (sc *storageContext) tidyIssuanceLogOrphans(stored map[string]bool) error {
+	recorded, err := sc.Storage.List(sc.Context, issuanceLogSerialPrefix)
+	if err != nil {
+		return fmt.Errorf("failed listing issuance log: %w", err)
+	}
+
+	for _, serial := range recorded {
+		if stored[serial] {
+			continue
+		}
+
+		// The certificate may have been issued after the certificate store
+		// was listed.
+		certEntry, err := fetchCertBySerial(sc, issuing.PathCerts, serial)
+		if err != nil {
+			return err
+		}
+		if certEntry != nil {
+			continue
+		}
+
+		if err := sc.deleteIssuanceLogEntry(serial); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,324 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/pki/issuing"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/ryanuber/go-glob"
+)
+
+const (
+	defaultCertSearchLimit = 100
+	maxCertSearchLimit     = 1000
+)
+
+// certSearchQuery holds the filters of a search; unset filters match every
+// entry.
+type certSearchQuery struct {
+	commonName    string
+	san           string
+	role          string
+	issuerId      issuing.IssuerID
+	expiresAfter  time.Time
+	expiresBefore time.Time
+}
+
+func (q *certSearchQuery) matches(entry *issuanceLogEntry) bool {
+	if q.commonName != "" && !glob.Glob(q.commonName, strings.ToLower(entry.CommonName)) {
+		return false
+	}
+	if q.san != "" {
+		found := false
+		for _, san := range entry.subjectAltNames() {
+			if glob.Glob(q.san, strings.ToLower(san)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.role != "" && entry.Role != q.role {
+		return false
+	}
+	if q.issuerId != "" && entry.IssuerId != q.issuerId {
+		return false
+	}
+	if !q.expiresAfter.IsZero() && entry.NotAfter.Before(q.expiresAfter) {
+		return false
+	}
+	if !q.expiresBefore.IsZero() && entry.NotAfter.After(q.expiresBefore) {
+		return false
+	}
+	return true
+}
+
+func pathSearchCerts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/search",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationVerb:   "search",
+			OperationSuffix: "certs",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"common_name": {
+				Type: framework.TypeString,
+				Description: `Only return certificates whose subject common name
+matches this glob, such as "*.payments.internal". Matching is case-insensitive.`,
+				Query: true,
+			},
+			"san": {
+				Type: framework.TypeString,
+				Description: `Only return certificates with a subject alternative
+name (DNS name, IP address, email address or URI) matching this glob.
+Matching is case-insensitive.`,
+				Query: true,
+			},
+			"role": {
+				Type:        framework.TypeString,
+				Description: `Only return certificates issued through this role.`,
+				Query:       true,
+			},
+			"issuer_ref": {
+				Type:        framework.TypeString,
+				Description: `Only return certificates signed by this issuer.`,
+				Query:       true,
+			},
+			"expires_after": {
+				Type:        framework.TypeString,
+				Description: `Only return certificates expiring at or after this RFC 3339 timestamp.`,
+				Query:       true,
+			},
+			"expires_before": {
+				Type:        framework.TypeString,
+				Description: `Only return certificates expiring at or before this RFC 3339 timestamp.`,
+				Query:       true,
+			},
+			"expires_within": {
+				Type: framework.TypeDurationSecond,
+				Description: `Only return certificates which have not yet expired
+but will within this duration; exclusive with expires_after and expires_before.`,
+				Query: true,
+			},
+			"after": {
+				Type: framework.TypeString,
+				Description: `Only return certificates with a serial number sorting
+after this one; set to the next_after value of the previous response to fetch
+the next page.`,
+				Query: true,
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: fmt.Sprintf(`The maximum number of certificates to return, at most %d.`, maxCertSearchLimit),
+				Default:     defaultCertSearchLimit,
+				Query:       true,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathSearchCertsRead,
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: "OK",
+						Fields: map[string]*framework.FieldSchema{
+							"keys": {
+								Type:        framework.TypeStringSlice,
+								Description: `Serial numbers of the matching certificates, in ascending order`,
+								Required:    true,
+							},
+							"key_info": {
+								Type:        framework.TypeMap,
+								Description: `Issuance details of the matching certificates, keyed by serial number`,
+								Required:    true,
+							},
+							"next_after": {
+								Type:        framework.TypeString,
+								Description: `Present when more certificates match; pass as after to fetch the next page`,
+								Required:    false,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    pathSearchCertsHelpSyn,
+		HelpDescription: pathSearchCertsHelpDesc,
+	}
+}
+
+func (b *backend) pathSearchCertsRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	config, err := sc.getIssuanceLogConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return logical.ErrorResponse("the issuance log is disabled; enable it through config/issuance-log to search certificates"), nil
+	}
+
+	query, resp := parseCertSearchQuery(sc, data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	limit := data.Get("limit").(int)
+	if limit < 1 || limit > maxCertSearchLimit {
+		return logical.ErrorResponse("limit must be between 1 and %d", maxCertSearchLimit), nil
+	}
+	after := normalizeSerial(data.Get("after").(string))
+
+	candidates, err := sc.listIssuanceLogCandidates(query)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	keyInfo := map[string]interface{}{}
+	nextAfter := ""
+	for _, serial := range candidates {
+		// Paging by serial keeps pages stable while certificates are issued
+		// or tidied in between requests.
+		if after != "" && serial <= after {
+			continue
+		}
+
+		entry, err := sc.fetchIssuanceLogEntry(serial)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil || !query.matches(entry) {
+			// Tidied since the log was listed, or filtered out.
+			continue
+		}
+
+		if len(keys) == limit {
+			nextAfter = keys[len(keys)-1]
+			break
+		}
+
+		info, err := certSearchKeyInfo(sc, entry)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, entry.SerialNumber)
+		keyInfo[entry.SerialNumber] = info
+	}
+
+	resp = logical.ListResponseWithInfo(keys, keyInfo)
+	if nextAfter != "" {
+		resp.Data["next_after"] = nextAfter
+	}
+	return resp, nil
+}
+
+func parseCertSearchQuery(sc *storageContext, data *framework.FieldData) (*certSearchQuery, *logical.Response) {
+	query := &certSearchQuery{
+		commonName: strings.ToLower(data.Get("common_name").(string)),
+		san:        strings.ToLower(data.Get("san").(string)),
+		role:       data.Get("role").(string),
+	}
+
+	if issuerRef := data.Get("issuer_ref").(string); issuerRef != "" {
+		issuerId, err := sc.resolveIssuerReference(issuerRef)
+		if err != nil {
+			return nil, logical.ErrorResponse("unable to resolve issuer_ref %q: %v", issuerRef, err)
+		}
+		query.issuerId = issuerId
+	}
+
+	for field, dest := range map[string]*time.Time{
+		"expires_after":  &query.expiresAfter,
+		"expires_before": &query.expiresBefore,
+	} {
+		value := data.Get(field).(string)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, logical.ErrorResponse("%s must be an RFC 3339 timestamp: %v", field, err)
+		}
+		*dest = parsed
+	}
+
+	if withinRaw, ok := data.GetOk("expires_within"); ok {
+		if !query.expiresAfter.IsZero() || !query.expiresBefore.IsZero() {
+			return nil, logical.ErrorResponse("expires_within can not be combined with expires_after or expires_before")
+		}
+		within := time.Duration(withinRaw.(int)) * time.Second
+		if within <= 0 {
+			return nil, logical.ErrorResponse("expires_within must be greater than 0")
+		}
+		query.expiresAfter = time.Now()
+		query.expiresBefore = query.expiresAfter.Add(within)
+	}
+
+	if !query.expiresAfter.IsZero() && !query.expiresBefore.IsZero() && query.expiresBefore.Before(query.expiresAfter) {
+		return nil, logical.ErrorResponse("expires_before must not be earlier than expires_after")
+	}
+
+	return query, nil
+}
+
+func certSearchKeyInfo(sc *storageContext, entry *issuanceLogEntry) (map[string]interface{}, error) {
+	revokedEntry, err := fetchCertBySerial(sc, revokedPath, entry.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"common_name":     entry.CommonName,
+		"subject":         entry.Subject,
+		"dns_names":       entry.DNSNames,
+		"ip_addresses":    entry.IPAddresses,
+		"email_addresses": entry.EmailAddresses,
+		"uris":            entry.URIs,
+		"role":            entry.Role,
+		"issuer_id":       entry.IssuerId,
+		"not_before":      entry.NotBefore.Format(time.RFC3339),
+		"not_after":       entry.NotAfter.Format(time.RFC3339),
+		"revoked":         revokedEntry != nil,
+	}, nil
+}
+
+const pathSearchCertsHelpSyn = `
+Search the certificates issued by this mount.
+`
+
+const pathSearchCertsHelpDesc = `
+Searches the issuance log, which records the subject, subject alternative
+names, role, issuer and validity of every certificate stored by this mount,
+by any combination of these. Results are ordered by serial number and paged
+through the after and limit parameters.
+
+The role, issuer_ref and expiration filters are served by indexes. The
+common_name and san globs are checked against every record selected by the
+other filters, or against the whole log when they are used alone, so they
+should be combined with an indexed filter on mounts storing many
+certificates.
+
+Certificates issued by roles with no_store set are not recorded. Records are
+removed along with their certificates by tidy_cert_store. Certificates issued
+before the issuance log existed, or while it was disabled, are only found once
+a tidy_cert_store run has recorded them.
+
+Recording a certificate takes three to four storage writes in addition to the
+certificate itself. The issuance log can be disabled through
+config/issuance-log, after which searches are refused.
+`
@@ -0,0 +1,283 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func searchCerts(t *testing.T, b *backend, s logical.Storage, query map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := CBReq(b, s, logical.ReadOperation, "certs/search", query)
+	requireSuccessNonNilResponse(t, resp, err)
+	return resp
+}
+
+func TestCertsSearch(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "ec",
+		"ttl":         "48h",
+		"issuer_name": "root",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	rootSerial := resp.Data["serial_number"].(string)
+
+	_, err = CBWrite(b, s, "roles/payments", map[string]interface{}{
+		"allowed_domains":  "payments.internal",
+		"allow_subdomains": true,
+		"key_type":         "ec",
+	})
+	require.NoError(t, err)
+	_, err = CBWrite(b, s, "roles/web", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "ec",
+	})
+	require.NoError(t, err)
+
+	issued := map[string]string{}
+	for name, params := range map[string]map[string]interface{}{
+		"a": {"role": "payments", "common_name": "a.payments.internal", "ttl": "1h"},
+		"b": {"role": "payments", "common_name": "b.payments.internal", "ttl": "10h"},
+		"c": {"role": "payments", "common_name": "c.payments.internal", "ttl": "40h"},
+		"w": {"role": "web", "common_name": "www.example.com", "alt_names": "api.example.com", "ttl": "1h"},
+	} {
+		resp, err := CBWrite(b, s, "issue/"+params["role"].(string), params)
+		requireSuccessNonNilResponse(t, resp, err)
+		issued[name] = resp.Data["serial_number"].(string)
+	}
+
+	// The root is recorded too, without a role
+	resp = searchCerts(t, b, s, nil)
+	require.Len(t, resp.Data["keys"], 5)
+	require.Contains(t, resp.Data["keys"], rootSerial)
+	require.Equal(t, "", resp.Data["key_info"].(map[string]interface{})[rootSerial].(map[string]interface{})["role"])
+
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "*.PAYMENTS.internal"})
+	require.ElementsMatch(t, []string{issued["a"], issued["b"], issued["c"]}, resp.Data["keys"])
+
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "*.payments.internal", "expires_within": "20h"})
+	require.ElementsMatch(t, []string{issued["a"], issued["b"]}, resp.Data["keys"])
+
+	resp = searchCerts(t, b, s, map[string]interface{}{
+		"expires_after":  time.Now().Add(5 * time.Hour).Format(time.RFC3339),
+		"expires_before": time.Now().Add(45 * time.Hour).Format(time.RFC3339),
+	})
+	require.ElementsMatch(t, []string{issued["b"], issued["c"]}, resp.Data["keys"])
+
+	resp = searchCerts(t, b, s, map[string]interface{}{"san": "api.*"})
+	require.Equal(t, []string{issued["w"]}, resp.Data["keys"])
+	info := resp.Data["key_info"].(map[string]interface{})[issued["w"]].(map[string]interface{})
+	require.Equal(t, "web", info["role"])
+	require.Equal(t, "www.example.com", info["common_name"])
+	require.ElementsMatch(t, []string{"www.example.com", "api.example.com"}, info["dns_names"])
+	require.Equal(t, false, info["revoked"])
+
+	resp = searchCerts(t, b, s, map[string]interface{}{"role": "payments", "issuer_ref": "root"})
+	require.Len(t, resp.Data["keys"], 3)
+	resp = searchCerts(t, b, s, map[string]interface{}{"role": "web"})
+	require.Equal(t, []string{issued["w"]}, resp.Data["keys"])
+	resp = searchCerts(t, b, s, map[string]interface{}{"issuer_ref": "root", "common_name": "www.*"})
+	require.Equal(t, []string{issued["w"]}, resp.Data["keys"])
+
+	_, err = CBReq(b, s, logical.ReadOperation, "certs/search", map[string]interface{}{"issuer_ref": "missing"})
+	require.Error(t, err)
+	_, err = CBReq(b, s, logical.ReadOperation, "certs/search", map[string]interface{}{
+		"expires_within": "720h",
+		"expires_after":  time.Now().Format(time.RFC3339),
+	})
+	require.Error(t, err)
+
+	// Page through the results one at a time
+	var paged []string
+	after := ""
+	for {
+		resp = searchCerts(t, b, s, map[string]interface{}{"role": "payments", "limit": 1, "after": after})
+		paged = append(paged, resp.Data["keys"].([]string)...)
+		next, ok := resp.Data["next_after"]
+		if !ok {
+			break
+		}
+		after = next.(string)
+		require.LessOrEqual(t, len(paged), 3)
+	}
+	require.ElementsMatch(t, []string{issued["a"], issued["b"], issued["c"]}, paged)
+
+	_, err = CBWrite(b, s, "revoke", map[string]interface{}{"serial_number": issued["a"]})
+	require.NoError(t, err)
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "a.payments.internal"})
+	require.Equal(t, true, resp.Data["key_info"].(map[string]interface{})[issued["a"]].(map[string]interface{})["revoked"])
+}
+
+func TestCertsSearch_Tidy(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+	ctx := context.Background()
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "ec",
+		"ttl":         "720h",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	rootIssuer := resp.Data["issuer_id"]
+
+	_, err = CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "issue/devices", map[string]interface{}{"common_name": "short", "ttl": "1s"})
+	requireSuccessNonNilResponse(t, resp, err)
+	short := resp.Data["serial_number"].(string)
+	resp, err = CBWrite(b, s, "issue/devices", map[string]interface{}{"common_name": "long", "ttl": "1h"})
+	requireSuccessNonNilResponse(t, resp, err)
+	long := resp.Data["serial_number"].(string)
+
+	// Simulate a certificate issued before the issuance log existed
+	sc := b.makeStorageContext(ctx, s)
+	require.NoError(t, sc.deleteIssuanceLogEntry(long))
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "long"})
+	require.Empty(t, resp.Data["keys"])
+
+	time.Sleep(2 * time.Second)
+	_, err = CBWrite(b, s, "tidy", map[string]interface{}{
+		"tidy_cert_store": true,
+		"safety_buffer":   "1s",
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		resp, err := CBRead(b, s, "tidy-status")
+		return err == nil && resp.Data["state"] == "Finished"
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// The expired certificate is gone from the log along with its index entry
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "short"})
+	require.Empty(t, resp.Data["keys"])
+	days, err := s.List(ctx, issuanceLogExpiryPrefix)
+	require.NoError(t, err)
+	for _, day := range days {
+		serials, err := s.List(ctx, issuanceLogExpiryPrefix+day)
+		require.NoError(t, err)
+		require.NotContains(t, serials, normalizeSerial(short))
+	}
+
+	// while the unrecorded one was attributed to its issuer
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "long"})
+	require.Equal(t, []string{long}, resp.Data["keys"])
+	info := resp.Data["key_info"].(map[string]interface{})[long].(map[string]interface{})
+	require.EqualValues(t, rootIssuer, info["issuer_id"])
+}
+
+func TestCertsSearch_TidyRevoked(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+	ctx := context.Background()
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "ec",
+		"ttl":         "720h",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	_, err = CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "issue/devices", map[string]interface{}{"common_name": "revoked", "ttl": "3s"})
+	requireSuccessNonNilResponse(t, resp, err)
+	serial := resp.Data["serial_number"].(string)
+	resp, err = CBWrite(b, s, "revoke", map[string]interface{}{"serial_number": serial})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Empty(t, resp.Warnings)
+
+	time.Sleep(5 * time.Second)
+	_, err = CBWrite(b, s, "tidy", map[string]interface{}{
+		"tidy_revoked_certs": true,
+		"safety_buffer":      "1s",
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		resp, err := CBRead(b, s, "tidy-status")
+		return err == nil && resp.Data["state"] == "Finished"
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// Tidying the revoked certificate removes its record and index entries
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "revoked"})
+	require.Empty(t, resp.Data["keys"])
+	sc := b.makeStorageContext(ctx, s)
+	entry, err := sc.fetchIssuanceLogEntry(serial)
+	require.NoError(t, err)
+	require.Nil(t, entry)
+	serials, err := s.List(ctx, issuanceLogRolePrefix+"devices/")
+	require.NoError(t, err)
+	require.Empty(t, serials)
+}
+
+func TestCertsSearch_Disabled(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+	ctx := context.Background()
+
+	resp, err := CBRead(b, s, "config/issuance-log")
+	requireSuccessNonNilResponse(t, resp, err)
+	schema.ValidateResponse(t, schema.GetResponseSchema(t, b.Route("config/issuance-log"), logical.ReadOperation), resp, true)
+	require.Equal(t, true, resp.Data["enabled"])
+
+	resp, err = CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "ec",
+		"ttl":         "720h",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	_, err = CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+	})
+	require.NoError(t, err)
+
+	// Nothing is recorded while the issuance log is disabled
+	resp, err = CBWrite(b, s, "config/issuance-log", map[string]interface{}{"enabled": false})
+	require.NoError(t, err)
+	schema.ValidateResponse(t, schema.GetResponseSchema(t, b.Route("config/issuance-log"), logical.UpdateOperation), resp, true)
+	resp, err = CBWrite(b, s, "issue/devices", map[string]interface{}{"common_name": "unlogged", "ttl": "1h"})
+	requireSuccessNonNilResponse(t, resp, err)
+	unlogged := resp.Data["serial_number"].(string)
+	recorded, err := s.List(ctx, issuanceLogSerialPrefix)
+	require.NoError(t, err)
+	require.NotContains(t, recorded, normalizeSerial(unlogged))
+	_, err = CBReq(b, s, logical.ReadOperation, "certs/search", nil)
+	require.Error(t, err)
+
+	// Once enabled again, tidy records the certificate
+	_, err = CBWrite(b, s, "config/issuance-log", map[string]interface{}{"enabled": true})
+	require.NoError(t, err)
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "unlogged"})
+	require.Empty(t, resp.Data["keys"])
+
+	_, err = CBWrite(b, s, "tidy", map[string]interface{}{"tidy_cert_store": true})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		resp, err := CBRead(b, s, "tidy-status")
+		return err == nil && resp.Data["state"] == "Finished"
+	}, 10*time.Second, 100*time.Millisecond)
+
+	resp = searchCerts(t, b, s, map[string]interface{}{"common_name": "unlogged"})
+	require.Equal(t, []string{unlogged}, resp.Data["keys"])
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"regexp"
 	"strconv"
@@ -22,7 +23,7 @@ import (
 const (
 	storageAcmeConfig      = "config/acme"
 	pathConfigAcmeHelpSyn  = "Configuration of ACME Endpoints"
-	pathConfigAcmeHelpDesc = "Here we configure:\n\nenabled=false, whether ACME is enabled, defaults to false meaning that clusters will by default not get ACME support,\nallowed_issuers=\"default\", which issuers are allowed for use with ACME; by default, this will only be the primary (default) issuer,\nallowed_roles=\"*\", which roles are allowed for use with ACME; by default these will be all roles matching our selection criteria,\ndefault_directory_policy=\"\", either \"forbid\", preventing the default directory from being used at all, \"role:<role_name>\" which is the role to be used for non-role-qualified ACME requests; or \"sign-verbatim\", the default meaning ACME issuance will be equivalent to sign-verbatim.,\ndns_resolver=\"\", which specifies a custom DNS resolver to use for all ACME-related DNS lookups"
+	pathConfigAcmeHelpDesc = "Here we configure:\n\nenabled=false, whether ACME is enabled, defaults to false meaning that clusters will by default not get ACME support,\nallowed_issuers=\"default\", which issuers are allowed for use with ACME; by default, this will only be the primary (default) issuer,\nallowed_roles=\"*\", which roles are allowed for use with ACME; by default these will be all roles matching our selection criteria,\ndefault_directory_policy=\"\", either \"forbid\", preventing the default directory from being used at all, \"role:<role_name>\" which is the role to be used for non-role-qualified ACME requests; or \"sign-verbatim\", the default meaning ACME issuance will be equivalent to sign-verbatim.,\ndns_resolver=\"\", which specifies a custom DNS resolver to use for all ACME-related DNS lookups,\ndns_resolvers=\"\", additional DNS resolvers tried in order when earlier ones fail to answer,\ndns_over_tls=false, whether the DNS resolvers are queried over TLS, verified with dns_tls_server_name and dns_tls_ca_bundle,\nchallenge_proxy=\"\", an HTTP proxy used to reach hosts for http-01 and tls-alpn-01 challenge validation"
 	disableAcmeEnvVar      = "VAULT_DISABLE_PUBLIC_ACME"
 	defaultAcmeMaxTTL      = 90 * (24 * time.Hour)
 )
@@ -34,10 +35,25 @@ type acmeConfigEntry struct {
 	AllowRoleExtKeyUsage   bool          `json:"allow_role_ext_key_usage"`
 	DefaultDirectoryPolicy string        `json:"default_directory_policy"`
 	DNSResolver            string        `json:"dns_resolver"`
+	DNSResolvers           []string      `json:"dns_resolvers"`
+	DNSOverTLS             bool          `json:"dns_over_tls"`
+	DNSTLSServerName       string        `json:"dns_tls_server_name"`
+	DNSTLSCABundle         string        `json:"dns_tls_ca_bundle"`
+	ChallengeProxy         string        `json:"challenge_proxy"`
 	EabPolicyName          EabPolicyName `json:"eab_policy_name"`
 	MaxTTL                 time.Duration `json:"max_ttl"`
 }
 
+// dnsResolverAddresses returns the DNS resolvers to query for challenge
+// validation, in order of preference.
+func (c *acmeConfigEntry) dnsResolverAddresses() []string {
+	var addresses []string
+	if c.DNSResolver != "" {
+		addresses = append(addresses, c.DNSResolver)
+	}
+	return append(addresses, c.DNSResolvers...)
+}
+
 var defaultAcmeConfig = acmeConfigEntry{
 	Enabled:                false,
 	AllowedIssuers:         []string{"*"},
@@ -133,6 +149,27 @@ func pathAcmeConfig(b *backend) *framework.Path {
 				Description: `DNS resolver to use for domain resolution on this mount. Defaults to using the default system resolver. Must be in the format <host>:<port>, with both parts mandatory.`,
 				Default:     "",
 			},
+			"dns_resolvers": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Additional DNS resolvers to use for domain resolution on this mount, tried in order after dns_resolver when a resolver fails to answer. Each must be in the format <host>:<port>, with both parts mandatory.`,
+			},
+			"dns_over_tls": {
+				Type:        framework.TypeBool,
+				Description: `Whether to query the DNS resolvers using DNS over TLS (RFC 7858). The resolver ports should then typically be 853.`,
+				Default:     false,
+			},
+			"dns_tls_server_name": {
+				Type:        framework.TypeString,
+				Description: `Name to verify the certificates of the DNS resolvers against when dns_over_tls is enabled. Defaults to the IP address of each resolver.`,
+			},
+			"dns_tls_ca_bundle": {
+				Type:        framework.TypeString,
+				Description: `PEM-encoded CA certificates to verify the DNS resolvers with when dns_over_tls is enabled. Defaults to the system trust store.`,
+			},
+			"challenge_proxy": {
+				Type:        framework.TypeString,
+				Description: `URL of an HTTP proxy to reach hosts through when validating http-01 and tls-alpn-01 challenges, e.g. 'http://proxy.internal:3128'. tls-alpn-01 connections are tunneled with HTTP CONNECT. Any password is redacted when reading the configuration, and writing back the redacted URL keeps the stored password as long as the user, scheme, host and port are unchanged. Defaults to connecting directly.`,
+			},
 			"eab_policy": {
 				Type:        framework.TypeString,
 				Description: `Specify the policy to use for external account binding behaviour, 'not-required', 'new-account-required' or 'always-required'`,
@@ -196,6 +233,11 @@ func genResponseFromAcmeConfig(config *acmeConfigEntry, warnings []string) *logi
 			"default_directory_policy": config.DefaultDirectoryPolicy,
 			"enabled":                  config.Enabled,
 			"dns_resolver":             config.DNSResolver,
+			"dns_resolvers":            config.DNSResolvers,
+			"dns_over_tls":             config.DNSOverTLS,
+			"dns_tls_server_name":      config.DNSTLSServerName,
+			"dns_tls_ca_bundle":        config.DNSTLSCABundle,
+			"challenge_proxy":          redactedChallengeProxy(config.ChallengeProxy),
 			"eab_policy":               config.EabPolicyName,
 			"max_ttl":                  config.MaxTTL.Seconds(),
 		},
@@ -244,15 +286,51 @@ func (b *backend) pathAcmeWrite(ctx context.Context, req *logical.Request, d *fr
 	if dnsResolverRaw, ok := d.GetOk("dns_resolver"); ok {
 		config.DNSResolver = dnsResolverRaw.(string)
 		if config.DNSResolver != "" {
-			addr, _, err := net.SplitHostPort(config.DNSResolver)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse DNS resolver address: %w", err)
+			if err := validateDNSResolverAddress(config.DNSResolver); err != nil {
+				return nil, err
 			}
-			if addr == "" {
-				return nil, fmt.Errorf("failed to parse DNS resolver address: got empty address")
+		}
+	}
+
+	if dnsResolversRaw, ok := d.GetOk("dns_resolvers"); ok {
+		config.DNSResolvers = dnsResolversRaw.([]string)
+		for _, resolver := range config.DNSResolvers {
+			if err := validateDNSResolverAddress(resolver); err != nil {
+				return nil, err
 			}
-			if net.ParseIP(addr) == nil {
-				return nil, fmt.Errorf("failed to parse DNS resolver address: expected IPv4/IPv6 address, likely got hostname")
+		}
+	}
+
+	if dnsOverTLSRaw, ok := d.GetOk("dns_over_tls"); ok {
+		config.DNSOverTLS = dnsOverTLSRaw.(bool)
+	}
+
+	if dnsTLSServerNameRaw, ok := d.GetOk("dns_tls_server_name"); ok {
+		config.DNSTLSServerName = dnsTLSServerNameRaw.(string)
+	}
+
+	if dnsTLSCABundleRaw, ok := d.GetOk("dns_tls_ca_bundle"); ok {
+		config.DNSTLSCABundle = dnsTLSCABundleRaw.(string)
+		if config.DNSTLSCABundle != "" {
+			if _, err := buildDNSTLSConfig(config); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if config.DNSOverTLS && len(config.dnsResolverAddresses()) == 0 {
+		return nil, fmt.Errorf("dns_over_tls requires dns_resolver or dns_resolvers to be set")
+	}
+
+	if challengeProxyRaw, ok := d.GetOk("challenge_proxy"); ok {
+		challengeProxy, err := unredactedChallengeProxy(challengeProxyRaw.(string), config.ChallengeProxy)
+		if err != nil {
+			return nil, err
+		}
+		config.ChallengeProxy = challengeProxy
+		if config.ChallengeProxy != "" {
+			if _, err := parseChallengeProxy(config.ChallengeProxy); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -367,6 +445,83 @@ func (b *backend) pathAcmeWrite(ctx context.Context, req *logical.Request, d *fr
 	return genResponseFromAcmeConfig(config, warnings), nil
 }
 
+func validateDNSResolverAddress(resolver string) error {
+	addr, _, err := net.SplitHostPort(resolver)
+	if err != nil {
+		return fmt.Errorf("failed to parse DNS resolver address: %w", err)
+	}
+	if addr == "" {
+		return fmt.Errorf("failed to parse DNS resolver address: got empty address")
+	}
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("failed to parse DNS resolver address: expected IPv4/IPv6 address, likely got hostname")
+	}
+	return nil
+}
+
+// redactedChallengeProxy hides any password in the proxy URL so that it is not
+// returned when reading the configuration.
+func redactedChallengeProxy(proxy string) string {
+	if proxy == "" {
+		return ""
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return ""
+	}
+	return proxyURL.Redacted()
+}
+
+// unredactedChallengeProxy restores the stored password of the proxy URL if
+// the written one is the redacted form returned when reading the
+// configuration. The password is only restored for the same user of the same
+// proxy, so that it cannot be sent elsewhere by pointing the redacted URL at
+// another host.
+func unredactedChallengeProxy(proxy, stored string) (string, error) {
+	if proxy == "" {
+		return proxy, nil
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.User == nil {
+		return proxy, nil
+	}
+	password, hasPassword := proxyURL.User.Password()
+	if !hasPassword || password != redactedPassword(proxyURL) {
+		return proxy, nil
+	}
+
+	var storedURL *url.URL
+	if stored != "" {
+		storedURL, err = url.Parse(stored)
+		if err != nil {
+			storedURL = nil
+		}
+	}
+	if storedURL == nil || storedURL.User == nil ||
+		!strings.EqualFold(proxyURL.Scheme, storedURL.Scheme) ||
+		!strings.EqualFold(proxyURL.Host, storedURL.Host) ||
+		proxyURL.User.Username() != storedURL.User.Username() {
+		return "", fmt.Errorf("the redacted password of challenge_proxy can only be kept for the same user, scheme, host and port as the stored proxy; provide the password instead")
+	}
+	storedPassword, hasStoredPassword := storedURL.User.Password()
+	if !hasStoredPassword {
+		return "", fmt.Errorf("the stored challenge_proxy has no password to keep; provide the password instead")
+	}
+
+	proxyURL.User = url.UserPassword(proxyURL.User.Username(), storedPassword)
+	return proxyURL.String(), nil
+}
+
+// redactedPassword returns the password placeholder url.URL.Redacted uses.
+func redactedPassword(u *url.URL) string {
+	redacted, err := url.Parse(u.Redacted())
+	if err != nil || redacted.User == nil {
+		return ""
+	}
+	password, _ := redacted.User.Password()
+	return password
+}
+
 func isPublicACMEDisabledByEnv() (bool, error) {
 	disableAcmeRaw, ok := os.LookupEnv(disableAcmeEnvVar)
 	if !ok {
@@ -0,0 +1,359 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/pki/issuing"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	storageEstConfig      = "config/est"
+	estWellKnownLabel     = "est"
+	pathConfigEstHelpSyn  = "Configuration of EST Endpoints"
+	pathConfigEstHelpDesc = "Here we configure:\n\nenabled=false, whether EST is enabled, defaults to false meaning that clusters will by default not get EST support,\ndefault_mount=false, whether this mount serves the default /.well-known/est endpoints of the cluster,\ndefault_path_policy=\"\", either \"sign-verbatim\" or \"role:<role_name>\", the policy used by the non-labelled EST endpoints; if empty, only labelled endpoints may be used,\nlabel_to_path_policy={}, a map of EST labels to \"sign-verbatim\" or \"role:<role_name>\" policies,\nauthenticators={}, the cert and/or userpass auth mounts EST clients authenticate against, as {\"cert\": {\"accessor\": \"...\", \"cert_role\": \"...\"}, \"userpass\": {\"accessor\": \"...\"}}.\n\nThe auth mounts must issue batch tokens, and their accessors must be listed in this mount's delegated_auth_accessors tunable."
+)
+
+var estLabelRegex = regexp.MustCompile(`^` + framework.GenericNameRegex("label") + `$`)
+
+type estConfigEntry struct {
+	Enabled           bool              `json:"enabled"`
+	DefaultMount      bool              `json:"default_mount"`
+	DefaultPathPolicy string            `json:"default_path_policy"`
+	LabelToPathPolicy map[string]string `json:"label_to_path_policy"`
+	Authenticators    estAuthenticators `json:"authenticators"`
+	LastUpdated       time.Time         `json:"last_updated"`
+}
+
+type estAuthenticators struct {
+	Cert     estCertAuthenticator     `json:"cert" mapstructure:"cert"`
+	Userpass estUserpassAuthenticator `json:"userpass" mapstructure:"userpass"`
+}
+
+type estCertAuthenticator struct {
+	Accessor string `json:"accessor" mapstructure:"accessor"`
+	CertRole string `json:"cert_role" mapstructure:"cert_role"`
+}
+
+type estUserpassAuthenticator struct {
+	Accessor string `json:"accessor" mapstructure:"accessor"`
+}
+
+func (a estAuthenticators) toMap() map[string]interface{} {
+	result := map[string]interface{}{}
+	if a.Cert.Accessor != "" {
+		result["cert"] = map[string]interface{}{
+			"accessor":  a.Cert.Accessor,
+			"cert_role": a.Cert.CertRole,
+		}
+	}
+	if a.Userpass.Accessor != "" {
+		result["userpass"] = map[string]interface{}{
+			"accessor": a.Userpass.Accessor,
+		}
+	}
+	return result
+}
+
+var defaultEstConfig = estConfigEntry{
+	Enabled:           false,
+	DefaultMount:      false,
+	DefaultPathPolicy: "",
+	LabelToPathPolicy: map[string]string{},
+}
+
+func getEstConfig(sc *storageContext) (*estConfigEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, storageEstConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping estConfigEntry
+	if entry == nil {
+		mapping = defaultEstConfig
+		mapping.LabelToPathPolicy = map[string]string{}
+		return &mapping, nil
+	}
+
+	if err := entry.DecodeJSON(&mapping); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode EST configuration: %v", err)}
+	}
+
+	if mapping.LabelToPathPolicy == nil {
+		mapping.LabelToPathPolicy = map[string]string{}
+	}
+
+	return &mapping, nil
+}
+
+func (sc *storageContext) setEstConfig(entry *estConfigEntry) error {
+	json, err := logical.StorageEntryJSON(storageEstConfig, entry)
+	if err != nil {
+		return fmt.Errorf("failed creating storage entry: %w", err)
+	}
+
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return fmt.Errorf("failed writing storage entry: %w", err)
+	}
+
+	return nil
+}
+
+func pathEstConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/est",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `whether EST is enabled, defaults to false meaning that clusters will by default not get EST support`,
+				Default:     false,
+			},
+			"default_mount": {
+				Type:        framework.TypeBool,
+				Description: `whether this mount serves the /.well-known/est endpoints of the cluster; only one mount within the cluster may be the default mount`,
+				Default:     false,
+			},
+			"default_path_policy": {
+				Type:        framework.TypeString,
+				Description: `the policy used by the non-labelled EST endpoints, either "sign-verbatim" or "role:<role_name>"; when empty, only labelled endpoints may be used`,
+				Default:     "",
+			},
+			"label_to_path_policy": {
+				Type:        framework.TypeKVPairs,
+				Description: `a map of EST labels, as used within /est/<label>/ paths, to either "sign-verbatim" or "role:<role_name>" policies`,
+			},
+			"authenticators": {
+				Type:        framework.TypeMap,
+				Description: `the auth mounts EST clients authenticate against; the "cert" entry takes an "accessor" and the "cert_role" to log in with, the "userpass" entry takes an "accessor"`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "est-configuration",
+				},
+				Callback: b.pathEstConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathEstConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "configure",
+					OperationSuffix: "est",
+				},
+				// Read more about why these flags are set in backend.go.
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigEstHelpSyn,
+		HelpDescription: pathConfigEstHelpDesc,
+	}
+}
+
+func (b *backend) pathEstConfigRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := getEstConfig(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	return genResponseFromEstConfig(config, nil), nil
+}
+
+func genResponseFromEstConfig(config *estConfigEntry, warnings []string) *logical.Response {
+	response := &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":              config.Enabled,
+			"default_mount":        config.DefaultMount,
+			"default_path_policy":  config.DefaultPathPolicy,
+			"label_to_path_policy": config.LabelToPathPolicy,
+			"authenticators":       config.Authenticators.toMap(),
+		},
+		Warnings: warnings,
+	}
+
+	if !config.LastUpdated.IsZero() {
+		response.Data["last_updated"] = config.LastUpdated.Format(time.RFC3339)
+	}
+
+	return response
+}
+
+func (b *backend) pathEstConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	config, err := getEstConfig(sc)
+	if err != nil {
+		return nil, err
+	}
+	previouslyDefaultMount := config.Enabled && config.DefaultMount
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if defaultMountRaw, ok := d.GetOk("default_mount"); ok {
+		config.DefaultMount = defaultMountRaw.(bool)
+	}
+
+	if defaultPathPolicyRaw, ok := d.GetOk("default_path_policy"); ok {
+		config.DefaultPathPolicy = defaultPathPolicyRaw.(string)
+	}
+
+	if labelToPathPolicyRaw, ok := d.GetOk("label_to_path_policy"); ok {
+		config.LabelToPathPolicy = labelToPathPolicyRaw.(map[string]string)
+	}
+
+	if authenticatorsRaw, ok := d.GetOk("authenticators"); ok {
+		var authenticators estAuthenticators
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			ErrorUnused: true,
+			Result:      &authenticators,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := decoder.Decode(authenticatorsRaw); err != nil {
+			return logical.ErrorResponse("failed parsing authenticators: %v", err), nil
+		}
+		config.Authenticators = authenticators
+	}
+
+	if config.DefaultPathPolicy != "" {
+		if _, err := getEstPathPolicyRole(sc, config.DefaultPathPolicy); err != nil {
+			return logical.ErrorResponse("invalid default_path_policy: %v", err), nil
+		}
+	}
+
+	for label, policy := range config.LabelToPathPolicy {
+		if !estLabelRegex.MatchString(label) {
+			return logical.ErrorResponse("invalid label %q in label_to_path_policy", label), nil
+		}
+		if isEstOperation(label) {
+			return logical.ErrorResponse("label %q in label_to_path_policy conflicts with an EST operation", label), nil
+		}
+		if _, err := getEstPathPolicyRole(sc, policy); err != nil {
+			return logical.ErrorResponse("invalid policy for label %q: %v", label, err), nil
+		}
+	}
+
+	if config.Authenticators.Cert.Accessor != "" && config.Authenticators.Cert.CertRole == "" {
+		return logical.ErrorResponse("the cert authenticator requires a cert_role to log in with"), nil
+	}
+
+	if config.Authenticators.Cert.Accessor == "" && config.Authenticators.Cert.CertRole != "" {
+		return logical.ErrorResponse("the cert authenticator requires an accessor"), nil
+	}
+
+	if config.Enabled && config.Authenticators.Cert.Accessor == "" && config.Authenticators.Userpass.Accessor == "" {
+		return logical.ErrorResponse("at least one authenticator must be configured to enable EST"), nil
+	}
+
+	if config.Enabled && config.DefaultPathPolicy == "" && len(config.LabelToPathPolicy) == 0 {
+		return logical.ErrorResponse("either default_path_policy or label_to_path_policy must be set to enable EST"), nil
+	}
+
+	if config.DefaultMount && config.DefaultPathPolicy == "" {
+		return logical.ErrorResponse("default_mount requires default_path_policy to be set"), nil
+	}
+
+	// Only register the well-known redirect once the configuration is known
+	// to be valid, as it is visible cluster-wide.
+	isDefaultMount := config.Enabled && config.DefaultMount
+	if isDefaultMount && !previouslyDefaultMount {
+		if err := b.registerEstWellKnownRedirect(ctx); err != nil {
+			return logical.ErrorResponse("unable to serve the default EST endpoints from this mount: %v", err), nil
+		}
+	}
+
+	config.LastUpdated = time.Now()
+	if err := sc.setEstConfig(config); err != nil {
+		if isDefaultMount && !previouslyDefaultMount {
+			b.deregisterEstWellKnownRedirect(ctx)
+		}
+		return nil, fmt.Errorf("failed persisting: %w", err)
+	}
+
+	if !isDefaultMount && previouslyDefaultMount {
+		b.deregisterEstWellKnownRedirect(ctx)
+	}
+
+	var warnings []string
+	if config.Enabled {
+		warnings = append(warnings, "the configured authenticator mounts must issue batch tokens and be listed within this mount's delegated_auth_accessors tunable")
+	}
+
+	return genResponseFromEstConfig(config, warnings), nil
+}
+
+// getEstPathPolicyRole returns the role to issue with for the given EST path
+// policy, validating that the role exists when one is referenced.
+func getEstPathPolicyRole(sc *storageContext, policy string) (*issuing.RoleEntry, error) {
+	policyType, roleName, err := getDefaultDirectoryPolicyType(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	switch policyType {
+	case SignVerbatim:
+		return issuing.SignVerbatimRoleWithOpts(issuing.WithNoStore(false)), nil
+	case Role:
+		role, err := sc.GetRole(roleName)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading role %v: %w", roleName, err)
+		}
+		if role == nil {
+			return nil, fmt.Errorf("role %v does not exist", roleName)
+		}
+		return role, nil
+	default:
+		return nil, fmt.Errorf("policy %v is not supported for EST, expected \"sign-verbatim\" or \"role:<role_name>\"", policy)
+	}
+}
+
+func (b *backend) registerEstWellKnownRedirect(ctx context.Context) error {
+	sysView, ok := b.System().(logical.ExtendedSystemView)
+	if !ok {
+		return fmt.Errorf("well-known redirects are not supported by this system view")
+	}
+
+	return sysView.RequestWellKnownRedirect(ctx, estWellKnownLabel, "est")
+}
+
+func (b *backend) deregisterEstWellKnownRedirect(ctx context.Context) {
+	if sysView, ok := b.System().(logical.ExtendedSystemView); ok {
+		sysView.DeregisterWellKnownRedirect(ctx, estWellKnownLabel)
+	}
+}
+
+// initializeEst re-registers the well-known EST redirect on startup, as
+// redirects are not persisted by the core.
+func (b *backend) initializeEst(sc *storageContext) {
+	config, err := getEstConfig(sc)
+	if err != nil {
+		b.Logger().Warn("failed to load EST configuration", "error", err)
+		return
+	}
+
+	if config.Enabled && config.DefaultMount {
+		if err := b.registerEstWellKnownRedirect(sc.Context); err != nil {
+			b.Logger().Warn("failed to serve the default EST endpoints from this mount", "error", err)
+		}
+	}
+}
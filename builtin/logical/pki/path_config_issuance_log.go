@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathConfigIssuanceLog(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuance-log",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `Whether issued certificates are recorded in the issuance log searched through certs/search. Defaults to true.`,
+				Default:     true,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "configure",
+					OperationSuffix: "issuance-log",
+				},
+				Callback: b.pathWriteIssuanceLogConfig,
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: "OK",
+						Fields: map[string]*framework.FieldSchema{
+							"enabled": {
+								Type:        framework.TypeBool,
+								Description: `Whether issued certificates are recorded in the issuance log`,
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "issuance-log-configuration",
+				},
+				Callback: b.pathReadIssuanceLogConfig,
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: "OK",
+						Fields: map[string]*framework.FieldSchema{
+							"enabled": {
+								Type:        framework.TypeBool,
+								Description: `Whether issued certificates are recorded in the issuance log`,
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    pathConfigIssuanceLogHelpSyn,
+		HelpDescription: pathConfigIssuanceLogHelpDesc,
+	}
+}
+
+func (b *backend) pathReadIssuanceLogConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getIssuanceLogConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled": config.Enabled,
+		},
+	}, nil
+}
+
+func (b *backend) pathWriteIssuanceLogConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getIssuanceLogConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *config
+	if value, ok := data.GetOk("enabled"); ok {
+		updated.Enabled = value.(bool)
+	}
+
+	if err := sc.writeIssuanceLogConfig(&updated); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled": updated.Enabled,
+		},
+	}, nil
+}
+
+const pathConfigIssuanceLogHelpSyn = `
+Configure the issuance log searched through certs/search.
+`
+
+const pathConfigIssuanceLogHelpDesc = `
+The issuance log records the subject, subject alternative names, role, issuer
+and validity of every certificate stored by this mount, so that certs/search
+doesn't need to load each certificate. Recording a certificate takes three to
+four storage writes in addition to the certificate itself.
+
+The log is enabled by default. While it is disabled, certificates are not
+recorded, tidy_cert_store does not record certificates missing from the log,
+and certs/search is refused. Records of certificates removed by tidy are still
+deleted. Certificates issued before the issuance log existed, or while it was
+disabled, are recorded by the next tidy_cert_store run once it is enabled.
+
+SCEP renewals check the role a certificate was issued for in the issuance log,
+so they fail for certificates which are not recorded.
+`
@@ -0,0 +1,194 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	storageScepConfig       = "config/scep"
+	pathConfigScepHelpSyn   = "Configuration of SCEP Endpoints"
+	pathConfigScepHelpDesc  = "Here we configure:\n\nenabled=false, whether SCEP is enabled, defaults to false meaning that clusters will by default not get SCEP support,\nallowed_roles=\"*\", which roles may be used through their /roles/:role/scep endpoint; by default, this will be all roles,\ndefault_challenge_ttl=\"1h\", how long challenge passwords generated through /roles/:role/scep/challenge remain valid when no ttl is requested.\n\nSCEP requires the issuer of the role to have an RSA key stored within Vault, as it is used to decrypt the requests."
+	defaultScepChallengeTTL = time.Hour
+)
+
+type scepConfigEntry struct {
+	Enabled             bool          `json:"enabled"`
+	AllowedRoles        []string      `json:"allowed_roles"`
+	DefaultChallengeTTL time.Duration `json:"default_challenge_ttl"`
+	LastUpdated         time.Time     `json:"last_updated"`
+}
+
+func (c *scepConfigEntry) isRoleAllowed(name string) bool {
+	return slices.Contains(c.AllowedRoles, "*") || slices.Contains(c.AllowedRoles, name)
+}
+
+var defaultScepConfig = scepConfigEntry{
+	Enabled:             false,
+	AllowedRoles:        []string{"*"},
+	DefaultChallengeTTL: defaultScepChallengeTTL,
+}
+
+func getScepConfig(sc *storageContext) (*scepConfigEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, storageScepConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping scepConfigEntry
+	if entry == nil {
+		mapping = defaultScepConfig
+		return &mapping, nil
+	}
+
+	if err := entry.DecodeJSON(&mapping); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode SCEP configuration: %v", err)}
+	}
+
+	return &mapping, nil
+}
+
+func (sc *storageContext) setScepConfig(entry *scepConfigEntry) error {
+	json, err := logical.StorageEntryJSON(storageScepConfig, entry)
+	if err != nil {
+		return fmt.Errorf("failed creating storage entry: %w", err)
+	}
+
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return fmt.Errorf("failed writing storage entry: %w", err)
+	}
+
+	return nil
+}
+
+func pathScepConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/scep",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `whether SCEP is enabled, defaults to false meaning that clusters will by default not get SCEP support`,
+				Default:     false,
+			},
+			"allowed_roles": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `which roles may be used through their /roles/:role/scep endpoint; by default via '*', these will be all roles`,
+				Default:     []string{"*"},
+			},
+			"default_challenge_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: `how long challenge passwords remain valid when no ttl is requested while generating them`,
+				Default:     defaultScepChallengeTTL.Seconds(),
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "scep-configuration",
+				},
+				Callback: b.pathScepConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathScepConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "configure",
+					OperationSuffix: "scep",
+				},
+				// Read more about why these flags are set in backend.go.
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigScepHelpSyn,
+		HelpDescription: pathConfigScepHelpDesc,
+	}
+}
+
+func (b *backend) pathScepConfigRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := getScepConfig(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	return genResponseFromScepConfig(config), nil
+}
+
+func genResponseFromScepConfig(config *scepConfigEntry) *logical.Response {
+	response := &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":               config.Enabled,
+			"allowed_roles":         config.AllowedRoles,
+			"default_challenge_ttl": int64(config.DefaultChallengeTTL.Seconds()),
+		},
+	}
+
+	if !config.LastUpdated.IsZero() {
+		response.Data["last_updated"] = config.LastUpdated.Format(time.RFC3339)
+	}
+
+	return response
+}
+
+func (b *backend) pathScepConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	config, err := getScepConfig(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if allowedRolesRaw, ok := d.GetOk("allowed_roles"); ok {
+		config.AllowedRoles = allowedRolesRaw.([]string)
+		if len(config.AllowedRoles) == 0 {
+			return logical.ErrorResponse("allowed_roles must take a non-zero length value; specify '*' as the value to allow anything or specify enabled=false to disable SCEP entirely"), nil
+		}
+	}
+
+	if ttlRaw, ok := d.GetOk("default_challenge_ttl"); ok {
+		ttl := time.Duration(ttlRaw.(int)) * time.Second
+		if ttl <= 0 {
+			return logical.ErrorResponse("default_challenge_ttl must be greater than 0"), nil
+		}
+		config.DefaultChallengeTTL = ttl
+	}
+
+	if !slices.Contains(config.AllowedRoles, "*") {
+		for index, name := range config.AllowedRoles {
+			role, err := sc.GetRole(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed loading role %v: %w", name, err)
+			}
+			if role == nil {
+				return logical.ErrorResponse("allowed_roles[%d]: role %v does not exist", index, name), nil
+			}
+		}
+	}
+
+	config.LastUpdated = time.Now()
+	if err := sc.setScepConfig(config); err != nil {
+		return nil, fmt.Errorf("failed persisting: %w", err)
+	}
+
+	return genResponseFromScepConfig(config), nil
+}
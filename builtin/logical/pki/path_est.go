@@ -0,0 +1,521 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/pki/issuing"
+	"github.com/hashicorp/vault/helper/pkcs7"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	estCertsOnlyContentType = "application/pkcs7-mime; smime-type=certs-only"
+	estCsrAttrsContentType  = "application/csrattrs"
+	estErrorContentType     = "text/plain"
+	estBasicAuthChallenge   = `Basic realm="estrealm"`
+	estMaxRequestSize       = 64 * 1024
+
+	estOpCACerts        = "cacerts"
+	estOpSimpleEnroll   = "simpleenroll"
+	estOpSimpleReEnroll = "simplereenroll"
+	estOpCsrAttrs       = "csrattrs"
+)
+
+var (
+	estOperations = []string{estOpCACerts, estOpSimpleEnroll, estOpSimpleReEnroll, estOpCsrAttrs}
+
+	oidRSAEncryption     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECPublicKey       = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidEd25519           = asn1.ObjectIdentifier{1, 3, 101, 112}
+	oidNamedCurveP224    = asn1.ObjectIdentifier{1, 3, 132, 0, 33}
+	oidNamedCurveP256    = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidNamedCurveP384    = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidNamedCurveP521    = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+	oidExtSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+)
+
+func isEstOperation(name string) bool {
+	return slices.Contains(estOperations, name)
+}
+
+// setupEstPaths registers the EST (RFC 7030) endpoints, both the default ones
+// under est/ and the labelled ones under est/<label>/.
+func setupEstPaths(b *backend) {
+	for _, prefix := range []struct {
+		pattern      string
+		unauthPrefix string
+	}{
+		{"est", "est"},
+		{"est/" + framework.GenericNameRegex("label"), "est/+"},
+	} {
+		b.Backend.Paths = append(b.Backend.Paths, pathEstCACerts(b, prefix.pattern))
+		b.Backend.Paths = append(b.Backend.Paths, pathEstCsrAttrs(b, prefix.pattern))
+		b.Backend.Paths = append(b.Backend.Paths, pathEstSimpleEnroll(b, prefix.pattern, estOpSimpleEnroll))
+		b.Backend.Paths = append(b.Backend.Paths, pathEstSimpleEnroll(b, prefix.pattern, estOpSimpleReEnroll))
+
+		// The EST protocol carries its own authentication, which we delegate to
+		// the configured auth mounts, so all endpoints are reachable without a
+		// Vault token.
+		for _, op := range estOperations {
+			b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, prefix.unauthPrefix+"/"+op)
+		}
+
+		// Enrollment requests carry a base64 encoded PKCS#10 body, not JSON.
+		b.PathsSpecial.Binary = append(b.PathsSpecial.Binary, prefix.unauthPrefix+"/"+estOpSimpleEnroll)
+		b.PathsSpecial.Binary = append(b.PathsSpecial.Binary, prefix.unauthPrefix+"/"+estOpSimpleReEnroll)
+	}
+}
+
+func addFieldsForEst(pattern string, fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	if strings.Contains(pattern, "(?P<label>") {
+		fields["label"] = &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `The EST label, as configured within label_to_path_policy`,
+			Required:    true,
+		}
+	}
+	return fields
+}
+
+func pathEstCACerts(b *backend, pattern string) *framework.Path {
+	return &framework.Path{
+		Pattern: pattern + "/" + estOpCACerts,
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationSuffix: "est-cacerts",
+		},
+		Fields: addFieldsForEst(pattern, map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathEstCACertsRead,
+			},
+		},
+
+		HelpSynopsis:    pathEstCACertsHelpSyn,
+		HelpDescription: pathEstCACertsHelpDesc,
+	}
+}
+
+func pathEstCsrAttrs(b *backend, pattern string) *framework.Path {
+	return &framework.Path{
+		Pattern: pattern + "/" + estOpCsrAttrs,
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationSuffix: "est-csrattrs",
+		},
+		Fields: addFieldsForEst(pattern, map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathEstCsrAttrsRead,
+			},
+		},
+
+		HelpSynopsis:    pathEstCsrAttrsHelpSyn,
+		HelpDescription: pathEstCsrAttrsHelpDesc,
+	}
+}
+
+func pathEstSimpleEnroll(b *backend, pattern string, op string) *framework.Path {
+	return &framework.Path{
+		Pattern: pattern + "/" + op,
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationSuffix: "est-" + op,
+		},
+		Fields: addFieldsForEst(pattern, map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathEstSimpleEnrollWrite,
+				// The request body can only be consumed once, so forward before
+				// reading it rather than relying on a later ErrReadOnly.
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathEstSimpleEnrollHelpSyn,
+		HelpDescription: pathEstSimpleEnrollHelpDesc,
+	}
+}
+
+func (b *backend) pathEstCACertsRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	_, role, resp := b.getEstConfigAndRole(sc, data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	signingBundle, err := sc.fetchCAInfo(estIssuerRef(role), issuing.IssuanceUsage)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading issuer for EST: %w", err)
+	}
+
+	var chain []byte
+	for _, block := range signingBundle.GetFullChain() {
+		chain = append(chain, block.Bytes...)
+	}
+
+	return estCertsOnlyResponse(chain)
+}
+
+func (b *backend) pathEstCsrAttrsRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	_, role, resp := b.getEstConfigAndRole(sc, data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	attrs, err := estCsrAttrsForRole(role)
+	if err != nil {
+		return nil, err
+	}
+
+	// Per RFC 7030 Section 4.5.2, a 204 signals that the server has no
+	// particular requirements for the CSR.
+	if len(attrs) == 0 {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPContentType: estCsrAttrsContentType,
+				logical.HTTPStatusCode:  http.StatusNoContent,
+				logical.HTTPRawBody:     []byte{},
+			},
+		}, nil
+	}
+
+	der, err := asn1.Marshal(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding CSR attributes: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: estCsrAttrsContentType,
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     []byte(base64.StdEncoding.EncodeToString(der)),
+		},
+	}, nil
+}
+
+func (b *backend) pathEstSimpleEnrollWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, role, resp := b.getEstConfigAndRole(sc, data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	if resp, err := estAuthenticate(req, config); resp != nil || err != nil {
+		return resp, err
+	}
+
+	csr, err := estReadCsr(req)
+	if err != nil {
+		return estErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	signingBundle, issuerId, err := sc.fetchCAInfoWithIssuer(estIssuerRef(role), issuing.IssuanceUsage)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading issuer for EST: %w", err)
+	}
+
+	if strings.HasSuffix(req.Path, "/"+estOpSimpleReEnroll) {
+		if err := estValidateReEnroll(sc, req, csr, signingBundle); err != nil {
+			return estErrorResponse(http.StatusForbidden, err.Error()), nil
+		}
+	}
+
+	parsedBundle, err := b.signAndStoreCsr(sc, req, role, signingBundle, issuerId, csr)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return estErrorResponse(http.StatusBadRequest, err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
+	b.Logger().Debug("issued certificate over EST", "serial", serialFromCert(parsedBundle.Certificate),
+		"issuer", issuerId, "role", role.Name)
+
+	return estCertsOnlyResponse(parsedBundle.CertificateBytes)
+}
+
+// getEstConfigAndRole resolves the role used by the EST request, depending on
+// whether the request uses a labelled path. A non-nil response is returned
+// when EST is not available for the request.
+func (b *backend) getEstConfigAndRole(sc *storageContext, data *framework.FieldData) (*estConfigEntry, *issuing.RoleEntry, *logical.Response) {
+	config, err := getEstConfig(sc)
+	if err != nil {
+		b.Logger().Error("failed loading EST configuration", "error", err)
+		return nil, nil, estErrorResponse(http.StatusInternalServerError, "failed loading EST configuration")
+	}
+
+	if !config.Enabled {
+		return nil, nil, estErrorResponse(http.StatusNotFound, "EST is not enabled on this mount")
+	}
+
+	policy := config.DefaultPathPolicy
+	if labelRaw, ok := data.GetOk("label"); ok {
+		label := labelRaw.(string)
+		policy, ok = config.LabelToPathPolicy[label]
+		if !ok {
+			return nil, nil, estErrorResponse(http.StatusNotFound, fmt.Sprintf("unknown EST label %q", label))
+		}
+	}
+	if policy == "" {
+		return nil, nil, estErrorResponse(http.StatusNotFound, "EST requests must use a configured label on this mount")
+	}
+
+	role, err := getEstPathPolicyRole(sc, policy)
+	if err != nil {
+		b.Logger().Error("failed loading role for EST", "policy", policy, "error", err)
+		return nil, nil, estErrorResponse(http.StatusInternalServerError, "failed loading the EST role")
+	}
+
+	return config, role, nil
+}
+
+func estIssuerRef(role *issuing.RoleEntry) string {
+	if role.Issuer == "" {
+		return defaultRef
+	}
+	return role.Issuer
+}
+
+// estAuthenticate ensures the request was authenticated by one of the
+// configured auth mounts. The EST credentials (a TLS client certificate or
+// HTTP Basic auth) are exchanged for a batch token through delegated
+// authentication, after which the core reissues the request with that token,
+// subject to its policies.
+func estAuthenticate(req *logical.Request, config *estConfigEntry) (*logical.Response, error) {
+	if req.ClientTokenSource == logical.ClientTokenFromInternalAuth && req.ClientToken != "" {
+		return nil, nil
+	}
+
+	errHandler := func(ctx context.Context, _, _ *logical.Request, _ *logical.Response, _ error) (*logical.Response, error) {
+		return estUnauthorizedResponse(config), nil
+	}
+
+	certAuth := config.Authenticators.Cert
+	if certAuth.Accessor != "" && req.Connection != nil && req.Connection.ConnState != nil &&
+		len(req.Connection.ConnState.PeerCertificates) > 0 {
+		return nil, logical.NewDelegatedAuthenticationRequest(certAuth.Accessor, "login",
+			map[string]interface{}{"name": certAuth.CertRole}, errHandler)
+	}
+
+	userpassAuth := config.Authenticators.Userpass
+	if userpassAuth.Accessor != "" && req.HTTPRequest != nil {
+		if username, password, ok := req.HTTPRequest.BasicAuth(); ok && username != "" {
+			return nil, logical.NewDelegatedAuthenticationRequest(userpassAuth.Accessor, "login/"+username,
+				map[string]interface{}{"password": password}, errHandler)
+		}
+	}
+
+	return estUnauthorizedResponse(config), nil
+}
+
+func estReadCsr(req *logical.Request) (*x509.CertificateRequest, error) {
+	if req.HTTPRequest == nil || req.HTTPRequest.Body == nil {
+		return nil, errors.New("no data in request body")
+	}
+	defer req.HTTPRequest.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(req.HTTPRequest.Body, estMaxRequestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading request body: %w", err)
+	}
+	if len(body) >= estMaxRequestSize {
+		return nil, errors.New("request is too large")
+	}
+
+	// The body is base64 encoded DER, possibly broken into multiple lines.
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(body)), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed base64 decoding the certificate request: %w", err)
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing the certificate request: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid certificate request signature: %w", err)
+	}
+
+	for _, ext := range csr.Extensions {
+		if ext.Id.Equal(certutil.ExtensionBasicConstraintsOID) {
+			isCa, _, err := certutil.ParseBasicConstraintExtension(ext)
+			if err != nil || isCa {
+				return nil, errors.New("refusing to accept a certificate request for a CA certificate")
+			}
+		}
+	}
+
+	return csr, nil
+}
+
+// estValidateReEnroll enforces RFC 7030 Section 4.2.2: the client must
+// present the certificate being renewed, which has to be an unexpired and
+// unrevoked certificate of the issuer, and the request must keep its subject
+// and subject alternative names.
+func estValidateReEnroll(sc *storageContext, req *logical.Request, csr *x509.CertificateRequest, signingBundle *certutil.CAInfoBundle) error {
+	if req.Connection == nil || req.Connection.ConnState == nil || len(req.Connection.ConnState.PeerCertificates) == 0 {
+		return errors.New("re-enrollment requires the current certificate to be presented as the TLS client certificate")
+	}
+	current := req.Connection.ConnState.PeerCertificates[0]
+
+	if err := current.CheckSignatureFrom(signingBundle.Certificate); err != nil {
+		return errors.New("the TLS client certificate was not issued by this EST server")
+	}
+
+	now := time.Now()
+	if now.Before(current.NotBefore) || now.After(current.NotAfter) {
+		return errors.New("the TLS client certificate is not within its validity period")
+	}
+
+	revoked, err := fetchCertBySerial(sc, revokedPath, serialFromCert(current))
+	if err != nil {
+		return fmt.Errorf("failed checking revocation status: %w", err)
+	}
+	if revoked != nil {
+		return errors.New("the TLS client certificate has been revoked")
+	}
+
+	if !bytes.Equal(csr.RawSubject, current.RawSubject) {
+		return errors.New("the certificate request subject does not match the current certificate")
+	}
+	if !bytes.Equal(estSubjectAltNames(csr.Extensions), estSubjectAltNames(current.Extensions)) {
+		return errors.New("the certificate request subject alternative names do not match the current certificate")
+	}
+
+	return nil
+}
+
+func estSubjectAltNames(exts []pkix.Extension) []byte {
+	for _, ext := range exts {
+		if ext.Id.Equal(oidExtSubjectAltName) {
+			return ext.Value
+		}
+	}
+	return nil
+}
+
+// estCsrAttrsForRole builds the CsrAttrs (RFC 7030 Section 4.5.2) describing
+// the key type the role expects.
+func estCsrAttrsForRole(role *issuing.RoleEntry) ([]asn1.RawValue, error) {
+	type attribute struct {
+		Type   asn1.ObjectIdentifier
+		Values []asn1.ObjectIdentifier `asn1:"set"`
+	}
+
+	var encoded []byte
+	var err error
+	switch role.KeyType {
+	case "rsa":
+		encoded, err = asn1.Marshal(oidRSAEncryption)
+	case "ed25519":
+		encoded, err = asn1.Marshal(oidEd25519)
+	case "ec":
+		var curve asn1.ObjectIdentifier
+		switch role.KeyBits {
+		case 224:
+			curve = oidNamedCurveP224
+		case 256:
+			curve = oidNamedCurveP256
+		case 384:
+			curve = oidNamedCurveP384
+		case 521:
+			curve = oidNamedCurveP521
+		default:
+			return nil, fmt.Errorf("unsupported EC key bits: %d", role.KeyBits)
+		}
+		encoded, err = asn1.Marshal(attribute{Type: oidECPublicKey, Values: []asn1.ObjectIdentifier{curve}})
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []asn1.RawValue{{FullBytes: encoded}}, nil
+}
+
+func estCertsOnlyResponse(certs []byte) (*logical.Response, error) {
+	p7, err := pkcs7.DegenerateCertificate(certs)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding certificates: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: estCertsOnlyContentType,
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     []byte(base64.StdEncoding.EncodeToString(p7)),
+		},
+	}, nil
+}
+
+func estErrorResponse(status int, msg string) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: estErrorContentType,
+			logical.HTTPStatusCode:  status,
+			logical.HTTPRawBody:     []byte(msg + "\n"),
+		},
+	}
+}
+
+func estUnauthorizedResponse(config *estConfigEntry) *logical.Response {
+	resp := estErrorResponse(http.StatusUnauthorized, "authentication required")
+	if config.Authenticators.Userpass.Accessor != "" {
+		resp.Data[logical.HTTPWWWAuthenticateHeader] = estBasicAuthChallenge
+	}
+	return resp
+}
+
+const pathEstCACertsHelpSyn = `Fetch the EST CA certificates.`
+
+const pathEstCACertsHelpDesc = `
+Returns the certificate chain of the issuer used by the EST endpoint as a
+base64 encoded PKCS#7 certs-only message, as described by RFC 7030 Section 4.1.
+`
+
+const pathEstCsrAttrsHelpSyn = `Fetch the EST CSR attributes.`
+
+const pathEstCsrAttrsHelpDesc = `
+Returns the attributes EST clients should include in their certificate
+requests, as described by RFC 7030 Section 4.5. Only the key type of the
+backing role is advertised; sign-verbatim policies return no attributes.
+`
+
+const pathEstSimpleEnrollHelpSyn = `Enroll or re-enroll for a certificate over EST.`
+
+const pathEstSimpleEnrollHelpDesc = `
+Signs the base64 encoded PKCS#10 certificate request within the body, as
+described by RFC 7030 Section 4.2, and returns the certificate as a base64
+encoded PKCS#7 certs-only message.
+
+Clients authenticate with a TLS client certificate against the configured cert
+auth mount, or with HTTP Basic auth against the configured userpass auth mount.
+The resulting token must be granted update access to this path by its policies.
+
+Re-enrollment requires the current certificate to be presented as the TLS
+client certificate, and the request must keep its subject and subject
+alternative names.
+`
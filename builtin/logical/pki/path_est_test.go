@@ -0,0 +1,434 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/helper/pkcs7"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/stretchr/testify/require"
+)
+
+func estTestCsr(t *testing.T, cn string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: cn},
+		DNSNames: []string{cn},
+	}, key)
+	require.NoError(t, err)
+
+	return []byte(base64.StdEncoding.EncodeToString(csr))
+}
+
+func estParseCertsOnly(t *testing.T, resp *logical.Response) []*x509.Certificate {
+	t.Helper()
+
+	require.Equal(t, http.StatusOK, resp.Data[logical.HTTPStatusCode], "unexpected response: %s", resp.Data[logical.HTTPRawBody])
+	require.Equal(t, estCertsOnlyContentType, resp.Data[logical.HTTPContentType])
+	der, err := base64.StdEncoding.DecodeString(string(resp.Data[logical.HTTPRawBody].([]byte)))
+	require.NoError(t, err)
+	p7, err := pkcs7.Parse(der)
+	require.NoError(t, err)
+	return p7.Certificates
+}
+
+func TestEst_Config(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	_, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "ec",
+	})
+	require.NoError(t, err)
+
+	resp, err := CBRead(b, s, "config/est")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, false, resp.Data["enabled"])
+
+	// EST can not be enabled without an authenticator
+	_, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":             true,
+		"default_path_policy": "sign-verbatim",
+	})
+	require.Error(t, err)
+
+	// Policies must reference existing roles
+	_, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":             true,
+		"default_path_policy": "role:missing",
+		"authenticators": map[string]interface{}{
+			"userpass": map[string]interface{}{"accessor": "auth_userpass_1234"},
+		},
+	})
+	require.Error(t, err)
+
+	// Labels can not shadow EST operations
+	_, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":              true,
+		"label_to_path_policy": map[string]interface{}{"cacerts": "sign-verbatim"},
+		"authenticators": map[string]interface{}{
+			"userpass": map[string]interface{}{"accessor": "auth_userpass_1234"},
+		},
+	})
+	require.Error(t, err)
+
+	// The cert authenticator needs a role to log in with
+	_, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":             true,
+		"default_path_policy": "sign-verbatim",
+		"authenticators": map[string]interface{}{
+			"cert": map[string]interface{}{"accessor": "auth_cert_1234"},
+		},
+	})
+	require.Error(t, err)
+
+	_, err = CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+		"key_bits":       384,
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":              true,
+		"default_path_policy":  "sign-verbatim",
+		"label_to_path_policy": map[string]interface{}{"devices": "role:devices"},
+		"authenticators": map[string]interface{}{
+			"cert": map[string]interface{}{"accessor": "auth_cert_1234", "cert_role": "est"},
+		},
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	resp, err = CBRead(b, s, "config/est")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, true, resp.Data["enabled"])
+	require.Equal(t, "sign-verbatim", resp.Data["default_path_policy"])
+	require.Equal(t, map[string]string{"devices": "role:devices"}, resp.Data["label_to_path_policy"])
+	require.Equal(t, map[string]interface{}{
+		"cert": map[string]interface{}{"accessor": "auth_cert_1234", "cert_role": "est"},
+	}, resp.Data["authenticators"])
+	require.NotEmpty(t, resp.Data["last_updated"])
+}
+
+func TestEst_Operations(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "ec",
+		"ttl":         "720h",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	rootCert := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "ec",
+		"key_bits":         256,
+		"ttl":              "1h",
+	})
+	require.NoError(t, err)
+
+	// Endpoints are unavailable until EST is enabled
+	resp, err = CBRead(b, s, "est/cacerts")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.Data[logical.HTTPStatusCode])
+
+	_, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":              true,
+		"label_to_path_policy": map[string]interface{}{"devices": "role:devices"},
+		"authenticators": map[string]interface{}{
+			"userpass": map[string]interface{}{"accessor": "auth_userpass_1234"},
+		},
+	})
+	require.NoError(t, err)
+
+	// Without a default path policy only labels may be used
+	resp, err = CBRead(b, s, "est/cacerts")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.Data[logical.HTTPStatusCode])
+
+	resp, err = CBRead(b, s, "est/devices/cacerts")
+	require.NoError(t, err)
+	certs := estParseCertsOnly(t, resp)
+	require.Len(t, certs, 1)
+	require.True(t, certs[0].Equal(rootCert))
+
+	resp, err = CBRead(b, s, "est/devices/csrattrs")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Data[logical.HTTPStatusCode])
+	der, err := base64.StdEncoding.DecodeString(string(resp.Data[logical.HTTPRawBody].([]byte)))
+	require.NoError(t, err)
+	var attrs []asn1.RawValue
+	_, err = asn1.Unmarshal(der, &attrs)
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+	var attr struct {
+		Type   asn1.ObjectIdentifier
+		Values []asn1.ObjectIdentifier `asn1:"set"`
+	}
+	_, err = asn1.Unmarshal(attrs[0].FullBytes, &attr)
+	require.NoError(t, err)
+	require.True(t, attr.Type.Equal(oidECPublicKey))
+	require.True(t, attr.Values[0].Equal(oidNamedCurveP256))
+
+	enrollReq := func(body []byte, user, password string) *logical.Request {
+		httpReq, err := http.NewRequest(http.MethodPost, "/v1/pki/est/devices/simpleenroll", bytes.NewReader(body))
+		require.NoError(t, err)
+		if user != "" {
+			httpReq.SetBasicAuth(user, password)
+		}
+		return &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "est/devices/simpleenroll",
+			Storage:     s,
+			HTTPRequest: httpReq,
+		}
+	}
+
+	// Unauthenticated requests are challenged
+	resp, err = b.HandleRequest(context.Background(), enrollReq(estTestCsr(t, "dev1.example.com"), "", ""))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.Data[logical.HTTPStatusCode])
+	require.Equal(t, estBasicAuthChallenge, resp.Data[logical.HTTPWWWAuthenticateHeader])
+
+	// Basic credentials are delegated to the userpass mount
+	_, err = b.HandleRequest(context.Background(), enrollReq(estTestCsr(t, "dev1.example.com"), "dev1", "secret"))
+	var delegatedErr *logical.RequestDelegatedAuthError
+	require.True(t, errors.As(err, &delegatedErr), "expected delegated auth request, got: %v", err)
+	require.Equal(t, "auth_userpass_1234", delegatedErr.MountAccessor())
+	require.Equal(t, "login/dev1", delegatedErr.Path())
+	require.Equal(t, map[string]interface{}{"password": "secret"}, delegatedErr.Data())
+
+	// Once authenticated, the CSR is signed according to the role
+	req := enrollReq(estTestCsr(t, "dev1.example.com"), "", "")
+	req.ClientToken = "batch-token"
+	req.ClientTokenSource = logical.ClientTokenFromInternalAuth
+	resp, err = b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	certs = estParseCertsOnly(t, resp)
+	require.Len(t, certs, 1)
+	require.Equal(t, "dev1.example.com", certs[0].Subject.CommonName)
+	requireSignedBy(t, certs[0], rootCert)
+
+	resp, err = CBRead(b, s, "cert/"+serialFromCert(certs[0]))
+	requireSuccessNonNilResponse(t, resp, err)
+
+	req = enrollReq(estTestCsr(t, "dev1.evil.com"), "", "")
+	req.ClientToken = "batch-token"
+	req.ClientTokenSource = logical.ClientTokenFromInternalAuth
+	resp, err = b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Data[logical.HTTPStatusCode])
+
+	// Re-enrollment requires the current certificate to be presented
+	req = enrollReq(estTestCsr(t, "dev1.example.com"), "", "")
+	req.Path = "est/devices/simplereenroll"
+	req.ClientToken = "batch-token"
+	req.ClientTokenSource = logical.ClientTokenFromInternalAuth
+	resp, err = b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.Data[logical.HTTPStatusCode])
+
+	req = enrollReq(estTestCsr(t, "dev2.example.com"), "", "")
+	req.Path = "est/devices/simplereenroll"
+	req.ClientToken = "batch-token"
+	req.ClientTokenSource = logical.ClientTokenFromInternalAuth
+	req.Connection = &logical.Connection{ConnState: &tls.ConnectionState{PeerCertificates: certs}}
+	resp, err = b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.Data[logical.HTTPStatusCode])
+
+	req = enrollReq(estTestCsr(t, "dev1.example.com"), "", "")
+	req.Path = "est/devices/simplereenroll"
+	req.ClientToken = "batch-token"
+	req.ClientTokenSource = logical.ClientTokenFromInternalAuth
+	req.Connection = &logical.Connection{ConnState: &tls.ConnectionState{PeerCertificates: certs}}
+	resp, err = b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	renewed := estParseCertsOnly(t, resp)
+	require.Len(t, renewed, 1)
+	require.Equal(t, "dev1.example.com", renewed[0].Subject.CommonName)
+	require.NotEqual(t, serialFromCert(certs[0]), serialFromCert(renewed[0]))
+}
+
+// TestEst_ReEnrollValidity verifies that only certificates within their
+// validity period can be re-enrolled.
+func TestEst_ReEnrollValidity(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+	sc := b.makeStorageContext(context.Background(), s)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root.com"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(48 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDer)
+	require.NoError(t, err)
+	signingBundle := &certutil.CAInfoBundle{ParsedCertBundle: certutil.ParsedCertBundle{Certificate: caCert}}
+
+	csrDer, err := base64.StdEncoding.DecodeString(string(estTestCsr(t, "dev1.example.com")))
+	require.NoError(t, err)
+	csr, err := x509.ParseCertificateRequest(csrDer)
+	require.NoError(t, err)
+
+	reEnrollReq := func(notBefore, notAfter time.Time) *logical.Request {
+		cert, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(notAfter.UnixNano()),
+			Subject:      pkix.Name{CommonName: "dev1.example.com"},
+			DNSNames:     []string{"dev1.example.com"},
+			NotBefore:    notBefore,
+			NotAfter:     notAfter,
+		}, caCert, csr.PublicKey, caKey)
+		require.NoError(t, err)
+		parsed, err := x509.ParseCertificate(cert)
+		require.NoError(t, err)
+		return &logical.Request{
+			Connection: &logical.Connection{ConnState: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{parsed}}},
+		}
+	}
+
+	now := time.Now()
+	require.NoError(t, estValidateReEnroll(sc, reEnrollReq(now.Add(-time.Hour), now.Add(time.Hour)), csr, signingBundle))
+	require.ErrorContains(t, estValidateReEnroll(sc, reEnrollReq(now.Add(-2*time.Hour), now.Add(-time.Hour)), csr, signingBundle), "validity period")
+	require.ErrorContains(t, estValidateReEnroll(sc, reEnrollReq(now.Add(time.Hour), now.Add(2*time.Hour)), csr, signingBundle), "validity period")
+}
+
+func TestEst_DelegatedUserpassAuth(t *testing.T) {
+	t.Parallel()
+
+	coreConfig := &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"userpass": userpass.Factory,
+		},
+		LogicalBackends: map[string]logical.Factory{
+			"pki": Factory,
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	client := cluster.Cores[0].Client
+
+	mountPKIEndpoint(t, client, "pki")
+	_, err := client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "ec",
+	})
+	require.NoError(t, err)
+	_, err = client.Logical().Write("pki/roles/devices", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "ec",
+		"ttl":              "1h",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Sys().EnableAuth("userpass", "userpass", ""))
+	auths, err := client.Sys().ListAuth()
+	require.NoError(t, err)
+	accessor := auths["userpass/"].Accessor
+
+	require.NoError(t, client.Sys().PutPolicy("est", `
+path "pki/est/*" {
+  capabilities = ["update"]
+}`))
+	_, err = client.Logical().Write("auth/userpass/users/device", map[string]interface{}{
+		"password":       "secret",
+		"token_policies": "est",
+		"token_type":     "batch",
+	})
+	require.NoError(t, err)
+	_, err = client.Logical().Write("auth/userpass/users/other", map[string]interface{}{
+		"password":   "secret",
+		"token_type": "batch",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Sys().TuneMount("pki", api.MountConfigInput{
+		DelegatedAuthAccessors: []string{accessor},
+	}))
+	_, err = client.Logical().Write("pki/config/est", map[string]interface{}{
+		"enabled":             true,
+		"default_path_policy": "role:devices",
+		"default_mount":       true,
+		"authenticators": map[string]interface{}{
+			"userpass": map[string]interface{}{"accessor": accessor},
+		},
+	})
+	require.NoError(t, err)
+
+	httpClient := cleanhttp.DefaultClient()
+	httpClient.Transport.(*http.Transport).TLSClientConfig = cluster.Cores[0].TLSConfig()
+	enroll := func(path, user, password string) (int, []byte) {
+		req, err := http.NewRequest(http.MethodPost, client.Address()+path, bytes.NewReader(estTestCsr(t, "dev1.example.com")))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/pkcs10")
+		req.SetBasicAuth(user, password)
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, body
+	}
+
+	status, _ := enroll("/v1/pki/est/simpleenroll", "device", "wrong")
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// Authenticated tokens still need to be authorized by their policies
+	status, _ = enroll("/v1/pki/est/simpleenroll", "other", "secret")
+	require.Equal(t, http.StatusForbidden, status)
+
+	for _, path := range []string{"/v1/pki/est/simpleenroll", "/.well-known/est/simpleenroll"} {
+		status, body := enroll(path, "device", "secret")
+		require.Equal(t, http.StatusOK, status, "%s: %s", path, body)
+		der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(body)))
+		require.NoError(t, err)
+		p7, err := pkcs7.Parse(der)
+		require.NoError(t, err)
+		require.Len(t, p7.Certificates, 1)
+		require.Equal(t, "dev1.example.com", p7.Certificates[0].Subject.CommonName)
+	}
+}
@@ -0,0 +1,700 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/hashicorp/vault/builtin/logical/pki/issuing"
+	"github.com/hashicorp/vault/helper/pkcs7"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	scepChallengePrefix = "scep/challenge/"
+	scepMaxRequestSize  = 64 * 1024
+	scepChallengeLength = 32
+
+	scepOpGetCACert    = "GetCACert"
+	scepOpGetCACaps    = "GetCACaps"
+	scepOpPKIOperation = "PKIOperation"
+
+	scepCACertContentType   = "application/x-x509-ca-cert"
+	scepCARACertContentType = "application/x-x509-ca-ra-cert"
+	scepPKIMessageType      = "application/x-pki-message"
+	scepTextContentType     = "text/plain"
+
+	// Message types, RFC 8894 Section 3.2.1.2.
+	scepMessageTypeCertRep    = "3"
+	scepMessageTypeRenewalReq = "17"
+	scepMessageTypePKCSReq    = "19"
+
+	// PKI statuses, RFC 8894 Section 3.2.1.3.
+	scepStatusSuccess = "0"
+	scepStatusFailure = "2"
+
+	// Failure reasons, RFC 8894 Section 3.2.1.4.
+	scepFailBadAlg          = "0"
+	scepFailBadMessageCheck = "1"
+	scepFailBadRequest      = "2"
+	scepFailBadTime         = "3"
+)
+
+// scepCACaps lists the capabilities advertised through GetCACaps, RFC 8894
+// Section 3.5.2.
+var scepCACaps = []string{"AES", "POSTPKIOperation", "Renewal", "SHA-256", "SHA-512", "SCEPStandard"}
+
+var (
+	oidScepMessageType    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 2}
+	oidScepPkiStatus      = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 3}
+	oidScepFailInfo       = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 4}
+	oidScepSenderNonce    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 5}
+	oidScepRecipientNonce = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 6}
+	oidScepTransactionID  = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 7}
+	oidChallengePassword  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 7}
+)
+
+type scepChallengeEntry struct {
+	Expiration time.Time `json:"expiration"`
+}
+
+// scepPKIMessage holds the signed envelope of a SCEP request, RFC 8894
+// Section 3.2.
+type scepPKIMessage struct {
+	signer        *x509.Certificate
+	messageType   string
+	transactionID string
+	senderNonce   []byte
+	envelope      []byte
+}
+
+func pathScep(b *backend, pattern string) *framework.Path {
+	return &framework.Path{
+		Pattern: pattern,
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationSuffix: "scep",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: `The desired role for SCEP enrollment`,
+				Required:    true,
+			},
+			"operation": {
+				Type:        framework.TypeString,
+				Description: `The SCEP operation: GetCACert, GetCACaps or PKIOperation`,
+				Query:       true,
+			},
+			"message": {
+				Type:        framework.TypeString,
+				Description: `The base64 encoded SCEP message, for PKIOperation requests using GET`,
+				Query:       true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathScepOperation,
+				// PKIOperation requests consume challenges and store
+				// certificates, even over GET.
+				ForwardPerformanceStandby: true,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathScepOperation,
+				// The request body can only be consumed once, so forward before
+				// reading it rather than relying on a later ErrReadOnly.
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathScepHelpSyn,
+		HelpDescription: pathScepHelpDesc,
+	}
+}
+
+func pathScepChallenge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("role") + "/scep/challenge",
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationVerb:   "generate",
+			OperationSuffix: "scep-challenge",
+		},
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: `The role the challenge password may be used with`,
+				Required:    true,
+			},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: `How long the challenge password remains valid; defaults to the default_challenge_ttl of the SCEP configuration`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                  b.pathScepChallengeWrite,
+				ForwardPerformanceStandby: true,
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: "OK",
+						Fields: map[string]*framework.FieldSchema{
+							"challenge": {
+								Type:        framework.TypeString,
+								Description: `The one-time challenge password`,
+								Required:    true,
+							},
+							"expiration": {
+								Type:        framework.TypeInt64,
+								Description: `The time at which the challenge password expires, as a Unix timestamp`,
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    pathScepChallengeHelpSyn,
+		HelpDescription: pathScepChallengeHelpDesc,
+	}
+}
+
+func (b *backend) pathScepChallengeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	roleName := data.Get("role").(string)
+
+	config, err := getScepConfig(sc)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return logical.ErrorResponse("SCEP is not enabled on this mount"), nil
+	}
+	if !config.isRoleAllowed(roleName) {
+		return logical.ErrorResponse("role %q is not allowed to be used with SCEP", roleName), nil
+	}
+
+	role, err := sc.GetRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("unknown role: %s", roleName), nil
+	}
+
+	ttl := config.DefaultChallengeTTL
+	if ttlRaw, ok := data.GetOk("ttl"); ok {
+		ttl = time.Duration(ttlRaw.(int)) * time.Second
+		if ttl <= 0 {
+			return logical.ErrorResponse("ttl must be greater than 0"), nil
+		}
+	}
+
+	challenge, err := base62.Random(scepChallengeLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating challenge password: %w", err)
+	}
+
+	entry := &scepChallengeEntry{Expiration: time.Now().Add(ttl)}
+	json, err := logical.StorageEntryJSON(scepChallengePath(roleName, challenge), entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, json); err != nil {
+		return nil, fmt.Errorf("failed storing challenge password: %w", err)
+	}
+
+	// Challenges that were never used would otherwise accumulate.
+	if err := b.purgeExpiredScepChallenges(sc, roleName); err != nil {
+		b.Logger().Warn("failed purging expired SCEP challenges", "role", roleName, "error", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"challenge":  challenge,
+			"expiration": entry.Expiration.Unix(),
+		},
+	}, nil
+}
+
+// scepChallengePath returns the storage location of a challenge password;
+// only its hash is stored.
+func scepChallengePath(roleName string, challenge string) string {
+	hash := sha256.Sum256([]byte(challenge))
+	return scepChallengePrefix + roleName + "/" + hex.EncodeToString(hash[:])
+}
+
+func (b *backend) purgeExpiredScepChallenges(sc *storageContext, roleName string) error {
+	b.scepChallengeLock.Lock()
+	defer b.scepChallengeLock.Unlock()
+
+	prefix := scepChallengePrefix + roleName + "/"
+	hashes, err := sc.Storage.List(sc.Context, prefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, hash := range hashes {
+		entry, err := sc.Storage.Get(sc.Context, prefix+hash)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+
+		var challenge scepChallengeEntry
+		if err := entry.DecodeJSON(&challenge); err != nil {
+			return err
+		}
+		if now.After(challenge.Expiration) {
+			if err := sc.Storage.Delete(sc.Context, prefix+hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// consumeScepChallenge validates a challenge password against the role,
+// removing it so that it can only be used once.
+func (b *backend) consumeScepChallenge(sc *storageContext, roleName string, challenge string) (bool, error) {
+	b.scepChallengeLock.Lock()
+	defer b.scepChallengeLock.Unlock()
+
+	path := scepChallengePath(roleName, challenge)
+	entry, err := sc.Storage.Get(sc.Context, path)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+
+	var stored scepChallengeEntry
+	if err := entry.DecodeJSON(&stored); err != nil {
+		return false, err
+	}
+	if err := sc.Storage.Delete(sc.Context, path); err != nil {
+		return false, err
+	}
+
+	return time.Now().Before(stored.Expiration), nil
+}
+
+func (b *backend) pathScepOperation(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	roleName := data.Get("role").(string)
+
+	config, err := getScepConfig(sc)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return scepTextResponse(http.StatusNotFound, "SCEP is not enabled on this mount"), nil
+	}
+	if !config.isRoleAllowed(roleName) {
+		return scepTextResponse(http.StatusNotFound, fmt.Sprintf("role %q is not allowed to be used with SCEP", roleName)), nil
+	}
+
+	role, err := sc.GetRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return scepTextResponse(http.StatusNotFound, fmt.Sprintf("unknown role %q", roleName)), nil
+	}
+
+	operation := data.Get("operation").(string)
+	if req.Operation == logical.UpdateOperation && req.HTTPRequest != nil {
+		operation = req.HTTPRequest.URL.Query().Get("operation")
+	}
+
+	switch operation {
+	case scepOpGetCACaps:
+		return scepTextResponse(http.StatusOK, strings.Join(scepCACaps, "\n")), nil
+	case scepOpGetCACert:
+		return b.scepGetCACert(sc, role)
+	case scepOpPKIOperation:
+		message, err := scepReadMessage(req, data)
+		if err != nil {
+			return scepTextResponse(http.StatusBadRequest, err.Error()), nil
+		}
+		return b.scepPKIOperation(sc, req, role, message)
+	default:
+		return scepTextResponse(http.StatusBadRequest, fmt.Sprintf("unsupported SCEP operation %q", operation)), nil
+	}
+}
+
+func (b *backend) scepGetCACert(sc *storageContext, role *issuing.RoleEntry) (*logical.Response, error) {
+	signingBundle, err := sc.fetchCAInfo(estIssuerRef(role), issuing.IssuanceUsage)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading issuer for SCEP: %w", err)
+	}
+
+	chain := signingBundle.GetFullChain()
+	if len(chain) == 1 {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPContentType: scepCACertContentType,
+				logical.HTTPStatusCode:  http.StatusOK,
+				logical.HTTPRawBody:     chain[0].Bytes,
+			},
+		}, nil
+	}
+
+	var certs []byte
+	for _, block := range chain {
+		certs = append(certs, block.Bytes...)
+	}
+	p7, err := pkcs7.DegenerateCertificate(certs)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding certificates: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: scepCARACertContentType,
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     p7,
+		},
+	}, nil
+}
+
+func scepReadMessage(req *logical.Request, data *framework.FieldData) ([]byte, error) {
+	if req.Operation == logical.UpdateOperation {
+		if req.HTTPRequest == nil || req.HTTPRequest.Body == nil {
+			return nil, errors.New("no data in request body")
+		}
+		defer req.HTTPRequest.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(req.HTTPRequest.Body, scepMaxRequestSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed reading request body: %w", err)
+		}
+		if len(body) >= scepMaxRequestSize {
+			return nil, errors.New("request is too large")
+		}
+		return body, nil
+	}
+
+	// Clients do not consistently escape the base64 message, in which case
+	// any '+' was decoded to a space along with the query string.
+	message := strings.ReplaceAll(data.Get("message").(string), " ", "+")
+	if message == "" {
+		return nil, errors.New("missing message parameter")
+	}
+	der, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed base64 decoding the message: %w", err)
+	}
+	return der, nil
+}
+
+func (b *backend) scepPKIOperation(sc *storageContext, req *logical.Request, role *issuing.RoleEntry, message []byte) (*logical.Response, error) {
+	signingBundle, issuerId, err := sc.fetchCAInfoWithIssuer(estIssuerRef(role), issuing.IssuanceUsage)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading issuer for SCEP: %w", err)
+	}
+
+	// The issuer doubles as the recipient of the encrypted requests, which
+	// the pkcs7 package can only decrypt with a local RSA key.
+	caKey, ok := signingBundle.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return scepTextResponse(http.StatusInternalServerError, "SCEP requires an issuer with an RSA key stored within Vault"), nil
+	}
+
+	msg, err := parseScepPKIMessage(message)
+	if err != nil {
+		return scepTextResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	certificate, alg, failInfo, err := b.scepHandleRequest(sc, req, role, signingBundle, issuerId, caKey, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var rep []byte
+	if failInfo != "" {
+		b.Logger().Debug("rejected SCEP request", "transaction_id", msg.transactionID,
+			"message_type", msg.messageType, "fail_info", failInfo, "role", role.Name)
+		rep, err = scepCertRep(msg, signingBundle.Certificate, caKey, scepStatusFailure, failInfo, nil)
+	} else {
+		b.Logger().Debug("issued certificate over SCEP", "serial", serialFromCert(certificate),
+			"issuer", issuerId, "role", role.Name, "transaction_id", msg.transactionID)
+
+		var degenerate, enveloped []byte
+		degenerate, err = pkcs7.DegenerateCertificate(certificate.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed encoding certificate: %w", err)
+		}
+		enveloped, err = pkcs7.EncryptWithAlgorithm(degenerate, []*x509.Certificate{msg.signer}, alg)
+		if err != nil {
+			return nil, fmt.Errorf("failed encrypting certificate: %w", err)
+		}
+		rep, err = scepCertRep(msg, signingBundle.Certificate, caKey, scepStatusSuccess, "", enveloped)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed building SCEP response: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: scepPKIMessageType,
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     rep,
+		},
+	}, nil
+}
+
+// scepHandleRequest decrypts and authorizes the certificate request carried
+// by msg, issuing the certificate. A non-empty failInfo is returned when the
+// request must be rejected with a failure CertRep.
+func (b *backend) scepHandleRequest(sc *storageContext, req *logical.Request, role *issuing.RoleEntry, signingBundle *certutil.CAInfoBundle, issuerId issuing.IssuerID, caKey *rsa.PrivateKey, msg *scepPKIMessage) (*x509.Certificate, int, string, error) {
+	if _, ok := msg.signer.PublicKey.(*rsa.PublicKey); !ok {
+		// The response is encrypted to the signer, which requires RSA.
+		return nil, 0, scepFailBadAlg, nil
+	}
+
+	envelope, err := pkcs7.Parse(msg.envelope)
+	if err != nil {
+		return nil, 0, scepFailBadMessageCheck, nil
+	}
+	alg, err := envelope.ContentEncryptionAlgorithm()
+	if err != nil {
+		return nil, 0, scepFailBadAlg, nil
+	}
+	der, err := envelope.Decrypt(signingBundle.Certificate, caKey)
+	if err != nil {
+		return nil, 0, scepFailBadMessageCheck, nil
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, 0, scepFailBadRequest, nil
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, 0, scepFailBadMessageCheck, nil
+	}
+
+	switch msg.messageType {
+	case scepMessageTypePKCSReq:
+		challenge, err := scepChallengePasswordFromCsr(csr)
+		if err != nil || challenge == "" {
+			return nil, 0, scepFailBadRequest, nil
+		}
+		valid, err := b.consumeScepChallenge(sc, role.Name, challenge)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("failed validating challenge password: %w", err)
+		}
+		if !valid {
+			return nil, 0, scepFailBadRequest, nil
+		}
+	case scepMessageTypeRenewalReq:
+		if failInfo, err := scepValidateRenewal(sc, role, msg.signer, csr, signingBundle); failInfo != "" || err != nil {
+			return nil, 0, failInfo, err
+		}
+	default:
+		return nil, 0, scepFailBadRequest, nil
+	}
+
+	parsedBundle, err := b.signAndStoreCsr(sc, req, role, signingBundle, issuerId, csr)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, 0, scepFailBadRequest, nil
+		default:
+			return nil, 0, "", err
+		}
+	}
+
+	return parsedBundle.Certificate, alg, "", nil
+}
+
+// scepValidateRenewal ensures the RenewalReq was signed with a valid
+// certificate previously issued by this issuer for the same role, with the
+// same subject and subject alternative names.
+func scepValidateRenewal(sc *storageContext, role *issuing.RoleEntry, signer *x509.Certificate, csr *x509.CertificateRequest, signingBundle *certutil.CAInfoBundle) (string, error) {
+	if err := signer.CheckSignatureFrom(signingBundle.Certificate); err != nil {
+		return scepFailBadRequest, nil
+	}
+
+	now := time.Now()
+	if now.Before(signer.NotBefore) || now.After(signer.NotAfter) {
+		return scepFailBadTime, nil
+	}
+
+	revoked, err := fetchCertBySerial(sc, revokedPath, serialFromCert(signer))
+	if err != nil {
+		return "", fmt.Errorf("failed checking revocation status: %w", err)
+	}
+	if revoked != nil {
+		return scepFailBadRequest, nil
+	}
+
+	// Only certificates recorded as issued for this role may be renewed
+	// through it, so that a certificate from one role cannot be used to
+	// obtain another role's certificates.
+	issued, err := sc.fetchIssuanceLogEntry(serialFromCert(signer))
+	if err != nil {
+		return "", fmt.Errorf("failed looking up the certificate being renewed: %w", err)
+	}
+	if issued == nil || issued.Role != role.Name {
+		return scepFailBadRequest, nil
+	}
+
+	if !bytes.Equal(csr.RawSubject, signer.RawSubject) {
+		return scepFailBadRequest, nil
+	}
+	if !bytes.Equal(estSubjectAltNames(csr.Extensions), estSubjectAltNames(signer.Extensions)) {
+		return scepFailBadRequest, nil
+	}
+
+	return "", nil
+}
+
+func parseScepPKIMessage(der []byte) (*scepPKIMessage, error) {
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing the SCEP message: %w", err)
+	}
+	if err := p7.Verify(); err != nil {
+		return nil, fmt.Errorf("failed verifying the SCEP message signature: %w", err)
+	}
+
+	msg := &scepPKIMessage{
+		signer:   p7.GetOnlySigner(),
+		envelope: p7.Content,
+	}
+	if msg.signer == nil {
+		return nil, errors.New("the SCEP message must have exactly one signer")
+	}
+	if err := p7.UnmarshalSignedAttribute(oidScepMessageType, &msg.messageType); err != nil {
+		return nil, fmt.Errorf("failed reading the SCEP message type: %w", err)
+	}
+	if err := p7.UnmarshalSignedAttribute(oidScepTransactionID, &msg.transactionID); err != nil {
+		return nil, fmt.Errorf("failed reading the SCEP transaction ID: %w", err)
+	}
+	if err := p7.UnmarshalSignedAttribute(oidScepSenderNonce, &msg.senderNonce); err != nil {
+		return nil, fmt.Errorf("failed reading the SCEP sender nonce: %w", err)
+	}
+
+	return msg, nil
+}
+
+// scepCertRep builds the signed CertRep answering msg, RFC 8894 Section
+// 3.3.2.
+func scepCertRep(msg *scepPKIMessage, caCert *x509.Certificate, caKey *rsa.PrivateKey, status string, failInfo string, content []byte) ([]byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	attrs := []pkcs7.Attribute{
+		{Type: oidScepTransactionID, Value: msg.transactionID},
+		{Type: oidScepMessageType, Value: scepMessageTypeCertRep},
+		{Type: oidScepPkiStatus, Value: status},
+		{Type: oidScepSenderNonce, Value: nonce},
+		{Type: oidScepRecipientNonce, Value: msg.senderNonce},
+	}
+	if failInfo != "" {
+		attrs = append(attrs, pkcs7.Attribute{Type: oidScepFailInfo, Value: failInfo})
+	}
+
+	signedData, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := signedData.AddSigner(caCert, caKey, pkcs7.SignerInfoConfig{ExtraSignedAttributes: attrs}); err != nil {
+		return nil, err
+	}
+
+	return signedData.Finish()
+}
+
+// scepChallengePasswordFromCsr extracts the PKCS#9 challengePassword
+// attribute, which crypto/x509 does not expose.
+func scepChallengePasswordFromCsr(csr *x509.CertificateRequest) (string, error) {
+	var tbs struct {
+		Raw           asn1.RawContent
+		Version       int
+		Subject       asn1.RawValue
+		PublicKey     asn1.RawValue
+		RawAttributes []asn1.RawValue `asn1:"tag:0"`
+	}
+	if _, err := asn1.Unmarshal(csr.RawTBSCertificateRequest, &tbs); err != nil {
+		return "", err
+	}
+
+	for _, rawAttr := range tbs.RawAttributes {
+		var attr struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		}
+		if _, err := asn1.Unmarshal(rawAttr.FullBytes, &attr); err != nil {
+			return "", err
+		}
+		if !attr.Type.Equal(oidChallengePassword) || len(attr.Values) == 0 {
+			continue
+		}
+
+		var challenge string
+		if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &challenge); err != nil {
+			return "", err
+		}
+		return challenge, nil
+	}
+
+	return "", nil
+}
+
+func scepTextResponse(status int, msg string) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: scepTextContentType,
+			logical.HTTPStatusCode:  status,
+			logical.HTTPRawBody:     []byte(msg + "\n"),
+		},
+	}
+}
+
+const pathScepHelpSyn = `SCEP (RFC 8894) endpoint for the role.`
+
+const pathScepHelpDesc = `
+Serves the GetCACert, GetCACaps and PKIOperation SCEP operations, selected
+through the operation query parameter. PKIOperation accepts PKCSReq messages
+carrying a challenge password generated through the roles/:role/scep/challenge
+endpoint, as well as RenewalReq messages signed with a certificate previously
+issued through the same role, requesting the same subject and subject
+alternative names. Roles with no_store set cannot renew certificates.
+`
+
+const pathScepChallengeHelpSyn = `Generate a one-time SCEP challenge password for the role.`
+
+const pathScepChallengeHelpDesc = `
+Generates a challenge password a SCEP client may use once to enroll through
+the role's SCEP endpoint. Only a hash of the password is stored; it expires
+after the requested ttl.
+`
@@ -0,0 +1,424 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/pkcs7"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// scepTestCsr builds a certificate request carrying a challengePassword
+// attribute, which crypto/x509 can not produce.
+func scepTestCsr(t *testing.T, key *rsa.PrivateKey, cn string, challenge string) []byte {
+	t.Helper()
+
+	template, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: cn},
+	}, key)
+	require.NoError(t, err)
+	parsed, err := x509.ParseCertificateRequest(template)
+	require.NoError(t, err)
+	if challenge == "" {
+		return template
+	}
+
+	attr, err := asn1.Marshal(struct {
+		Type   asn1.ObjectIdentifier
+		Values []string `asn1:"set"`
+	}{oidChallengePassword, []string{challenge}})
+	require.NoError(t, err)
+
+	tbs, err := asn1.Marshal(struct {
+		Version       int
+		Subject       asn1.RawValue
+		PublicKey     asn1.RawValue
+		RawAttributes []asn1.RawValue `asn1:"tag:0"`
+	}{
+		Subject:       asn1.RawValue{FullBytes: parsed.RawSubject},
+		PublicKey:     asn1.RawValue{FullBytes: parsed.RawSubjectPublicKeyInfo},
+		RawAttributes: []asn1.RawValue{{FullBytes: attr}},
+	})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(tbs)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	csr, err := asn1.Marshal(struct {
+		TBS       asn1.RawValue
+		Algorithm pkix.AlgorithmIdentifier
+		Signature asn1.BitString
+	}{
+		TBS:       asn1.RawValue{FullBytes: tbs},
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: pkcs7.OIDEncryptionAlgorithmRSASHA256, Parameters: asn1.NullRawValue},
+		Signature: asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+	require.NoError(t, err)
+
+	return csr
+}
+
+// scepTestRenewalCsr builds the CSR of a RenewalReq, which carries the
+// subject alternative names of the certificate being renewed.
+func scepTestRenewalCsr(t *testing.T, key *rsa.PrivateKey, cn string, dnsNames ...string) []byte {
+	t.Helper()
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: cn},
+		DNSNames: dnsNames,
+	}, key)
+	require.NoError(t, err)
+	return csr
+}
+
+func scepTestSelfSigned(t *testing.T, key *rsa.PrivateKey, cn string) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// scepTestPKIMessage wraps the CSR the way a SCEP client does, returning the
+// message and its sender nonce.
+func scepTestPKIMessage(t *testing.T, caCert, signer *x509.Certificate, key *rsa.PrivateKey, messageType string, csr []byte) ([]byte, []byte) {
+	t.Helper()
+
+	envelope, err := pkcs7.EncryptWithAlgorithm(csr, []*x509.Certificate{caCert}, pkcs7.EncryptionAlgorithmAES128CBC)
+	require.NoError(t, err)
+
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+
+	signedData, err := pkcs7.NewSignedData(envelope)
+	require.NoError(t, err)
+	err = signedData.AddSigner(signer, key, pkcs7.SignerInfoConfig{
+		ExtraSignedAttributes: []pkcs7.Attribute{
+			{Type: oidScepMessageType, Value: messageType},
+			{Type: oidScepTransactionID, Value: "transaction-" + messageType},
+			{Type: oidScepSenderNonce, Value: nonce},
+		},
+	})
+	require.NoError(t, err)
+	message, err := signedData.Finish()
+	require.NoError(t, err)
+
+	return message, nonce
+}
+
+// scepTestCertRep verifies the CertRep and returns its status, failure
+// information and, on success, the issued certificate.
+func scepTestCertRep(t *testing.T, resp *logical.Response, caCert, recipient *x509.Certificate, key *rsa.PrivateKey, nonce []byte) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	require.Equal(t, http.StatusOK, resp.Data[logical.HTTPStatusCode], "unexpected response: %s", resp.Data[logical.HTTPRawBody])
+	require.Equal(t, scepPKIMessageType, resp.Data[logical.HTTPContentType])
+
+	p7, err := pkcs7.Parse(resp.Data[logical.HTTPRawBody].([]byte))
+	require.NoError(t, err)
+	require.NoError(t, p7.Verify())
+	require.True(t, p7.GetOnlySigner().Equal(caCert))
+
+	var messageType, status, failInfo string
+	var recipientNonce []byte
+	require.NoError(t, p7.UnmarshalSignedAttribute(oidScepMessageType, &messageType))
+	require.Equal(t, scepMessageTypeCertRep, messageType)
+	require.NoError(t, p7.UnmarshalSignedAttribute(oidScepRecipientNonce, &recipientNonce))
+	require.Equal(t, nonce, recipientNonce)
+	require.NoError(t, p7.UnmarshalSignedAttribute(oidScepPkiStatus, &status))
+	if status != scepStatusSuccess {
+		require.NoError(t, p7.UnmarshalSignedAttribute(oidScepFailInfo, &failInfo))
+		return status, failInfo, nil
+	}
+
+	envelope, err := pkcs7.Parse(p7.Content)
+	require.NoError(t, err)
+	alg, err := envelope.ContentEncryptionAlgorithm()
+	require.NoError(t, err)
+	require.Equal(t, pkcs7.EncryptionAlgorithmAES128CBC, alg)
+	degenerate, err := envelope.Decrypt(recipient, key)
+	require.NoError(t, err)
+	certs, err := pkcs7.Parse(degenerate)
+	require.NoError(t, err)
+	require.Len(t, certs.Certificates, 1)
+
+	return status, "", certs.Certificates[0]
+}
+
+func TestScep_Config(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBRead(b, s, "config/scep")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, false, resp.Data["enabled"])
+	require.Equal(t, []string{"*"}, resp.Data["allowed_roles"])
+	require.Equal(t, int64(3600), resp.Data["default_challenge_ttl"])
+
+	_, err = CBWrite(b, s, "config/scep", map[string]interface{}{
+		"enabled":       true,
+		"allowed_roles": "missing",
+	})
+	require.Error(t, err)
+
+	_, err = CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allow_any_name": true,
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "config/scep", map[string]interface{}{
+		"enabled":               true,
+		"allowed_roles":         "devices",
+		"default_challenge_ttl": "10m",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	resp, err = CBRead(b, s, "config/scep")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, true, resp.Data["enabled"])
+	require.Equal(t, []string{"devices"}, resp.Data["allowed_roles"])
+	require.Equal(t, int64(600), resp.Data["default_challenge_ttl"])
+	require.NotEmpty(t, resp.Data["last_updated"])
+}
+
+func TestScep_Operations(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.com",
+		"key_type":    "rsa",
+		"ttl":         "720h",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	caCert := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"ttl":              "1h",
+	})
+	require.NoError(t, err)
+	_, err = CBWrite(b, s, "roles/other", map[string]interface{}{
+		"allow_any_name": true,
+	})
+	require.NoError(t, err)
+
+	scepRead := func(role string, data map[string]interface{}) *logical.Response {
+		resp, err := CBReq(b, s, logical.ReadOperation, "roles/"+role+"/scep", data)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		return resp
+	}
+
+	// Endpoints are unavailable until SCEP is enabled
+	resp = scepRead("devices", map[string]interface{}{"operation": scepOpGetCACaps})
+	require.Equal(t, http.StatusNotFound, resp.Data[logical.HTTPStatusCode])
+	_, err = CBWrite(b, s, "roles/devices/scep/challenge", nil)
+	require.Error(t, err)
+
+	_, err = CBWrite(b, s, "config/scep", map[string]interface{}{
+		"enabled":       true,
+		"allowed_roles": "devices",
+	})
+	require.NoError(t, err)
+
+	resp = scepRead("other", map[string]interface{}{"operation": scepOpGetCACaps})
+	require.Equal(t, http.StatusNotFound, resp.Data[logical.HTTPStatusCode])
+	_, err = CBWrite(b, s, "roles/other/scep/challenge", nil)
+	require.Error(t, err)
+
+	resp = scepRead("devices", map[string]interface{}{"operation": scepOpGetCACaps})
+	require.Equal(t, http.StatusOK, resp.Data[logical.HTTPStatusCode])
+	require.Contains(t, string(resp.Data[logical.HTTPRawBody].([]byte)), "POSTPKIOperation")
+
+	resp = scepRead("devices", map[string]interface{}{"operation": scepOpGetCACert})
+	require.Equal(t, http.StatusOK, resp.Data[logical.HTTPStatusCode])
+	require.Equal(t, scepCACertContentType, resp.Data[logical.HTTPContentType])
+	require.Equal(t, caCert.Raw, resp.Data[logical.HTTPRawBody])
+
+	resp = scepRead("devices", map[string]interface{}{"operation": "GetNextCACert"})
+	require.Equal(t, http.StatusBadRequest, resp.Data[logical.HTTPStatusCode])
+
+	// Enroll with a one-time challenge password
+	resp, err = CBWrite(b, s, "roles/devices/scep/challenge", map[string]interface{}{"ttl": "5m"})
+	requireSuccessNonNilResponse(t, resp, err)
+	challenge := resp.Data["challenge"].(string)
+	require.NotEmpty(t, challenge)
+	require.InDelta(t, time.Now().Add(5*time.Minute).Unix(), resp.Data["expiration"], 5)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	selfSigned := scepTestSelfSigned(t, key, "dev1.example.com")
+	message, nonce := scepTestPKIMessage(t, caCert, selfSigned, key, scepMessageTypePKCSReq,
+		scepTestCsr(t, key, "dev1.example.com", challenge))
+
+	resp = scepRead("devices", map[string]interface{}{
+		"operation": scepOpPKIOperation,
+		"message":   base64.StdEncoding.EncodeToString(message),
+	})
+	status, _, issued := scepTestCertRep(t, resp, caCert, selfSigned, key, nonce)
+	require.Equal(t, scepStatusSuccess, status)
+	require.Equal(t, "dev1.example.com", issued.Subject.CommonName)
+	requireSignedBy(t, issued, caCert)
+
+	resp, err = CBRead(b, s, "cert/"+serialFromCert(issued))
+	requireSuccessNonNilResponse(t, resp, err)
+
+	// The challenge password can not be used a second time
+	resp = scepRead("devices", map[string]interface{}{
+		"operation": scepOpPKIOperation,
+		"message":   base64.StdEncoding.EncodeToString(message),
+	})
+	status, failInfo, _ := scepTestCertRep(t, resp, caCert, selfSigned, key, nonce)
+	require.Equal(t, scepStatusFailure, status)
+	require.Equal(t, scepFailBadRequest, failInfo)
+
+	// Nor can a request without one
+	message, nonce = scepTestPKIMessage(t, caCert, selfSigned, key, scepMessageTypePKCSReq,
+		scepTestCsr(t, key, "dev1.example.com", ""))
+	resp = scepRead("devices", map[string]interface{}{
+		"operation": scepOpPKIOperation,
+		"message":   base64.StdEncoding.EncodeToString(message),
+	})
+	status, failInfo, _ = scepTestCertRep(t, resp, caCert, selfSigned, key, nonce)
+	require.Equal(t, scepStatusFailure, status)
+	require.Equal(t, scepFailBadRequest, failInfo)
+
+	// Renew over POST, signing with the issued certificate
+	message, nonce = scepTestPKIMessage(t, caCert, issued, key, scepMessageTypeRenewalReq,
+		scepTestRenewalCsr(t, key, "dev1.example.com", issued.DNSNames...))
+	httpReq, err := http.NewRequest(http.MethodPost, "/v1/pki/roles/devices/scep?operation=PKIOperation", bytes.NewReader(message))
+	require.NoError(t, err)
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "roles/devices/scep",
+		Storage:     s,
+		MountPoint:  "pki/",
+		HTTPRequest: httpReq,
+	})
+	require.NoError(t, err)
+	status, _, renewed := scepTestCertRep(t, resp, caCert, issued, key, nonce)
+	require.Equal(t, scepStatusSuccess, status)
+	require.Equal(t, "dev1.example.com", renewed.Subject.CommonName)
+	require.NotEqual(t, issued.SerialNumber, renewed.SerialNumber)
+
+	// Renewal requests must be signed by a certificate of the issuer
+	message, nonce = scepTestPKIMessage(t, caCert, selfSigned, key, scepMessageTypeRenewalReq,
+		scepTestRenewalCsr(t, key, "dev1.example.com", issued.DNSNames...))
+	resp = scepRead("devices", map[string]interface{}{
+		"operation": scepOpPKIOperation,
+		"message":   base64.StdEncoding.EncodeToString(message),
+	})
+	status, failInfo, _ = scepTestCertRep(t, resp, caCert, selfSigned, key, nonce)
+	require.Equal(t, scepStatusFailure, status)
+	require.Equal(t, scepFailBadRequest, failInfo)
+
+	// They must keep the subject and subject alternative names of the
+	// certificate being renewed
+	for _, csr := range [][]byte{
+		scepTestRenewalCsr(t, key, "dev2.example.com", issued.DNSNames...),
+		scepTestRenewalCsr(t, key, "dev1.example.com", "dev1.example.com", "dev2.example.com"),
+	} {
+		message, nonce = scepTestPKIMessage(t, caCert, issued, key, scepMessageTypeRenewalReq, csr)
+		resp = scepRead("devices", map[string]interface{}{
+			"operation": scepOpPKIOperation,
+			"message":   base64.StdEncoding.EncodeToString(message),
+		})
+		status, failInfo, _ = scepTestCertRep(t, resp, caCert, issued, key, nonce)
+		require.Equal(t, scepStatusFailure, status)
+		require.Equal(t, scepFailBadRequest, failInfo)
+	}
+
+	// And can only renew certificates issued for the same role
+	_, err = CBWrite(b, s, "config/scep", map[string]interface{}{
+		"enabled":       true,
+		"allowed_roles": "devices,other",
+	})
+	require.NoError(t, err)
+	message, nonce = scepTestPKIMessage(t, caCert, issued, key, scepMessageTypeRenewalReq,
+		scepTestRenewalCsr(t, key, "dev1.example.com", issued.DNSNames...))
+	resp = scepRead("other", map[string]interface{}{
+		"operation": scepOpPKIOperation,
+		"message":   base64.StdEncoding.EncodeToString(message),
+	})
+	status, failInfo, _ = scepTestCertRep(t, resp, caCert, issued, key, nonce)
+	require.Equal(t, scepStatusFailure, status)
+	require.Equal(t, scepFailBadRequest, failInfo)
+
+	// Nor may they have been revoked
+	_, err = CBWrite(b, s, "revoke", map[string]interface{}{"serial_number": serialFromCert(issued)})
+	require.NoError(t, err)
+	message, nonce = scepTestPKIMessage(t, caCert, issued, key, scepMessageTypeRenewalReq,
+		scepTestRenewalCsr(t, key, "dev1.example.com", issued.DNSNames...))
+	resp = scepRead("devices", map[string]interface{}{
+		"operation": scepOpPKIOperation,
+		"message":   base64.StdEncoding.EncodeToString(message),
+	})
+	status, failInfo, _ = scepTestCertRep(t, resp, caCert, issued, key, nonce)
+	require.Equal(t, scepStatusFailure, status)
+	require.Equal(t, scepFailBadRequest, failInfo)
+}
+
+func TestScep_ChallengeExpiry(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	_, err := CBWrite(b, s, "roles/devices", map[string]interface{}{
+		"allow_any_name": true,
+	})
+	require.NoError(t, err)
+	_, err = CBWrite(b, s, "config/scep", map[string]interface{}{"enabled": true})
+	require.NoError(t, err)
+
+	resp, err := CBWrite(b, s, "roles/devices/scep/challenge", map[string]interface{}{"ttl": "1s"})
+	requireSuccessNonNilResponse(t, resp, err)
+	expired := resp.Data["challenge"].(string)
+	time.Sleep(1500 * time.Millisecond)
+
+	sc := b.makeStorageContext(context.Background(), s)
+	valid, err := b.consumeScepChallenge(sc, "devices", expired)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	// Generating a new challenge purges the expired, unused ones
+	resp, err = CBWrite(b, s, "roles/devices/scep/challenge", map[string]interface{}{"ttl": "1s"})
+	requireSuccessNonNilResponse(t, resp, err)
+	time.Sleep(1500 * time.Millisecond)
+	resp, err = CBWrite(b, s, "roles/devices/scep/challenge", nil)
+	requireSuccessNonNilResponse(t, resp, err)
+
+	hashes, err := s.List(context.Background(), scepChallengePrefix+"devices/")
+	require.NoError(t, err)
+	require.Len(t, hashes, 1)
+
+	valid, err = b.consumeScepChallenge(sc, "devices", resp.Data["challenge"].(string))
+	require.NoError(t, err)
+	require.True(t, valid)
+}
@@ -1008,6 +1008,26 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 		return fmt.Errorf("error fetching list of certs: %w", err)
 	}
 
+	// The issuance log is tidied along with the certificates it describes.
+	sc := b.makeStorageContext(ctx, req.Storage)
+	issuers, err := revocation.FetchIssuerMapForRevocationChecking(sc)
+	if err != nil {
+		return err
+	}
+	issuanceLog, err := sc.getIssuanceLogConfig()
+	if err != nil {
+		return err
+	}
+	recordedSerials, err := req.Storage.List(ctx, issuanceLogSerialPrefix)
+	if err != nil {
+		return fmt.Errorf("error fetching issuance log: %w", err)
+	}
+	recorded := make(map[string]bool, len(recordedSerials))
+	for _, serial := range recordedSerials {
+		recorded[serial] = true
+	}
+	stored := make(map[string]bool, len(serials))
+
 	serialCount := len(serials)
 	metrics.SetGauge([]string{"secrets", "pki", "tidy", "cert_store_total_entries"}, float32(serialCount))
 	for i, serial := range serials {
@@ -1034,6 +1054,9 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 			if err := req.Storage.Delete(ctx, issuing.PathCerts+serial); err != nil {
 				return fmt.Errorf("error deleting nil entry with serial %s: %w", serial, err)
 			}
+			if err := sc.deleteIssuanceLogEntry(serial); err != nil {
+				return err
+			}
 			b.tidyStatusIncCertStoreCount()
 			continue
 		}
@@ -1043,6 +1066,9 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 			if err := req.Storage.Delete(ctx, issuing.PathCerts+serial); err != nil {
 				return fmt.Errorf("error deleting entry with nil value with serial %s: %w", serial, err)
 			}
+			if err := sc.deleteIssuanceLogEntry(serial); err != nil {
+				return err
+			}
 			b.tidyStatusIncCertStoreCount()
 			continue
 		}
@@ -1056,8 +1082,23 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 			if err := req.Storage.Delete(ctx, issuing.PathCerts+serial); err != nil {
 				return fmt.Errorf("error deleting serial %q from storage: %w", serial, err)
 			}
+			if err := sc.deleteIssuanceLogEntry(serial); err != nil {
+				return err
+			}
 			b.tidyStatusIncCertStoreCount()
+			continue
 		}
+
+		stored[normalizeSerial(serial)] = true
+		if issuanceLog.Enabled && !recorded[normalizeSerial(serial)] {
+			if err := sc.recordExistingIssuance(cert, issuers); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := sc.tidyIssuanceLogOrphans(stored); err != nil {
+		return err
 	}
 
 	b.tidyStatusLock.RLock()
@@ -1167,6 +1208,9 @@ func (b *backend) doTidyRevocationStore(ctx context.Context, req *logical.Reques
 				if err := req.Storage.Delete(ctx, issuing.PathCerts+serial); err != nil {
 					return fmt.Errorf("error deleting serial %q from store when tidying revoked: %w", serial, err)
 				}
+				if err := sc.deleteIssuanceLogEntry(serial); err != nil {
+					return err
+				}
 				rebuildCRL = true
 				storeCert = false
 				b.tidyStatusIncRevokedCertCount()
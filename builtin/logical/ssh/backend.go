@@ -7,8 +7,10 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/salt"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -20,6 +22,10 @@ type backend struct {
 	view      logical.Storage
 	salt      *salt.Salt
 	saltMutex sync.RWMutex
+
+	issuersLock  sync.Mutex
+	revokeLock   sync.Mutex
+	lastCertTidy time.Time
 }
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -43,6 +49,7 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			Unauthenticated: []string{
 				"verify",
 				"public_key",
+				"krl",
 			},
 
 			LocalStorage: []string{
@@ -52,6 +59,7 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			SealWrapStorage: []string{
 				caPrivateKey,
 				caPrivateKeyStoragePath,
+				issuerStoragePrefix,
 				keysStoragePrefix,
 			},
 		},
@@ -64,18 +72,28 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			pathLookup(&b),
 			pathVerify(&b),
 			pathConfigCA(&b),
+			pathConfigIssuers(&b),
+			pathListIssuers(&b),
+			pathGenerateIssuer(&b),
+			pathImportIssuer(&b),
+			pathIssuer(&b),
 			pathSign(&b),
 			pathIssue(&b),
 			pathFetchPublicKey(&b),
 			pathCleanupKeys(&b),
+			pathListCerts(&b),
+			pathRevoke(&b),
+			pathFetchKRL(&b),
 		},
 
 		Secrets: []*framework.Secret{
 			secretOTP(&b),
 		},
 
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		InitializeFunc: b.initialize,
+		Invalidate:     b.invalidate,
+		PeriodicFunc:   b.tidyCertificates,
+		BackendType:    logical.TypeLogical,
 	}
 	return &b, nil
 }
@@ -103,6 +121,26 @@ func (b *backend) Salt(ctx context.Context) (*salt.Salt, error) {
 	return salt, nil
 }
 
+// initialize migrates a legacy CA key pair into an issuer. Storage is only
+// written on the node owning it: not on performance standbys or DR
+// secondaries, nor on performance secondaries, which replicate the primary's
+// migration, unless the mount is local.
+func (b *backend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	if b.System().ReplicationState().HasState(consts.ReplicationDRSecondary|consts.ReplicationPerformanceStandby) ||
+		(!b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary)) {
+		return nil
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
+		b.Logger().Error("failed to migrate the CA key pair to an issuer", "error", err)
+		return err
+	}
+	return nil
+}
+
 func (b *backend) invalidate(_ context.Context, key string) {
 	switch key {
 	case salt.DefaultLocation:
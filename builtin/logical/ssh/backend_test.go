@@ -2757,15 +2757,23 @@ func TestProperAuthing(t *testing.T) {
 	// key := resp.Data["key"].(string)
 
 	paths := map[string]pathAuthChecker{
+		"certs/":             shouldBeAuthed,
 		"config/ca":          shouldBeAuthed,
+		"config/issuers":     shouldBeAuthed,
 		"config/zeroaddress": shouldBeAuthed,
 		"creds/test-otp":     shouldBeAuthed,
 		"issue/test-ca":      shouldBeAuthed,
+		"issuer/default":     shouldBeAuthed,
+		"issuers/":           shouldBeAuthed,
+		"issuers/generate":   shouldBeAuthed,
+		"issuers/import":     shouldBeAuthed,
+		"krl":                shouldBeUnauthedReadList,
 		"lookup":             shouldBeAuthed,
 		"public_key":         shouldBeUnauthedReadList,
 		"roles/test-ca":      shouldBeAuthed,
 		"roles/test-otp":     shouldBeAuthed,
 		"roles/":             shouldBeAuthed,
+		"revoke":             shouldBeAuthed,
 		"sign/test-ca":       shouldBeAuthed,
 		"tidy/dynamic-keys":  shouldBeAuthed,
 		"verify":             shouldBeUnauthedWriteOnly,
@@ -2806,6 +2814,7 @@ func TestProperAuthing(t *testing.T) {
 		if strings.Contains(raw_path, "{role}") && strings.Contains(raw_path, "creds") {
 			raw_path = strings.ReplaceAll(raw_path, "{role}", "test-otp")
 		}
+		raw_path = strings.ReplaceAll(raw_path, "{issuer_ref}", "default")
 
 		handler, present := paths[raw_path]
 		if !present {
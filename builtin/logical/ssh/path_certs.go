@@ -0,0 +1,289 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	certsStoragePrefix   = "certs/"
+	revokedStoragePrefix = "revoked/"
+
+	// Expired certificates no longer authenticate, so neither their
+	// records nor their revocations need to be kept around.
+	certTidyInterval = 1 * time.Hour
+)
+
+// sshCertEntry records a certificate signed by a role with
+// store_certificates set. The same entry is stored under revoked/ once the
+// certificate is revoked. The signing CA's public key is kept along with the
+// issuer ID, so that the revocation still reaches the KRL should the issuer
+// be deleted while servers trust it.
+type sshCertEntry struct {
+	SerialNumber    string    `json:"serial_number"`
+	KeyID           string    `json:"key_id"`
+	Role            string    `json:"role"`
+	IssuerID        string    `json:"issuer_id"`
+	CAPublicKey     string    `json:"ca_public_key,omitempty"`
+	CertType        string    `json:"cert_type"`
+	ValidPrincipals []string  `json:"valid_principals"`
+	ValidBefore     time.Time `json:"valid_before"`
+	RevocationTime  time.Time `json:"revocation_time,omitempty"`
+}
+
+func (e *sshCertEntry) revoked() bool {
+	return !e.RevocationTime.IsZero()
+}
+
+func (e *sshCertEntry) toMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"serial_number":    e.SerialNumber,
+		"key_id":           e.KeyID,
+		"role":             e.Role,
+		"issuer_id":        e.IssuerID,
+		"cert_type":        e.CertType,
+		"valid_principals": e.ValidPrincipals,
+		"valid_before":     e.ValidBefore.Format(time.RFC3339),
+		"revoked":          e.revoked(),
+	}
+	if e.revoked() {
+		result["revocation_time"] = e.RevocationTime.Format(time.RFC3339)
+	}
+	return result
+}
+
+// normalizeSerial converts a hex serial number as returned by the sign and
+// issue endpoints into the form used for storage keys.
+func normalizeSerial(serial string) (string, error) {
+	parsed, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(serial), "0x"), 16, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid serial number %q: expected a 64-bit hex value", serial)
+	}
+	return strconv.FormatUint(parsed, 16), nil
+}
+
+func storeCertificate(ctx context.Context, s logical.Storage, roleName, issuerId string, cert *ssh.Certificate) error {
+	certType := "user"
+	if cert.CertType == ssh.HostCert {
+		certType = "host"
+	}
+
+	certEntry := &sshCertEntry{
+		SerialNumber:    strconv.FormatUint(cert.Serial, 16),
+		KeyID:           cert.KeyId,
+		Role:            roleName,
+		IssuerID:        issuerId,
+		CertType:        certType,
+		ValidPrincipals: cert.ValidPrincipals,
+		ValidBefore:     time.Unix(int64(cert.ValidBefore), 0).UTC(),
+	}
+	if cert.SignatureKey != nil {
+		certEntry.CAPublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert.SignatureKey)))
+	}
+
+	entry, err := logical.StorageEntryJSON(certsStoragePrefix+certEntry.SerialNumber, certEntry)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to store certificate: %w", err)
+	}
+	return nil
+}
+
+func fetchCertEntry(ctx context.Context, s logical.Storage, prefix, serial string) (*sshCertEntry, error) {
+	entry, err := s.Get(ctx, prefix+serial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate %v: %w", serial, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var certEntry sshCertEntry
+	if err := entry.DecodeJSON(&certEntry); err != nil {
+		return nil, fmt.Errorf("failed to decode certificate %v: %w", serial, err)
+	}
+	return &certEntry, nil
+}
+
+// listRevokedCerts returns the revocations of certificates which have not yet
+// expired.
+func listRevokedCerts(ctx context.Context, s logical.Storage, now time.Time) ([]*sshCertEntry, error) {
+	serials, err := s.List(ctx, revokedStoragePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revoked certificates: %w", err)
+	}
+
+	var revoked []*sshCertEntry
+	for _, serial := range serials {
+		certEntry, err := fetchCertEntry(ctx, s, revokedStoragePrefix, serial)
+		if err != nil {
+			return nil, err
+		}
+		if certEntry == nil || certEntry.ValidBefore.Before(now) {
+			continue
+		}
+		revoked = append(revoked, certEntry)
+	}
+	return revoked, nil
+}
+
+func pathListCerts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixSSH,
+			OperationSuffix: "certificates",
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathCertsList,
+		},
+
+		HelpSynopsis:    `List the stored certificates.`,
+		HelpDescription: `This lists the serial numbers of the unexpired certificates signed by roles with store_certificates set, along with their key IDs.`,
+	}
+}
+
+func (b *backend) pathCertsList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serials, err := req.Storage.List(ctx, certsStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := map[string]interface{}{}
+	for _, serial := range serials {
+		certEntry, err := fetchCertEntry(ctx, req.Storage, certsStoragePrefix, serial)
+		if err != nil {
+			return nil, err
+		}
+		if certEntry == nil {
+			continue
+		}
+		keyInfo[serial] = certEntry.toMap()
+	}
+
+	return logical.ListResponseWithInfo(serials, keyInfo), nil
+}
+
+func pathRevoke(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "revoke",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixSSH,
+			OperationVerb:   "revoke",
+			OperationSuffix: "certificate",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"serial_number": {
+				Type:        framework.TypeString,
+				Description: `Serial number of the certificate to revoke, in hex as returned when it was signed.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRevokeWrite,
+		},
+
+		HelpSynopsis: `Revoke a stored certificate.`,
+		HelpDescription: `This revokes a certificate signed by a role with store_certificates set,
+adding its serial number to the key revocation list served at the 'krl'
+endpoint until the certificate expires.`,
+	}
+}
+
+func (b *backend) pathRevokeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serialRaw := d.Get("serial_number").(string)
+	if serialRaw == "" {
+		return logical.ErrorResponse("missing serial_number"), nil
+	}
+	serial, err := normalizeSerial(serialRaw)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	b.revokeLock.Lock()
+	defer b.revokeLock.Unlock()
+
+	certEntry, err := fetchCertEntry(ctx, req.Storage, certsStoragePrefix, serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil {
+		return logical.ErrorResponse("certificate with serial %s not found; only unexpired certificates signed by roles with store_certificates set can be revoked", serialRaw), nil
+	}
+
+	if !certEntry.revoked() {
+		certEntry.RevocationTime = time.Now().UTC()
+
+		for _, prefix := range []string{revokedStoragePrefix, certsStoragePrefix} {
+			entry, err := logical.StorageEntryJSON(prefix+serial, certEntry)
+			if err != nil {
+				return nil, err
+			}
+			if err := req.Storage.Put(ctx, entry); err != nil {
+				return nil, fmt.Errorf("failed to store revocation: %w", err)
+			}
+		}
+	}
+
+	return &logical.Response{
+		Data: certEntry.toMap(),
+	}, nil
+}
+
+// tidyCertificates periodically removes the records and revocations of
+// expired certificates.
+func (b *backend) tidyCertificates(ctx context.Context, req *logical.Request) error {
+	// Only the node holding the writable copy of storage tidies.
+	replicationState := b.System().ReplicationState()
+	if !b.System().LocalMount() && replicationState.HasState(consts.ReplicationPerformanceSecondary|consts.ReplicationPerformanceStandby) {
+		return nil
+	}
+
+	now := time.Now()
+	if now.Sub(b.lastCertTidy) < certTidyInterval {
+		return nil
+	}
+
+	b.revokeLock.Lock()
+	defer b.revokeLock.Unlock()
+
+	for _, prefix := range []string{certsStoragePrefix, revokedStoragePrefix} {
+		serials, err := req.Storage.List(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("failed to list stored certificates: %w", err)
+		}
+
+		for _, serial := range serials {
+			certEntry, err := fetchCertEntry(ctx, req.Storage, prefix, serial)
+			if err != nil {
+				return err
+			}
+			if certEntry != nil && certEntry.ValidBefore.After(now) {
+				continue
+			}
+			if err := req.Storage.Delete(ctx, prefix+serial); err != nil {
+				return fmt.Errorf("failed to remove expired certificate %v: %w", serial, err)
+			}
+		}
+	}
+
+	b.lastCertTidy = now
+	return nil
+}
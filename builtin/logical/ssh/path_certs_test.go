@@ -0,0 +1,185 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ssh
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func createBackendWithStorage(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Backend(config)
+	require.NoError(t, err)
+	require.NoError(t, b.Setup(context.Background(), config))
+	return b, config.StorageView
+}
+
+func handleRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      data,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.False(t, resp != nil && resp.IsError(), "unexpected error response: %v", resp)
+	return resp
+}
+
+func TestSSHBackend_StoredCertificatesAndKRL(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	handleRequest(t, b, s, logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"private_key": testCAPrivateKey,
+	})
+	handleRequest(t, b, s, logical.UpdateOperation, "roles/stored", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"store_certificates":      true,
+	})
+	handleRequest(t, b, s, logical.UpdateOperation, "roles/unstored", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+	})
+
+	resp := handleRequest(t, b, s, logical.ReadOperation, "roles/stored", nil)
+	require.Equal(t, true, resp.Data["store_certificates"])
+
+	// Nothing is revoked yet, so the KRL only carries its header.
+	resp = handleRequest(t, b, s, logical.ReadOperation, "krl", nil)
+	require.Equal(t, krlContentType, resp.Data[logical.HTTPContentType])
+	emptyKRL := resp.Data[logical.HTTPRawBody].([]byte)
+	require.Equal(t, krlMagic, binary.BigEndian.Uint64(emptyKRL))
+	require.Zero(t, binary.BigEndian.Uint64(emptyKRL[12:]), "krl version")
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "sign/stored", map[string]interface{}{
+		"public_key":       testPublicKeyInstall,
+		"valid_principals": "alice",
+	})
+	serial := resp.Data["serial_number"].(string)
+	signedKey := resp.Data["signed_key"].(string)
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "issue/stored", map[string]interface{}{
+		"key_type":         "ed25519",
+		"valid_principals": "bob",
+	})
+	issuedSerial := resp.Data["serial_number"].(string)
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "sign/unstored", map[string]interface{}{
+		"public_key": testPublicKeyInstall,
+	})
+	unstoredSerial := resp.Data["serial_number"].(string)
+
+	resp = handleRequest(t, b, s, logical.ListOperation, "certs/", nil)
+	require.ElementsMatch(t, []string{serial, issuedSerial}, resp.Data["keys"])
+	info := resp.Data["key_info"].(map[string]interface{})[serial].(map[string]interface{})
+	require.Equal(t, "stored", info["role"])
+	require.Equal(t, "user", info["cert_type"])
+	require.Equal(t, []string{"alice"}, info["valid_principals"])
+	require.True(t, strings.HasPrefix(info["key_id"].(string), "vault-"))
+	require.Equal(t, false, info["revoked"])
+
+	// Only stored certificates can be revoked.
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "revoke",
+		Data:      map[string]interface{}{"serial_number": unstoredSerial},
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": strings.ToUpper(serial),
+	})
+	require.Equal(t, true, resp.Data["revoked"])
+	revocationTime := resp.Data["revocation_time"]
+
+	// Revoking again keeps the original revocation time.
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+	require.Equal(t, revocationTime, resp.Data["revocation_time"])
+
+	resp = handleRequest(t, b, s, logical.ReadOperation, "krl", nil)
+	krl := resp.Data[logical.HTTPRawBody].([]byte)
+	require.NotZero(t, binary.BigEndian.Uint64(krl[12:]), "krl version")
+
+	parsedSerial, err := strconv.ParseUint(serial, 16, 64)
+	require.NoError(t, err)
+	serialBytes := binary.BigEndian.AppendUint64(nil, parsedSerial)
+	require.Contains(t, string(krl), string(serialBytes))
+
+	caKey, err := parsePublicSSHKey(testCAPublicKey)
+	require.NoError(t, err)
+	require.Contains(t, string(krl), string(caKey.Marshal()))
+
+	// When available, have OpenSSH confirm the certificate is revoked by the
+	// KRL.
+	if _, err := exec.LookPath("ssh-keygen"); err == nil {
+		dir := t.TempDir()
+		krlPath := filepath.Join(dir, "revoked.krl")
+		certPath := filepath.Join(dir, "key-cert.pub")
+		require.NoError(t, os.WriteFile(krlPath, krl, 0o600))
+		require.NoError(t, os.WriteFile(certPath, []byte(signedKey), 0o600))
+
+		out, err := exec.Command("ssh-keygen", "-Q", "-f", krlPath, certPath).CombinedOutput()
+		require.Error(t, err, "ssh-keygen should report a revoked key: %s", out)
+		require.Contains(t, string(out), "REVOKED")
+	}
+}
+
+func TestSSHBackend_TidyCertificates(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	cert := &ssh.Certificate{
+		Serial:      0xabc,
+		KeyId:       "expired",
+		CertType:    ssh.UserCert,
+		ValidBefore: uint64(time.Now().Add(-time.Minute).Unix()),
+	}
+	require.NoError(t, storeCertificate(ctx, s, "stored", "", cert))
+	cert.Serial = 0xdef
+	cert.KeyId = "valid"
+	cert.ValidBefore = uint64(time.Now().Add(time.Hour).Unix())
+	require.NoError(t, storeCertificate(ctx, s, "stored", "", cert))
+
+	handleRequest(t, b, s, logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": "abc"})
+	handleRequest(t, b, s, logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": "def"})
+
+	revoked, err := listRevokedCerts(ctx, s, time.Now())
+	require.NoError(t, err)
+	require.Len(t, revoked, 1)
+	require.Equal(t, "def", revoked[0].SerialNumber)
+
+	require.NoError(t, b.tidyCertificates(ctx, &logical.Request{Storage: s}))
+
+	for _, prefix := range []string{certsStoragePrefix, revokedStoragePrefix} {
+		serials, err := s.List(ctx, prefix)
+		require.NoError(t, err)
+		require.Equal(t, []string{"def"}, serials)
+	}
+}
@@ -99,30 +99,53 @@ by this mount. The fields must be in the standard private and public SSH format.
 
 For security reasons, the private key cannot be retrieved later.
 
-Read operations will return the public key, if already stored/generated.`,
+Read operations will return the public key, if already stored/generated.
+
+The key pair is stored as the default issuer of the mount; use the issuers/
+endpoints to manage additional CA key pairs.`,
 	}
 }
 
 func (b *backend) pathConfigCARead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+	issuer, err := fetchIssuer(ctx, req.Storage, defaultRef)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CA public key: %w", err)
 	}
 
-	if publicKeyEntry == nil {
+	if issuer == nil {
 		return logical.ErrorResponse("keys haven't been configured yet"), nil
 	}
 
 	response := &logical.Response{
 		Data: map[string]interface{}{
-			"public_key": publicKeyEntry.Key,
+			"public_key": issuer.PublicKey,
 		},
 	}
 
 	return response, nil
 }
 
+// pathConfigCADelete deletes the default issuer, leaving the mount without
+// one until another is configured.
 func (b *backend) pathConfigCADelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DefaultIssuerID != "" {
+		if err := req.Storage.Delete(ctx, issuerStoragePrefix+config.DefaultIssuerID); err != nil {
+			return nil, err
+		}
+		config.DefaultIssuerID = ""
+		if err := setIssuersConfig(ctx, req.Storage, config); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := req.Storage.Delete(ctx, caPrivateKeyStoragePath); err != nil {
 		return nil, err
 	}
@@ -244,51 +267,32 @@ func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request,
 		return nil, fmt.Errorf("failed to generate or parse the keys")
 	}
 
-	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CA public key: %w", err)
-	}
-
-	privateKeyEntry, err := caKey(ctx, req.Storage, caPrivateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CA private key: %w", err)
-	}
-
-	if (publicKeyEntry != nil && publicKeyEntry.Key != "") || (privateKeyEntry != nil && privateKeyEntry.Key != "") {
-		return logical.ErrorResponse("keys are already configured; delete them before reconfiguring"), nil
-	}
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
 
-	entry, err := logical.StorageEntryJSON(caPublicKeyStoragePath, &keyStorageEntry{
-		Key: publicKey,
-	})
+	config, err := getIssuersConfig(ctx, req.Storage)
 	if err != nil {
 		return nil, err
 	}
 
-	// Save the public key
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
-		return nil, err
+	if config.DefaultIssuerID != "" {
+		return logical.ErrorResponse("keys are already configured; delete them before reconfiguring"), nil
 	}
 
-	entry, err = logical.StorageEntryJSON(caPrivateKeyStoragePath, &keyStorageEntry{
-		Key: privateKey,
-	})
+	issuer, err := createIssuer(ctx, req.Storage, "", publicKey, privateKey)
 	if err != nil {
 		return nil, err
 	}
 
-	// Save the private key
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
+	config.DefaultIssuerID = issuer.ID
+	if err := setIssuersConfig(ctx, req.Storage, config); err != nil {
 		var mErr *multierror.Error
 
-		mErr = multierror.Append(mErr, fmt.Errorf("failed to store CA private key: %w", err))
+		mErr = multierror.Append(mErr, err)
 
-		// If storing private key fails, the corresponding public key should be
-		// removed
-		if delErr := req.Storage.Delete(ctx, caPublicKeyStoragePath); delErr != nil {
-			mErr = multierror.Append(mErr, fmt.Errorf("failed to cleanup CA public key: %w", delErr))
+		// If storing the default fails, the new issuer should be removed
+		if delErr := req.Storage.Delete(ctx, issuerStoragePrefix+issuer.ID); delErr != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to cleanup CA key pair: %w", delErr))
 			return nil, mErr
 		}
 
@@ -0,0 +1,176 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ssh
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+// Constants of the OpenSSH key revocation list format, as described in
+// PROTOCOL.krl of the OpenSSH sources.
+const (
+	krlMagic         uint64 = 0x5353484b524c0a00
+	krlFormatVersion uint32 = 1
+
+	krlSectionCertificates   byte = 1
+	krlSectionCertSerialList byte = 0x20
+
+	krlContentType = "application/octet-stream"
+)
+
+func pathFetchKRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `krl`,
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixSSH,
+			OperationSuffix: "krl",
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchKRL,
+		},
+
+		HelpSynopsis: `Retrieve the key revocation list.`,
+		HelpDescription: `This returns, in the binary OpenSSH KRL format, the serial numbers of the
+revoked certificates which have not yet expired, grouped by the CA which signed
+them, including those of deleted issuers. Point the RevokedKeys option of sshd
+at a periodically refreshed copy of it. This is a raw response endpoint
+without JSON encoding; use -format=raw or an external tool (e.g., curl) to
+fetch this value.`,
+	}
+}
+
+func (b *backend) pathFetchKRL(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	revoked, err := listRevokedCerts(ctx, req.Storage, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Certificates are revoked in the section of the CA which signed them.
+	// Revocations of deleted issuers are kept, as servers may still trust
+	// them; only entries recorded without the CA's public key rely on the
+	// issuer still existing.
+	byCA := map[string]int{}
+	var sections []krlCASection
+	for _, certEntry := range revoked {
+		key := certEntry.CAPublicKey
+		if key == "" {
+			issuerId := certEntry.IssuerID
+			if issuerId == "" {
+				issuerId = config.DefaultIssuerID
+			}
+			issuer, err := fetchIssuerByID(ctx, req.Storage, issuerId)
+			if err != nil {
+				return nil, err
+			}
+			if issuer == nil {
+				continue
+			}
+			key = issuer.PublicKey
+		}
+
+		caPublicKey, err := parsePublicSSHKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA public key of certificate %v: %w", certEntry.SerialNumber, err)
+		}
+		marshaled := string(caPublicKey.Marshal())
+		i, ok := byCA[marshaled]
+		if !ok {
+			i = len(sections)
+			byCA[marshaled] = i
+			sections = append(sections, krlCASection{caPublicKey: caPublicKey})
+		}
+		sections[i].revoked = append(sections[i].revoked, certEntry)
+	}
+
+	krl, err := buildKRL(sections, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: krlContentType,
+			logical.HTTPRawBody:     krl,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+// krlCASection holds the revoked certificates signed by one CA.
+type krlCASection struct {
+	caPublicKey ssh.PublicKey
+	revoked     []*sshCertEntry
+}
+
+// buildKRL encodes a key revocation list revoking the given certificates by
+// serial number. The KRL version is the time of the latest revocation, so it
+// only increases as certificates are revoked.
+func buildKRL(sections []krlCASection, now time.Time) ([]byte, error) {
+	var version uint64
+	var body []byte
+
+	// Keep the output stable across requests.
+	sort.Slice(sections, func(i, j int) bool {
+		return string(sections[i].caPublicKey.Marshal()) < string(sections[j].caPublicKey.Marshal())
+	})
+
+	for _, section := range sections {
+		serials := make([]uint64, 0, len(section.revoked))
+		for _, certEntry := range section.revoked {
+			serial, err := strconv.ParseUint(certEntry.SerialNumber, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stored serial number %q: %w", certEntry.SerialNumber, err)
+			}
+			serials = append(serials, serial)
+
+			if revokedAt := uint64(certEntry.RevocationTime.Unix()); revokedAt > version {
+				version = revokedAt
+			}
+		}
+		sort.Slice(serials, func(i, j int) bool { return serials[i] < serials[j] })
+
+		var serialList []byte
+		for _, serial := range serials {
+			serialList = binary.BigEndian.AppendUint64(serialList, serial)
+		}
+
+		sectionData := appendKRLString(nil, section.caPublicKey.Marshal())
+		sectionData = appendKRLString(sectionData, nil) // reserved
+		sectionData = append(sectionData, krlSectionCertSerialList)
+		sectionData = appendKRLString(sectionData, serialList)
+
+		body = append(body, krlSectionCertificates)
+		body = appendKRLString(body, sectionData)
+	}
+
+	krl := binary.BigEndian.AppendUint64(nil, krlMagic)
+	krl = binary.BigEndian.AppendUint32(krl, krlFormatVersion)
+	krl = binary.BigEndian.AppendUint64(krl, version)
+	krl = binary.BigEndian.AppendUint64(krl, uint64(now.Unix()))
+	krl = binary.BigEndian.AppendUint64(krl, 0) // flags
+	krl = appendKRLString(krl, nil)             // reserved
+	krl = appendKRLString(krl, nil)             // comment
+	return append(krl, body...), nil
+}
+
+func appendKRLString(b []byte, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
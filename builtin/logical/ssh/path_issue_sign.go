@@ -101,17 +101,17 @@ func (b *backend) pathSignIssueCertificateHelper(ctx context.Context, req *logic
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	privateKeyEntry, err := caKey(ctx, req.Storage, caPrivateKey)
+	issuer, err := fetchIssuer(ctx, req.Storage, role.issuerRef())
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CA private key: %w", err)
 	}
-	if privateKeyEntry == nil || privateKeyEntry.Key == "" {
-		return nil, errors.New("failed to read CA private key")
+	if issuer == nil {
+		return nil, fmt.Errorf("failed to read CA private key: issuer %q not found", role.issuerRef())
 	}
 
-	signer, err := ssh.ParsePrivateKey([]byte(privateKeyEntry.Key))
+	signer, err := issuer.signer()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse stored CA private key: %w", err)
+		return nil, err
 	}
 
 	cBundle := creationBundle{
@@ -136,10 +136,17 @@ func (b *backend) pathSignIssueCertificateHelper(ctx context.Context, req *logic
 		return nil, errors.New("error marshaling signed certificate")
 	}
 
+	if role.StoreCertificates {
+		if err := storeCertificate(ctx, req.Storage, data.Get("role").(string), issuer.ID, certificate); err != nil {
+			return nil, err
+		}
+	}
+
 	response := &logical.Response{
 		Data: map[string]interface{}{
 			"serial_number": strconv.FormatUint(certificate.Serial, 16),
 			"signed_key":    string(signedSSHCertificate),
+			"issuer_id":     issuer.ID,
 		},
 	}
 
@@ -0,0 +1,618 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	issuerStoragePrefix      = "config/issuer/"
+	issuersConfigStoragePath = "config/issuers"
+
+	// defaultRef refers to the issuer config/issuers points at.
+	defaultRef = "default"
+)
+
+var issuerNameMatcher = regexp.MustCompile("^" + framework.GenericNameRegex("issuer_name") + "$")
+
+// sshIssuerEntry is a named CA key pair. Every issuer is trusted, and so
+// listed by the public_key endpoint; issuers imported without a private
+// key can't sign.
+type sshIssuerEntry struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+type sshIssuersConfig struct {
+	DefaultIssuerID string `json:"default"`
+}
+
+func (i *sshIssuerEntry) toMap(isDefault bool) map[string]interface{} {
+	return map[string]interface{}{
+		"issuer_id":   i.ID,
+		"issuer_name": i.Name,
+		"public_key":  i.PublicKey,
+		"can_sign":    i.PrivateKey != "",
+		"is_default":  isDefault,
+	}
+}
+
+func (i *sshIssuerEntry) signer() (ssh.Signer, error) {
+	if i.PrivateKey == "" {
+		return nil, fmt.Errorf("issuer %v has no private key and can not sign certificates", i.ID)
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(i.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored CA private key: %w", err)
+	}
+	return signer, nil
+}
+
+// getIssuersConfig returns the issuers configuration. Until a CA key pair
+// configured before multiple issuers were supported has been migrated by
+// migrateLegacyCA, the mount has no default issuer.
+func getIssuersConfig(ctx context.Context, s logical.Storage) (*sshIssuersConfig, error) {
+	entry, err := s.Get(ctx, issuersConfigStoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuers configuration: %w", err)
+	}
+	if entry == nil {
+		return &sshIssuersConfig{}, nil
+	}
+
+	var config sshIssuersConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// migrateLegacyCA moves a CA key pair configured before multiple issuers were
+// supported into an issuer of its own, which becomes the default. The legacy
+// entries are only removed once the issuers configuration recording the
+// migration has been stored; an issuer left behind by an interrupted
+// migration is reused rather than duplicated.
+func migrateLegacyCA(ctx context.Context, s logical.Storage) error {
+	entry, err := s.Get(ctx, issuersConfigStoragePath)
+	if err != nil {
+		return fmt.Errorf("failed to read issuers configuration: %w", err)
+	}
+	if entry != nil {
+		return nil
+	}
+
+	publicKeyEntry, err := caKey(ctx, s, caPublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to read CA public key: %w", err)
+	}
+	privateKeyEntry, err := caKey(ctx, s, caPrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to read CA private key: %w", err)
+	}
+	if publicKeyEntry == nil || publicKeyEntry.Key == "" || privateKeyEntry == nil || privateKeyEntry.Key == "" {
+		return nil
+	}
+
+	ids, err := listIssuers(ctx, s)
+	if err != nil {
+		return err
+	}
+	var issuer *sshIssuerEntry
+	for _, id := range ids {
+		existing, err := fetchIssuerByID(ctx, s, id)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.PublicKey == publicKeyEntry.Key && existing.PrivateKey == privateKeyEntry.Key {
+			issuer = existing
+			break
+		}
+	}
+	if issuer == nil {
+		issuer, err = createIssuer(ctx, s, "", publicKeyEntry.Key, privateKeyEntry.Key)
+		if err != nil {
+			return fmt.Errorf("failed to upgrade CA key pair: %w", err)
+		}
+	}
+
+	if err := setIssuersConfig(ctx, s, &sshIssuersConfig{DefaultIssuerID: issuer.ID}); err != nil {
+		return err
+	}
+
+	for _, path := range []string{caPublicKeyStoragePath, caPrivateKeyStoragePath} {
+		if err := s.Delete(ctx, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setIssuersConfig(ctx context.Context, s logical.Storage, config *sshIssuersConfig) error {
+	entry, err := logical.StorageEntryJSON(issuersConfigStoragePath, config)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to store issuers configuration: %w", err)
+	}
+	return nil
+}
+
+func listIssuers(ctx context.Context, s logical.Storage) ([]string, error) {
+	ids, err := s.List(ctx, issuerStoragePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issuers: %w", err)
+	}
+	return ids, nil
+}
+
+func fetchIssuerByID(ctx context.Context, s logical.Storage, id string) (*sshIssuerEntry, error) {
+	entry, err := s.Get(ctx, issuerStoragePrefix+id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuer %v: %w", id, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var issuer sshIssuerEntry
+	if err := entry.DecodeJSON(&issuer); err != nil {
+		return nil, fmt.Errorf("failed to decode issuer %v: %w", id, err)
+	}
+	return &issuer, nil
+}
+
+func writeIssuer(ctx context.Context, s logical.Storage, issuer *sshIssuerEntry) error {
+	entry, err := logical.StorageEntryJSON(issuerStoragePrefix+issuer.ID, issuer)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to store issuer: %w", err)
+	}
+	return nil
+}
+
+// resolveIssuerReference returns the ID of the issuer referred to by ref,
+// which is either "default", an issuer name or an issuer ID. An empty ID is
+// returned when no such issuer exists.
+func resolveIssuerReference(ctx context.Context, s logical.Storage, ref string) (string, error) {
+	if ref == defaultRef {
+		config, err := getIssuersConfig(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		return config.DefaultIssuerID, nil
+	}
+
+	ids, err := listIssuers(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	for _, id := range ids {
+		if id == ref {
+			return id, nil
+		}
+	}
+	for _, id := range ids {
+		issuer, err := fetchIssuerByID(ctx, s, id)
+		if err != nil {
+			return "", err
+		}
+		if issuer != nil && issuer.Name != "" && issuer.Name == ref {
+			return id, nil
+		}
+	}
+
+	return "", nil
+}
+
+// fetchIssuer returns the issuer referred to by ref, or nil when no such
+// issuer exists.
+func fetchIssuer(ctx context.Context, s logical.Storage, ref string) (*sshIssuerEntry, error) {
+	id, err := resolveIssuerReference(ctx, s, ref)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, nil
+	}
+	return fetchIssuerByID(ctx, s, id)
+}
+
+func validateIssuerName(ctx context.Context, s logical.Storage, name, ownID string) error {
+	if name == "" {
+		return nil
+	}
+	if name == defaultRef {
+		return fmt.Errorf("issuer name %q is reserved", defaultRef)
+	}
+	if !issuerNameMatcher.MatchString(name) {
+		return fmt.Errorf("issuer name %q contains invalid characters", name)
+	}
+
+	id, err := resolveIssuerReference(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	if id != "" && id != ownID {
+		return fmt.Errorf("issuer name %q is already in use", name)
+	}
+	return nil
+}
+
+func createIssuer(ctx context.Context, s logical.Storage, name, publicKey, privateKey string) (*sshIssuerEntry, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := &sshIssuerEntry{
+		ID:         id,
+		Name:       name,
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+	}
+	if err := writeIssuer(ctx, s, issuer); err != nil {
+		return nil, err
+	}
+	return issuer, nil
+}
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixSSH,
+			OperationSuffix: "issuers",
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathIssuersList,
+		},
+
+		HelpSynopsis:    `List the CA key pairs of this mount.`,
+		HelpDescription: `This lists the IDs of the issuers of this mount, along with their names and public keys.`,
+	}
+}
+
+func (b *backend) pathIssuersList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := listIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := map[string]interface{}{}
+	for _, id := range ids {
+		issuer, err := fetchIssuerByID(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == nil {
+			continue
+		}
+		keyInfo[id] = issuer.toMap(id == config.DefaultIssuerID)
+	}
+
+	return logical.ListResponseWithInfo(ids, keyInfo), nil
+}
+
+func issuerKeyFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"issuer_name": {
+			Type:        framework.TypeString,
+			Description: `Optional name of the issuer, usable in place of its ID wherever an issuer is referenced.`,
+		},
+		"set_default": {
+			Type:        framework.TypeBool,
+			Description: `If set, the new issuer becomes the default issuer of the mount.`,
+		},
+	}
+}
+
+func pathGenerateIssuer(b *backend) *framework.Path {
+	fields := issuerKeyFields()
+	fields["key_type"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Specifies the desired key type; could be a OpenSSH key type identifier (ssh-rsa, ecdsa-sha2-nistp256, ecdsa-sha2-nistp384, ecdsa-sha2-nistp521, or ssh-ed25519) or an algorithm (rsa, ec, ed25519).`,
+		Default:     "ssh-rsa",
+	}
+	fields["key_bits"] = &framework.FieldSchema{
+		Type:        framework.TypeInt,
+		Description: `Specifies the desired key bits for variable-length keys (such as when key_type="ssh-rsa") or which NIST P-curve to use when key_type="ec" (256, 384, or 521).`,
+		Default:     0,
+	}
+
+	return &framework.Path{
+		Pattern: "issuers/generate",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixSSH,
+			OperationVerb:   "generate",
+			OperationSuffix: "issuer",
+		},
+
+		Fields: fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerGenerate,
+		},
+
+		HelpSynopsis:    `Generate a new CA key pair.`,
+		HelpDescription: `This generates a new issuer, which is immediately trusted but only signs certificates once it is the default issuer or is selected by a role.`,
+	}
+}
+
+func pathImportIssuer(b *backend) *framework.Path {
+	fields := issuerKeyFields()
+	fields["public_key"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Public half of the SSH key.`,
+	}
+	fields["private_key"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Private half of the SSH key; when omitted, the issuer is only trusted and can not sign certificates.`,
+	}
+
+	return &framework.Path{
+		Pattern: "issuers/import",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixSSH,
+			OperationVerb:   "import",
+			OperationSuffix: "issuer",
+		},
+
+		Fields: fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerImport,
+		},
+
+		HelpSynopsis:    `Import an existing CA key pair.`,
+		HelpDescription: `This imports an issuer from an existing key pair, or only its public key to keep trusting a CA whose private key lives elsewhere.`,
+	}
+}
+
+func (b *backend) pathIssuerGenerate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	publicKey, privateKey, err := generateSSHKeyPair(b.Backend.GetRandomReader(), d.Get("key_type").(string), d.Get("key_bits").(int))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.storeNewIssuer(ctx, req, d, publicKey, privateKey)
+}
+
+func (b *backend) pathIssuerImport(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	publicKey := d.Get("public_key").(string)
+	privateKey := d.Get("private_key").(string)
+	if publicKey == "" {
+		return logical.ErrorResponse("missing public_key"), nil
+	}
+
+	parsedPublicKey, err := parsePublicSSHKey(publicKey)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("Unable to parse public_key as an SSH public key: %v", err)), nil
+	}
+
+	if privateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("Unable to parse private_key as an SSH private key: %v", err)), nil
+		}
+		if string(signer.PublicKey().Marshal()) != string(parsedPublicKey.Marshal()) {
+			return logical.ErrorResponse("public_key does not match private_key"), nil
+		}
+	}
+
+	return b.storeNewIssuer(ctx, req, d, publicKey, privateKey)
+}
+
+func (b *backend) storeNewIssuer(ctx context.Context, req *logical.Request, d *framework.FieldData, publicKey, privateKey string) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	name := d.Get("issuer_name").(string)
+	if err := validateIssuerName(ctx, req.Storage, name, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	setDefault := d.Get("set_default").(bool)
+	if setDefault && privateKey == "" {
+		return logical.ErrorResponse("the default issuer must have a private key"), nil
+	}
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := createIssuer(ctx, req.Storage, name, publicKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if setDefault {
+		config.DefaultIssuerID = issuer.ID
+		if err := setIssuersConfig(ctx, req.Storage, config); err != nil {
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: issuer.toMap(issuer.ID == config.DefaultIssuerID),
+	}, nil
+}
+
+func pathIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixSSH,
+			OperationSuffix: "issuer",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": {
+				Type:        framework.TypeString,
+				Description: `Reference to an issuer: "default", an issuer name or an issuer ID.`,
+			},
+			"issuer_name": {
+				Type:        framework.TypeString,
+				Description: `New name of the issuer.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.UpdateOperation: b.pathIssuerUpdate,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis: `Read, rename or delete a CA key pair.`,
+		HelpDescription: `Deleting an issuer stops it from being trusted. Delete an old issuer only
+once every server has fetched a public_key bundle containing its successor and
+certificates signed by it have expired; revocations of its certificates remain
+in the KRL until then. The default issuer can not be deleted.`,
+	}
+}
+
+func (b *backend) pathIssuerRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := fetchIssuer(ctx, req.Storage, d.Get("issuer_ref").(string))
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: issuer.toMap(issuer.ID == config.DefaultIssuerID),
+	}, nil
+}
+
+func (b *backend) pathIssuerUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := d.Get("issuer_ref").(string)
+	issuer, err := fetchIssuer(ctx, req.Storage, ref)
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return logical.ErrorResponse("unknown issuer %q", ref), nil
+	}
+
+	if nameRaw, ok := d.GetOk("issuer_name"); ok {
+		name := nameRaw.(string)
+		if err := validateIssuerName(ctx, req.Storage, name, issuer.ID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		issuer.Name = name
+	}
+
+	if err := writeIssuer(ctx, req.Storage, issuer); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: issuer.toMap(issuer.ID == config.DefaultIssuerID),
+	}, nil
+}
+
+func (b *backend) pathIssuerDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := d.Get("issuer_ref").(string)
+	id, err := resolveIssuerReference(ctx, req.Storage, ref)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, nil
+	}
+	if id == config.DefaultIssuerID {
+		return logical.ErrorResponse("issuer %q is the default issuer; set another default issuer or delete config/ca first", ref), nil
+	}
+
+	if err := req.Storage.Delete(ctx, issuerStoragePrefix+id); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// trustedPublicKeys returns the public keys of all issuers, the default
+// issuer first, in authorized_keys format.
+func trustedPublicKeys(ctx context.Context, s logical.Storage) (string, error) {
+	config, err := getIssuersConfig(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	ids, err := listIssuers(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	var keys []string
+	for _, id := range ids {
+		issuer, err := fetchIssuerByID(ctx, s, id)
+		if err != nil {
+			return "", err
+		}
+		if issuer == nil {
+			continue
+		}
+
+		key := strings.TrimSpace(issuer.PublicKey)
+		if id == config.DefaultIssuerID {
+			keys = append([]string{key}, keys...)
+		} else {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return "", nil
+	}
+	return strings.Join(keys, "\n") + "\n", nil
+}
@@ -0,0 +1,274 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ssh
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func requestError(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) string {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      data,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.True(t, resp.IsError(), "expected an error response, got: %v", resp)
+	return resp.Error().Error()
+}
+
+func signingKeyOf(t *testing.T, signedKey string) ssh.PublicKey {
+	t.Helper()
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(signedKey))
+	require.NoError(t, err)
+	return key.(*ssh.Certificate).SignatureKey
+}
+
+func TestSSH_IssuersLegacyUpgrade(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	// A key pair stored by config/ca before multiple issuers existed
+	for path, key := range map[string]string{
+		caPublicKeyStoragePath:  testCAPublicKey,
+		caPrivateKeyStoragePath: testCAPrivateKey,
+	} {
+		entry, err := logical.StorageEntryJSON(path, &keyStorageEntry{Key: key})
+		require.NoError(t, err)
+		require.NoError(t, s.Put(ctx, entry))
+	}
+
+	// Requests leave storage alone; the migration happens on initialization
+	resp := handleRequest(t, b, s, logical.ReadOperation, "config/issuers", nil)
+	require.Empty(t, resp.Data["default"])
+	ids, err := listIssuers(ctx, s)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	// An interrupted migration may have left an issuer behind, which is
+	// reused
+	leftover, err := createIssuer(ctx, s, "", testCAPublicKey, testCAPrivateKey)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Initialize(ctx, &logical.InitializationRequest{Storage: s}))
+
+	resp = handleRequest(t, b, s, logical.ReadOperation, "config/issuers", nil)
+	require.Equal(t, leftover.ID, resp.Data["default"])
+	ids, err = listIssuers(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, []string{leftover.ID}, ids)
+
+	resp = handleRequest(t, b, s, logical.ReadOperation, "issuer/default", nil)
+	require.Equal(t, leftover.ID, resp.Data["issuer_id"])
+	require.Equal(t, testCAPublicKey, resp.Data["public_key"])
+
+	for _, path := range []string{caPublicKeyStoragePath, caPrivateKeyStoragePath} {
+		entry, err := s.Get(ctx, path)
+		require.NoError(t, err)
+		require.Nil(t, entry)
+	}
+
+	resp = handleRequest(t, b, s, logical.ReadOperation, "config/ca", nil)
+	require.Equal(t, testCAPublicKey, resp.Data["public_key"])
+
+	// Initializing again is a no-op
+	require.NoError(t, b.Initialize(ctx, &logical.InitializationRequest{Storage: s}))
+	ids, err = listIssuers(ctx, s)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+}
+
+func TestSSH_IssuersLegacyUpgradeStandby(t *testing.T) {
+	ctx := context.Background()
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		ReplicationStateVal: consts.ReplicationPerformanceStandby,
+	}
+	b, err := Backend(config)
+	require.NoError(t, err)
+	require.NoError(t, b.Setup(ctx, config))
+	s := config.StorageView
+
+	for path, key := range map[string]string{
+		caPublicKeyStoragePath:  testCAPublicKey,
+		caPrivateKeyStoragePath: testCAPrivateKey,
+	} {
+		entry, err := logical.StorageEntryJSON(path, &keyStorageEntry{Key: key})
+		require.NoError(t, err)
+		require.NoError(t, s.Put(ctx, entry))
+	}
+
+	// Only the active node migrates the key pair
+	require.NoError(t, b.Initialize(ctx, &logical.InitializationRequest{Storage: s}))
+	ids, err := listIssuers(ctx, s)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+	for _, path := range []string{caPublicKeyStoragePath, caPrivateKeyStoragePath} {
+		entry, err := s.Get(ctx, path)
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+	}
+}
+
+func TestSSH_IssuersRotation(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp := handleRequest(t, b, s, logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"private_key": testCAPrivateKey,
+	})
+	require.Nil(t, resp)
+	oldKey, err := parsePublicSSHKey(testCAPublicKey)
+	require.NoError(t, err)
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "issuers/generate", map[string]interface{}{
+		"issuer_name": "next",
+		"key_type":    "ed25519",
+	})
+	nextId := resp.Data["issuer_id"].(string)
+	require.Equal(t, false, resp.Data["is_default"])
+	nextKey, err := parsePublicSSHKey(resp.Data["public_key"].(string))
+	require.NoError(t, err)
+
+	require.Contains(t, requestError(t, b, s, logical.UpdateOperation, "issuers/generate", map[string]interface{}{
+		"issuer_name": "next",
+		"key_type":    "ed25519",
+	}), "already in use")
+	require.Contains(t, requestError(t, b, s, logical.UpdateOperation, "issuers/generate", map[string]interface{}{
+		"issuer_name": "default",
+	}), "reserved")
+
+	// Both CAs are trusted, the default one first.
+	resp = handleRequest(t, b, s, logical.ReadOperation, "public_key", nil)
+	trusted := strings.Split(strings.TrimSpace(string(resp.Data[logical.HTTPRawBody].([]byte))), "\n")
+	require.Len(t, trusted, 2)
+	require.Equal(t, strings.TrimSpace(testCAPublicKey), trusted[0])
+
+	resp = handleRequest(t, b, s, logical.ListOperation, "issuers/", nil)
+	require.Len(t, resp.Data["keys"], 2)
+	require.Equal(t, "next", resp.Data["key_info"].(map[string]interface{})[nextId].(map[string]interface{})["issuer_name"])
+
+	roleData := map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+	}
+	handleRequest(t, b, s, logical.UpdateOperation, "roles/current", roleData)
+	roleData["issuer_ref"] = "next"
+	handleRequest(t, b, s, logical.UpdateOperation, "roles/canary", roleData)
+	roleData["issuer_ref"] = "missing"
+	require.Contains(t, requestError(t, b, s, logical.UpdateOperation, "roles/broken", roleData), "unknown issuer_ref")
+
+	resp = handleRequest(t, b, s, logical.ReadOperation, "roles/current", nil)
+	require.Equal(t, "default", resp.Data["issuer_ref"])
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "sign/current", map[string]interface{}{"public_key": testPublicKeyInstall})
+	require.Equal(t, oldKey.Marshal(), signingKeyOf(t, resp.Data["signed_key"].(string)).Marshal())
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "sign/canary", map[string]interface{}{"public_key": testPublicKeyInstall})
+	require.Equal(t, nextKey.Marshal(), signingKeyOf(t, resp.Data["signed_key"].(string)).Marshal())
+	require.Equal(t, nextId, resp.Data["issuer_id"])
+
+	// Rotate the default; roles following it sign with the new key.
+	require.Contains(t, requestError(t, b, s, logical.DeleteOperation, "issuer/default", nil), "default issuer")
+	resp = handleRequest(t, b, s, logical.ReadOperation, "config/issuers", nil)
+	oldId := resp.Data["default"].(string)
+	handleRequest(t, b, s, logical.UpdateOperation, "config/issuers", map[string]interface{}{"default": "next"})
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "sign/current", map[string]interface{}{"public_key": testPublicKeyInstall})
+	require.Equal(t, nextKey.Marshal(), signingKeyOf(t, resp.Data["signed_key"].(string)).Marshal())
+
+	resp = handleRequest(t, b, s, logical.ReadOperation, "public_key", nil)
+	trusted = strings.Split(strings.TrimSpace(string(resp.Data[logical.HTTPRawBody].([]byte))), "\n")
+	require.Equal(t, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(nextKey))), trusted[0])
+
+	// Retire the old CA.
+	handleRequest(t, b, s, logical.DeleteOperation, "issuer/"+oldId, nil)
+	resp = handleRequest(t, b, s, logical.ReadOperation, "public_key", nil)
+	require.Equal(t, string(ssh.MarshalAuthorizedKey(nextKey)), string(resp.Data[logical.HTTPRawBody].([]byte)))
+}
+
+func TestSSH_IssuersImportPublicKeyOnly(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	handleRequest(t, b, s, logical.UpdateOperation, "config/ca", nil)
+
+	require.Contains(t, requestError(t, b, s, logical.UpdateOperation, "issuers/import", map[string]interface{}{
+		"public_key":  testCAPublicKeyEd25519,
+		"private_key": testCAPrivateKey,
+	}), "does not match")
+	require.Contains(t, requestError(t, b, s, logical.UpdateOperation, "issuers/import", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"set_default": true,
+	}), "must have a private key")
+
+	resp := handleRequest(t, b, s, logical.UpdateOperation, "issuers/import", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"issuer_name": "external",
+	})
+	require.Equal(t, false, resp.Data["can_sign"])
+
+	require.Contains(t, requestError(t, b, s, logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default": "external",
+	}), "must have a private key")
+
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "issuer/external", map[string]interface{}{
+		"issuer_name": "legacy-ca",
+	})
+	require.Equal(t, "legacy-ca", resp.Data["issuer_name"])
+
+	resp = handleRequest(t, b, s, logical.ReadOperation, "public_key", nil)
+	require.Contains(t, string(resp.Data[logical.HTTPRawBody].([]byte)), strings.TrimSpace(testCAPublicKey))
+}
+
+func TestSSH_IssuersDeletedKeepRevocations(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	handleRequest(t, b, s, logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"private_key": testCAPrivateKey,
+	})
+	resp := handleRequest(t, b, s, logical.UpdateOperation, "issuers/generate", map[string]interface{}{
+		"issuer_name": "retired",
+		"key_type":    "ed25519",
+	})
+	retiredKey, err := parsePublicSSHKey(resp.Data["public_key"].(string))
+	require.NoError(t, err)
+
+	handleRequest(t, b, s, logical.UpdateOperation, "roles/stored", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"store_certificates":      true,
+		"issuer_ref":              "retired",
+	})
+	resp = handleRequest(t, b, s, logical.UpdateOperation, "sign/stored", map[string]interface{}{"public_key": testPublicKeyInstall})
+	serial := resp.Data["serial_number"].(string)
+	handleRequest(t, b, s, logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": serial})
+
+	// Servers may still trust the deleted issuer, so its revocations stay
+	// in the KRL until the certificates expire.
+	handleRequest(t, b, s, logical.DeleteOperation, "issuer/retired", nil)
+	resp = handleRequest(t, b, s, logical.ReadOperation, "krl", nil)
+	krl := string(resp.Data[logical.HTTPRawBody].([]byte))
+
+	parsedSerial, err := strconv.ParseUint(serial, 16, 64)
+	require.NoError(t, err)
+	require.Contains(t, krl, string(binary.BigEndian.AppendUint64(nil, parsedSerial)))
+	require.Contains(t, krl, string(retiredKey.Marshal()))
+}
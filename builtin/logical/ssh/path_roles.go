@@ -66,6 +66,17 @@ type sshRole struct {
 	AlgorithmSigner            string            `mapstructure:"algorithm_signer" json:"algorithm_signer"`
 	Version                    int               `mapstructure:"role_version" json:"role_version"`
 	NotBeforeDuration          time.Duration     `mapstructure:"not_before_duration" json:"not_before_duration"`
+	StoreCertificates          bool              `mapstructure:"store_certificates" json:"store_certificates"`
+	IssuerRef                  string            `mapstructure:"issuer_ref" json:"issuer_ref"`
+}
+
+// issuerRef returns the issuer of the role; roles written before issuers
+// could be selected use the default issuer.
+func (r *sshRole) issuerRef() string {
+	if r.IssuerRef == "" {
+		return defaultRef
+	}
+	return r.IssuerRef
 }
 
 func pathListRoles(b *backend) *framework.Path {
@@ -363,6 +374,24 @@ func pathRoles(b *backend) *framework.Path {
 					Value: 30,
 				},
 			},
+			"store_certificates": {
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for OTP type] [Optional for CA type]
+				If set, the serial number and key ID of certificates signed by this role are
+				stored until they expire, so that they can be listed and revoked.
+				`,
+				Default: false,
+			},
+			"issuer_ref": {
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for OTP type] [Optional for CA type]
+				Reference (name or ID) to the issuer which signs certificates for this role.
+				Defaults to the mount's default issuer.
+				`,
+				Default: defaultRef,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -460,6 +489,16 @@ func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *fr
 		if errorResponse != nil {
 			return errorResponse, nil
 		}
+
+		if role.IssuerRef != defaultRef {
+			issuerId, err := resolveIssuerReference(ctx, req.Storage, role.IssuerRef)
+			if err != nil {
+				return nil, err
+			}
+			if issuerId == "" {
+				return logical.ErrorResponse("unknown issuer_ref %q", role.IssuerRef), nil
+			}
+		}
 		roleEntry = *role
 	} else {
 		return logical.ErrorResponse("invalid key type"), nil
@@ -499,6 +538,8 @@ func (b *backend) createCARole(allowedUsers, defaultUser, signer string, data *f
 		AlgorithmSigner:           signer,
 		Version:                   roleEntryVersion,
 		NotBeforeDuration:         time.Duration(data.Get("not_before_duration").(int)) * time.Second,
+		StoreCertificates:         data.Get("store_certificates").(bool),
+		IssuerRef:                 data.Get("issuer_ref").(string),
 	}
 
 	if !role.AllowUserCertificates && !role.AllowHostCertificates {
@@ -587,17 +628,17 @@ func (b *backend) checkUpgrade(ctx context.Context, s logical.Storage, n string,
 		// signing key type as we want to make ssh-rsa an explicitly notated
 		// algorithm choice.
 		var publicKey ssh.PublicKey
-		publicKeyEntry, err := caKey(ctx, s, caPublicKey)
+		issuer, err := fetchIssuer(ctx, s, defaultRef)
 		if err != nil {
 			b.Logger().Debug(fmt.Sprintf("failed to load public key entry while attempting to migrate: %v", err))
 			goto SKIPVERSION2
 		}
-		if publicKeyEntry == nil || publicKeyEntry.Key == "" {
+		if issuer == nil || issuer.PublicKey == "" {
 			b.Logger().Debug(fmt.Sprintf("got empty public key entry while attempting to migrate"))
 			goto SKIPVERSION2
 		}
 
-		publicKey, err = parsePublicSSHKey(publicKeyEntry.Key)
+		publicKey, err = parsePublicSSHKey(issuer.PublicKey)
 		if err == nil {
 			// Move an empty signing algorithm to an explicit ssh-rsa (SHA-1)
 			// if this key is of type RSA. This isn't a secure default but
@@ -691,6 +732,8 @@ func (b *backend) parseRole(role *sshRole) (map[string]interface{}, error) {
 			"allowed_user_key_lengths":    role.AllowedUserKeyTypesLengths,
 			"algorithm_signer":            role.AlgorithmSigner,
 			"not_before_duration":         int64(role.NotBeforeDuration.Seconds()),
+			"store_certificates":          role.StoreCertificates,
+			"issuer_ref":                  role.issuerRef(),
 		}
 	case KeyTypeDynamic:
 		return nil, fmt.Errorf("dynamic key type roles are no longer supported")
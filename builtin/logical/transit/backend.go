@@ -49,6 +49,12 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 				"archive/",
 				"policy/",
 			},
+
+			// Streams are passed through as raw request bodies
+			Streaming: []string{
+				"encrypt-stream/*",
+				"decrypt-stream/*",
+			},
 		},
 
 		Paths: []*framework.Path{
@@ -66,6 +72,8 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathKeysConfig(),
 			b.pathEncrypt(),
 			b.pathDecrypt(),
+			b.pathEncryptStream(),
+			b.pathDecryptStream(),
 			b.pathDatakey(),
 			b.pathRandom(),
 			b.pathHash(),
@@ -65,6 +65,29 @@ convergent encryption is enabled for this key and the key was generated with
 Vault 0.6.1. Not required for keys created in 0.6.2+.`,
 			},
 
+			"format": {
+				Type: framework.TypeString,
+				Description: `
+The name of the alphabet used during encryption with a format-preserving
+encryption key. Required for, and only valid with, format-preserving
+encryption keys.`,
+			},
+
+			"tweak": {
+				Type: framework.TypeString,
+				Description: `
+Base64 encoded tweak used during encryption with a format-preserving
+encryption key.`,
+			},
+
+			"key_version": {
+				Type: framework.TypeInt,
+				Description: `
+The version of the key used during encryption with a format-preserving
+encryption key, as returned by encrypt. Required for format-preserving
+encryption keys, as their ciphertexts do not carry the key version.`,
+			},
+
 			"partial_failure_response_code": {
 				Type: framework.TypeInt,
 				Description: `
@@ -129,6 +152,9 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, d
 			Context:        d.Get("context").(string),
 			Nonce:          d.Get("nonce").(string),
 			AssociatedData: d.Get("associated_data").(string),
+			Format:         d.Get("format").(string),
+			Tweak:          d.Get("tweak").(string),
+			KeyVersion:     d.Get("key_version").(int),
 		}
 	}
 
@@ -168,6 +194,16 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, d
 				continue
 			}
 		}
+
+		// Decode the tweak
+		if len(item.Tweak) != 0 {
+			batchInputItems[i].DecodedTweak, err = base64.StdEncoding.DecodeString(item.Tweak)
+			if err != nil {
+				userErrorInBatch = true
+				batchResponseItems[i].Error = err.Error()
+				continue
+			}
+		}
 	}
 
 	// Get the policy
@@ -192,6 +228,32 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, d
 			continue
 		}
 
+		if p.Type.FormatPreservingEncryptionSupported() {
+			plaintext, err := p.DecryptFPE(item.KeyVersion, item.DecodedContext, item.Ciphertext, keysutil.FPEOpts{
+				Format: item.Format,
+				Tweak:  item.DecodedTweak,
+			})
+			if err != nil {
+				switch err.(type) {
+				case errutil.InternalError:
+					internalErrorInBatch = true
+				default:
+					userErrorInBatch = true
+				}
+				batchResponseItems[i].Error = err.Error()
+				continue
+			}
+			successesInBatch = true
+			batchResponseItems[i].Plaintext = plaintext
+			continue
+		}
+
+		if item.Format != "" || item.Tweak != "" {
+			userErrorInBatch = true
+			batchResponseItems[i].Error = fmt.Sprintf("format and tweak are only valid for format-preserving encryption keys, not %v", p.Type)
+			continue
+		}
+
 		var factory interface{}
 		if item.AssociatedData != "" {
 			if !p.Type.AssociatedDataSupported() {
@@ -49,6 +49,15 @@ type BatchRequestItem struct {
 	// Reference is an arbitrary caller supplied string value that will be placed on the
 	// batch response to ease correlation between inputs and outputs
 	Reference string `json:"reference" structs:"reference" mapstructure:"reference"`
+
+	// Format names the alphabet used with format-preserving encryption keys
+	Format string `json:"format" structs:"format" mapstructure:"format"`
+
+	// Tweak for format-preserving encryption keys
+	Tweak string `json:"tweak" structs:"tweak" mapstructure:"tweak"`
+
+	// DecodedTweak is the base64 decoded version of Tweak
+	DecodedTweak []byte
 }
 
 // EncryptBatchResponseItem represents a response item for batch processing
@@ -127,7 +136,27 @@ encryption key) this nonce value is **never reused**.
 				Description: `
 This parameter is required when encryption key is expected to be created.
 When performing an upsert operation, the type of key to create. Currently,
-"aes128-gcm96" (symmetric) and "aes256-gcm96" (symmetric) are the only types supported. Defaults to "aes256-gcm96".`,
+"aes128-gcm96" (symmetric), "aes256-gcm96" (symmetric), "aes256-ff1" and
+"aes256-ff3-1" (format-preserving) are the only types supported. Defaults to "aes256-gcm96".`,
+			},
+
+			"format": {
+				Type: framework.TypeString,
+				Description: `
+The name of the alphabet to use with format-preserving encryption keys, either
+one of the built-in "numeric", "alphanumeric-lower", "alphanumeric-upper" and
+"alphanumeric" alphabets or one configured on the key. Characters of the
+plaintext outside of the alphabet are left in place. Required for, and only
+valid with, format-preserving encryption keys.`,
+			},
+
+			"tweak": {
+				Type: framework.TypeString,
+				Description: `
+Base64 encoded tweak for format-preserving encryption keys. For "aes256-ff3-1"
+it must be exactly 56 bits (7 bytes) long; for "aes256-ff1" it may be up to
+256 bytes long. Defaults to an empty (all-zero for "aes256-ff3-1") tweak. Not
+allowed with convergent keys, which derive the tweak from the context.`,
 			},
 
 			"convergent_encryption": {
@@ -302,6 +331,24 @@ func decodeBatchRequestItems(src interface{}, requirePlaintext bool, requireCiph
 				errs.Errors = append(errs.Errors, fmt.Sprintf("'[%d].reference' expected type 'string', got unconvertible type '%T'", i, item["reference"]))
 			}
 		}
+
+		if v, has := item["format"]; has {
+			if !reflect.ValueOf(v).IsValid() {
+			} else if casted, ok := v.(string); ok {
+				(*dst)[i].Format = casted
+			} else {
+				errs.Errors = append(errs.Errors, fmt.Sprintf("'[%d].format' expected type 'string', got unconvertible type '%T'", i, item["format"]))
+			}
+		}
+
+		if v, has := item["tweak"]; has {
+			if !reflect.ValueOf(v).IsValid() {
+			} else if casted, ok := v.(string); ok {
+				(*dst)[i].Tweak = casted
+			} else {
+				errs.Errors = append(errs.Errors, fmt.Sprintf("'[%d].tweak' expected type 'string', got unconvertible type '%T'", i, item["tweak"]))
+			}
+		}
 	}
 
 	if len(errs.Errors) > 0 {
@@ -357,6 +404,8 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, d
 			Nonce:          d.Get("nonce").(string),
 			KeyVersion:     d.Get("key_version").(int),
 			AssociatedData: d.Get("associated_data").(string),
+			Format:         d.Get("format").(string),
+			Tweak:          d.Get("tweak").(string),
 		}
 	}
 
@@ -401,6 +450,16 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, d
 				continue
 			}
 		}
+
+		// Decode the tweak
+		if len(item.Tweak) != 0 {
+			batchInputItems[i].DecodedTweak, err = base64.StdEncoding.DecodeString(item.Tweak)
+			if err != nil {
+				userErrorInBatch = true
+				batchResponseItems[i].Error = err.Error()
+				continue
+			}
+		}
 	}
 
 	// Get the policy
@@ -435,6 +494,10 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, d
 			polReq.KeyType = keysutil.KeyType_AES256_GCM96
 		case "chacha20-poly1305":
 			polReq.KeyType = keysutil.KeyType_ChaCha20_Poly1305
+		case "aes256-ff1":
+			polReq.KeyType = keysutil.KeyType_AES256_FF1
+		case "aes256-ff3-1":
+			polReq.KeyType = keysutil.KeyType_AES256_FF3_1
 		case "ecdsa-p256", "ecdsa-p384", "ecdsa-p521":
 			return logical.ErrorResponse(fmt.Sprintf("key type %v not supported for this operation", keyType)), logical.ErrInvalidRequest
 		case "managed_key":
@@ -482,6 +545,34 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, d
 			warnAboutNonceUsage = true
 		}
 
+		if p.Type.FormatPreservingEncryptionSupported() {
+			ciphertext, keyVersion, err := p.EncryptFPE(item.KeyVersion, item.DecodedContext, item.Plaintext, keysutil.FPEOpts{
+				Format: item.Format,
+				Tweak:  item.DecodedTweak,
+			})
+			if err != nil {
+				switch err.(type) {
+				case errutil.InternalError:
+					internalErrorInBatch = true
+				default:
+					userErrorInBatch = true
+				}
+				batchResponseItems[i].Error = err.Error()
+				continue
+			}
+
+			successesInBatch = true
+			batchResponseItems[i].Ciphertext = ciphertext
+			batchResponseItems[i].KeyVersion = keyVersion
+			continue
+		}
+
+		if item.Format != "" || item.Tweak != "" {
+			userErrorInBatch = true
+			batchResponseItems[i].Error = fmt.Sprintf("format and tweak are only valid for format-preserving encryption keys, not %v", p.Type)
+			continue
+		}
+
 		var factory interface{}
 		if item.AssociatedData != "" {
 			if !p.Type.AssociatedDataSupported() {
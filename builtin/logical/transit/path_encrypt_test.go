@@ -5,6 +5,7 @@ package transit
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -1065,3 +1066,155 @@ func TestTransit_EncryptWithRSAPublicKey(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTransit_FormatPreservingEncryption(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	handle := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error for %s, resp:%#v", path, resp)
+		}
+	}
+
+	for _, keyType := range []string{"aes256-ff1", "aes256-ff3-1"} {
+		keyPath := "keys/" + keyType
+		handle(keyPath, map[string]interface{}{"type": keyType})
+
+		// A card number keeps its length and separators
+		plaintext := base64.StdEncoding.EncodeToString([]byte("4111-1111-1111-1111"))
+		resp := handle("encrypt/"+keyType, map[string]interface{}{
+			"plaintext": plaintext,
+			"format":    "numeric",
+		})
+		ciphertext := resp.Data["ciphertext"].(string)
+		if len(ciphertext) != 19 || ciphertext[4] != '-' || ciphertext == "4111-1111-1111-1111" {
+			t.Fatalf("format not preserved: %q", ciphertext)
+		}
+		if resp.Data["key_version"].(int) != 1 {
+			t.Fatalf("bad key_version: %#v", resp.Data)
+		}
+
+		resp = handle("decrypt/"+keyType, map[string]interface{}{
+			"ciphertext":  ciphertext,
+			"format":      "numeric",
+			"key_version": 1,
+		})
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("bad plaintext: %#v", resp.Data)
+		}
+
+		// Older versions decrypt with their key_version after rotation
+		handle(keyPath+"/rotate", nil)
+		resp = handle("decrypt/"+keyType, map[string]interface{}{
+			"ciphertext":  ciphertext,
+			"format":      "numeric",
+			"key_version": 1,
+		})
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("bad plaintext: %#v", resp.Data)
+		}
+
+		// Custom alphabets are configured on the key and cannot change
+		handle(keyPath+"/config", map[string]interface{}{
+			"fpe_alphabets": map[string]interface{}{"hex": "0123456789abcdef"},
+		})
+		expectError(keyPath+"/config", map[string]interface{}{
+			"fpe_alphabets": map[string]interface{}{"hex": "0123456789ABCDEF"},
+		})
+		expectError(keyPath+"/config", map[string]interface{}{
+			"fpe_alphabets": map[string]interface{}{"numeric": "0123456789"},
+		})
+		expectError(keyPath+"/config", map[string]interface{}{
+			"fpe_alphabets": map[string]interface{}{"dup": "00"},
+		})
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      keyPath,
+			Storage:   s,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		if alphabets := resp.Data["fpe_alphabets"].(map[string]string); alphabets["hex"] != "0123456789abcdef" {
+			t.Fatalf("bad fpe_alphabets: %#v", resp.Data)
+		}
+
+		tweak := base64.StdEncoding.EncodeToString([]byte("1234567"))
+		hexPlaintext := base64.StdEncoding.EncodeToString([]byte("deadbeefcafe"))
+		resp = handle("encrypt/"+keyType, map[string]interface{}{
+			"batch_input": []interface{}{
+				map[string]interface{}{"plaintext": hexPlaintext, "format": "hex", "tweak": tweak},
+			},
+		})
+		batchResults := resp.Data["batch_results"].([]EncryptBatchResponseItem)
+		if batchResults[0].Error != "" || batchResults[0].KeyVersion != 2 {
+			t.Fatalf("bad batch results: %#v", batchResults)
+		}
+		resp = handle("decrypt/"+keyType, map[string]interface{}{
+			"ciphertext":  batchResults[0].Ciphertext,
+			"format":      "hex",
+			"tweak":       tweak,
+			"key_version": batchResults[0].KeyVersion,
+		})
+		if resp.Data["plaintext"] != hexPlaintext {
+			t.Fatalf("bad plaintext: %#v", resp.Data)
+		}
+
+		expectError("decrypt/"+keyType, map[string]interface{}{"ciphertext": ciphertext, "format": "numeric"})
+		expectError("rewrap/"+keyType, map[string]interface{}{"ciphertext": ciphertext})
+		expectError("encrypt/"+keyType, map[string]interface{}{"plaintext": plaintext})
+		expectError("encrypt/"+keyType, map[string]interface{}{"plaintext": plaintext, "format": "unknown"})
+	}
+
+	// Convergent keys derive the tweak from the context
+	handle("keys/convergent-fpe", map[string]interface{}{
+		"type":                  "aes256-ff3-1",
+		"derived":               true,
+		"convergent_encryption": true,
+	})
+	plaintext := base64.StdEncoding.EncodeToString([]byte("123-45-6789"))
+	encContext := base64.StdEncoding.EncodeToString([]byte("ssn"))
+	first := handle("encrypt/convergent-fpe", map[string]interface{}{
+		"plaintext": plaintext,
+		"context":   encContext,
+		"format":    "numeric",
+	}).Data["ciphertext"]
+	second := handle("encrypt/convergent-fpe", map[string]interface{}{
+		"plaintext": plaintext,
+		"context":   encContext,
+		"format":    "numeric",
+	}).Data["ciphertext"]
+	if first != second {
+		t.Fatalf("expected convergent ciphertexts, got %q and %q", first, second)
+	}
+	expectError("encrypt/convergent-fpe", map[string]interface{}{
+		"plaintext": plaintext,
+		"context":   encContext,
+		"format":    "numeric",
+		"tweak":     base64.StdEncoding.EncodeToString([]byte("1234567")),
+	})
+
+	// Format and tweak are rejected for other key types
+	handle("keys/gcm", nil)
+	expectError("encrypt/gcm", map[string]interface{}{"plaintext": plaintext, "format": "numeric"})
+}
@@ -65,6 +65,8 @@ func TestTransit_Export_KeyVersion_ExportsCorrectVersion(t *testing.T) {
 	verifyExportsCorrectVersion(t, "encryption-key", "aes128-gcm96")
 	verifyExportsCorrectVersion(t, "encryption-key", "aes256-gcm96")
 	verifyExportsCorrectVersion(t, "encryption-key", "chacha20-poly1305")
+	verifyExportsCorrectVersion(t, "encryption-key", "aes256-ff1")
+	verifyExportsCorrectVersion(t, "encryption-key", "aes256-ff3-1")
 	verifyExportsCorrectVersion(t, "encryption-key", "rsa-2048")
 	verifyExportsCorrectVersion(t, "encryption-key", "rsa-3072")
 	verifyExportsCorrectVersion(t, "encryption-key", "rsa-4096")
@@ -78,6 +80,8 @@ func TestTransit_Export_KeyVersion_ExportsCorrectVersion(t *testing.T) {
 	verifyExportsCorrectVersion(t, "hmac-key", "aes128-gcm96")
 	verifyExportsCorrectVersion(t, "hmac-key", "aes256-gcm96")
 	verifyExportsCorrectVersion(t, "hmac-key", "chacha20-poly1305")
+	verifyExportsCorrectVersion(t, "hmac-key", "aes256-ff1")
+	verifyExportsCorrectVersion(t, "hmac-key", "aes256-ff3-1")
 	verifyExportsCorrectVersion(t, "hmac-key", "ecdsa-p256")
 	verifyExportsCorrectVersion(t, "hmac-key", "ecdsa-p384")
 	verifyExportsCorrectVersion(t, "hmac-key", "ecdsa-p521")
@@ -65,6 +65,15 @@ the latest version of the key is allowed.`,
 being automatically rotated. A value of 0
 disables automatic rotation for the key.`,
 			},
+
+			"fpe_alphabets": {
+				Type: framework.TypeKVPairs,
+				Description: `Named alphabets usable as format with
+format-preserving encryption keys, in addition
+to the built-in "numeric", "alphanumeric-lower",
+"alphanumeric-upper" and "alphanumeric" ones.
+Alphabets cannot be changed once added.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -104,6 +113,7 @@ func (b *backend) pathKeysConfigWrite(ctx context.Context, req *logical.Request,
 	originalDeletionAllowed := p.DeletionAllowed
 	originalExportable := p.Exportable
 	originalAllowPlaintextBackup := p.AllowPlaintextBackup
+	originalFPEAlphabets := p.FPEAlphabets
 
 	defer func() {
 		if retErr != nil || (resp != nil && resp.IsError()) {
@@ -112,6 +122,7 @@ func (b *backend) pathKeysConfigWrite(ctx context.Context, req *logical.Request,
 			p.DeletionAllowed = originalDeletionAllowed
 			p.Exportable = originalExportable
 			p.AllowPlaintextBackup = originalAllowPlaintextBackup
+			p.FPEAlphabets = originalFPEAlphabets
 		}
 	}()
 
@@ -224,6 +235,37 @@ func (b *backend) pathKeysConfigWrite(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	fpeAlphabetsRaw, ok := d.GetOk("fpe_alphabets")
+	if ok {
+		if !p.Type.FormatPreservingEncryptionSupported() {
+			return logical.ErrorResponse(fmt.Sprintf("fpe_alphabets is not valid for key type %v", p.Type)), nil
+		}
+
+		alphabets := make(map[string]string, len(p.FPEAlphabets))
+		for name, alphabet := range p.FPEAlphabets {
+			alphabets[name] = alphabet
+		}
+		for name, alphabet := range fpeAlphabetsRaw.(map[string]string) {
+			// Existing ciphertexts can only be decrypted with the alphabet
+			// they were encrypted with
+			if existing, ok := alphabets[name]; ok {
+				if existing != alphabet {
+					return logical.ErrorResponse(fmt.Sprintf("alphabet %q cannot be changed", name)), nil
+				}
+				continue
+			}
+			if _, ok := keysutil.BuiltinFPEAlphabets[name]; ok {
+				return logical.ErrorResponse(fmt.Sprintf("alphabet %q is built-in", name)), nil
+			}
+			if err := keysutil.ValidateFPEAlphabet(alphabet); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid alphabet %q: %s", name, err)), nil
+			}
+			alphabets[name] = alphabet
+			persistNeeded = true
+		}
+		p.FPEAlphabets = alphabets
+	}
+
 	if !persistNeeded {
 		resp, err := b.formatKeyPolicy(p, nil)
 		if err != nil {
@@ -150,6 +150,10 @@ func (b *backend) pathRewrapWrite(ctx context.Context, req *logical.Request, d *
 	}
 	defer p.Unlock()
 
+	if p.Type.FormatPreservingEncryptionSupported() {
+		return logical.ErrorResponse("rewrap is not supported for format-preserving encryption keys; decrypt and encrypt again instead"), logical.ErrInvalidRequest
+	}
+
 	warnAboutNonceUsage := false
 	for i, item := range batchInputItems {
 		if batchResponseItems[i].Error != "" {
@@ -234,5 +238,7 @@ const pathRewrapHelpDesc = `
 After key rotation, this function can be used to rewrap the given ciphertext or
 a batch of given ciphertext blocks with the latest version of the named key.
 If the given ciphertext is already using the latest version of the key, this
-function is a no-op.
+function is a no-op. Format-preserving encryption keys do not support rewrap,
+as their ciphertexts do not record the key version; decrypt and encrypt such
+ciphertexts again instead.
 `
@@ -0,0 +1,271 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// StreamStatusTrailer is the HTTP trailer reporting whether a streamed
+// response completed, as failures occurring after the response headers have
+// been sent cannot be reported through the status code.
+const StreamStatusTrailer = "X-Vault-Transit-Stream-Status"
+
+func (b *backend) pathEncryptStream() *framework.Path {
+	return &framework.Path{
+		Pattern: "encrypt-stream/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTransit,
+			OperationVerb:   "encrypt",
+			OperationSuffix: "stream",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"context": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded context for key derivation. Required if key derivation is enabled",
+			},
+
+			"key_version": {
+				Type: framework.TypeInt,
+				Description: `The version of the key to use for encryption.
+Must be 0 (for latest) or a value greater than or equal
+to the min_encryption_version configured on the key.`,
+			},
+
+			"segment_size": {
+				Type:    framework.TypeInt,
+				Default: keysutil.DefaultStreamSegmentSize,
+				Description: `The size in bytes of the plaintext segments the
+stream is encrypted in, between 1024 and 16777216 bytes.
+Defaults to 65536.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathEncryptStreamWrite,
+		},
+
+		HelpSynopsis:    pathEncryptStreamHelpSyn,
+		HelpDescription: pathEncryptStreamHelpDesc,
+	}
+}
+
+func (b *backend) pathDecryptStream() *framework.Path {
+	return &framework.Path{
+		Pattern: "decrypt-stream/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTransit,
+			OperationVerb:   "decrypt",
+			OperationSuffix: "stream",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"context": {
+				Type: framework.TypeString,
+				Description: `
+Base64 encoded context for key derivation. Required if key derivation is
+enabled.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDecryptStreamWrite,
+		},
+
+		HelpSynopsis:    pathDecryptStreamHelpSyn,
+		HelpDescription: pathDecryptStreamHelpDesc,
+	}
+}
+
+func (b *backend) pathEncryptStreamWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.handleStream(ctx, req, d, nil, func(p *keysutil.Policy, context, _ []byte) (*keysutil.StreamCipher, error) {
+		return p.NewStreamEncrypter(d.Get("key_version").(int), context, d.Get("segment_size").(int), b.GetRandomReader())
+	})
+}
+
+func (b *backend) pathDecryptStreamWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.handleStream(ctx, req, d, keysutil.ReadStreamHeader, func(p *keysutil.Policy, context, header []byte) (*keysutil.StreamCipher, error) {
+		return p.NewStreamDecrypter(context, header)
+	})
+}
+
+// handleStream runs a streaming operation from the raw request body to the
+// response writer. The header of the stream, if any, is read before the
+// policy is locked, and the policy is unlocked again once the cipher has been
+// created, so that the lock is not held for as long as the client streams.
+//
+// Streams are exempt from max_request_duration, so they only stop early once
+// the request context is canceled, such as when the node steps down. The
+// body is then closed, so that a read blocked on the client returns.
+func (b *backend) handleStream(ctx context.Context, req *logical.Request, d *framework.FieldData, readHeader func(io.Reader) ([]byte, error), newCipher func(p *keysutil.Policy, context, header []byte) (*keysutil.StreamCipher, error)) (*logical.Response, error) {
+	// The original body is not limited by max_request_size, which would
+	// otherwise cap the size of the stream
+	src, ok := logical.ContextOriginalBodyValue(ctx)
+	if !ok && req.HTTPRequest != nil {
+		src = req.HTTPRequest.Body
+	}
+	if src == nil || req.ResponseWriter == nil {
+		return logical.ErrorResponse("streaming is only supported over the HTTP API"), logical.ErrInvalidRequest
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			src.Close()
+		case <-done:
+		}
+	}()
+
+	var decodedContext []byte
+	if encodedContext := d.Get("context").(string); encodedContext != "" {
+		var err error
+		decodedContext, err = base64.StdEncoding.DecodeString(encodedContext)
+		if err != nil {
+			return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
+		}
+	}
+
+	var header []byte
+	if readHeader != nil {
+		var err error
+		header, err = readHeader(src)
+		if err != nil {
+			return streamErrorResponse(err)
+		}
+	}
+
+	c, resp, err := b.newStreamCipher(ctx, req, d, decodedContext, header, newCipher)
+	if c == nil {
+		return resp, err
+	}
+
+	w := &streamResponseWriter{w: req.ResponseWriter}
+	err = c.Stream(ctx, w, src)
+	if w.started {
+		// The response is already underway, so the outcome can only be
+		// reported in the trailer
+		status := "ok"
+		if err != nil {
+			b.Logger().Debug("streaming operation failed", "path", req.Path, "error", err)
+			status = err.Error()
+		}
+		w.w.Header().Set(StreamStatusTrailer, status)
+		return nil, nil
+	}
+
+	if err == nil {
+		return nil, errors.New("stream produced no output")
+	}
+	return streamErrorResponse(err)
+}
+
+// newStreamCipher creates the cipher of a stream with the policy read locked.
+// The cipher holds the key derived for the stream, so the stream itself can
+// run without the lock.
+func (b *backend) newStreamCipher(ctx context.Context, req *logical.Request, d *framework.FieldData, context, header []byte, newCipher func(p *keysutil.Policy, context, header []byte) (*keysutil.StreamCipher, error)) (*keysutil.StreamCipher, *logical.Response, error) {
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    d.Get("name").(string),
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	c, err := newCipher(p, context, header)
+	if err != nil {
+		resp, err := streamErrorResponse(err)
+		return nil, resp, err
+	}
+	return c, nil, nil
+}
+
+func streamErrorResponse(err error) (*logical.Response, error) {
+	switch err.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	default:
+		return nil, err
+	}
+}
+
+// streamResponseWriter sends the response headers on the first write, so
+// that failures before any output can still be returned as regular error
+// responses.
+type streamResponseWriter struct {
+	w       *logical.HTTPResponseWriter
+	started bool
+}
+
+func (s *streamResponseWriter) Write(p []byte) (int, error) {
+	if !s.started {
+		s.started = true
+		h := s.w.Header()
+		h.Set("Content-Type", "application/octet-stream")
+		h.Set("Trailer", StreamStatusTrailer)
+		s.w.WriteHeader(http.StatusOK)
+	}
+	return s.w.Write(p)
+}
+
+const pathEncryptStreamHelpSyn = `Encrypt a stream of data using a named key`
+
+const pathEncryptStreamHelpDesc = `
+This path uses the named key from the request path to encrypt the raw
+request body, which is not subject to max_request_size, and streams the
+ciphertext back as the response body. Parameters are given in the query
+string. The plaintext is encrypted in segments with a key derived for the
+stream, so that segments cannot be modified, reordered or truncated without
+decryption failing. The key version used is recorded in the ciphertext.
+
+Failures occurring once the response has started are reported in the
+X-Vault-Transit-Stream-Status trailer, which is "ok" for a complete response.
+Streams are not bound by the listener's max_request_duration, but are
+stopped when the node seals or steps down, and reading the request body is
+still bound by the listener's http_read_timeout.
+`
+
+const pathDecryptStreamHelpSyn = `Decrypt a stream of data using a named key`
+
+const pathDecryptStreamHelpDesc = `
+This path uses the named key from the request path to decrypt a ciphertext
+returned by encrypt-stream, given as the raw request body, and streams the
+plaintext back as the response body. Parameters are given in the query
+string. Each segment is authenticated before its plaintext is sent, but a
+response is only complete, with no segments dropped, when the
+X-Vault-Transit-Stream-Status trailer is "ok". Streams are not bound by the
+listener's max_request_duration, but are stopped when the node seals or steps
+down, and reading the request body is still bound by the listener's
+http_read_timeout.
+`
@@ -0,0 +1,222 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package transit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_Stream(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/stream",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"derived": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	// stream sends body through the path and returns the recorded response
+	stream := func(path string, data map[string]interface{}, body []byte) (*http.Response, *logical.Response, error) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx := logical.CreateContextOriginalBody(context.Background(), io.NopCloser(bytes.NewReader(body)))
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation:      logical.UpdateOperation,
+			Path:           path,
+			Storage:        s,
+			Data:           data,
+			ResponseWriter: logical.NewHTTPResponseWriter(recorder),
+		})
+		return recorder.Result(), resp, err
+	}
+
+	plaintext := make([]byte, 200*1024+17)
+	if _, err := rand.Read(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	encContext := base64.StdEncoding.EncodeToString([]byte("backups"))
+
+	httpResp, resp, err := stream("encrypt-stream/stream", map[string]interface{}{
+		"context":      encContext,
+		"segment_size": 4096,
+	}, plaintext)
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	ciphertext, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if httpResp.Header.Get("Content-Type") != "application/octet-stream" || httpResp.Trailer.Get(StreamStatusTrailer) != "ok" {
+		t.Fatalf("bad response: %#v", httpResp)
+	}
+
+	// Older ciphertexts stay decryptable after rotation
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/stream/rotate",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	httpResp, resp, err = stream("decrypt-stream/stream", map[string]interface{}{
+		"context": encContext,
+	}, ciphertext)
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	decrypted, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) || httpResp.Trailer.Get(StreamStatusTrailer) != "ok" {
+		t.Fatalf("bad decryption, trailer %q", httpResp.Trailer.Get(StreamStatusTrailer))
+	}
+
+	// Failures before any output are regular error responses
+	_, resp, _ = stream("decrypt-stream/stream", map[string]interface{}{
+		"context": base64.StdEncoding.EncodeToString([]byte("other")),
+	}, ciphertext)
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for wrong context, resp:%#v", resp)
+	}
+
+	// Failures once the response is underway are reported in the trailer
+	httpResp, resp, err = stream("decrypt-stream/stream", map[string]interface{}{
+		"context": encContext,
+	}, ciphertext[:len(ciphertext)-4096])
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if status := httpResp.Trailer.Get(StreamStatusTrailer); status == "" || status == "ok" {
+		t.Fatalf("expected failure in trailer, got %q", status)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt-stream/stream",
+		Storage:   s,
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error without a request body, err:%v resp:%#v", err, resp)
+	}
+}
+
+func TestTransit_StreamUnlocked(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/stream",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	// The stream blocks on its body until the key has been rotated
+	pr, pw := io.Pipe()
+	recorder := httptest.NewRecorder()
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.HandleRequest(logical.CreateContextOriginalBody(context.Background(), pr), &logical.Request{
+			Operation:      logical.UpdateOperation,
+			Path:           "encrypt-stream/stream",
+			Storage:        s,
+			ResponseWriter: logical.NewHTTPResponseWriter(recorder),
+		})
+		done <- err
+	}()
+	if _, err := pw.Write([]byte("the quick brown fox")); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := make(chan error, 1)
+	go func() {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "keys/stream/rotate",
+			Storage:   s,
+		})
+		if err == nil && resp != nil && resp.IsError() {
+			err = resp.Error()
+		}
+		rotated <- err
+	}()
+	select {
+	case err := <-rotated:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("key rotation blocked by stream")
+	}
+
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if status := recorder.Result().Trailer.Get(StreamStatusTrailer); status != "ok" {
+		t.Fatalf("bad stream status %q", status)
+	}
+}
+
+func TestTransit_StreamCanceled(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/stream",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	// The stream blocks on its body, which the client never completes
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(logical.CreateContextOriginalBody(context.Background(), pr))
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation:      logical.UpdateOperation,
+			Path:           "encrypt-stream/stream",
+			Storage:        s,
+			ResponseWriter: logical.NewHTTPResponseWriter(httptest.NewRecorder()),
+		})
+		done <- err
+	}()
+	if _, err := pw.Write([]byte("the quick brown fox")); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected the canceled stream to fail")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("stream did not return once its request was canceled")
+	}
+}
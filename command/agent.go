@@ -35,6 +35,7 @@ import (
 	"github.com/hashicorp/vault/command/agentproxyshared"
 	"github.com/hashicorp/vault/command/agentproxyshared/auth"
 	"github.com/hashicorp/vault/command/agentproxyshared/cache"
+	"github.com/hashicorp/vault/command/agentproxyshared/failover"
 	"github.com/hashicorp/vault/command/agentproxyshared/sink"
 	"github.com/hashicorp/vault/command/agentproxyshared/sink/file"
 	"github.com/hashicorp/vault/command/agentproxyshared/sink/inmem"
@@ -311,6 +312,31 @@ func (c *AgentCommand) Run(args []string) int {
 	}
 	c.metricsHelper = metricsutil.NewMetricsHelper(inmemMetrics, prometheusEnabled)
 
+	// Fail over between the configured Vault servers, unless the address was
+	// overridden by flag or environment variable.
+	var vaultSelector *failover.Selector
+	var templateAddressCh, execAddressCh <-chan string
+	if config.Vault != nil && len(config.Vault.Addresses) > 1 {
+		if config.Vault.Address != config.Vault.Addresses[0] {
+			c.UI.Warn(fmt.Sprintf("==> Note: Vault address overridden to %q, ignoring the 'addresses' "+
+				"of the 'vault' stanza", config.Vault.Address))
+		} else {
+			vaultSelector, err = failover.NewSelector(&failover.SelectorConfig{
+				Logger:              c.logger.Named("failover"),
+				Addresses:           config.Vault.Addresses,
+				Client:              client,
+				HealthCheckInterval: config.Vault.HealthCheckInterval,
+			})
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error creating Vault server selector: %v", err))
+				return 1
+			}
+			vaultSelector.RegisterClient(client)
+			templateAddressCh = vaultSelector.Subscribe()
+			execAddressCh = vaultSelector.Subscribe()
+		}
+	}
+
 	var templateNamespace string
 	// This indicates whether the namespace for the client has been set by environment variable.
 	// If it has, we don't touch it
@@ -340,6 +366,10 @@ func (c *AgentCommand) Run(args []string) int {
 			return 1
 		}
 
+		if vaultSelector != nil {
+			vaultSelector.RegisterClient(sinkClient)
+		}
+
 		if config.DisableIdleConnsAutoAuth {
 			sinkClient.SetMaxIdleConnections(-1)
 		}
@@ -489,6 +519,10 @@ func (c *AgentCommand) Run(args []string) int {
 		return 1
 	}
 
+	if vaultSelector != nil {
+		vaultSelector.RegisterClient(proxyClient)
+	}
+
 	if config.DisableIdleConnsAPIProxy {
 		proxyClient.SetMaxIdleConnections(-1)
 	}
@@ -534,7 +568,7 @@ func (c *AgentCommand) Run(args []string) int {
 
 		// Configure persistent storage and add to LeaseCache
 		if config.Cache.Persist != nil {
-			deferFunc, oldToken, err := agentproxyshared.AddPersistentStorageToLeaseCache(ctx, leaseCache, config.Cache.Persist, cacheLogger)
+			deferFunc, oldToken, err := agentproxyshared.AddPersistentStorageToLeaseCache(ctx, leaseCache, config.Cache.Persist, client, cacheLogger)
 			if err != nil {
 				c.UI.Error(fmt.Sprintf("Error creating persistent cache: %v", err))
 				return 1
@@ -556,8 +590,8 @@ func (c *AgentCommand) Run(args []string) int {
 	var ts *template.Server
 	var es *exec.Server
 	if method != nil {
-		enableTemplateTokenCh := len(config.Templates) > 0
-		enableEnvTemplateTokenCh := len(config.EnvTemplates) > 0
+		enableTemplateTokenCh := config.HasTemplates()
+		enableEnvTemplateTokenCh := config.HasEnvTemplates()
 
 		// Auth Handler is going to set its own retry values, so we want to
 		// work on a copy of the client to not affect other subsystems.
@@ -572,6 +606,10 @@ func (c *AgentCommand) Run(args []string) int {
 			ahClient.SetNamespace(config.AutoAuth.Method.Namespace)
 		}
 
+		if vaultSelector != nil {
+			vaultSelector.RegisterClient(ahClient)
+		}
+
 		if config.DisableIdleConnsAutoAuth {
 			ahClient.SetMaxIdleConnections(-1)
 		}
@@ -602,20 +640,22 @@ func (c *AgentCommand) Run(args []string) int {
 		})
 
 		ts = template.NewServer(&template.ServerConfig{
-			Logger:        c.logger.Named("template.server"),
-			LogLevel:      c.logger.GetLevel(),
-			LogWriter:     c.logWriter,
-			AgentConfig:   c.config,
-			Namespace:     templateNamespace,
-			ExitAfterAuth: config.ExitAfterAuth,
+			Logger:         c.logger.Named("template.server"),
+			LogLevel:       c.logger.GetLevel(),
+			LogWriter:      c.logWriter,
+			AgentConfig:    c.config,
+			Namespace:      templateNamespace,
+			ExitAfterAuth:  config.ExitAfterAuth,
+			VaultAddressCh: templateAddressCh,
 		})
 
 		es, err = exec.NewServer(&exec.ServerConfig{
-			AgentConfig: c.config,
-			Namespace:   templateNamespace,
-			Logger:      c.logger.Named("exec.server"),
-			LogLevel:    c.logger.GetLevel(),
-			LogWriter:   c.logWriter,
+			AgentConfig:    c.config,
+			Namespace:      templateNamespace,
+			Logger:         c.logger.Named("exec.server"),
+			LogLevel:       c.logger.GetLevel(),
+			LogWriter:      c.logWriter,
+			VaultAddressCh: execAddressCh,
 		})
 		if err != nil {
 			c.logger.Error("could not create exec server", "error", err)
@@ -626,7 +666,7 @@ func (c *AgentCommand) Run(args []string) int {
 	var listeners []net.Listener
 
 	// If there are templates, add an in-process listener
-	if len(config.Templates) > 0 || len(config.EnvTemplates) > 0 {
+	if config.HasTemplates() || config.HasEnvTemplates() {
 		config.Listeners = append(config.Listeners, &configutil.Listener{Type: listenerutil.BufConnType})
 	}
 
@@ -798,6 +838,16 @@ func (c *AgentCommand) Run(args []string) int {
 		}
 	}, func(error) {})
 
+	// Start probing the health of the Vault servers to fail over between
+	if vaultSelector != nil {
+		g.Add(func() error {
+			vaultSelector.Run(ctx)
+			return nil
+		}, func(error) {
+			cancelFunc()
+		})
+	}
+
 	// Start auto-auth and sink servers
 	if method != nil {
 
@@ -829,7 +879,7 @@ func (c *AgentCommand) Run(args []string) int {
 			}()
 
 			// Wait until templates are rendered
-			if len(config.Templates) > 0 {
+			if config.HasTemplates() {
 				<-ts.DoneCh
 			}
 
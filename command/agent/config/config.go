@@ -40,6 +40,7 @@ type Config struct {
 	Vault                       *Vault                     `hcl:"vault"`
 	TemplateConfig              *TemplateConfig            `hcl:"template_config"`
 	Templates                   []*ctconfig.TemplateConfig `hcl:"templates"`
+	TemplateGroups              []*TemplateGroup           `hcl:"-"`
 	DisableIdleConns            []string                   `hcl:"disable_idle_connections"`
 	DisableIdleConnsAPIProxy    bool                       `hcl:"-"`
 	DisableIdleConnsTemplating  bool                       `hcl:"-"`
@@ -92,6 +93,12 @@ type Vault struct {
 	TLSServerName    string      `hcl:"tls_server_name"`
 	Namespace        string      `hcl:"namespace"`
 	Retry            *Retry      `hcl:"retry"`
+
+	// Addresses is an ordered list of Vault servers to fail over between.
+	// The first one is used as Address.
+	Addresses              []string      `hcl:"addresses"`
+	HealthCheckInterval    time.Duration `hcl:"-"`
+	HealthCheckIntervalRaw interface{}   `hcl:"health_check_interval"`
 }
 
 // transportDialer is an interface that allows passing a custom dialer function
@@ -172,12 +179,93 @@ type TemplateConfig struct {
 	LeaseRenewalThreshold    *float64      `hcl:"lease_renewal_threshold"`
 }
 
+// TemplateGroup is a set of templates whose files are replaced together. They
+// are rendered into a staging directory and, once all of them are rendered and
+// the optional validation command succeeded, moved into place before a single
+// command runs.
+type TemplateGroup struct {
+	Name string `mapstructure:"-"`
+
+	// StagingDir holds the rendered files of the group, named after their
+	// destination, until they are moved into place.
+	StagingDir string `mapstructure:"staging_dir"`
+
+	// ValidateCommand runs in the staging directory once all the templates of
+	// the group are rendered. The files are not moved into place if it fails.
+	ValidateCommand        []string      `mapstructure:"validate_command"`
+	ValidateCommandTimeout time.Duration `mapstructure:"validate_command_timeout"`
+
+	// Command runs after the files are moved into place.
+	Command        []string      `mapstructure:"command"`
+	CommandTimeout time.Duration `mapstructure:"command_timeout"`
+
+	Templates []*ctconfig.TemplateConfig `mapstructure:"-"`
+}
+
+// HasTemplates returns whether templates are configured, either on their own
+// or in template groups.
+func (c *Config) HasTemplates() bool {
+	return len(c.Templates) > 0 || len(c.TemplateGroups) > 0
+}
+
 type ExecConfig struct {
 	Command                []string  `hcl:"command,attr" mapstructure:"command"`
 	RestartOnSecretChanges string    `hcl:"restart_on_secret_changes,optional" mapstructure:"restart_on_secret_changes"`
 	RestartStopSignal      os.Signal `hcl:"-" mapstructure:"restart_stop_signal"`
 	ChildProcessStdout     string    `mapstructure:"child_process_stdout"`
 	ChildProcessStderr     string    `mapstructure:"child_process_stderr"`
+
+	// Processes are the named child processes to supervise, each with its
+	// own env templates. They replace the command above and the top-level
+	// env templates.
+	Processes []*ExecProcess `hcl:"-" mapstructure:"-"`
+}
+
+// ExecProcess is a child process supervised by the exec server.
+type ExecProcess struct {
+	Name                   string    `mapstructure:"-"`
+	Command                []string  `mapstructure:"command"`
+	RestartOnSecretChanges string    `mapstructure:"restart_on_secret_changes"`
+	RestartStopSignal      os.Signal `mapstructure:"restart_stop_signal"`
+	ChildProcessStdout     string    `mapstructure:"child_process_stdout"`
+	ChildProcessStderr     string    `mapstructure:"child_process_stderr"`
+
+	// RestartPolicy is whether the process is restarted when it exits on its
+	// own: "never", which stops the agent, "on-failure" or "always".
+	RestartPolicy string `mapstructure:"restart_policy"`
+
+	EnvTemplates []*ctconfig.TemplateConfig `mapstructure:"-"`
+}
+
+// ExecProcesses returns the child processes of the exec stanza: either its
+// named processes, or a single unnamed one made of its command and the
+// top-level env templates.
+func (c *Config) ExecProcesses() []*ExecProcess {
+	if c.Exec == nil {
+		return nil
+	}
+	if len(c.Exec.Processes) > 0 {
+		return c.Exec.Processes
+	}
+	if len(c.EnvTemplates) == 0 {
+		return nil
+	}
+
+	return []*ExecProcess{{
+		Command:                c.Exec.Command,
+		RestartOnSecretChanges: c.Exec.RestartOnSecretChanges,
+		RestartStopSignal:      c.Exec.RestartStopSignal,
+		ChildProcessStdout:     c.Exec.ChildProcessStdout,
+		ChildProcessStderr:     c.Exec.ChildProcessStderr,
+		RestartPolicy:          "never",
+		EnvTemplates:           c.EnvTemplates,
+	}}
+}
+
+// HasEnvTemplates returns whether env templates are configured, either at
+// the top level or in the processes of the exec stanza.
+func (c *Config) HasEnvTemplates() bool {
+	return len(c.EnvTemplates) > 0 || (c.Exec != nil && len(c.Exec.Processes) > 0)
 }
 
 func NewConfig() *Config {
@@ -262,6 +350,13 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.Templates = append(result.Templates, l)
 	}
 
+	for _, l := range c.TemplateGroups {
+		result.TemplateGroups = append(result.TemplateGroups, l)
+	}
+	for _, l := range c2.TemplateGroups {
+		result.TemplateGroups = append(result.TemplateGroups, l)
+	}
+
 	result.ExitAfterAuth = c.ExitAfterAuth
 	if c2.ExitAfterAuth {
 		result.ExitAfterAuth = c2.ExitAfterAuth
@@ -318,7 +413,7 @@ func (c *Config) ValidateConfig() error {
 	}
 
 	if c.Cache != nil {
-		if len(c.Listeners) < 1 && len(c.Templates) < 1 && len(c.EnvTemplates) < 1 {
+		if len(c.Listeners) < 1 && !c.HasTemplates() && !c.HasEnvTemplates() {
 			return fmt.Errorf("enabling the cache requires at least 1 template or 1 listener to be defined")
 		}
 
@@ -350,8 +445,8 @@ func (c *Config) ValidateConfig() error {
 	if c.AutoAuth != nil {
 		if len(c.AutoAuth.Sinks) == 0 &&
 			(c.APIProxy == nil || !c.APIProxy.UseAutoAuthToken) &&
-			len(c.Templates) == 0 &&
-			len(c.EnvTemplates) == 0 {
+			!c.HasTemplates() &&
+			!c.HasEnvTemplates() {
 			return fmt.Errorf("auto_auth requires at least one sink or at least one template or api_proxy.use_auto_auth_token=true")
 		}
 	}
@@ -360,9 +455,55 @@ func (c *Config) ValidateConfig() error {
 		return fmt.Errorf("no auto_auth, cache, or listener block found in config")
 	}
 
+	if err := c.validateTemplateGroups(); err != nil {
+		return err
+	}
+
 	return c.validateEnvTemplateConfig()
 }
 
+func (c *Config) validateTemplateGroups() error {
+	names := make(map[string]struct{})
+	for _, group := range c.TemplateGroups {
+		if _, exists := names[group.Name]; exists {
+			return fmt.Errorf("duplicate 'template_group' name: %q", group.Name)
+		}
+		names[group.Name] = struct{}{}
+
+		if group.StagingDir == "" {
+			return fmt.Errorf("template_group[%s]: 'staging_dir' is required", group.Name)
+		}
+
+		if len(group.Templates) == 0 {
+			return fmt.Errorf("template_group[%s]: at least one 'template' is required", group.Name)
+		}
+
+		stagedFiles := make(map[string]struct{})
+		for _, template := range group.Templates {
+			if template.Destination == nil || *template.Destination == "" {
+				return fmt.Errorf("template_group[%s]: templates require a 'destination'", group.Name)
+			}
+
+			// the files are staged under the name of their destination
+			stagedFile := filepath.Base(*template.Destination)
+			if _, exists := stagedFiles[stagedFile]; exists {
+				return fmt.Errorf("template_group[%s]: destinations must have different file names, %q is used more than once", group.Name, stagedFile)
+			}
+			stagedFiles[stagedFile] = struct{}{}
+
+			if template.Command != nil || template.Exec != nil {
+				return fmt.Errorf("template_group[%s]: templates cannot have their own 'exec', use the 'command' of the group", group.Name)
+			}
+
+			if template.Backup != nil {
+				return fmt.Errorf("template_group[%s]: 'backup' is not allowed", group.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (c *Config) validateEnvTemplateConfig() error {
 	// if we are not in env-template mode, exit early
 	if c.Exec == nil && len(c.EnvTemplates) == 0 {
@@ -373,7 +514,14 @@ func (c *Config) validateEnvTemplateConfig() error {
 		return fmt.Errorf("a top-level 'exec' element must be specified with 'env_template' entries")
 	}
 
-	if len(c.EnvTemplates) == 0 {
+	if len(c.Exec.Processes) > 0 {
+		if len(c.EnvTemplates) > 0 {
+			return fmt.Errorf("top-level 'env_template' entries cannot be specified with 'exec.process' elements")
+		}
+		if len(c.Exec.Command) > 0 {
+			return fmt.Errorf("'exec.command' cannot be specified with 'exec.process' elements")
+		}
+	} else if len(c.EnvTemplates) == 0 {
 		return fmt.Errorf("must specify at least one 'env_template' element with a top-level 'exec' element")
 	}
 
@@ -381,21 +529,50 @@ func (c *Config) validateEnvTemplateConfig() error {
 		return fmt.Errorf("'api_proxy' cannot be specified with 'env_template' entries")
 	}
 
-	if len(c.Templates) > 0 {
+	if c.HasTemplates() {
 		return fmt.Errorf("'template' cannot be specified with 'env_template' entries")
 	}
 
-	if len(c.Exec.Command) == 0 {
-		return fmt.Errorf("'exec' requires a non-empty 'command' field")
-	}
+	names := make(map[string]struct{})
+	for _, process := range c.ExecProcesses() {
+		name := "exec"
+		if process.Name != "" {
+			name = fmt.Sprintf("exec.process[%s]", process.Name)
+		}
+
+		if _, exists := names[process.Name]; exists {
+			return fmt.Errorf("duplicate 'exec.process' name: %q", process.Name)
+		}
+		names[process.Name] = struct{}{}
 
-	if !slices.Contains([]string{"always", "never"}, c.Exec.RestartOnSecretChanges) {
-		return fmt.Errorf("'exec.restart_on_secret_changes' unexpected value: %q", c.Exec.RestartOnSecretChanges)
+		if len(process.Command) == 0 {
+			return fmt.Errorf("'%s' requires a non-empty 'command' field", name)
+		}
+
+		if !slices.Contains([]string{"always", "never"}, process.RestartOnSecretChanges) {
+			return fmt.Errorf("'%s.restart_on_secret_changes' unexpected value: %q", name, process.RestartOnSecretChanges)
+		}
+
+		if !slices.Contains([]string{"never", "on-failure", "always"}, process.RestartPolicy) {
+			return fmt.Errorf("'%s.restart_policy' unexpected value: %q", name, process.RestartPolicy)
+		}
+
+		if len(process.EnvTemplates) == 0 {
+			return fmt.Errorf("'%s' requires at least one 'env_template' element", name)
+		}
+
+		if err := validateEnvTemplates(process.EnvTemplates); err != nil {
+			return err
+		}
 	}
 
+	return nil
+}
+
+func validateEnvTemplates(envTemplates []*ctconfig.TemplateConfig) error {
 	uniqueKeys := make(map[string]struct{})
 
-	for _, template := range c.EnvTemplates {
+	for _, template := range envTemplates {
 		// Required:
 		//   - the key (environment variable name)
 		//   - either "contents" or "source"
@@ -645,6 +822,10 @@ func LoadConfigFile(path string) (*Config, error) {
 		return nil, fmt.Errorf("error parsing 'template': %w", err)
 	}
 
+	if err := parseTemplateGroups(result, list); err != nil {
+		return nil, fmt.Errorf("error parsing 'template_group': %w", err)
+	}
+
 	if err := parseExec(result, list); err != nil {
 		return nil, fmt.Errorf("error parsing 'exec': %w", err)
 	}
@@ -752,6 +933,20 @@ func parseVault(result *Config, list *ast.ObjectList) error {
 		}
 	}
 
+	if len(v.Addresses) > 0 {
+		if v.Address != "" && v.Address != v.Addresses[0] {
+			return fmt.Errorf("%q must be the first of %q when both are set", "address", "addresses")
+		}
+		v.Address = v.Addresses[0]
+	}
+
+	if v.HealthCheckIntervalRaw != nil {
+		if v.HealthCheckInterval, err = parseutil.ParseDurationSecond(v.HealthCheckIntervalRaw); err != nil {
+			return fmt.Errorf("error parsing %q: %w", "health_check_interval", err)
+		}
+		v.HealthCheckIntervalRaw = nil
+	}
+
 	result.Vault = &v
 
 	subs, ok := item.Val.(*ast.ObjectType)
@@ -1149,11 +1344,21 @@ func parseTemplateConfig(result *Config, list *ast.ObjectList) error {
 }
 
 func parseTemplates(result *Config, list *ast.ObjectList) error {
+	tcs, err := parseTemplateList(list)
+	if err != nil {
+		return err
+	}
+
+	result.Templates = tcs
+	return nil
+}
+
+func parseTemplateList(list *ast.ObjectList) ([]*ctconfig.TemplateConfig, error) {
 	name := "template"
 
 	templateList := list.Filter(name)
 	if len(templateList.Items) < 1 {
-		return nil
+		return nil, nil
 	}
 
 	var tcs []*ctconfig.TemplateConfig
@@ -1161,13 +1366,13 @@ func parseTemplates(result *Config, list *ast.ObjectList) error {
 	for _, item := range templateList.Items {
 		var shadow interface{}
 		if err := hcl.DecodeObject(&shadow, item.Val); err != nil {
-			return fmt.Errorf("error decoding config: %s", err)
+			return nil, fmt.Errorf("error decoding config: %s", err)
 		}
 
 		// Convert to a map and flatten the keys we want to flatten
 		parsed, ok := shadow.(map[string]interface{})
 		if !ok {
-			return errors.New("error converting config")
+			return nil, errors.New("error converting config")
 		}
 
 		// flatten the wait or exec fields. The initial "wait" or "exec" value, if given, is a
@@ -1203,15 +1408,76 @@ func parseTemplates(result *Config, list *ast.ObjectList) error {
 			Metadata:    &md,
 			Result:      &tc,
 		})
+		if err != nil {
+			return nil, errors.New("mapstructure decoder creation failed")
+		}
+		if err := decoder.Decode(parsed); err != nil {
+			return nil, err
+		}
+		tcs = append(tcs, &tc)
+	}
+	return tcs, nil
+}
+
+func parseTemplateGroups(result *Config, list *ast.ObjectList) error {
+	name := "template_group"
+
+	groupList := list.Filter(name)
+	if len(groupList.Items) < 1 {
+		return nil
+	}
+
+	groups := make([]*TemplateGroup, 0, len(groupList.Items))
+	for _, item := range groupList.Items {
+		if numberOfKeys := len(item.Keys); numberOfKeys != 1 {
+			return fmt.Errorf("expected one and only one template group name, got %d", numberOfKeys)
+		}
+
+		var shadow interface{}
+		if err := hcl.DecodeObject(&shadow, item.Val); err != nil {
+			return fmt.Errorf("error decoding config: %s", err)
+		}
+
+		parsed, ok := shadow.(map[string]interface{})
+		if !ok {
+			return errors.New("error converting config")
+		}
+
+		// template blocks are parsed separately
+		delete(parsed, "template")
+
+		var group TemplateGroup
+		var md mapstructure.Metadata
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToSliceHookFunc(","),
+				mapstructure.StringToTimeDurationHookFunc(),
+			),
+			ErrorUnused: true,
+			Metadata:    &md,
+			Result:      &group,
+		})
 		if err != nil {
 			return errors.New("mapstructure decoder creation failed")
 		}
 		if err := decoder.Decode(parsed); err != nil {
 			return err
 		}
-		tcs = append(tcs, &tc)
+
+		// hcl parses this with extra quotes if quoted in config file
+		group.Name = strings.Trim(item.Keys[0].Token.Text, `"`)
+
+		if subs, ok := item.Val.(*ast.ObjectType); ok {
+			group.Templates, err = parseTemplateList(subs.List)
+			if err != nil {
+				return fmt.Errorf("error parsing 'template' of group %q: %w", group.Name, err)
+			}
+		}
+
+		groups = append(groups, &group)
 	}
-	result.Templates = tcs
+
+	result.TemplateGroups = groups
 	return nil
 }
 
@@ -1238,6 +1504,9 @@ func parseExec(result *Config, list *ast.ObjectList) error {
 		return errors.New("error converting config")
 	}
 
+	// process blocks are parsed separately
+	delete(parsed, "process")
+
 	var execConfig ExecConfig
 	var md mapstructure.Metadata
 	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
@@ -1268,17 +1537,104 @@ func parseExec(result *Config, list *ast.ObjectList) error {
 		execConfig.RestartOnSecretChanges = "always"
 	}
 
+	if subs, ok := item.Val.(*ast.ObjectType); ok {
+		processes, err := parseExecProcesses(subs.List)
+		if err != nil {
+			return fmt.Errorf("error parsing 'process': %w", err)
+		}
+		execConfig.Processes = processes
+	}
+
 	result.Exec = &execConfig
 	return nil
 }
 
+func parseExecProcesses(list *ast.ObjectList) ([]*ExecProcess, error) {
+	processList := list.Filter("process")
+	if len(processList.Items) == 0 {
+		return nil, nil
+	}
+
+	processes := make([]*ExecProcess, 0, len(processList.Items))
+	for _, item := range processList.Items {
+		if numberOfKeys := len(item.Keys); numberOfKeys != 1 {
+			return nil, fmt.Errorf("expected one and only one process name, got %d", numberOfKeys)
+		}
+
+		var shadow interface{}
+		if err := hcl.DecodeObject(&shadow, item.Val); err != nil {
+			return nil, fmt.Errorf("error decoding config: %s", err)
+		}
+
+		parsed, ok := shadow.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("error converting config")
+		}
+
+		// env_template blocks are parsed separately
+		delete(parsed, "env_template")
+
+		var process ExecProcess
+		var md mapstructure.Metadata
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToSliceHookFunc(","),
+				ctsignals.StringToSignalFunc(),
+			),
+			ErrorUnused: true,
+			Metadata:    &md,
+			Result:      &process,
+		})
+		if err != nil {
+			return nil, errors.New("mapstructure decoder creation failed")
+		}
+		if err := decoder.Decode(parsed); err != nil {
+			return nil, err
+		}
+
+		// hcl parses this with extra quotes if quoted in config file
+		process.Name = strings.Trim(item.Keys[0].Token.Text, `"`)
+
+		if process.RestartStopSignal == nil {
+			process.RestartStopSignal = syscall.SIGTERM
+		}
+		if process.RestartOnSecretChanges == "" {
+			process.RestartOnSecretChanges = "always"
+		}
+		if process.RestartPolicy == "" {
+			process.RestartPolicy = "never"
+		}
+
+		if subs, ok := item.Val.(*ast.ObjectType); ok {
+			process.EnvTemplates, err = parseEnvTemplateList(subs.List)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing 'env_template' of process %q: %w", process.Name, err)
+			}
+		}
+
+		processes = append(processes, &process)
+	}
+
+	return processes, nil
+}
+
 func parseEnvTemplates(result *Config, list *ast.ObjectList) error {
+	envTemplates, err := parseEnvTemplateList(list)
+	if err != nil {
+		return err
+	}
+
+	result.EnvTemplates = envTemplates
+	return nil
+}
+
+func parseEnvTemplateList(list *ast.ObjectList) ([]*ctconfig.TemplateConfig, error) {
 	name := "env_template"
 
 	envTemplateList := list.Filter(name)
 
 	if len(envTemplateList.Items) < 1 {
-		return nil
+		return nil, nil
 	}
 
 	envTemplates := make([]*ctconfig.TemplateConfig, 0, len(envTemplateList.Items))
@@ -1286,13 +1642,13 @@ func parseEnvTemplates(result *Config, list *ast.ObjectList) error {
 	for _, item := range envTemplateList.Items {
 		var shadow interface{}
 		if err := hcl.DecodeObject(&shadow, item.Val); err != nil {
-			return fmt.Errorf("error decoding config: %s", err)
+			return nil, fmt.Errorf("error decoding config: %s", err)
 		}
 
 		// Convert to a map and flatten the keys we want to flatten
 		parsed, ok := shadow.(map[string]any)
 		if !ok {
-			return errors.New("error converting config")
+			return nil, errors.New("error converting config")
 		}
 
 		var templateConfig ctconfig.TemplateConfig
@@ -1310,15 +1666,15 @@ func parseEnvTemplates(result *Config, list *ast.ObjectList) error {
 			Result:      &templateConfig,
 		})
 		if err != nil {
-			return errors.New("mapstructure decoder creation failed")
+			return nil, errors.New("mapstructure decoder creation failed")
 		}
 		if err := decoder.Decode(parsed); err != nil {
-			return err
+			return nil, err
 		}
 
 		// parse the keys in the item for the environment variable name
 		if numberOfKeys := len(item.Keys); numberOfKeys != 1 {
-			return fmt.Errorf("expected one and only one environment variable name, got %d", numberOfKeys)
+			return nil, fmt.Errorf("expected one and only one environment variable name, got %d", numberOfKeys)
 		}
 
 		// hcl parses this with extra quotes if quoted in config file
@@ -1329,6 +1685,5 @@ func parseEnvTemplates(result *Config, list *ast.ObjectList) error {
 		envTemplates = append(envTemplates, &templateConfig)
 	}
 
-	result.EnvTemplates = envTemplates
-	return nil
+	return envTemplates, nil
 }
@@ -1032,6 +1032,26 @@ func TestLoadConfigFile_AgentCache_Persist(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFile_AgentCache_PersistTransit(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config-cache-persist-transit.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &agentproxyshared.PersistConfig{
+		Type:             "transit",
+		Path:             "/vault/agent-cache/",
+		KeepAfterImport:  true,
+		TransitMount:     "agent-transit",
+		TransitKey:       "agent-cache",
+		TransitTokenFile: "/etc/vault/transit-token",
+	}
+
+	if diff := deep.Equal(config.Cache.Persist, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
 func TestLoadConfigFile_AgentCache_PersistMissingType(t *testing.T) {
 	_, err := LoadConfigFile("./test-fixtures/config-cache-persist-empty-type.hcl")
 	if err == nil || os.IsNotExist(err) {
@@ -1314,6 +1334,58 @@ func TestLoadConfigFile_Template_NoSinks(t *testing.T) {
 
 // TestLoadConfigFile_Template_WithCache tests ensures that cache {} stanza is
 // permitted in vault agent configuration with template(s)
+func TestLoadConfigFile_TemplateGroups(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config-template-groups.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := config.ValidateConfig(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*TemplateGroup{
+		{
+			Name:                   "tls",
+			StagingDir:             "/etc/tls/.staging",
+			ValidateCommand:        []string{"openssl x509 -noout -in cert.pem"},
+			ValidateCommandTimeout: 10 * time.Second,
+			Command:                []string{"systemctl reload nginx"},
+			Templates: []*ctconfig.TemplateConfig{
+				{
+					Source:      pointerutil.StringPtr("/path/on/disk/to/cert.ctmpl"),
+					Destination: pointerutil.StringPtr("/etc/tls/cert.pem"),
+				},
+				{
+					Source:      pointerutil.StringPtr("/path/on/disk/to/key.ctmpl"),
+					Destination: pointerutil.StringPtr("/etc/tls/key.pem"),
+					Perms:       pointerutil.FileModePtr(0o600),
+				},
+			},
+		},
+	}
+
+	if diff := deep.Equal(config.TemplateGroups, expected); diff != nil {
+		t.Fatal(diff)
+	}
+	if !config.HasTemplates() {
+		t.Fatal("expected template groups to count as templates")
+	}
+}
+
+// TestLoadConfigFile_Bad_TemplateGroups_DuplicateDestination ensures that
+// ValidateConfig errors when two templates of a group would be staged under
+// the same file name
+func TestLoadConfigFile_Bad_TemplateGroups_DuplicateDestination(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/bad-config-template-groups-duplicate-destination.hcl")
+	if err != nil {
+		t.Fatalf("error loading config file: %s", err)
+	}
+
+	if err := config.ValidateConfig(); err == nil {
+		t.Fatal("expected an error from ValidateConfig: destinations of a template group must have different file names")
+	}
+}
+
 func TestLoadConfigFile_Template_WithCache(t *testing.T) {
 	config, err := LoadConfigFile("./test-fixtures/config-template-with-cache.hcl")
 	if err != nil {
@@ -1448,6 +1520,66 @@ func TestLoadConfigFile_Vault_Retry_Empty(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFile_Vault_Addresses(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config-vault-addresses.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Config{
+		SharedConfig: &configutil.SharedConfig{
+			PidFile: "./pidfile",
+		},
+		AutoAuth: &AutoAuth{
+			Method: &Method{
+				Type:      "aws",
+				MountPath: "auth/aws",
+				Namespace: "my-namespace/",
+				Config: map[string]interface{}{
+					"role": "foobar",
+				},
+			},
+			Sinks: []*Sink{
+				{
+					Type:   "file",
+					DHType: "curve25519",
+					DHPath: "/tmp/file-foo-dhpath",
+					AAD:    "foobar",
+					Config: map[string]interface{}{
+						"path": "/tmp/file-foo",
+					},
+				},
+			},
+		},
+		TemplateConfig: &TemplateConfig{
+			MaxConnectionsPerHost: DefaultTemplateConfigMaxConnsPerHost,
+		},
+		Vault: &Vault{
+			Address: "https://vault-east.example.com:8200",
+			Addresses: []string{
+				"https://vault-east.example.com:8200",
+				"https://vault-west.example.com:8200",
+			},
+			HealthCheckInterval: 30 * time.Second,
+			Retry: &Retry{
+				ctconfig.DefaultRetryAttempts,
+			},
+		},
+	}
+
+	config.Prune()
+	if diff := deep.Equal(config, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadConfigFile_Bad_Vault_Addresses(t *testing.T) {
+	_, err := LoadConfigFile("./test-fixtures/bad-config-vault-addresses.hcl")
+	if err == nil {
+		t.Fatal("LoadConfigFile should return an error when address is not the first of addresses")
+	}
+}
+
 func TestLoadConfigFile_EnforceConsistency(t *testing.T) {
 	config, err := LoadConfigFile("./test-fixtures/config-consistency.hcl")
 	if err != nil {
@@ -2400,6 +2532,67 @@ func TestLoadConfigFile_EnvTemplates_ExecComplex(t *testing.T) {
 	}
 }
 
+// TestLoadConfigFile_EnvTemplates_ExecProcesses validates an exec section with
+// multiple named processes, each with its own env templates
+func TestLoadConfigFile_EnvTemplates_ExecProcesses(t *testing.T) {
+	cfg, err := LoadConfigFile("./test-fixtures/config-env-templates-processes.hcl")
+	if err != nil {
+		t.Fatalf("error loading config file: %s", err)
+	}
+
+	if err := cfg.ValidateConfig(); err != nil {
+		t.Fatalf("validation error: %s", err)
+	}
+
+	processes := cfg.ExecProcesses()
+	if len(processes) != 2 {
+		t.Fatalf("expected 2 processes, got %d", len(processes))
+	}
+
+	web, worker := processes[0], processes[1]
+	if web.Name != "web" || worker.Name != "worker" {
+		t.Fatalf("unexpected process names %q and %q", web.Name, worker.Name)
+	}
+
+	if !slices.Equal(web.Command, []string{"/path/to/web", "--port", "8080"}) {
+		t.Fatal("web command does not have expected value")
+	}
+
+	// check defaults
+	if web.RestartOnSecretChanges != "always" || web.RestartStopSignal != syscall.SIGTERM || web.RestartPolicy != "always" {
+		t.Fatalf("unexpected web process settings: %+v", web)
+	}
+
+	if worker.RestartOnSecretChanges != "never" || worker.RestartStopSignal != syscall.SIGINT || worker.RestartPolicy != "on-failure" {
+		t.Fatalf("unexpected worker process settings: %+v", worker)
+	}
+
+	if worker.ChildProcessStderr != "/var/log/worker.log" {
+		t.Fatalf("expected worker stderr to be '/var/log/worker.log', got %q", worker.ChildProcessStderr)
+	}
+
+	// the same environment variable can be set for different processes
+	if len(web.EnvTemplates) != 1 || len(worker.EnvTemplates) != 2 {
+		t.Fatalf("expected 1 and 2 env templates, got %d and %d", len(web.EnvTemplates), len(worker.EnvTemplates))
+	}
+	if *worker.EnvTemplates[1].MapToEnvironmentVariable != "DB_PASSWORD" {
+		t.Fatalf("expected 'DB_PASSWORD', got %q", *worker.EnvTemplates[1].MapToEnvironmentVariable)
+	}
+}
+
+// TestLoadConfigFile_Bad_EnvTemplates_ExecProcessesWithCommand ensures that
+// ValidateConfig errors when "exec" has both a command and "process" blocks
+func TestLoadConfigFile_Bad_EnvTemplates_ExecProcessesWithCommand(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/bad-config-env-templates-processes-with-command.hcl")
+	if err != nil {
+		t.Fatalf("error loading config file: %s", err)
+	}
+
+	if err := config.ValidateConfig(); err == nil {
+		t.Fatal("expected an error from ValidateConfig: exec.command cannot be specified with process blocks")
+	}
+}
+
 // TestLoadConfigFile_Bad_EnvTemplates_MissingExec ensures that ValidateConfig
 // errors when "env_template" stanza(s) are specified but "exec" is missing
 func TestLoadConfigFile_Bad_EnvTemplates_MissingExec(t *testing.T) {
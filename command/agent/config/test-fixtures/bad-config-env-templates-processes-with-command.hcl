@@ -0,0 +1,29 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: BUSL-1.1
+
+auto_auth {
+
+  method {
+    type = "token_file"
+
+    config {
+      token_file_path = "/home/username/.vault-token"
+    }
+  }
+}
+
+vault {
+  address = "http://localhost:8200"
+}
+
+exec {
+  command = ["/path/to/my/app"]
+
+  process "web" {
+    command = ["/path/to/web"]
+
+    env_template "DB_PASSWORD" {
+      contents = "{{ with secret \"secret/data/web\" }}{{ .Data.data.password }}{{ end }}"
+    }
+  }
+}
@@ -0,0 +1,29 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: BUSL-1.1
+
+pid_file = "./pidfile"
+
+auto_auth {
+  method {
+    type = "approle"
+
+    config = {
+      role_id_file_path   = "/path/to/role-id"
+      secret_id_file_path = "/path/to/secret-id"
+    }
+  }
+}
+
+template_group "tls" {
+  staging_dir = "/etc/tls/.staging"
+
+  template {
+    source      = "/path/on/disk/to/cert.ctmpl"
+    destination = "/etc/tls/cert.pem"
+  }
+
+  template {
+    source      = "/path/on/disk/to/other-cert.ctmpl"
+    destination = "/etc/other/cert.pem"
+  }
+}
@@ -0,0 +1,19 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: BUSL-1.1
+
+pid_file = "./pidfile"
+
+cache {
+    persist "transit" {
+        keep_after_import = true
+        path = "/vault/agent-cache/"
+        transit_mount = "agent-transit"
+        transit_key = "agent-cache"
+        transit_token_file = "/etc/vault/transit-token"
+    }
+}
+
+listener "tcp" {
+    address = "127.0.0.1:8300"
+    tls_disable = true
+}
@@ -0,0 +1,43 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: BUSL-1.1
+
+auto_auth {
+
+  method {
+    type = "token_file"
+
+    config {
+      token_file_path = "/home/username/.vault-token"
+    }
+  }
+}
+
+vault {
+  address = "http://localhost:8200"
+}
+
+exec {
+  process "web" {
+    command        = ["/path/to/web", "--port", "8080"]
+    restart_policy = "always"
+
+    env_template "DB_PASSWORD" {
+      contents = "{{ with secret \"secret/data/web\" }}{{ .Data.data.password }}{{ end }}"
+    }
+  }
+
+  process "worker" {
+    command                   = ["/path/to/worker"]
+    restart_on_secret_changes = "never"
+    restart_stop_signal       = "SIGINT"
+    restart_policy            = "on-failure"
+    child_process_stderr      = "/var/log/worker.log"
+
+    env_template "QUEUE_TOKEN" {
+      contents = "{{ with secret \"secret/data/worker\" }}{{ .Data.data.token }}{{ end }}"
+    }
+    env_template "DB_PASSWORD" {
+      contents = "{{ with secret \"secret/data/worker\" }}{{ .Data.data.password }}{{ end }}"
+    }
+  }
+}
@@ -0,0 +1,33 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: BUSL-1.1
+
+pid_file = "./pidfile"
+
+auto_auth {
+  method {
+    type = "approle"
+
+    config = {
+      role_id_file_path   = "/path/to/role-id"
+      secret_id_file_path = "/path/to/secret-id"
+    }
+  }
+}
+
+template_group "tls" {
+  staging_dir              = "/etc/tls/.staging"
+  validate_command         = "openssl x509 -noout -in cert.pem"
+  validate_command_timeout = "10s"
+  command                  = "systemctl reload nginx"
+
+  template {
+    source      = "/path/on/disk/to/cert.ctmpl"
+    destination = "/etc/tls/cert.pem"
+  }
+
+  template {
+    source      = "/path/on/disk/to/key.ctmpl"
+    destination = "/etc/tls/key.pem"
+    perms       = "0600"
+  }
+}
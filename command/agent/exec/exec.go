@@ -50,13 +50,32 @@ type ServerConfig struct {
 	// the same io.Writer that Vault Agent itself is using.
 	LogLevel  hclog.Level
 	LogWriter io.Writer
+
+	// VaultAddressCh receives the address of the Vault server to fail over
+	// to, when multiple addresses are configured. It is ignored when the
+	// runner goes through the in-process cache listener, whose API proxy
+	// fails over on its own.
+	VaultAddressCh <-chan string
 }
 
+// Server supervises the child processes of the exec stanza, all of them
+// sharing the auto-auth token.
 type Server struct {
 	// config holds the ServerConfig used to create it. It's passed along in other
 	// methods
 	config *ServerConfig
 
+	logger hclog.Logger
+
+	processes []*process
+}
+
+// process supervises a single child process, rendering its env templates
+// with its own consul-template runner.
+type process struct {
+	config       *config.ExecProcess
+	serverConfig *ServerConfig
+
 	// runner is the consul-template runner
 	runner *manager.Runner
 
@@ -68,11 +87,12 @@ type Server struct {
 
 	logger hclog.Logger
 
-	childProcess       *child.Child
-	childProcessState  childProcessState
-	childProcessLock   sync.Mutex
-	childProcessStdout io.WriteCloser
-	childProcessStderr io.WriteCloser
+	childProcess          *child.Child
+	childProcessState     childProcessState
+	childProcessStartedAt time.Time
+	childProcessLock      sync.Mutex
+	childProcessStdout    io.WriteCloser
+	childProcessStderr    io.WriteCloser
 
 	// exit channel of the child process
 	childProcessExitCh chan int
@@ -91,78 +111,164 @@ func (e *ProcessExitError) Error() string {
 }
 
 func NewServer(cfg *ServerConfig) (*Server, error) {
+	server := Server{
+		logger: cfg.Logger,
+		config: cfg,
+	}
+
+	for _, processConfig := range cfg.AgentConfig.ExecProcesses() {
+		logger := cfg.Logger
+		if processConfig.Name != "" {
+			logger = logger.Named(processConfig.Name)
+		}
+
+		p, err := newProcess(cfg, processConfig, logger)
+		if err != nil {
+			server.close()
+			return nil, err
+		}
+		server.processes = append(server.processes, p)
+	}
+
+	return &server, nil
+}
+
+func newProcess(cfg *ServerConfig, processConfig *config.ExecProcess, logger hclog.Logger) (*process, error) {
 	var err error
 
 	childProcessStdout := os.Stdout
 	childProcessStderr := os.Stderr
 
-	if cfg.AgentConfig.Exec != nil {
-		if cfg.AgentConfig.Exec.ChildProcessStdout != "" {
-			childProcessStdout, err = os.OpenFile(cfg.AgentConfig.Exec.ChildProcessStdout, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				return nil, fmt.Errorf("could not open %q, %w", cfg.AgentConfig.Exec.ChildProcessStdout, err)
-			}
+	if processConfig.ChildProcessStdout != "" {
+		childProcessStdout, err = os.OpenFile(processConfig.ChildProcessStdout, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %q, %w", processConfig.ChildProcessStdout, err)
 		}
+	}
 
-		if cfg.AgentConfig.Exec.ChildProcessStderr != "" {
-			childProcessStderr, err = os.OpenFile(cfg.AgentConfig.Exec.ChildProcessStderr, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				return nil, fmt.Errorf("could not open %q, %w", cfg.AgentConfig.Exec.ChildProcessStdout, err)
+	if processConfig.ChildProcessStderr != "" {
+		childProcessStderr, err = os.OpenFile(processConfig.ChildProcessStderr, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			if childProcessStdout != os.Stdout {
+				_ = childProcessStdout.Close()
 			}
+			return nil, fmt.Errorf("could not open %q, %w", processConfig.ChildProcessStderr, err)
 		}
 	}
 
-	server := Server{
-		logger:             cfg.Logger,
-		config:             cfg,
+	return &process{
+		config:             processConfig,
+		serverConfig:       cfg,
+		logger:             logger,
 		childProcessState:  childProcessStateNotStarted,
 		childProcessExitCh: make(chan int),
 		childProcessStdout: childProcessStdout,
 		childProcessStderr: childProcessStderr,
-	}
-
-	return &server, nil
+	}, nil
 }
 
+// Run starts supervising the child processes once a token is received. It
+// returns when ctx is done, or as soon as one of the processes fails, after
+// stopping all of them.
 func (s *Server) Run(ctx context.Context, incomingVaultToken chan string) error {
-	latestToken := new(string)
 	s.logger.Info("starting exec server")
 	defer func() {
 		s.logger.Info("exec server stopped")
 	}()
 
-	if len(s.config.AgentConfig.EnvTemplates) == 0 || s.config.AgentConfig.Exec == nil {
+	if len(s.processes) == 0 {
 		s.logger.Info("no env templates or exec config, exiting")
 		<-ctx.Done()
 		return nil
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(s.processes))
+	tokenChs := make([]chan string, len(s.processes))
+	addressChs := make([]chan string, len(s.processes))
+	for i, p := range s.processes {
+		tokenChs[i] = make(chan string, 1)
+		addressChs[i] = make(chan string, 1)
+
+		wg.Add(1)
+		go func(p *process, tokenCh, addressCh chan string) {
+			defer wg.Done()
+
+			err := p.run(ctx, tokenCh, addressCh)
+			if err != nil && p.config.Name != "" {
+				err = fmt.Errorf("process %q: %w", p.config.Name, err)
+			}
+			errCh <- err
+		}(p, tokenChs[i], addressChs[i])
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+
+		case token := <-incomingVaultToken:
+			for _, ch := range tokenChs {
+				sendLatest(ch, token)
+			}
+
+		case address := <-s.config.VaultAddressCh:
+			for _, ch := range addressChs {
+				sendLatest(ch, address)
+			}
+
+		case err := <-errCh:
+			// processes only return early on failure, stop the others
+			cancel()
+			wg.Wait()
+			return err
+		}
+	}
+}
+
+// sendLatest sends value on a channel with a buffer of one, replacing any
+// value not received yet.
+func sendLatest(ch chan string, value string) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- value
+}
+
+func (p *process) run(ctx context.Context, incomingVaultToken, incomingVaultAddress <-chan string) error {
+	latestToken := new(string)
+
 	managerConfig := ctmanager.ManagerConfig{
-		AgentConfig: s.config.AgentConfig,
-		Namespace:   s.config.Namespace,
-		LogLevel:    s.config.LogLevel,
-		LogWriter:   s.config.LogWriter,
+		AgentConfig: p.serverConfig.AgentConfig,
+		Namespace:   p.serverConfig.Namespace,
+		LogLevel:    p.serverConfig.LogLevel,
+		LogWriter:   p.serverConfig.LogWriter,
 	}
 
-	runnerConfig, err := ctmanager.NewConfig(managerConfig, s.config.AgentConfig.EnvTemplates)
+	runnerConfig, err := ctmanager.NewConfig(managerConfig, p.config.EnvTemplates)
 	if err != nil {
 		return fmt.Errorf("template server failed to generate runner config: %w", err)
 	}
 
 	// We leave this in "dry" mode, as there are no files to render;
 	// we will get the environment variables rendered contents from the incoming events
-	s.runner, err = manager.NewRunner(runnerConfig, true)
+	p.runner, err = manager.NewRunner(runnerConfig, true)
 	if err != nil {
 		return fmt.Errorf("template server failed to create: %w", err)
 	}
 
 	// prevent the templates from being rendered to stdout in "dry" mode
-	s.runner.SetOutStream(io.Discard)
+	p.runner.SetOutStream(io.Discard)
 
-	s.numberOfTemplates = len(s.runner.TemplateConfigMapping())
+	p.numberOfTemplates = len(p.runner.TemplateConfigMapping())
 
 	// We receive multiple events every staticSecretRenderInterval
-	// from <-s.runner.TemplateRenderedCh(), one for each secret. Only the last
+	// from <-p.runner.TemplateRenderedCh(), one for each secret. Only the last
 	// event in a batch will contain the latest set of all secrets and the
 	// corresponding environment variables. This timer will fire after 2 seconds
 	// unless an event comes in which resets the timer back to 2 seconds.
@@ -175,24 +281,27 @@ func (s *Server) Run(ctx context.Context, incomingVaultToken chan string) error
 	// consul template server
 	restartBackoff := backoff.NewBackoff(math.MaxInt, consts.DefaultMinBackoff, consts.DefaultMaxBackoff)
 
+	// and another one for restarting the child process after it exited
+	exitBackoff := backoff.NewBackoff(math.MaxInt, consts.DefaultMinBackoff, consts.DefaultMaxBackoff)
+
 	for {
 		select {
 		case <-ctx.Done():
-			s.runner.Stop()
-			s.childProcessLock.Lock()
-			if s.childProcess != nil {
-				s.childProcess.Stop()
+			p.runner.Stop()
+			p.childProcessLock.Lock()
+			if p.childProcess != nil {
+				p.childProcess.Stop()
 			}
-			s.childProcessState = childProcessStateStopped
-			s.close()
-			s.childProcessLock.Unlock()
+			p.childProcessState = childProcessStateStopped
+			p.close()
+			p.childProcessLock.Unlock()
 			return nil
 
 		case token := <-incomingVaultToken:
 			if token != *latestToken {
-				s.logger.Info("exec server received new token")
+				p.logger.Info("exec server received new token")
 
-				s.runner.Stop()
+				p.runner.Stop()
 				*latestToken = token
 				newTokenConfig := ctconfig.Config{
 					Vault: &ctconfig.VaultConfig{
@@ -203,52 +312,80 @@ func (s *Server) Run(ctx context.Context, incomingVaultToken chan string) error
 
 				// got a new auth token, merge it in with the existing config
 				runnerConfig = runnerConfig.Merge(&newTokenConfig)
-				s.runner, err = manager.NewRunner(runnerConfig, true)
+				p.runner, err = manager.NewRunner(runnerConfig, true)
 				if err != nil {
-					s.logger.Error("template server failed with new Vault token", "error", err)
+					p.logger.Error("template server failed with new Vault token", "error", err)
 					continue
 				}
 
 				// prevent the templates from being rendered to stdout in "dry" mode
-				s.runner.SetOutStream(io.Discard)
+				p.runner.SetOutStream(io.Discard)
+
+				go p.runner.Start()
+			}
 
-				go s.runner.Start()
+		case address := <-incomingVaultAddress:
+			if ctmanager.UsesInProcDialer(runnerConfig) {
+				continue
 			}
+			p.logger.Info("exec server switching Vault server", "address", address)
+			runnerConfig = runnerConfig.Merge(&ctconfig.Config{
+				Vault: &ctconfig.VaultConfig{
+					Address: pointerutil.StringPtr(address),
+				},
+			})
 
-		case err := <-s.runner.ErrCh:
-			s.logger.Error("template server error", "error", err.Error())
-			s.runner.StopImmediately()
+			// The runner is only started once a token was received, which
+			// will pick up the new address.
+			if *latestToken == "" {
+				continue
+			}
+			p.runner.Stop()
+			p.runner, err = manager.NewRunner(runnerConfig, true)
+			if err != nil {
+				p.logger.Error("template server failed with new Vault address", "error", err)
+				continue
+			}
+
+			// prevent the templates from being rendered to stdout in "dry" mode
+			p.runner.SetOutStream(io.Discard)
+
+			go p.runner.Start()
+
+		case err := <-p.runner.ErrCh:
+			p.logger.Error("template server error", "error", err.Error())
+			p.runner.StopImmediately()
 
 			// Return after stopping the runner if exit on retry failure was specified
-			if s.config.AgentConfig.TemplateConfig != nil && s.config.AgentConfig.TemplateConfig.ExitOnRetryFailure {
+			if p.serverConfig.AgentConfig.TemplateConfig != nil && p.serverConfig.AgentConfig.TemplateConfig.ExitOnRetryFailure {
 				return fmt.Errorf("template server: %w", err)
 			}
 
 			// Calculate the amount of time to backoff using exponential backoff
 			sleep, err := restartBackoff.Next()
 			if err != nil {
-				s.logger.Error("template server: reached maximum number restart attempts")
+				p.logger.Error("template server: reached maximum number restart attempts")
 				restartBackoff.Reset()
 			}
 
 			// Sleep for the calculated backoff time then attempt to create a new runner
-			s.logger.Warn(fmt.Sprintf("template server restart: retry attempt after %s", sleep))
+			p.logger.Warn(fmt.Sprintf("template server restart: retry attempt after %s", sleep))
 			time.Sleep(sleep)
 
-			s.runner, err = manager.NewRunner(runnerConfig, true)
+			p.runner, err = manager.NewRunner(runnerConfig, true)
 			if err != nil {
 				return fmt.Errorf("template server failed to create: %w", err)
 			}
-			go s.runner.Start()
+			go p.runner.Start()
 
-		case <-s.runner.TemplateRenderedCh():
+		case <-p.runner.TemplateRenderedCh():
 			// A template has been rendered, figure out what to do
-			s.logger.Trace("template rendered")
-			events := s.runner.RenderEvents()
+			p.logger.Trace("template rendered")
+			events := p.runner.RenderEvents()
 
 			// This checks if we've finished rendering the initial set of templates,
-			// for every consecutive re-render len(events) should equal s.numberOfTemplates
-			if len(events) < s.numberOfTemplates {
+			// for every consecutive re-render len(events) should equal p.numberOfTemplates
+			if len(events) < p.numberOfTemplates {
 				// Not all templates have been rendered yet
 				continue
 			}
@@ -275,23 +412,23 @@ func (s *Server) Run(ctx context.Context, incomingVaultToken chan string) error
 			// sort the environment variables for a deterministic output and easy comparison
 			sort.Strings(renderedEnvVars)
 
-			s.logger.Trace("done rendering templates")
+			p.logger.Trace("done rendering templates")
 
 			// don't restart the process unless a change is detected
-			if slices.Equal(s.lastRenderedEnvVars, renderedEnvVars) {
+			if slices.Equal(p.lastRenderedEnvVars, renderedEnvVars) {
 				continue
 			}
 
-			s.lastRenderedEnvVars = renderedEnvVars
+			p.lastRenderedEnvVars = renderedEnvVars
 
-			s.logger.Debug("detected a change in the environment variables: restarting the child process")
+			p.logger.Debug("detected a change in the environment variables: restarting the child process")
 
 			// if a timer exists, stop it
 			if debounceTimer != nil {
 				debounceTimer.Stop()
 			}
 			debounceTimer = time.AfterFunc(2*time.Second, func() {
-				if err := s.restartChildProcess(renderedEnvVars); err != nil {
+				if err := p.restartChildProcess(renderedEnvVars); err != nil {
 					restartChildProcessErrCh <- fmt.Errorf("unable to restart the child process: %w", err)
 				}
 			})
@@ -300,66 +437,110 @@ func (s *Server) Run(ctx context.Context, incomingVaultToken chan string) error
 			// catch the error from restarting
 			return err
 
-		case exitCode := <-s.childProcessExitCh:
+		case exitCode := <-p.childProcessExitCh:
 			// process exited on its own
-			return &ProcessExitError{ExitCode: exitCode}
+			if !p.restartOnExit(exitCode) {
+				return &ProcessExitError{ExitCode: exitCode}
+			}
+
+			p.childProcessLock.Lock()
+			// don't keep backing off if the process ran for a while
+			if time.Since(p.childProcessStartedAt) > consts.DefaultMaxBackoff {
+				exitBackoff.Reset()
+			}
+			p.childProcessState = childProcessStateNotStarted
+			p.childProcessLock.Unlock()
+
+			sleep, err := exitBackoff.Next()
+			if err != nil {
+				exitBackoff.Reset()
+			}
+
+			p.logger.Warn(fmt.Sprintf("child process exited with %d, restarting after %s", exitCode, sleep),
+				"restart_policy", p.config.RestartPolicy)
+
+			envVars := p.lastRenderedEnvVars
+			time.AfterFunc(sleep, func() {
+				if err := p.restartChildProcess(envVars); err != nil {
+					restartChildProcessErrCh <- fmt.Errorf("unable to restart the child process: %w", err)
+				}
+			})
 		}
 	}
 }
 
-func (s *Server) restartChildProcess(newEnvVars []string) error {
-	s.childProcessLock.Lock()
-	defer s.childProcessLock.Unlock()
+// restartOnExit returns whether the child process is restarted after exiting
+// with the given code, according to its restart policy.
+func (p *process) restartOnExit(exitCode int) bool {
+	switch p.config.RestartPolicy {
+	case "always":
+		return true
+	case "on-failure":
+		return exitCode != 0
+	default:
+		return false
+	}
+}
+
+func (p *process) restartChildProcess(newEnvVars []string) error {
+	p.childProcessLock.Lock()
+	defer p.childProcessLock.Unlock()
 
-	switch s.config.AgentConfig.Exec.RestartOnSecretChanges {
+	// the server is shutting down
+	if p.childProcessState == childProcessStateStopped {
+		return nil
+	}
+
+	switch p.config.RestartOnSecretChanges {
 	case "always":
-		if s.childProcessState == childProcessStateRunning {
+		if p.childProcessState == childProcessStateRunning {
 			// process is running, need to kill it first
-			s.logger.Info("stopping process", "process_id", s.childProcess.Pid())
-			s.childProcessState = childProcessStateRestarting
-			s.childProcess.Stop()
+			p.logger.Info("stopping process", "process_id", p.childProcess.Pid())
+			p.childProcessState = childProcessStateRestarting
+			p.childProcess.Stop()
 		}
 	case "never":
-		if s.childProcessState == childProcessStateRunning {
-			s.logger.Info("detected update, but not restarting process", "process_id", s.childProcess.Pid())
+		if p.childProcessState == childProcessStateRunning {
+			p.logger.Info("detected update, but not restarting process", "process_id", p.childProcess.Pid())
 			return nil
 		}
 	default:
-		return fmt.Errorf("invalid value for restart-on-secret-changes: %q", s.config.AgentConfig.Exec.RestartOnSecretChanges)
+		return fmt.Errorf("invalid value for restart-on-secret-changes: %q", p.config.RestartOnSecretChanges)
 	}
 
-	args, subshell, err := child.CommandPrep(s.config.AgentConfig.Exec.Command)
+	args, subshell, err := child.CommandPrep(p.config.Command)
 	if err != nil {
 		return fmt.Errorf("unable to parse command: %w", err)
 	}
 
 	childInput := &child.NewInput{
 		Stdin:        os.Stdin,
-		Stdout:       s.childProcessStdout,
-		Stderr:       s.childProcessStderr,
+		Stdout:       p.childProcessStdout,
+		Stderr:       p.childProcessStderr,
 		Command:      args[0],
 		Args:         args[1:],
 		Timeout:      0, // let it run forever
 		Env:          append(os.Environ(), newEnvVars...),
 		ReloadSignal: nil, // can't reload w/ new env vars
-		KillSignal:   s.config.AgentConfig.Exec.RestartStopSignal,
+		KillSignal:   p.config.RestartStopSignal,
 		KillTimeout:  30 * time.Second,
 		Splay:        0,
 		Setpgid:      subshell,
-		Logger:       s.logger.StandardLogger(nil),
+		Logger:       p.logger.StandardLogger(nil),
 	}
 
 	proc, err := child.New(childInput)
 	if err != nil {
 		return err
 	}
-	s.childProcess = proc
+	p.childProcess = proc
 
-	if err := s.childProcess.Start(); err != nil {
+	if err := p.childProcess.Start(); err != nil {
 		return fmt.Errorf("error starting the child process: %w", err)
 	}
 
-	s.childProcessState = childProcessStateRunning
+	p.childProcessState = childProcessStateRunning
+	p.childProcessStartedAt = time.Now()
 
 	// Listen if the child process exits and bubble it up to the main loop.
 	//
@@ -370,7 +551,7 @@ func (s *Server) restartChildProcess(newEnvVars []string) error {
 		case exitCode, ok := <-proc.ExitCh():
 			// ignore ExitCh channel closures caused by our restarts
 			if ok {
-				s.childProcessExitCh <- exitCode
+				p.childProcessExitCh <- exitCode
 			}
 		}
 	}()
@@ -379,16 +560,24 @@ func (s *Server) restartChildProcess(newEnvVars []string) error {
 }
 
 func (s *Server) Close() {
-	s.childProcessLock.Lock()
-	defer s.childProcessLock.Unlock()
-	s.close()
+	for _, p := range s.processes {
+		p.childProcessLock.Lock()
+		p.close()
+		p.childProcessLock.Unlock()
+	}
 }
 
 func (s *Server) close() {
-	if s.childProcessStdout != os.Stdout {
-		_ = s.childProcessStdout.Close()
+	for _, p := range s.processes {
+		p.close()
+	}
+}
+
+func (p *process) close() {
+	if p.childProcessStdout != os.Stdout {
+		_ = p.childProcessStdout.Close()
 	}
-	if s.childProcessStderr != os.Stderr {
-		_ = s.childProcessStderr.Close()
+	if p.childProcessStderr != os.Stderr {
+		_ = p.childProcessStderr.Close()
 	}
 }
@@ -565,6 +565,157 @@ func TestExecServer_LogFiles(t *testing.T) {
 // we use it, but we're not the process that needs to open the port,
 // and we still need to be able to access it.
 // We should be fine so long as we don't make the tests parallel.
+// TestExecServer_Processes verifies that multiple named processes are started
+// with their own environment variables, and that a process exiting with a
+// failure is restarted according to its restart policy.
+func TestExecServer_Processes(t *testing.T) {
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Fatalf("could not find go binary on path: %s", err)
+	}
+
+	testAppBinary := filepath.Join(os.TempDir(), "test-app")
+
+	if err := exec.Command(goBinary, "build", "-o", testAppBinary, "./test-app").Run(); err != nil {
+		t.Fatalf("could not build the test application: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := os.Remove(testAppBinary); err != nil {
+			t.Fatalf("could not remove %q test application: %s", testAppBinary, err)
+		}
+	})
+
+	fakeVault := fakeVaultServer(t)
+	defer fakeVault.Close()
+
+	webPort, workerPort := findOpenPort(t), findOpenPort(t)
+
+	execServer, err := NewServer(&ServerConfig{
+		Logger: logging.NewVaultLogger(hclog.Trace),
+		AgentConfig: &config.Config{
+			Vault: &config.Vault{
+				Address: fakeVault.URL,
+				Retry: &config.Retry{
+					NumRetries: 3,
+				},
+			},
+			Exec: &config.ExecConfig{
+				Processes: []*config.ExecProcess{
+					{
+						Name:                   "web",
+						Command:                []string{testAppBinary, "--port", strconv.Itoa(webPort), "--stop-after", "60s"},
+						RestartOnSecretChanges: "always",
+						RestartStopSignal:      syscall.SIGTERM,
+						RestartPolicy:          "never",
+						EnvTemplates: []*ctconfig.TemplateConfig{{
+							Contents:                 pointerutil.StringPtr(`{{ with secret "kv/my-app/creds" }}{{ .Data.data.user }}{{ end }}`),
+							MapToEnvironmentVariable: pointerutil.StringPtr("MY_USER"),
+						}},
+					},
+					{
+						Name:                   "worker",
+						Command:                []string{testAppBinary, "--port", strconv.Itoa(workerPort), "--stop-after", "3s", "--exit-code", "3"},
+						RestartOnSecretChanges: "always",
+						RestartStopSignal:      syscall.SIGTERM,
+						RestartPolicy:          "on-failure",
+						EnvTemplates: []*ctconfig.TemplateConfig{{
+							Contents:                 pointerutil.StringPtr(`{{ with secret "kv/my-app/creds" }}{{ .Data.data.user }}{{ end }}`),
+							MapToEnvironmentVariable: pointerutil.StringPtr("WORKER_USER"),
+						}},
+					},
+				},
+			},
+			TemplateConfig: &config.TemplateConfig{
+				ExitOnRetryFailure: true,
+			},
+		},
+		LogLevel:  hclog.Trace,
+		LogWriter: hclog.DefaultOutput,
+	})
+	if err != nil {
+		t.Fatalf("could not create exec server: %q", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var (
+		execServerErrCh   = make(chan error, 1)
+		execServerTokenCh = make(chan string, 1)
+	)
+	go func() {
+		execServerErrCh <- execServer.Run(ctx, execServerTokenCh)
+	}()
+
+	// send a dummy token to kick off the server
+	execServerTokenCh <- "my-token"
+
+	type testAppResponse struct {
+		EnvironmentVariables map[string]string `json:"environment_variables"`
+		ProcessID            int               `json:"process_id"`
+	}
+	query := func(port int) (*testAppResponse, error) {
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%d", port))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		var response testAppResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, err
+		}
+		return &response, nil
+	}
+	waitFor := func(port int, condition func(*testAppResponse) bool) *testAppResponse {
+		t.Helper()
+
+		for {
+			select {
+			case err := <-execServerErrCh:
+				t.Fatalf("exec server exited early: %v", err)
+			case <-ctx.Done():
+				t.Fatalf("timeout reached waiting for the test app on port %d", port)
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			if response, err := query(port); err == nil && condition(response) {
+				return response
+			}
+		}
+	}
+
+	web := waitFor(webPort, func(*testAppResponse) bool { return true })
+	if web.EnvironmentVariables["MY_USER"] != "app-user" {
+		t.Fatalf("expected MY_USER to be 'app-user', got %q", web.EnvironmentVariables["MY_USER"])
+	}
+	if _, ok := web.EnvironmentVariables["WORKER_USER"]; ok {
+		t.Fatal("expected the web process not to get the environment variables of the worker")
+	}
+
+	worker := waitFor(workerPort, func(*testAppResponse) bool { return true })
+	if worker.EnvironmentVariables["WORKER_USER"] != "app-user" {
+		t.Fatalf("expected WORKER_USER to be 'app-user', got %q", worker.EnvironmentVariables["WORKER_USER"])
+	}
+
+	// the worker exits with a failure and is restarted, the web process is left alone
+	restarted := waitFor(workerPort, func(response *testAppResponse) bool {
+		return response.ProcessID != worker.ProcessID
+	})
+	if restarted.EnvironmentVariables["WORKER_USER"] != "app-user" {
+		t.Fatalf("expected the restarted worker to get WORKER_USER, got %q", restarted.EnvironmentVariables["WORKER_USER"])
+	}
+
+	if response, err := query(webPort); err != nil || response.ProcessID != web.ProcessID {
+		t.Fatalf("expected the web process to still be running: %v", err)
+	}
+
+	cancel()
+	if err := <-execServerErrCh; err != nil {
+		t.Fatalf("exec server did not expect an error, got: %v", err)
+	}
+}
+
 func findOpenPort(t *testing.T) int {
 	t.Helper()
 	ln, err := net.Listen("tcp", ":0")
@@ -157,3 +157,10 @@ func logLevelToStringPtr(level hclog.Level) *string {
 	}
 	return pointerutil.StringPtr(levelStr)
 }
+
+// UsesInProcDialer reports whether the runner configuration reaches Vault
+// through the in-process listener, whose API proxy follows Vault address
+// changes on its own, rather than at its configured Vault address.
+func UsesInProcDialer(conf *ctconfig.Config) bool {
+	return conf.Vault != nil && conf.Vault.Transport != nil && conf.Vault.Transport.CustomDialer != nil
+}
@@ -0,0 +1,281 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package template
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	ctconfig "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/manager"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/sdk/helper/pointerutil"
+)
+
+// defaultGroupCommandTimeout matches the default timeout of template commands
+// in Consul Template.
+const defaultGroupCommandTimeout = 30 * time.Second
+
+// templateGroup renders the templates of a config.TemplateGroup into its
+// staging directory, and moves the files into place together once all of them
+// are rendered and validated.
+type templateGroup struct {
+	config *config.TemplateGroup
+	logger hclog.Logger
+
+	// destinations maps the staged file of each template to its destination
+	destinations map[string]string
+}
+
+func newTemplateGroup(groupConfig *config.TemplateGroup, logger hclog.Logger) (*templateGroup, []*ctconfig.TemplateConfig) {
+	group := &templateGroup{
+		config:       groupConfig,
+		logger:       logger.Named(groupConfig.Name),
+		destinations: make(map[string]string, len(groupConfig.Templates)),
+	}
+
+	templates := make([]*ctconfig.TemplateConfig, 0, len(groupConfig.Templates))
+	for _, tmpl := range groupConfig.Templates {
+		staged := tmpl.Copy()
+		stagedFile := filepath.Join(groupConfig.StagingDir, filepath.Base(*tmpl.Destination))
+		staged.Destination = pointerutil.StringPtr(stagedFile)
+
+		group.destinations[stagedFile] = *tmpl.Destination
+		templates = append(templates, staged)
+	}
+
+	return group, templates
+}
+
+// rendered returns whether all the templates of the group were rendered by
+// the runner. Events are keyed by template ID, which is shared by templates
+// with the same source or contents, so lookupMap is used to find all the
+// templates of an event.
+func (g *templateGroup) rendered(events map[string]*manager.RenderEvent, lookupMap map[string][]*ctconfig.TemplateConfig) bool {
+	renderedFiles := 0
+	for id, event := range events {
+		if event.LastWouldRender.IsZero() {
+			continue
+		}
+		for _, tcfg := range lookupMap[id] {
+			if tcfg.Destination == nil {
+				continue
+			}
+			if _, ok := g.destinations[*tcfg.Destination]; ok {
+				renderedFiles++
+			}
+		}
+	}
+
+	return renderedFiles == len(g.destinations)
+}
+
+// apply moves the staged files into place if any of them changed, after
+// running the validation command, and then runs the command of the group.
+func (g *templateGroup) apply(ctx context.Context) error {
+	changed, err := g.changedFiles()
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if len(g.config.ValidateCommand) > 0 {
+		g.logger.Debug("validating rendered files", "staging_dir", g.config.StagingDir)
+		if err := g.runCommand(ctx, g.config.ValidateCommand, g.config.ValidateCommandTimeout, g.config.StagingDir); err != nil {
+			return fmt.Errorf("validation failed, keeping the current files: %w", err)
+		}
+	}
+
+	if err := g.swap(changed); err != nil {
+		return err
+	}
+	g.logger.Info("replaced rendered files", "files", len(changed))
+
+	if len(g.config.Command) > 0 {
+		if err := g.runCommand(ctx, g.config.Command, g.config.CommandTimeout, ""); err != nil {
+			return fmt.Errorf("command failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// changedFiles returns the staged files which differ from their destination.
+func (g *templateGroup) changedFiles() ([]string, error) {
+	var changed []string
+	for stagedFile, destination := range g.destinations {
+		staged, err := os.ReadFile(stagedFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading staged file: %w", err)
+		}
+
+		current, err := os.ReadFile(destination)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error reading destination: %w", err)
+		}
+
+		if err != nil || !bytes.Equal(staged, current) {
+			changed = append(changed, stagedFile)
+		}
+	}
+
+	return changed, nil
+}
+
+// rename is os.Rename, replaced by tests to inject failures.
+var rename = os.Rename
+
+// swapFile is a destination being replaced by swap.
+type swapFile struct {
+	destination string
+	tempFile    string
+	// backup holds the previous contents of the destination, or is empty if
+	// the destination did not exist.
+	backup   string
+	replaced bool
+}
+
+// swap copies the staged files, and the current destinations, next to their
+// destination first, and only renames them over their destination once all
+// of them are written. If any of the renames fails, the destinations replaced
+// so far are restored from their backup, so that either all the files are
+// updated or none of them are. The staged files are kept so that Consul
+// Template does not render them again unchanged.
+func (g *templateGroup) swap(stagedFiles []string) error {
+	files := make([]*swapFile, 0, len(stagedFiles))
+	cleanup := func() {
+		for _, f := range files {
+			if f.tempFile != "" {
+				_ = os.Remove(f.tempFile)
+			}
+			if f.backup != "" {
+				_ = os.Remove(f.backup)
+			}
+		}
+	}
+	defer cleanup()
+
+	for _, stagedFile := range stagedFiles {
+		f := &swapFile{destination: g.destinations[stagedFile]}
+		files = append(files, f)
+
+		var err error
+		f.tempFile, err = copyToTempFile(stagedFile, filepath.Dir(f.destination))
+		if err != nil {
+			return fmt.Errorf("error staging %q: %w", f.destination, err)
+		}
+
+		f.backup, err = copyToTempFile(f.destination, filepath.Dir(f.destination))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error backing up %q: %w", f.destination, err)
+		}
+	}
+
+	for _, f := range files {
+		if err := rename(f.tempFile, f.destination); err != nil {
+			g.rollback(files)
+			return fmt.Errorf("error replacing %q, restored the previous files: %w", f.destination, err)
+		}
+		f.tempFile = ""
+		f.replaced = true
+	}
+
+	return nil
+}
+
+// rollback restores the destinations replaced by swap from their backup, and
+// removes the ones which did not exist before.
+func (g *templateGroup) rollback(files []*swapFile) {
+	for _, f := range files {
+		if !f.replaced {
+			continue
+		}
+
+		var err error
+		if f.backup != "" {
+			err = rename(f.backup, f.destination)
+			if err == nil {
+				f.backup = ""
+			}
+		} else {
+			err = os.Remove(f.destination)
+		}
+		if err != nil {
+			g.logger.Error("error restoring file", "destination", f.destination, "error", err)
+		}
+	}
+}
+
+// copyToTempFile copies a file into a new temporary file of dir, with the same
+// permissions.
+func copyToTempFile(source, dir string) (string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(source)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(source)+".tmp")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.Write(contents)
+	if err == nil {
+		err = f.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+func (g *templateGroup) runCommand(ctx context.Context, command []string, timeout time.Duration, dir string) error {
+	args, _, err := child.CommandPrep(command)
+	if err != nil {
+		return fmt.Errorf("unable to parse command: %w", err)
+	}
+
+	if timeout == 0 {
+		timeout = defaultGroupCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if len(output) > 0 {
+		g.logger.Debug("command output", "command", command, "output", string(output))
+	}
+	if err != nil {
+		return fmt.Errorf("%q: %w: %s", command, err, bytes.TrimSpace(output))
+	}
+
+	return nil
+}
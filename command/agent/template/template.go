@@ -50,6 +50,12 @@ type ServerConfig struct {
 	// the same io.Writer that Vault Agent itself is using.
 	LogLevel  hclog.Level
 	LogWriter io.Writer
+
+	// VaultAddressCh receives the address of the Vault server to fail over
+	// to, when multiple addresses are configured. It is ignored when the
+	// runner goes through the in-process cache listener, whose API proxy
+	// fails over on its own.
+	VaultAddressCh <-chan string
 }
 
 // Server manages the Consul Template Runner which renders templates
@@ -106,6 +112,17 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 		ts.logger.Info("template server stopped")
 	}()
 
+	// The templates of groups are rendered into their staging directory, by
+	// the same runner.
+	var groups []*templateGroup
+	if ts.config.AgentConfig != nil {
+		for _, groupConfig := range ts.config.AgentConfig.TemplateGroups {
+			group, groupTemplates := newTemplateGroup(groupConfig, ts.logger)
+			groups = append(groups, group)
+			templates = append(templates[:len(templates):len(templates)], groupTemplates...)
+		}
+	}
+
 	// If there are no templates, we wait for context cancellation and then return
 	if len(templates) == 0 {
 		ts.logger.Info("no templates found")
@@ -192,6 +209,30 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 				go ts.runner.Start()
 			}
 
+		case address := <-ts.config.VaultAddressCh:
+			if ctmanager.UsesInProcDialer(runnerConfig) {
+				continue
+			}
+			ts.logger.Info("template server switching Vault server", "address", address)
+			runnerConfig = runnerConfig.Merge(&ctconfig.Config{
+				Vault: &ctconfig.VaultConfig{
+					Address: pointerutil.StringPtr(address),
+				},
+			})
+
+			// The runner is only started once a token was received, which
+			// will pick up the new address.
+			if !ts.runnerStarted.Load() {
+				continue
+			}
+			ts.runner.Stop()
+			ts.runner, err = manager.NewRunner(runnerConfig, false)
+			if err != nil {
+				ts.logger.Error("template server failed with new Vault address", "error", err)
+				continue
+			}
+			go ts.runner.Start()
+
 		case err := <-ts.runner.ErrCh:
 			ts.logger.Error("template server error", "error", err.Error())
 			ts.runner.StopImmediately()
@@ -223,6 +264,18 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 			// A template has been rendered, figure out what to do
 			events := ts.runner.RenderEvents()
 
+			// Replace the files of the groups which are completely rendered
+			var groupErr error
+			for _, group := range groups {
+				if !group.rendered(events, ts.lookupMap) {
+					continue
+				}
+				if err := group.apply(ctx); err != nil {
+					ts.logger.Error("template group error", "group", group.config.Name, "error", err)
+					groupErr = errors.Join(groupErr, fmt.Errorf("template group %q: %w", group.config.Name, err))
+				}
+			}
+
 			// events are keyed by template ID, and can be matched up to the id's from
 			// the lookupMap
 			if len(events) < len(ts.lookupMap) {
@@ -244,6 +297,9 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 				// return. The deferred closing of the DoneCh will allow agent to
 				// continue with closing down
 				ts.runner.Stop()
+				if groupErr != nil {
+					return fmt.Errorf("template server: %w", groupErr)
+				}
 				return nil
 			}
 		case err := <-ts.runner.ServerErrCh:
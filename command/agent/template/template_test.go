@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	sync "sync/atomic"
 	"testing"
@@ -449,6 +450,174 @@ func TestServerRun(t *testing.T) {
 	}
 }
 
+// TestServerRun_TemplateGroup tests that the files of a template group are
+// only moved into place once validated, and that its command runs after.
+func TestServerRun_TemplateGroup(t *testing.T) {
+	ts := createHttpTestServer()
+	defer ts.Close()
+
+	testCases := map[string]struct {
+		validateCommand []string
+		expectError     bool
+	}{
+		"valid": {
+			validateCommand: []string{"test -s cert.pem && test -s key.pem"},
+		},
+		"no validation": {},
+		"invalid": {
+			validateCommand: []string{"test -s missing.pem"},
+			expectError:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			reloadedFile := filepath.Join(tmpDir, "reloaded")
+
+			group := &config.TemplateGroup{
+				Name:            "tls",
+				StagingDir:      filepath.Join(tmpDir, "staging"),
+				ValidateCommand: tc.validateCommand,
+				Command:         []string{"touch", reloadedFile},
+			}
+			for _, fileName := range []string{"cert.pem", "key.pem"} {
+				group.Templates = append(group.Templates, &ctconfig.TemplateConfig{
+					Contents:    pointerutil.StringPtr(templateContents),
+					Destination: pointerutil.StringPtr(filepath.Join(tmpDir, "tls", fileName)),
+				})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+			server := NewServer(&ServerConfig{
+				Logger: logging.NewVaultLogger(hclog.Trace),
+				AgentConfig: &config.Config{
+					Vault: &config.Vault{
+						Address: ts.URL,
+						Retry: &config.Retry{
+							NumRetries: 3,
+						},
+					},
+					TemplateConfig: &config.TemplateConfig{},
+					TemplateGroups: []*config.TemplateGroup{group},
+				},
+				LogLevel:      hclog.Trace,
+				LogWriter:     hclog.DefaultOutput,
+				ExitAfterAuth: true,
+			})
+
+			templateTokenCh := make(chan string, 1)
+			errCh := make(chan error)
+			go func() {
+				errCh <- server.Run(ctx, templateTokenCh, nil, &sync.Bool{}, make(chan error, 1))
+			}()
+			templateTokenCh <- "test"
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("timeout reached before templates were rendered")
+			case err := <-errCh:
+				if tc.expectError {
+					if err == nil {
+						t.Fatal("expected an error")
+					}
+					for _, template := range group.Templates {
+						if _, err := os.Stat(*template.Destination); !os.IsNotExist(err) {
+							t.Fatalf("expected %q not to be replaced, got: %v", *template.Destination, err)
+						}
+					}
+					if _, err := os.Stat(reloadedFile); !os.IsNotExist(err) {
+						t.Fatal("expected the command not to run")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("did not expect error, got: %v", err)
+				}
+			}
+
+			for _, template := range group.Templates {
+				content, err := os.ReadFile(*template.Destination)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(content), `"username":"appuser"`) {
+					t.Fatalf("unexpected contents of %q: %s", *template.Destination, content)
+				}
+			}
+			if _, err := os.Stat(reloadedFile); err != nil {
+				t.Fatalf("expected the command to run: %v", err)
+			}
+		})
+	}
+}
+
+// TestTemplateGroup_SwapRollback verifies that the files of a group are
+// restored when one of them cannot be replaced.
+func TestTemplateGroup_SwapRollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	group := &config.TemplateGroup{
+		Name:       "tls",
+		StagingDir: filepath.Join(tmpDir, "staging"),
+	}
+	for _, fileName := range []string{"ca.pem", "cert.pem", "key.pem"} {
+		group.Templates = append(group.Templates, &ctconfig.TemplateConfig{
+			Destination: pointerutil.StringPtr(filepath.Join(tmpDir, "tls", fileName)),
+		})
+	}
+	g, _ := newTemplateGroup(group, logging.NewVaultLogger(hclog.Trace))
+
+	require.NoError(t, os.MkdirAll(group.StagingDir, 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "tls"), 0o755))
+	var stagedFiles []string
+	for stagedFile, destination := range g.destinations {
+		require.NoError(t, os.WriteFile(stagedFile, []byte("new"), 0o600))
+		// key.pem is new to the group
+		if filepath.Base(destination) != "key.pem" {
+			require.NoError(t, os.WriteFile(destination, []byte("old"), 0o600))
+		}
+		stagedFiles = append(stagedFiles, stagedFile)
+	}
+
+	// Fail the last replacement, once the others went through
+	renames := 0
+	rename = func(oldpath, newpath string) error {
+		renames++
+		if renames == len(stagedFiles) {
+			return fmt.Errorf("injected failure")
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	defer func() { rename = os.Rename }()
+
+	err := g.swap(stagedFiles)
+	require.ErrorContains(t, err, "injected failure")
+
+	for _, destination := range g.destinations {
+		content, err := os.ReadFile(destination)
+		if filepath.Base(destination) == "key.pem" {
+			require.True(t, os.IsNotExist(err), "expected %q to be removed, got: %v", destination, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, "old", string(content), destination)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(tmpDir, "tls"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "expected temporary files to be removed")
+
+	// Without failures all the files are replaced
+	rename = os.Rename
+	require.NoError(t, g.swap(stagedFiles))
+	for _, destination := range g.destinations {
+		content, err := os.ReadFile(destination)
+		require.NoError(t, err)
+		require.Equal(t, "new", string(content), destination)
+	}
+}
+
 // TestNewServerLogLevels tests that the server can be started with any log
 // level.
 func TestNewServerLogLevels(t *testing.T) {
@@ -36,12 +36,16 @@ const (
 // APIProxy is an implementation of the proxier interface that is used to
 // forward the request to Vault and get the response.
 type APIProxy struct {
-	client                  *api.Client
-	logger                  hclog.Logger
-	enforceConsistency      EnforceConsistency
-	whenInconsistentAction  WhenInconsistentAction
-	l                       sync.RWMutex
-	lastIndexStates         []string
+	client                 *api.Client
+	logger                 hclog.Logger
+	enforceConsistency     EnforceConsistency
+	whenInconsistentAction WhenInconsistentAction
+	l                      sync.RWMutex
+	lastIndexStates        []string
+	// lastIndexAddress is the Vault server lastIndexStates were recorded
+	// from. Index states are meaningless to other servers, such as the one
+	// failed over to, so they are only required from the same address.
+	lastIndexAddress        string
 	userAgentString         string
 	userAgentStringFunction func(string) string
 	// clientNamespace is a one-time set representation of the namespace of the client
@@ -136,8 +140,11 @@ func (ap *APIProxy) Send(ctx context.Context, req *SendRequest) (*SendResponse,
 
 	if manageState {
 		client = client.WithResponseCallbacks(api.RecordState(&newState))
+		var lastStates []string
 		ap.l.RLock()
-		lastStates := ap.lastIndexStates
+		if ap.lastIndexAddress == client.Address() {
+			lastStates = ap.lastIndexStates
+		}
 		ap.l.RUnlock()
 		if len(lastStates) != 0 {
 			client = client.WithRequestCallbacks(api.RequireState(lastStates...))
@@ -167,6 +174,10 @@ func (ap *APIProxy) Send(ctx context.Context, req *SendRequest) (*SendResponse,
 
 	if newState != "" {
 		ap.l.Lock()
+		if address := client.Address(); ap.lastIndexAddress != address {
+			ap.lastIndexStates = nil
+			ap.lastIndexAddress = address
+		}
 		// We want to be using the "newest" states seen, but newer isn't well
 		// defined here.  There can be two states S1 and S2 which aren't strictly ordered:
 		// S1 could have a newer localindex and S2 could have a newer replicatedindex.  So
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -155,6 +157,65 @@ func TestAPIProxy_queryParams(t *testing.T) {
 	}
 }
 
+// TestAPIProxy_IndexStatesFailover verifies that the index states recorded
+// from one Vault server are not required from another one, such as after
+// failing over.
+func TestAPIProxy_IndexStatesFailover(t *testing.T) {
+	newServer := func(state string, seen *[]string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*seen = append(*seen, r.Header.Get(vaulthttp.VaultIndexHeaderName))
+			w.Header().Set(vaulthttp.VaultIndexHeaderName, state)
+			w.Write([]byte("{}"))
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	var seen1, seen2 []string
+	srv1 := newServer("state1", &seen1)
+	srv2 := newServer("state2", &seen2)
+
+	client, err := api.NewClient(&api.Config{Address: srv1.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxier, err := NewAPIProxy(&APIProxyConfig{
+		Client:                  client,
+		Logger:                  logging.NewVaultLogger(hclog.Trace),
+		EnforceConsistency:      EnforceConsistencyAlways,
+		UserAgentStringFunction: useragent.ProxyStringWithProxiedUserAgent,
+		UserAgentString:         useragent.ProxyAPIProxyString(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func() {
+		t.Helper()
+		req, err := http.NewRequest("GET", "http://127.0.0.1/v1/secret/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := proxier.Send(context.Background(), &SendRequest{Request: req}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send()
+	send()
+	if err := client.SetAddress(srv2.URL); err != nil {
+		t.Fatal(err)
+	}
+	send()
+	send()
+
+	if !reflect.DeepEqual(seen1, []string{"", "state1"}) {
+		t.Fatalf("bad index states sent to the first server: %q", seen1)
+	}
+	if !reflect.DeepEqual(seen2, []string{"", "state2"}) {
+		t.Fatalf("bad index states sent to the second server: %q", seen2)
+	}
+}
+
 // setupClusterAndAgent is a helper func used to set up a test cluster and
 // caching agent against the active node. It returns a cleanup func that should
 // be deferred immediately along with two clients, one for direct cluster
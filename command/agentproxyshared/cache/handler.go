@@ -103,6 +103,9 @@ func ProxyHandler(ctx context.Context, logger hclog.Logger, proxier Proxier, inm
 		if resp.CacheMeta != nil {
 			if resp.CacheMeta.Hit {
 				metrics.IncrCounter([]string{"agent", "cache", "hit"}, 1)
+				if resp.CacheMeta.Stale {
+					metrics.IncrCounter([]string{"agent", "cache", "stale"}, 1)
+				}
 			} else {
 				metrics.IncrCounter([]string{"agent", "cache", "miss"}, 1)
 			}
@@ -135,6 +138,10 @@ func setHeaders(w http.ResponseWriter, resp *SendResponse) {
 
 			// Update the date value
 			w.Header().Set("Date", time.Now().Format(http.TimeFormat))
+
+			if resp.CacheMeta.Stale {
+				w.Header().Set("X-Cache-Stale", "true")
+			}
 		}
 
 		w.Header().Set("X-Cache", xCacheVal)
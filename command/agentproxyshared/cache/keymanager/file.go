@@ -0,0 +1,159 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package keymanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/golang/protobuf/proto"
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/go-kms-wrapping/wrappers/aead/v2"
+)
+
+var _ KeyManager = (*FileKeyManager)(nil)
+
+// FileKeyManager seals the encryption key with a key encryption key read from
+// a local file, so that the key can be stored next to the data it protects and
+// recovered after a restart by any process able to read the key file.
+type FileKeyManager struct {
+	passthrough *PassthroughKeyManager
+	kek         *aead.Wrapper
+}
+
+// NewFileKeyManager returns a new instance of the file-sealed encryption key.
+// The key encryption key is read from keyFile, which holds 32 base64-encoded
+// bytes and must not be accessible to group or others. If a retrieval token is
+// provided, the encryption key is unsealed from it, otherwise one will be
+// generated, along with the key file if it does not exist.
+func NewFileKeyManager(ctx context.Context, keyFile string, token []byte) (*FileKeyManager, error) {
+	// A missing key file can only seal a new encryption key, as the one of
+	// the retrieval token is lost without it.
+	kekBytes, err := loadOrCreateKeyFile(keyFile, len(token) == 0)
+	if err != nil {
+		return nil, err
+	}
+
+	kek := aead.NewWrapper()
+	if _, err := kek.SetConfig(ctx, wrapping.WithConfigMap(map[string]string{"key_id": KeyID})); err != nil {
+		return nil, err
+	}
+	if err := kek.SetAesGcmKeyBytes(kekBytes); err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if len(token) > 0 {
+		var blob wrapping.BlobInfo
+		if err := proto.Unmarshal(token, &blob); err != nil {
+			return nil, fmt.Errorf("error decoding retrieval token: %w", err)
+		}
+		key, err = kek.Decrypt(ctx, &blob)
+		if err != nil {
+			return nil, fmt.Errorf("error unsealing encryption key with %q: %w", keyFile, err)
+		}
+	}
+
+	passthrough, err := NewPassthroughKeyManager(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileKeyManager{
+		passthrough: passthrough,
+		kek:         kek,
+	}, nil
+}
+
+// Wrapper returns the manager's wrapper for key operations.
+func (f *FileKeyManager) Wrapper() wrapping.Wrapper {
+	return f.passthrough.Wrapper()
+}
+
+// RetrievalToken returns the encryption key sealed with the key encryption
+// key.
+func (f *FileKeyManager) RetrievalToken(ctx context.Context) ([]byte, error) {
+	key, err := f.passthrough.RetrievalToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := f.kek.Encrypt(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("error sealing encryption key: %w", err)
+	}
+
+	return proto.Marshal(blob)
+}
+
+// loadOrCreateKeyFile reads the key encryption key from path, or, if create is
+// set, writes a new one readable only by the current user if the file does not
+// exist.
+func loadOrCreateKeyFile(path string, create bool) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("key file path is required")
+	}
+
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && create:
+		return createKeyFile(path)
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("key file %q does not exist, but the persistent cache was sealed with it", path)
+	case err != nil:
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	if perms := info.Mode().Perm(); runtime.GOOS != "windows" && perms&0o077 != 0 {
+		return nil, fmt.Errorf("key file %q is accessible to group or others, its permissions should be %v, got %v", path, os.FileMode(0o600), perms)
+	}
+
+	encoded, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding key file %q: %w", path, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key size in key file %q, should be 32, got %d", path, len(key))
+	}
+
+	return key, nil
+}
+
+// createKeyFile writes a new key encryption key to path, readable only by the
+// current user.
+func createKeyFile(path string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error creating key file: %w", err)
+	}
+	_, err = f.WriteString(base64.StdEncoding.EncodeToString(key))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error writing key file: %w", err)
+	}
+
+	return key, nil
+}
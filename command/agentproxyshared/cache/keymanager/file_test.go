@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package keymanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyManager_FileKeyManager(t *testing.T) {
+	ctx := context.Background()
+	keyFile := filepath.Join(t.TempDir(), "cache.key")
+
+	m, err := NewFileKeyManager(ctx, keyFile, nil)
+	require.NoError(t, err)
+	require.NotNil(t, m.Wrapper())
+
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	key, err := m.passthrough.RetrievalToken(ctx)
+	require.NoError(t, err)
+	token, err := m.RetrievalToken(ctx)
+	require.NoError(t, err)
+	require.NotContains(t, string(token), string(key))
+
+	// The key is unsealed from the token with the same key file
+	restored, err := NewFileKeyManager(ctx, keyFile, token)
+	require.NoError(t, err)
+	restoredKey, err := restored.passthrough.RetrievalToken(ctx)
+	require.NoError(t, err)
+	require.Equal(t, key, restoredKey)
+
+	// but not with another one
+	_, err = NewFileKeyManager(ctx, filepath.Join(t.TempDir(), "other.key"), token)
+	require.Error(t, err)
+
+	// nor without one, which is not generated again
+	missingKeyFile := filepath.Join(t.TempDir(), "missing.key")
+	_, err = NewFileKeyManager(ctx, missingKeyFile, token)
+	require.Error(t, err)
+	require.NoFileExists(t, missingKeyFile)
+
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Chmod(keyFile, 0o640))
+		_, err = NewFileKeyManager(ctx, keyFile, token)
+		require.ErrorContains(t, err, "accessible to group or others")
+	}
+
+	invalidKeyFile := filepath.Join(t.TempDir(), "invalid.key")
+	require.NoError(t, os.WriteFile(invalidKeyFile, []byte("Zm9vYmFy"), 0o600))
+	_, err = NewFileKeyManager(ctx, invalidKeyFile, nil)
+	require.Error(t, err)
+}
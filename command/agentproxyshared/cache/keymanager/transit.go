@@ -0,0 +1,113 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package keymanager
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/vault/api"
+)
+
+var _ KeyManager = (*TransitKeyManager)(nil)
+
+// TransitKeyManager encrypts the encryption key with a key of a transit secrets
+// engine, so that the key can be stored next to the data it protects and
+// recovered after a restart by any client allowed to decrypt with that key.
+type TransitKeyManager struct {
+	passthrough *PassthroughKeyManager
+	client      *api.Client
+	mountPath   string
+	keyName     string
+}
+
+// NewTransitKeyManager returns a new instance of the transit-encrypted
+// encryption key. If a retrieval token is provided, it is decrypted with the
+// named key of the transit mount to get the encryption key, otherwise one will
+// be generated. The client must carry a token allowed to use the key.
+func NewTransitKeyManager(ctx context.Context, client *api.Client, mountPath, keyName string, token []byte) (*TransitKeyManager, error) {
+	if client == nil {
+		return nil, errors.New("nil client provided")
+	}
+	if mountPath == "" {
+		return nil, errors.New("transit mount path is required")
+	}
+	if keyName == "" {
+		return nil, errors.New("transit key name is required")
+	}
+
+	t := &TransitKeyManager{
+		client:    client,
+		mountPath: mountPath,
+		keyName:   keyName,
+	}
+
+	var key []byte
+	if len(token) > 0 {
+		var err error
+		key, err = t.decrypt(ctx, string(token))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	passthrough, err := NewPassthroughKeyManager(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	t.passthrough = passthrough
+
+	return t, nil
+}
+
+// Wrapper returns the manager's wrapper for key operations.
+func (t *TransitKeyManager) Wrapper() wrapping.Wrapper {
+	return t.passthrough.Wrapper()
+}
+
+// RetrievalToken returns the transit ciphertext of the encryption key.
+func (t *TransitKeyManager) RetrievalToken(ctx context.Context) ([]byte, error) {
+	key, err := t.passthrough.RetrievalToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := t.client.Logical().WriteWithContext(ctx, path.Join(t.mountPath, "encrypt", t.keyName), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting encryption key with transit: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty response encrypting encryption key with transit")
+	}
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok || ciphertext == "" {
+		return nil, errors.New("no ciphertext in transit response")
+	}
+
+	return []byte(ciphertext), nil
+}
+
+func (t *TransitKeyManager) decrypt(ctx context.Context, ciphertext string) ([]byte, error) {
+	secret, err := t.client.Logical().WriteWithContext(ctx, path.Join(t.mountPath, "decrypt", t.keyName), map[string]interface{}{
+		"ciphertext": ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting encryption key with transit: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty response decrypting encryption key with transit")
+	}
+	plaintext, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("no plaintext in transit response")
+	}
+
+	return base64.StdEncoding.DecodeString(plaintext)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package keymanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/require"
+)
+
+// testTransitServer serves the encrypt and decrypt endpoints of the "cache"
+// key of a transit mount, with a ciphertext which simply prefixes the
+// plaintext.
+func testTransitServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var data map[string]string
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/cache":
+			data = map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]}
+		case "/v1/transit/decrypt/cache":
+			if !strings.HasPrefix(body["ciphertext"], "vault:v1:") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data = map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestKeyManager_TransitKeyManager(t *testing.T) {
+	ctx := context.Background()
+	server := testTransitServer(t)
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	client, err := api.NewClient(config)
+	require.NoError(t, err)
+
+	m, err := NewTransitKeyManager(ctx, client, "transit", "cache", nil)
+	require.NoError(t, err)
+	require.NotNil(t, m.Wrapper())
+
+	key, err := m.passthrough.RetrievalToken(ctx)
+	require.NoError(t, err)
+	token, err := m.RetrievalToken(ctx)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(token), "vault:v1:"))
+
+	restored, err := NewTransitKeyManager(ctx, client, "transit", "cache", token)
+	require.NoError(t, err)
+	restoredKey, err := restored.passthrough.RetrievalToken(ctx)
+	require.NoError(t, err)
+	require.Equal(t, key, restoredKey)
+
+	_, err = NewTransitKeyManager(ctx, client, "transit", "other", token)
+	require.Error(t, err)
+}
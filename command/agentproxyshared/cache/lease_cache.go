@@ -52,6 +52,10 @@ const (
 	vaultPathLeaseRevokePrefix   = "/v1/sys/leases/revoke-prefix"
 )
 
+// DefaultStaticSecretMaxStaleness is for how long cached static secrets can
+// be served while Vault is unavailable, when no maximum is configured.
+const DefaultStaticSecretMaxStaleness = 5 * time.Minute
+
 var (
 	contextIndexID  = contextIndex{}
 	errInvalidType  = errors.New("invalid type provided")
@@ -114,6 +118,19 @@ type LeaseCache struct {
 	// capabilityManager is used when static secrets are enabled to
 	// manage the capabilities of cached tokens.
 	capabilityManager *StaticSecretCapabilityManager
+
+	// staticSecretStaleIfError is used to determine if cached static secrets
+	// should be served when Vault is unavailable, as long as they are not
+	// more than staticSecretMaxStaleness out of date.
+	staticSecretStaleIfError bool
+	staticSecretMaxStaleness time.Duration
+
+	// staticSecretEventsConnected is set while the static secret cache
+	// updater receives events, which keep the cached static secrets up to
+	// date. staticSecretEventsDisconnectedAt is the time in nanoseconds at
+	// which it last stopped receiving them.
+	staticSecretEventsConnected      atomic.Bool
+	staticSecretEventsDisconnectedAt atomic.Int64
 }
 
 // LeaseCacheConfig is the configuration for initializing a new
@@ -127,6 +144,11 @@ type LeaseCacheConfig struct {
 	Storage             *cacheboltdb.BoltStorage
 	CacheStaticSecrets  bool
 	CacheDynamicSecrets bool
+
+	// StaticSecretStaleIfError enables serving cached static secrets when
+	// Vault is unavailable, for up to StaticSecretMaxStaleness.
+	StaticSecretStaleIfError bool
+	StaticSecretMaxStaleness time.Duration
 }
 
 type inflightRequest struct {
@@ -172,19 +194,26 @@ func NewLeaseCache(conf *LeaseCacheConfig) (*LeaseCache, error) {
 	// Create a base context for the lease cache layer
 	baseCtxInfo := cachememdb.NewContextInfo(conf.BaseContext)
 
+	maxStaleness := conf.StaticSecretMaxStaleness
+	if maxStaleness == 0 {
+		maxStaleness = DefaultStaticSecretMaxStaleness
+	}
+
 	return &LeaseCache{
-		client:              conf.Client,
-		proxier:             conf.Proxier,
-		logger:              conf.Logger,
-		userAgentToUse:      conf.UserAgentToUse,
-		db:                  db,
-		baseCtxInfo:         baseCtxInfo,
-		l:                   &sync.RWMutex{},
-		idLocks:             locksutil.CreateLocks(),
-		inflightCache:       gocache.New(gocache.NoExpiration, gocache.NoExpiration),
-		ps:                  conf.Storage,
-		cacheStaticSecrets:  conf.CacheStaticSecrets,
-		cacheDynamicSecrets: conf.CacheDynamicSecrets,
+		client:                   conf.Client,
+		proxier:                  conf.Proxier,
+		logger:                   conf.Logger,
+		userAgentToUse:           conf.UserAgentToUse,
+		db:                       db,
+		baseCtxInfo:              baseCtxInfo,
+		l:                        &sync.RWMutex{},
+		idLocks:                  locksutil.CreateLocks(),
+		inflightCache:            gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+		ps:                       conf.Storage,
+		cacheStaticSecrets:       conf.CacheStaticSecrets,
+		cacheDynamicSecrets:      conf.CacheDynamicSecrets,
+		staticSecretStaleIfError: conf.StaticSecretStaleIfError,
+		staticSecretMaxStaleness: maxStaleness,
 	}, nil
 }
 
@@ -194,6 +223,34 @@ func (c *LeaseCache) SetCapabilityManager(capabilityManager *StaticSecretCapabil
 	c.capabilityManager = capabilityManager
 }
 
+// setStaticSecretEventsConnected records whether the static secret cache
+// updater is receiving events, and so whether the cached static secrets are
+// known to be up to date.
+func (c *LeaseCache) setStaticSecretEventsConnected(connected bool) {
+	if connected {
+		c.staticSecretEventsConnected.Store(true)
+		return
+	}
+	if c.staticSecretEventsConnected.Swap(false) {
+		c.staticSecretEventsDisconnectedAt.Store(time.Now().UnixNano())
+	}
+}
+
+// staticSecretStaleness returns for how long a cached static secret may have
+// been out of date. It is zero while events keep the cache up to date, and
+// otherwise counts from when the secret was last known to be up to date.
+func (c *LeaseCache) staticSecretStaleness(index *cachememdb.Index) time.Duration {
+	if c.staticSecretEventsConnected.Load() {
+		return 0
+	}
+
+	upToDate := index.LastRenewed
+	if disconnectedAt := time.Unix(0, c.staticSecretEventsDisconnectedAt.Load()); disconnectedAt.After(upToDate) {
+		upToDate = disconnectedAt
+	}
+	return time.Since(upToDate)
+}
+
 // SetShuttingDown is a setter for the shuttingDown field
 func (c *LeaseCache) SetShuttingDown(in bool) {
 	c.shuttingDown.Store(in)
@@ -272,6 +329,79 @@ func (c *LeaseCache) checkCacheForRequest(id string, req *SendRequest) (*SendRes
 		}
 	}
 
+	var stale bool
+	if req != nil && c.staticSecretStaleIfError {
+		// Past the maximum staleness, the request goes to Vault instead
+		// of getting a response which may be out of date.
+		staleness := c.staticSecretStaleness(index)
+		if staleness > c.staticSecretMaxStaleness {
+			c.logger.Debug("cached static secret exceeds the maximum staleness", "id", id, "staleness", staleness)
+			return nil, nil
+		}
+		stale = staleness > 0
+	}
+
+	sendResp, err := c.responseFromIndex(index, req)
+	if err != nil || sendResp == nil {
+		return sendResp, err
+	}
+	sendResp.CacheMeta.Stale = stale
+
+	return sendResp, nil
+}
+
+// checkCacheForStaleStaticSecretRequest checks the cache for a static secret
+// to serve in place of an error from Vault. Instead of the tokens which
+// retrieved the cached secret, it checks the capabilities of the token kept
+// up to date by the capability manager, as the token may have been dropped
+// from the index after failing to reach Vault. It returns nil if the
+// secret is more than the maximum staleness out of date.
+func (c *LeaseCache) checkCacheForStaleStaticSecretRequest(id string, req *SendRequest) (*SendResponse, error) {
+	c.logger.Trace("checking cache for stale static secret request", "id", id)
+
+	capabilitiesIndex, err := c.db.GetCapabilitiesIndex(cachememdb.IndexNameID, hashStaticSecretIndex(req.Token))
+	if errors.Is(err, cachememdb.ErrCacheItemNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	capabilitiesIndex.IndexLock.RLock()
+	_, readable := capabilitiesIndex.ReadablePaths[getStaticSecretPathFromRequest(req)]
+	capabilitiesIndex.IndexLock.RUnlock()
+	if !readable {
+		return nil, nil
+	}
+
+	index, err := c.db.Get(cachememdb.IndexNameID, id)
+	if errors.Is(err, cachememdb.ErrCacheItemNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index.IndexLock.RLock()
+	defer index.IndexLock.RUnlock()
+
+	if staleness := c.staticSecretStaleness(index); staleness > c.staticSecretMaxStaleness {
+		c.logger.Debug("cached static secret exceeds the maximum staleness", "id", id, "staleness", staleness)
+		return nil, nil
+	}
+
+	sendResp, err := c.responseFromIndex(index, req)
+	if err != nil || sendResp == nil {
+		return sendResp, err
+	}
+	sendResp.CacheMeta.Stale = true
+
+	return sendResp, nil
+}
+
+// responseFromIndex deserializes the response cached in the index for the
+// version of the request, if any. The caller must hold the index lock.
+func (c *LeaseCache) responseFromIndex(index *cachememdb.Index, req *SendRequest) (*SendResponse, error) {
 	var response []byte
 	version := getStaticSecretVersionFromRequest(req)
 	if version == 0 {
@@ -437,6 +567,17 @@ func (c *LeaseCache) Send(ctx context.Context, req *SendRequest) (*SendResponse,
 	// Pass the request down and get a response
 	resp, err := c.proxier.Send(ctx, req)
 	if err != nil {
+		if c.staticSecretStaleIfError && staticSecretCacheId != "" && req.Request.Method == http.MethodGet && req.Token != "" &&
+			(resp == nil || resp.Response.StatusCode >= http.StatusInternalServerError) {
+			staleResp, staleErr := c.checkCacheForStaleStaticSecretRequest(staticSecretCacheId, req)
+			if staleErr != nil {
+				c.logger.Error("failed to check cache for stale static secret", "error", staleErr)
+			}
+			if staleResp != nil {
+				c.logger.Warn("Vault unavailable, returning stale cached static secret response", "id", staticSecretCacheId, "path", getStaticSecretPathFromRequest(req), "error", err)
+				return staleResp, nil
+			}
+		}
 		return resp, err
 	}
 
@@ -6,6 +6,7 @@ package cache
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -692,6 +693,85 @@ func TestLeaseCache_SendCacheableStaticSecret(t *testing.T) {
 	}
 }
 
+// unavailableProxier returns its response to the first request, and fails
+// the following ones as if Vault was unavailable.
+type unavailableProxier struct {
+	response *SendResponse
+	sent     bool
+}
+
+func (p *unavailableProxier) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	if !p.sent {
+		p.sent = true
+		return p.response, nil
+	}
+	return newTestSendResponse(http.StatusServiceUnavailable, ""), errors.New("Vault is sealed")
+}
+
+// TestLeaseCache_StaticSecret_StaleIfError tests that cached static secrets
+// are served when Vault is unavailable, as long as the token can read them and
+// they are within the maximum staleness.
+func TestLeaseCache_StaticSecret_StaleIfError(t *testing.T) {
+	client, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+	lc, err := NewLeaseCache(&LeaseCacheConfig{
+		Client:                   client,
+		BaseContext:              context.Background(),
+		Proxier:                  &unavailableProxier{response: newTestSendResponse(http.StatusOK, `{"data": {"foo": "bar"}, "mount_type": "kv"}`)},
+		Logger:                   logging.NewVaultLogger(hclog.Trace).Named("cache.leasecache"),
+		CacheStaticSecrets:       true,
+		UserAgentToUse:           "test",
+		StaticSecretStaleIfError: true,
+		StaticSecretMaxStaleness: time.Minute,
+	})
+	require.NoError(t, err)
+
+	newRequest := func(token string) *SendRequest {
+		return &SendRequest{
+			Token:   token,
+			Request: httptest.NewRequest("GET", "http://example.com/v1/secrets/foo/bar", nil),
+		}
+	}
+
+	// Cache the secret while the events keep it up to date
+	lc.setStaticSecretEventsConnected(true)
+	_, err = lc.Send(context.Background(), newRequest("token"))
+	require.NoError(t, err)
+
+	resp, err := lc.Send(context.Background(), newRequest("token"))
+	require.NoError(t, err)
+	require.True(t, resp.CacheMeta.Hit)
+	require.False(t, resp.CacheMeta.Stale)
+
+	// Once events stop, cache hits are marked as stale
+	lc.setStaticSecretEventsConnected(false)
+	resp, err = lc.Send(context.Background(), newRequest("token"))
+	require.NoError(t, err)
+	require.True(t, resp.CacheMeta.Hit)
+	require.True(t, resp.CacheMeta.Stale)
+
+	// A token dropped from the index is still served the secret through its
+	// capabilities when Vault is unavailable
+	index, err := lc.db.Get(cachememdb.IndexNameID, computeStaticSecretCacheIndex(newRequest("token")))
+	require.NoError(t, err)
+	delete(index.Tokens, "token")
+	resp, err = lc.Send(context.Background(), newRequest("token"))
+	require.NoError(t, err)
+	require.True(t, resp.CacheMeta.Stale)
+	require.Equal(t, http.StatusOK, resp.Response.StatusCode)
+
+	// but not another token
+	_, err = lc.Send(context.Background(), newRequest("other"))
+	require.Error(t, err)
+
+	// Past the maximum staleness, the error from Vault is returned
+	index.Tokens["token"] = struct{}{}
+	index.LastRenewed = time.Now().Add(-2 * time.Minute)
+	lc.staticSecretEventsDisconnectedAt.Store(index.LastRenewed.UnixNano())
+	_, err = lc.Send(context.Background(), newRequest("token"))
+	require.Error(t, err)
+}
+
 func TestLeaseCache_SendNonCacheable(t *testing.T) {
 	responses := []*SendResponse{
 		newTestSendResponse(http.StatusOK, `{"value": "output"}`),
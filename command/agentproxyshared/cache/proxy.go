@@ -39,6 +39,10 @@ type SendResponse struct {
 type CacheMeta struct {
 	Hit bool
 	Age time.Duration
+
+	// Stale is set when the cached response is served while it may be out
+	// of date, because Vault is unavailable.
+	Stale bool
 }
 
 // Proxier is the interface implemented by different components that are
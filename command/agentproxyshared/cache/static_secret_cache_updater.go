@@ -153,6 +153,10 @@ func NewStaticSecretCacheUpdater(conf *StaticSecretCacheUpdaterConfig) (*StaticS
 // For best results, the caller of this function should retry on error with backoff,
 // if it is desired for the cache to always remain up to date.
 func (updater *StaticSecretCacheUpdater) streamStaticSecretEvents(ctx context.Context) error {
+	// The cached secrets are only known to be up to date while we receive
+	// events.
+	defer updater.leaseCache.setStaticSecretEventsConnected(false)
+
 	// First, ensure our token is up-to-date:
 	updater.client.SetToken(updater.tokenSink.(sink.SinkReader).Token())
 	conn, err := updater.openWebSocketConnection(ctx)
@@ -165,6 +169,7 @@ func (updater *StaticSecretCacheUpdater) streamStaticSecretEvents(ctx context.Co
 	if err != nil {
 		return fmt.Errorf("error when performing pre-event stream secret update: %w", err)
 	}
+	updater.leaseCache.setStaticSecretEventsConnected(true)
 
 	for {
 		select {
@@ -437,6 +442,7 @@ func (updater *StaticSecretCacheUpdater) updateStaticSecret(ctx context.Context,
 	var resp *api.Response
 	var tokensToRemove []string
 	var successfulAttempt bool
+	var unavailableErr error
 	for _, token := range maps.Keys(index.Tokens) {
 		client.SetToken(token)
 		request.Headers.Set(api.AuthHeaderName, token)
@@ -468,6 +474,12 @@ func (updater *StaticSecretCacheUpdater) updateStaticSecret(ctx context.Context,
 
 		if err != nil {
 			updater.logger.Trace("received error when trying to update cache", "path", path, "err", err, "token", token, "namespace", index.Namespace)
+			if updater.leaseCache.staticSecretStaleIfError && isVaultUnavailableError(err) {
+				// Vault could not tell us whether the token can access this
+				// secret, so keep serving the cached copy for now.
+				unavailableErr = err
+				continue
+			}
 			// We cannot access this secret with this token for whatever reason,
 			// so token for removal.
 			tokensToRemove = append(tokensToRemove, token)
@@ -537,6 +549,11 @@ func (updater *StaticSecretCacheUpdater) updateStaticSecret(ctx context.Context,
 		if err != nil {
 			return err
 		}
+	} else if unavailableErr != nil {
+		// Keep the secret as is, it will be updated once Vault is available
+		// again and we reconnect to the event stream.
+		updater.logger.Warn("unable to reach Vault to update static secret, keeping the cached response", "path", path, "error", unavailableErr)
+		return fmt.Errorf("unable to reach Vault to update static secret: %w", unavailableErr)
 	} else {
 		// No token could successfully update the secret, or secret was deleted.
 		// We should evict the cache instead of re-storing the secret.
@@ -550,6 +567,17 @@ func (updater *StaticSecretCacheUpdater) updateStaticSecret(ctx context.Context,
 	return nil
 }
 
+// isVaultUnavailableError returns whether the error comes from failing to
+// reach Vault or from Vault failing to serve the request, rather than from
+// Vault refusing the request.
+func isVaultUnavailableError(err error) bool {
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
 // openWebSocketConnection opens a websocket connection to the event system for
 // the events that the static secret cache updater is interested in.
 func (updater *StaticSecretCacheUpdater) openWebSocketConnection(ctx context.Context) (*websocket.Conn, error) {
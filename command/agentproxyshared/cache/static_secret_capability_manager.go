@@ -159,7 +159,11 @@ func (sscm *StaticSecretCapabilityManager) StartRenewingCapabilities(indexToRene
 		capabilities, err := getCapabilities(indexReadablePaths, client)
 		if err != nil {
 			sscm.logger.Warn("error when attempting to retrieve updated token capabilities", "indexToRenew.ID", indexToRenew.ID, "err", err)
-			if sscm.tokenCapabilityRefreshBehaviour == TokenCapabilityRefreshBehaviourPessimistic {
+			// When serving stale static secrets is enabled, failing to reach
+			// Vault does not revoke capabilities, as the cache is what keeps
+			// serving requests during an outage.
+			unavailable := sscm.leaseCache.staticSecretStaleIfError && isVaultUnavailableError(err)
+			if sscm.tokenCapabilityRefreshBehaviour == TokenCapabilityRefreshBehaviourPessimistic && !unavailable {
 				// Vault is be sealed or unreachable. If pessimistic, assume we might have
 				// lost access. Set capabilities to an empty set, so they are all removed.
 				capabilities = make(map[string][]string)
@@ -0,0 +1,232 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+)
+
+// DefaultHealthCheckInterval is how often the Vault server in use is probed
+// when no interval is configured.
+const DefaultHealthCheckInterval = 10 * time.Second
+
+// SelectorConfig is the configuration of a Selector.
+type SelectorConfig struct {
+	Logger hclog.Logger
+
+	// Addresses are the Vault servers to choose from, in order of preference.
+	Addresses []string
+
+	// Client is cloned to probe the health of each address, so it should
+	// carry the TLS configuration used to reach Vault.
+	Client *api.Client
+
+	HealthCheckInterval time.Duration
+}
+
+// Selector chooses the Vault server that Agent and Proxy send requests to
+// from an ordered list of addresses. It probes the server in use through
+// sys/health and, when it stops responding or is sealed, switches to the
+// first healthy address of the list. The selection is sticky: it does not
+// switch back once an earlier address recovers, only when the server in use
+// fails in turn.
+type Selector struct {
+	logger      hclog.Logger
+	addresses   []string
+	probeClient *api.Client
+	interval    time.Duration
+
+	lock        sync.RWMutex
+	current     int
+	clients     []*api.Client
+	subscribers []chan string
+}
+
+// NewSelector creates a Selector which starts out with the first address.
+func NewSelector(conf *SelectorConfig) (*Selector, error) {
+	if conf == nil {
+		return nil, errors.New("nil configuration provided")
+	}
+	if len(conf.Addresses) == 0 {
+		return nil, errors.New("no Vault addresses provided")
+	}
+	if conf.Client == nil {
+		return nil, errors.New("nil client provided")
+	}
+	for _, address := range conf.Addresses {
+		if _, err := url.Parse(address); err != nil {
+			return nil, fmt.Errorf("invalid Vault address %q: %w", address, err)
+		}
+	}
+
+	probeClient, err := conf.Client.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("error cloning client for health checks: %w", err)
+	}
+	// A failed probe is retried on the next interval, or answered by moving
+	// on to the next address.
+	probeClient.SetMaxRetries(0)
+
+	interval := conf.HealthCheckInterval
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+
+	logger := conf.Logger
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	return &Selector{
+		logger:      logger,
+		addresses:   conf.Addresses,
+		probeClient: probeClient,
+		interval:    interval,
+	}, nil
+}
+
+// Address returns the address of the Vault server currently in use.
+func (s *Selector) Address() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.addresses[s.current]
+}
+
+// RegisterClient points the client at the Vault server currently in use, and
+// at the new one whenever the selection changes.
+func (s *Selector) RegisterClient(client *api.Client) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.setAddress(client, s.addresses[s.current])
+	s.clients = append(s.clients, client)
+}
+
+// Subscribe returns a channel receiving the new address whenever the
+// selection changes, for users which cannot share an api.Client. Only the
+// latest address is kept if the receiver falls behind.
+func (s *Selector) Subscribe() <-chan string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ch := make(chan string, 1)
+	s.subscribers = append(s.subscribers, ch)
+	return ch
+}
+
+// Run probes the Vault server in use right away, and then every health check
+// interval until ctx is done.
+func (s *Selector) Run(ctx context.Context) {
+	s.logger.Info("starting Vault server health checks", "addresses", s.addresses, "interval", s.interval)
+	s.check(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+// check probes the Vault server in use and fails over if it is unhealthy.
+func (s *Selector) check(ctx context.Context) {
+	s.lock.RLock()
+	current := s.current
+	s.lock.RUnlock()
+
+	err := s.probe(ctx, s.addresses[current])
+	if err == nil {
+		return
+	}
+	s.logger.Warn("Vault server failed health check", "address", s.addresses[current], "error", err)
+	metrics.IncrCounter([]string{"failover", "health_check", "failure"}, 1)
+
+	for next := range s.addresses {
+		if next == current {
+			continue
+		}
+		if err := s.probe(ctx, s.addresses[next]); err != nil {
+			s.logger.Debug("Vault server failed health check", "address", s.addresses[next], "error", err)
+			continue
+		}
+
+		s.switchTo(next)
+		return
+	}
+
+	s.logger.Error("no healthy Vault server found, keeping the current one", "address", s.addresses[current])
+}
+
+func (s *Selector) switchTo(next int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	address := s.addresses[next]
+	s.logger.Warn("failing over to Vault server", "from", s.addresses[s.current], "to", address)
+	metrics.IncrCounter([]string{"failover", "switch"}, 1)
+	s.current = next
+
+	for _, client := range s.clients {
+		s.setAddress(client, address)
+	}
+	for _, ch := range s.subscribers {
+		// Replace an address the subscriber has not picked up yet.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- address
+	}
+}
+
+func (s *Selector) setAddress(client *api.Client, address string) {
+	// The addresses were validated by NewSelector, so this is not expected
+	// to fail.
+	if err := client.SetAddress(address); err != nil {
+		s.logger.Error("error setting client address", "address", address, "error", err)
+	}
+}
+
+// probe returns an error unless the Vault server at address is initialized,
+// unsealed and able to serve requests.
+func (s *Selector) probe(ctx context.Context, address string) error {
+	client, err := s.probeClient.Clone()
+	if err != nil {
+		return err
+	}
+	if err := client.SetAddress(address); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	health, err := client.Sys().HealthWithContext(ctx)
+	switch {
+	case err != nil:
+		return err
+	case !health.Initialized:
+		return errors.New("not initialized")
+	case health.Sealed:
+		return errors.New("sealed")
+	case health.ReplicationDRMode == "secondary":
+		return errors.New("DR secondary")
+	}
+	return nil
+}
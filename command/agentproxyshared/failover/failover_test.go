@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/require"
+)
+
+// testHealthServer serves sys/health, reporting sealed once sealed is set.
+func testHealthServer(t *testing.T, sealed *atomic.Bool) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		if sealed.Load() {
+			status = http.StatusServiceUnavailable
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(&api.HealthResponse{
+			Initialized: true,
+			Sealed:      sealed.Load(),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSelector_Failover(t *testing.T) {
+	ctx := context.Background()
+
+	var sealed1, sealed2, sealed3 atomic.Bool
+	server1 := testHealthServer(t, &sealed1)
+	server2 := testHealthServer(t, &sealed2)
+	server3 := testHealthServer(t, &sealed3)
+
+	client, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+
+	selector, err := NewSelector(&SelectorConfig{
+		Addresses: []string{server1.URL, server2.URL, server3.URL},
+		Client:    client,
+	})
+	require.NoError(t, err)
+	selector.RegisterClient(client)
+	addressCh := selector.Subscribe()
+	require.Equal(t, server1.URL, client.Address())
+
+	selector.check(ctx)
+	require.Equal(t, server1.URL, selector.Address())
+	require.Empty(t, addressCh)
+
+	// The first healthy address in order is picked.
+	sealed1.Store(true)
+	sealed2.Store(true)
+	selector.check(ctx)
+	require.Equal(t, server3.URL, selector.Address())
+	require.Equal(t, server3.URL, client.Address())
+	require.Equal(t, server3.URL, <-addressCh)
+
+	// The selection is sticky.
+	sealed1.Store(false)
+	selector.check(ctx)
+	require.Equal(t, server3.URL, selector.Address())
+
+	// Without a healthy address the current one is kept.
+	sealed1.Store(true)
+	sealed3.Store(true)
+	selector.check(ctx)
+	require.Equal(t, server3.URL, selector.Address())
+
+	sealed2.Store(false)
+	server3.Close()
+	selector.check(ctx)
+	require.Equal(t, server2.URL, selector.Address())
+	require.Equal(t, server2.URL, client.Address())
+	require.Equal(t, server2.URL, <-addressCh)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/command/agentproxyshared/auth"
 	"github.com/hashicorp/vault/command/agentproxyshared/auth/alicloud"
 	"github.com/hashicorp/vault/command/agentproxyshared/auth/approle"
@@ -77,11 +78,25 @@ type PersistConfig struct {
 	KeepAfterImport         bool   `hcl:"keep_after_import"`
 	ExitOnErr               bool   `hcl:"exit_on_err"`
 	ServiceAccountTokenFile string `hcl:"service_account_token_file"`
+
+	// KeyFile holds the key sealing the cache encryption key, for the "file"
+	// type. It is generated along with a new cache if it does not exist, and
+	// must not be accessible to group or others.
+	KeyFile string `hcl:"key_file"`
+
+	// TransitMount and TransitKey name the transit key encrypting the cache
+	// encryption key, for the "transit" type. TransitTokenFile holds the token
+	// used to reach transit, as the auto-auth token is not available yet
+	// when the cache is restored.
+	TransitMount     string `hcl:"transit_mount"`
+	TransitKey       string `hcl:"transit_key"`
+	TransitTokenFile string `hcl:"transit_token_file"`
 }
 
 // AddPersistentStorageToLeaseCache adds persistence to a lease cache, based on a given PersistConfig
-// Returns a close function to be deferred and the old token, if found, or an error
-func AddPersistentStorageToLeaseCache(ctx context.Context, leaseCache *cache.LeaseCache, persistConfig *PersistConfig, logger log.Logger) (func() error, string, error) {
+// Returns a close function to be deferred and the old token, if found, or an error.
+// The client is only used by the "transit" type, to reach the transit secrets engine.
+func AddPersistentStorageToLeaseCache(ctx context.Context, leaseCache *cache.LeaseCache, persistConfig *PersistConfig, client *api.Client, logger log.Logger) (func() error, string, error) {
 	if persistConfig == nil {
 		return nil, "", errors.New("persist config was nil")
 	}
@@ -103,6 +118,22 @@ func AddPersistentStorageToLeaseCache(ctx context.Context, leaseCache *cache.Lea
 			}
 			return nil, "", fmt.Errorf("failed to read service account token from %s: %w", tokenFileName, err)
 		}
+	case "file":
+		if persistConfig.KeyFile == "" {
+			return nil, "", errors.New("must specify key_file for the file persistent key protection type")
+		}
+		aad, err = persistCacheIdentity(persistConfig)
+		if err != nil {
+			return nil, "", err
+		}
+	case "transit":
+		if persistConfig.TransitKey == "" {
+			return nil, "", errors.New("must specify transit_key for the transit persistent key protection type")
+		}
+		aad, err = persistCacheIdentity(persistConfig)
+		if err != nil {
+			return nil, "", err
+		}
 	default:
 		return nil, "", fmt.Errorf("persistent key protection type %q not supported", persistConfig.Type)
 	}
@@ -133,7 +164,7 @@ func AddPersistentStorageToLeaseCache(ctx context.Context, leaseCache *cache.Lea
 			return nil, "", fmt.Errorf("failed to close persistent cache file after getting retrieval token: %w", err)
 		}
 
-		km, err := keymanager.NewPassthroughKeyManager(ctx, token)
+		km, err := newPersistKeyManager(ctx, persistConfig, client, token)
 		if err != nil {
 			return nil, "", fmt.Errorf("failed to configure persistence encryption for cache: %w", err)
 		}
@@ -197,7 +228,7 @@ func AddPersistentStorageToLeaseCache(ctx context.Context, leaseCache *cache.Lea
 			return nil, previousToken, nil
 		}
 	} else {
-		km, err := keymanager.NewPassthroughKeyManager(ctx, nil)
+		km, err := newPersistKeyManager(ctx, persistConfig, client, nil)
 		if err != nil {
 			return nil, "", fmt.Errorf("failed to configure persistence encryption for cache: %w", err)
 		}
@@ -226,6 +257,66 @@ func AddPersistentStorageToLeaseCache(ctx context.Context, leaseCache *cache.Lea
 	}
 }
 
+// persistCacheIdentity returns the AAD of the entries of a cache protected by
+// a key file or transit key, which binds them to the cache directory and the
+// key sealing its encryption key, as these keys may be shared between caches.
+func persistCacheIdentity(persistConfig *PersistConfig) (string, error) {
+	cachePath, err := filepath.Abs(persistConfig.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve persistent cache path %s: %w", persistConfig.Path, err)
+	}
+
+	switch persistConfig.Type {
+	case "file":
+		keyFile, err := filepath.Abs(persistConfig.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve key file path %s: %w", persistConfig.KeyFile, err)
+		}
+		return fmt.Sprintf("file:%s:%s", cachePath, keyFile), nil
+	default:
+		mountPath := persistConfig.TransitMount
+		if mountPath == "" {
+			mountPath = "transit"
+		}
+		return fmt.Sprintf("transit:%s:%s/%s", cachePath, strings.Trim(mountPath, "/"), persistConfig.TransitKey), nil
+	}
+}
+
+// newPersistKeyManager returns the key manager of the persistent key protection
+// type, sourcing the encryption key from the retrieval token if one is given.
+func newPersistKeyManager(ctx context.Context, persistConfig *PersistConfig, client *api.Client, token []byte) (keymanager.KeyManager, error) {
+	switch persistConfig.Type {
+	case "file":
+		return keymanager.NewFileKeyManager(ctx, persistConfig.KeyFile, token)
+	case "transit":
+		if client == nil {
+			return nil, errors.New("a client is required for the transit persistent key protection type")
+		}
+		transitClient, err := client.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if persistConfig.TransitTokenFile != "" {
+			transitToken, err := os.ReadFile(persistConfig.TransitTokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read transit token from %s: %w", persistConfig.TransitTokenFile, err)
+			}
+			transitClient.SetToken(strings.TrimSpace(string(transitToken)))
+		}
+		if transitClient.Token() == "" {
+			return nil, errors.New("no token available to reach transit, set transit_token_file")
+		}
+
+		mountPath := persistConfig.TransitMount
+		if mountPath == "" {
+			mountPath = "transit"
+		}
+		return keymanager.NewTransitKeyManager(ctx, transitClient, mountPath, persistConfig.TransitKey, token)
+	default:
+		return keymanager.NewPassthroughKeyManager(ctx, token)
+	}
+}
+
 // getServiceAccountJWT attempts to read the service account JWT from the specified token file path.
 // Defaults to using the Kubernetes default service account file path if token file path is empty.
 func getServiceAccountJWT(tokenFile string) (string, error) {
@@ -6,6 +6,7 @@ package agentproxyshared
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -75,7 +76,7 @@ func Test_AddPersistentStorageToLeaseCache(t *testing.T) {
 		t.Fatal("persistent storage was available before ours was added")
 	}
 
-	deferFunc, token, err := AddPersistentStorageToLeaseCache(context.Background(), leaseCache, persistConfig, logging.NewVaultLogger(hclog.Info))
+	deferFunc, token, err := AddPersistentStorageToLeaseCache(context.Background(), leaseCache, persistConfig, nil, logging.NewVaultLogger(hclog.Info))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -92,3 +93,47 @@ func Test_AddPersistentStorageToLeaseCache(t *testing.T) {
 		t.Fatal("expected deferFunc to not be nil")
 	}
 }
+
+// Test_AddPersistentStorageToLeaseCache_FileKey tests that a persistent cache
+// protected by a file-sealed key can be opened again after a restart
+func Test_AddPersistentStorageToLeaseCache_FileKey(t *testing.T) {
+	tempDir := t.TempDir()
+	persistConfig := &PersistConfig{
+		Type:            "file",
+		Path:            tempDir,
+		KeepAfterImport: true,
+		ExitOnErr:       true,
+		KeyFile:         filepath.Join(t.TempDir(), "cache.key"),
+	}
+
+	leaseCache := testNewLeaseCache(t, nil)
+	deferFunc, _, err := AddPersistentStorageToLeaseCache(context.Background(), leaseCache, persistConfig, nil, logging.NewVaultLogger(hclog.Info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deferFunc(); err != nil {
+		t.Fatal(err)
+	}
+
+	leaseCache = testNewLeaseCache(t, nil)
+	deferFunc, _, err = AddPersistentStorageToLeaseCache(context.Background(), leaseCache, persistConfig, nil, logging.NewVaultLogger(hclog.Info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaseCache.PersistentStorage() == nil {
+		t.Fatal("persistent storage was not added")
+	}
+	if err := deferFunc(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A different key file cannot unseal the key
+	persistConfig.KeyFile = filepath.Join(t.TempDir(), "other.key")
+	_, _, err = AddPersistentStorageToLeaseCache(context.Background(), testNewLeaseCache(t, nil), persistConfig, nil, logging.NewVaultLogger(hclog.Info))
+	if err == nil {
+		t.Fatal("expected an error with another key file")
+	}
+	if _, err := os.Stat(persistConfig.KeyFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no key file to be created for an existing cache, got: %v", err)
+	}
+}
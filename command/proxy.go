@@ -31,6 +31,7 @@ import (
 	"github.com/hashicorp/vault/command/agentproxyshared"
 	"github.com/hashicorp/vault/command/agentproxyshared/auth"
 	"github.com/hashicorp/vault/command/agentproxyshared/cache"
+	"github.com/hashicorp/vault/command/agentproxyshared/failover"
 	"github.com/hashicorp/vault/command/agentproxyshared/sink"
 	"github.com/hashicorp/vault/command/agentproxyshared/sink/file"
 	"github.com/hashicorp/vault/command/agentproxyshared/sink/inmem"
@@ -287,6 +288,28 @@ func (c *ProxyCommand) Run(args []string) int {
 	}
 	c.metricsHelper = metricsutil.NewMetricsHelper(inmemMetrics, prometheusEnabled)
 
+	// Fail over between the configured Vault servers, unless the address was
+	// overridden by flag or environment variable.
+	var vaultSelector *failover.Selector
+	if config.Vault != nil && len(config.Vault.Addresses) > 1 {
+		if config.Vault.Address != config.Vault.Addresses[0] {
+			c.UI.Warn(fmt.Sprintf("==> Note: Vault address overridden to %q, ignoring the 'addresses' "+
+				"of the 'vault' stanza", config.Vault.Address))
+		} else {
+			vaultSelector, err = failover.NewSelector(&failover.SelectorConfig{
+				Logger:              c.logger.Named("failover"),
+				Addresses:           config.Vault.Addresses,
+				Client:              client,
+				HealthCheckInterval: config.Vault.HealthCheckInterval,
+			})
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error creating Vault server selector: %v", err))
+				return 1
+			}
+			vaultSelector.RegisterClient(client)
+		}
+	}
+
 	// This indicates whether the namespace for the client has been set by environment variable.
 	// If it has, we don't touch it
 	namespaceSetByEnvironmentVariable := client.Namespace() != ""
@@ -311,6 +334,10 @@ func (c *ProxyCommand) Run(args []string) int {
 			return 1
 		}
 
+		if vaultSelector != nil {
+			vaultSelector.RegisterClient(sinkClient)
+		}
+
 		if config.DisableIdleConnsAutoAuth {
 			sinkClient.SetMaxIdleConnections(-1)
 		}
@@ -423,6 +450,10 @@ func (c *ProxyCommand) Run(args []string) int {
 		return 1
 	}
 
+	if vaultSelector != nil {
+		vaultSelector.RegisterClient(proxyClient)
+	}
+
 	if config.DisableIdleConnsAPIProxy {
 		proxyClient.SetMaxIdleConnections(-1)
 	}
@@ -469,19 +500,21 @@ func (c *ProxyCommand) Run(args []string) int {
 			Logger:             cacheLogger.Named("leasecache"),
 			CacheStaticSecrets: config.Cache.CacheStaticSecrets,
 			// dynamic secrets are configured as default-on to preserve backwards compatibility
-			CacheDynamicSecrets: !config.Cache.DisableCachingDynamicSecrets,
-			UserAgentToUse:      useragent.AgentProxyString(),
+			CacheDynamicSecrets:      !config.Cache.DisableCachingDynamicSecrets,
+			UserAgentToUse:           useragent.AgentProxyString(),
+			StaticSecretStaleIfError: config.Cache.StaticSecretStaleIfError,
+			StaticSecretMaxStaleness: config.Cache.StaticSecretMaxStaleness,
 		})
 		if err != nil {
 			c.UI.Error(fmt.Sprintf("Error creating lease cache: %v", err))
 			return 1
 		}
 
-		cacheLogger.Info("cache configured", "cache_static_secrets", config.Cache.CacheStaticSecrets, "disable_caching_dynamic_secrets", config.Cache.DisableCachingDynamicSecrets)
+		cacheLogger.Info("cache configured", "cache_static_secrets", config.Cache.CacheStaticSecrets, "disable_caching_dynamic_secrets", config.Cache.DisableCachingDynamicSecrets, "static_secret_stale_if_error", config.Cache.StaticSecretStaleIfError)
 
 		// Configure persistent storage and add to LeaseCache
 		if config.Cache.Persist != nil {
-			deferFunc, oldToken, err := agentproxyshared.AddPersistentStorageToLeaseCache(ctx, leaseCache, config.Cache.Persist, cacheLogger)
+			deferFunc, oldToken, err := agentproxyshared.AddPersistentStorageToLeaseCache(ctx, leaseCache, config.Cache.Persist, client, cacheLogger)
 			if err != nil {
 				c.UI.Error(fmt.Sprintf("Error creating persistent cache: %v", err))
 				return 1
@@ -554,6 +587,10 @@ func (c *ProxyCommand) Run(args []string) int {
 			ahClient.SetNamespace(config.AutoAuth.Method.Namespace)
 		}
 
+		if vaultSelector != nil {
+			vaultSelector.RegisterClient(ahClient)
+		}
+
 		if config.DisableIdleConnsAutoAuth {
 			ahClient.SetMaxIdleConnections(-1)
 		}
@@ -755,6 +792,16 @@ func (c *ProxyCommand) Run(args []string) int {
 		}
 	}, func(error) {})
 
+	// Start probing the health of the Vault servers to fail over between
+	if vaultSelector != nil {
+		g.Add(func() error {
+			vaultSelector.Run(ctx)
+			return nil
+		}, func(error) {
+			cancelFunc()
+		})
+	}
+
 	// Start auto-auth and sink servers
 	if method != nil {
 		g.Add(func() error {
@@ -79,6 +79,12 @@ type Vault struct {
 	TLSServerName    string      `hcl:"tls_server_name"`
 	Namespace        string      `hcl:"namespace"`
 	Retry            *Retry      `hcl:"retry"`
+
+	// Addresses is an ordered list of Vault servers to fail over between.
+	// The first one is used as Address.
+	Addresses              []string      `hcl:"addresses"`
+	HealthCheckInterval    time.Duration `hcl:"-"`
+	HealthCheckIntervalRaw interface{}   `hcl:"health_check_interval"`
 }
 
 // transportDialer is an interface that allows passing a custom dialer function
@@ -110,6 +116,9 @@ type Cache struct {
 	StaticSecretTokenCapabilityRefreshIntervalRaw interface{}                     `hcl:"static_secret_token_capability_refresh_interval"`
 	StaticSecretTokenCapabilityRefreshInterval    time.Duration                   `hcl:"-"`
 	StaticSecretTokenCapabilityRefreshBehaviour   string                          `hcl:"static_secret_token_capability_refresh_behavior"`
+	StaticSecretStaleIfError                      bool                            `hcl:"static_secret_stale_if_error"`
+	StaticSecretMaxStalenessRaw                   interface{}                     `hcl:"static_secret_max_staleness"`
+	StaticSecretMaxStaleness                      time.Duration                   `hcl:"-"`
 }
 
 // AutoAuth is the configured authentication method and sinks
@@ -281,6 +290,10 @@ func (c *Config) ValidateConfig() error {
 		}
 	}
 
+	if c.Cache != nil && !c.Cache.CacheStaticSecrets && (c.Cache.StaticSecretStaleIfError || c.Cache.StaticSecretMaxStaleness != 0) {
+		return fmt.Errorf("cache.static_secret_stale_if_error and cache.static_secret_max_staleness require cache.cache_static_secrets=true")
+	}
+
 	return nil
 }
 
@@ -526,6 +539,20 @@ func parseVault(result *Config, list *ast.ObjectList) error {
 		}
 	}
 
+	if len(v.Addresses) > 0 {
+		if v.Address != "" && v.Address != v.Addresses[0] {
+			return fmt.Errorf("%q must be the first of %q when both are set", "address", "addresses")
+		}
+		v.Address = v.Addresses[0]
+	}
+
+	if v.HealthCheckIntervalRaw != nil {
+		if v.HealthCheckInterval, err = parseutil.ParseDurationSecond(v.HealthCheckIntervalRaw); err != nil {
+			return fmt.Errorf("error parsing %q: %w", "health_check_interval", err)
+		}
+		v.HealthCheckIntervalRaw = nil
+	}
+
 	result.Vault = &v
 
 	subs, ok := item.Val.(*ast.ObjectType)
@@ -648,6 +675,14 @@ func parseCache(result *Config, list *ast.ObjectList) error {
 		result.Cache.StaticSecretTokenCapabilityRefreshIntervalRaw = nil
 	}
 
+	if result.Cache.StaticSecretMaxStalenessRaw != nil {
+		var err error
+		if result.Cache.StaticSecretMaxStaleness, err = parseutil.ParseDurationSecond(result.Cache.StaticSecretMaxStalenessRaw); err != nil {
+			return fmt.Errorf("error parsing static_secret_max_staleness, must be provided as a duration string: %w", err)
+		}
+		result.Cache.StaticSecretMaxStalenessRaw = nil
+	}
+
 	return nil
 }
 
@@ -203,3 +203,58 @@ func TestLoadConfigFile_ProxyCacheStaticSecrets(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+// TestLoadConfigFile_ProxyCacheStaticSecretsStaleIfError tests loading a
+// config file with static secrets served from the cache while Vault is
+// unavailable.
+func TestLoadConfigFile_ProxyCacheStaticSecretsStaleIfError(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config-cache-static-secret-stale-if-error.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ValidateConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Cache{
+		CacheStaticSecrets:       true,
+		StaticSecretStaleIfError: true,
+		StaticSecretMaxStaleness: 15 * time.Minute,
+	}
+
+	config.Prune()
+	if diff := deep.Equal(config.Cache, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	config.Cache.CacheStaticSecrets = false
+	if err := config.ValidateConfig(); err == nil {
+		t.Fatal("expected error, as serving stale static secrets requires caching them")
+	}
+}
+
+// TestLoadConfigFile_VaultAddresses tests loading a config file with
+// multiple Vault addresses to fail over between.
+func TestLoadConfigFile_VaultAddresses(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config-vault-addresses.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Vault{
+		Address: "https://vault-east.example.com:8200",
+		Addresses: []string{
+			"https://vault-east.example.com:8200",
+			"https://vault-west.example.com:8200",
+		},
+		HealthCheckInterval: 30 * time.Second,
+		Retry: &Retry{
+			NumRetries: 12,
+		},
+	}
+
+	config.Prune()
+	if diff := deep.Equal(config.Vault, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,17 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: BUSL-1.1
+
+pid_file = "./pidfile"
+
+listener "tcp" {
+    address = "127.0.0.1:8300"
+    tls_disable = true
+}
+
+vault {
+	addresses = [
+		"https://vault-east.example.com:8200",
+		"https://vault-west.example.com:8200",
+	]
+	health_check_interval = "30s"
+}
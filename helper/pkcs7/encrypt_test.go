@@ -46,6 +46,45 @@ func TestEncrypt(t *testing.T) {
 	}
 }
 
+func TestEncryptWithAlgorithm(t *testing.T) {
+	modes := []int{
+		EncryptionAlgorithmDESCBC,
+		EncryptionAlgorithmAES128CBC,
+		EncryptionAlgorithmAES256CBC,
+		EncryptionAlgorithmAES128GCM,
+		EncryptionAlgorithmAES256GCM,
+	}
+	cert, err := createTestCertificate(x509.SHA256WithRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range modes {
+		plaintext := []byte("Hello Secret World!")
+		encrypted, err := EncryptWithAlgorithm(plaintext, []*x509.Certificate{cert.Certificate}, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p7, err := Parse(encrypted)
+		if err != nil {
+			t.Fatalf("cannot Parse encrypted result: %s", err)
+		}
+		alg, err := p7.ContentEncryptionAlgorithm()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alg != mode {
+			t.Errorf("unexpected content encryption algorithm: expected %d, got %d", mode, alg)
+		}
+		result, err := p7.Decrypt(cert.Certificate, *cert.PrivateKey)
+		if err != nil {
+			t.Fatalf("cannot Decrypt encrypted result: %s", err)
+		}
+		if !bytes.Equal(plaintext, result) {
+			t.Errorf("encrypted data does not match plaintext:\n\tExpected: %s\n\tActual: %s", plaintext, result)
+		}
+	}
+}
+
 func TestEncryptUsingPSK(t *testing.T) {
 	modes := []int{
 		EncryptionAlgorithmDESCBC,
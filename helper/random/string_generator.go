@@ -139,6 +139,42 @@ func (g *StringGenerator) generate(rng io.Reader) (str string, err error) {
 	return string(candidate), nil
 }
 
+// Validate that the provided value could have been generated by this generator. Since values are typically
+// provided by users rather than generated, the length of the generator is treated as a minimum length.
+func (g *StringGenerator) Validate(value string) error {
+	// Ensure the generator is configured well since it may be manually created rather than parsed from HCL
+	err := g.validateConfig()
+	if err != nil {
+		return err
+	}
+
+	candidate := []rune(value)
+	if len(candidate) < g.Length {
+		return fmt.Errorf("must be at least %d characters long", g.Length)
+	}
+
+	g.charsetLock.RLock()
+	charset := g.charset
+	g.charsetLock.RUnlock()
+	for _, r := range candidate {
+		if !charIn(r, charset) {
+			return fmt.Errorf("contains characters outside of the allowed charset")
+		}
+	}
+
+	for _, rule := range g.Rules {
+		if rule.Pass(candidate) {
+			continue
+		}
+		if cr, ok := rule.(CharsetRule); ok {
+			return fmt.Errorf("must contain at least %d of the characters %q", cr.MinChars, string(cr.Charset))
+		}
+		return fmt.Errorf("does not satisfy the %q rule", rule.Type())
+	}
+
+	return nil
+}
+
 const (
 	// maxCharsetLen is the maximum length a charset is allowed to be when generating a candidate string.
 	// This is the total number of numbers available for selecting an index out of the charset slice.
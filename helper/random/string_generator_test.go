@@ -557,6 +557,71 @@ func (badReader) Read([]byte) (int, error) {
 	return 0, fmt.Errorf("test error")
 }
 
+func TestStringGenerator_Validate(t *testing.T) {
+	generator := &StringGenerator{
+		Length: 8,
+		Rules: []Rule{
+			CharsetRule{
+				Charset:  LowercaseRuneset,
+				MinChars: 1,
+			},
+			CharsetRule{
+				Charset:  NumericRuneset,
+				MinChars: 2,
+			},
+		},
+	}
+
+	type testCase struct {
+		value     string
+		expectErr bool
+	}
+
+	tests := map[string]testCase{
+		"valid": {
+			value:     "abcdef12",
+			expectErr: false,
+		},
+		"longer than length": {
+			value:     "abcdefghijkl1234",
+			expectErr: false,
+		},
+		"too short": {
+			value:     "abc12",
+			expectErr: true,
+		},
+		"character outside of charset": {
+			value:     "abcdef12!",
+			expectErr: true,
+		},
+		"rule not satisfied": {
+			value:     "abcdefg1",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := generator.Validate(test.value)
+			if test.expectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+		})
+	}
+
+	// Generated strings always pass validation
+	str, err := DefaultStringGenerator.Generate(context.Background(), rand.Reader)
+	if err != nil {
+		t.Fatalf("no error expected, got: %s", err)
+	}
+	if err := DefaultStringGenerator.Validate(str); err != nil {
+		t.Fatalf("no error expected, got: %s", err)
+	}
+}
+
 func TestValidate(t *testing.T) {
 	type testCase struct {
 		generator *StringGenerator
@@ -385,10 +385,10 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 		// Start with the request context
 		ctx := r.Context()
 		var cancelFunc context.CancelFunc
-		// Add our timeout, but not for the monitor or events endpoints, as they are streaming
+		// Add our timeout, but not for the monitor, events or backend streaming endpoints, as they are streaming
 		// Request URL path for sys/monitor looks like /v1/sys/monitor
 		// Request URL paths for event subscriptions look like /v1/sys/events/subscribe/{eventType}. Example: /v1/sys/events/subscribe/kv*
-		if r.URL.Path == "/v1/sys/monitor" || strings.HasPrefix(r.URL.Path, "/v1/sys/events/subscribe") {
+		if r.URL.Path == "/v1/sys/monitor" || strings.HasPrefix(r.URL.Path, "/v1/sys/events/subscribe") || isStreamingRequest(core, r) {
 			ctx, cancelFunc = context.WithCancel(ctx)
 		} else {
 			ctx, cancelFunc = context.WithTimeout(ctx, maxRequestDuration)
@@ -1421,3 +1421,15 @@ func respondOIDCPermissionDenied(w http.ResponseWriter) {
 func trimPath(ns *namespace.Namespace, path string) string {
 	return ns.TrimmedPath(path[len("/v1/"):])
 }
+
+// isStreamingRequest checks whether the request is for one of the streaming
+// paths of a backend. The namespace of the request is not resolved yet, so
+// the path is looked up from the root namespace with the namespace header
+// prepended.
+func isStreamingRequest(core *vault.Core, r *http.Request) bool {
+	if (r.Method != http.MethodPost && r.Method != http.MethodPut) || !strings.HasPrefix(r.URL.Path, "/v1/") {
+		return false
+	}
+	path := namespace.Canonicalize(r.Header.Get(consts.NamespaceHeaderName)) + r.URL.Path[len("/v1/"):]
+	return core.RouterAccess().IsStreamingPath(namespace.RootContext(r.Context()), path)
+}
@@ -45,6 +45,11 @@ func (b *bufferedReader) Close() error {
 
 const MergePatchContentTypeHeader = "application/merge-patch+json"
 
+// SCIMContentTypeHeader is the content type of SCIM requests. SCIM clients
+// send PATCH requests with it, so it is accepted on the identity store's SCIM
+// endpoints in place of a merge patch.
+const SCIMContentTypeHeader = "application/scim+json"
+
 func buildLogicalRequestNoAuth(perfStandby bool, ra *vault.RouterAccess, w http.ResponseWriter, r *http.Request) (*logical.Request, io.ReadCloser, int, error) {
 	ns, err := namespace.FromContext(r.Context())
 	if err != nil {
@@ -110,7 +115,15 @@ func buildLogicalRequestNoAuth(perfStandby bool, ra *vault.RouterAccess, w http.
 		// add the HTTP request to the logical request object for later consumption.
 		contentType := r.Header.Get("Content-Type")
 
-		if (ra != nil && ra.IsBinaryPath(r.Context(), path)) ||
+		if ra != nil && ra.IsStreamingPath(r.Context(), path) {
+			// Streaming request bodies are not parsed, so parameters come
+			// from the query string instead, and the backend streams its
+			// response.
+			passHTTPReq = true
+			origBody = r.Body
+			data = parseQuery(r.URL.Query())
+			responseWriter = w
+		} else if (ra != nil && ra.IsBinaryPath(r.Context(), path)) ||
 			path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" {
 			passHTTPReq = true
 			origBody = r.Body
@@ -160,7 +173,8 @@ func buildLogicalRequestNoAuth(perfStandby bool, ra *vault.RouterAccess, w http.
 			return nil, nil, status, err
 		}
 
-		if contentType != MergePatchContentTypeHeader {
+		isSCIMPatch := contentType == SCIMContentTypeHeader && strings.HasPrefix(path, "identity/scim/")
+		if contentType != MergePatchContentTypeHeader && !isSCIMPatch {
 			return nil, nil, http.StatusUnsupportedMediaType, fmt.Errorf("PATCH requires Content-Type of %s, provided %s", MergePatchContentTypeHeader, contentType)
 		}
 
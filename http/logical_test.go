@@ -457,6 +457,92 @@ func TestLogical_BinaryPath(t *testing.T) {
 	}
 }
 
+// TestLogical_StreamingPath tests that streaming paths receive their
+// parameters from the query string, can stream their response and are not
+// bound by max_request_duration
+func TestLogical_StreamingPath(t *testing.T) {
+	t.Parallel()
+
+	maxRequestDuration := 100 * time.Millisecond
+	echoHandler := func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		body, ok := logical.ContextOriginalBodyValue(ctx)
+		if !ok || req.ResponseWriter == nil {
+			return logical.ErrorResponse("missing body or response writer"), nil
+		}
+		time.Sleep(3 * maxRequestDuration)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		req.ResponseWriter.Header().Set("Content-Type", "application/octet-stream")
+		if _, err := req.ResponseWriter.Write([]byte(data.Get("prefix").(string))); err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(req.ResponseWriter, body); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	conf := &vault.CoreConfig{
+		BuiltinRegistry: corehelpers.NewMockBuiltinRegistry(),
+		LogicalBackends: map[string]logical.Factory{
+			"streamtest": func(ctx context.Context, config *logical.BackendConfig) (logical.Backend, error) {
+				b := new(framework.Backend)
+				b.BackendType = logical.TypeLogical
+				b.Paths = []*framework.Path{
+					{
+						Pattern: "echo",
+						Fields: map[string]*framework.FieldSchema{
+							"prefix": {Type: framework.TypeString},
+						},
+						Operations: map[logical.Operation]framework.OperationHandler{
+							logical.UpdateOperation: &framework.PathOperation{Callback: echoHandler},
+						},
+					},
+				}
+				b.PathsSpecial = &logical.Paths{Streaming: []string{"echo"}}
+				err := b.Setup(ctx, config)
+				return b, err
+			},
+		},
+	}
+
+	core, _, token := vault.TestCoreUnsealedWithConfig(t, conf)
+	ln, addr := TestListener(t)
+	defer ln.Close()
+	TestServerWithListenerAndProperties(t, ln, addr, core, &vault.HandlerProperties{
+		Core: core,
+		ListenerConfig: &configutil.Listener{
+			MaxRequestDuration: maxRequestDuration,
+		},
+	})
+	TestServerAuth(t, addr, token)
+
+	mountReq := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		ClientToken: token,
+		Path:        "sys/mounts/streamtest",
+		Data: map[string]interface{}{
+			"type": "streamtest",
+		},
+	}
+	mountResp, err := core.HandleRequest(namespace.RootContext(nil), mountReq)
+	if err != nil || mountResp.IsError() {
+		t.Fatalf("failed mounting stream-test engine: %v %v", err, mountResp)
+	}
+
+	body := []byte{0x00, 0x01, 0xfe, 0xff}
+	resp := testHttpPostBinaryData(t, token, addr+"/v1/streamtest/echo?prefix=abc", body)
+	testResponseStatus(t, resp, http.StatusOK)
+	all, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := append([]byte("abc"), body...); !bytes.Equal(all, expected) {
+		t.Fatalf("expected %v, got %v", expected, all)
+	}
+}
+
 func TestLogical_ListWithQueryParameters(t *testing.T) {
 	core, _, rootToken := vault.TestCoreUnsealed(t)
 
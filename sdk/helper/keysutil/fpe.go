@@ -0,0 +1,549 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+)
+
+const (
+	// fpeMinDomainSize is the minimum number of possible values of a numeral
+	// string, as required by NIST SP 800-38G Rev. 1.
+	fpeMinDomainSize = 1000000
+
+	// fpeMaxRadix is the maximum number of characters in an alphabet.
+	fpeMaxRadix = 1 << 16
+
+	// FF3-1 tweaks are always 56 bits long.
+	ff31TweakLen = 7
+
+	// ff1MaxTweakLen bounds user supplied FF1 tweaks, which may otherwise be
+	// of any length.
+	ff1MaxTweakLen = 256
+
+	// ff1DerivedTweakLen is the length of the FF1 tweaks derived from the
+	// context of convergent keys.
+	ff1DerivedTweakLen = 16
+)
+
+// BuiltinFPEAlphabets are the alphabets usable as format with any
+// format-preserving encryption key.
+var BuiltinFPEAlphabets = map[string]string{
+	"numeric":            "0123456789",
+	"alphanumeric-lower": "0123456789abcdefghijklmnopqrstuvwxyz",
+	"alphanumeric-upper": "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"alphanumeric":       "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+}
+
+// FPEOpts are the arguments to format-preserving encryption operations.
+type FPEOpts struct {
+	// Format is the name of the alphabet of the values. Characters outside of
+	// the alphabet are left in place.
+	Format string
+	// Tweak is the user supplied tweak. It must be empty for convergent keys,
+	// which derive it from the context.
+	Tweak []byte
+}
+
+// ValidateFPEAlphabet checks that the alphabet can be used with
+// format-preserving encryption.
+func ValidateFPEAlphabet(alphabet string) error {
+	if !utf8.ValidString(alphabet) {
+		return fmt.Errorf("alphabet is not valid UTF-8")
+	}
+	seen := make(map[rune]struct{})
+	for _, r := range alphabet {
+		if _, ok := seen[r]; ok {
+			return fmt.Errorf("alphabet contains %q more than once", r)
+		}
+		seen[r] = struct{}{}
+	}
+	if len(seen) < 2 || len(seen) > fpeMaxRadix {
+		return fmt.Errorf("alphabet must contain between 2 and %d characters", fpeMaxRadix)
+	}
+	return nil
+}
+
+// FPEAlphabet returns the alphabet of the given format, which is either the
+// name of an alphabet configured on the policy or a built-in one.
+func (p *Policy) FPEAlphabet(format string) (string, error) {
+	if format == "" {
+		return "", errutil.UserError{Err: "format is required for format-preserving encryption"}
+	}
+	if alphabet, ok := p.FPEAlphabets[format]; ok {
+		return alphabet, nil
+	}
+	if alphabet, ok := BuiltinFPEAlphabets[format]; ok {
+		return alphabet, nil
+	}
+	return "", errutil.UserError{Err: fmt.Sprintf("unknown format %q", format)}
+}
+
+// EncryptFPE encrypts the base64 encoded plaintext with format-preserving
+// encryption. The ciphertext has the same format as the plaintext, so unlike
+// Encrypt it carries no version prefix; the version used is returned instead.
+func (p *Policy) EncryptFPE(ver int, context []byte, value string, opts FPEOpts) (string, int, error) {
+	if !p.Type.FormatPreservingEncryptionSupported() {
+		return "", 0, errutil.UserError{Err: fmt.Sprintf("format-preserving encryption not supported for key type %v", p.Type)}
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", 0, errutil.UserError{Err: err.Error()}
+	}
+
+	switch {
+	case ver == 0:
+		ver = p.LatestVersion
+	case ver < 0:
+		return "", 0, errutil.UserError{Err: "requested version for encryption is negative"}
+	case ver > p.LatestVersion:
+		return "", 0, errutil.UserError{Err: "requested version for encryption is higher than the latest key version"}
+	case ver < p.MinEncryptionVersion:
+		return "", 0, errutil.UserError{Err: "requested version for encryption is less than the minimum encryption key version"}
+	}
+
+	ciphertext, err := p.fpeTransform(ver, context, string(plaintext), opts, true)
+	if err != nil {
+		return "", 0, err
+	}
+	return ciphertext, ver, nil
+}
+
+// DecryptFPE decrypts a ciphertext returned by EncryptFPE with the given key
+// version, which is required as the ciphertext does not carry it. The
+// plaintext is returned base64 encoded.
+func (p *Policy) DecryptFPE(ver int, context []byte, value string, opts FPEOpts) (string, error) {
+	if !p.Type.FormatPreservingEncryptionSupported() {
+		return "", errutil.UserError{Err: fmt.Sprintf("format-preserving decryption not supported for key type %v", p.Type)}
+	}
+
+	switch {
+	case ver == 0:
+		return "", errutil.UserError{Err: "key version is required for format-preserving decryption"}
+	case ver < 0:
+		return "", errutil.UserError{Err: "requested version for decryption is negative"}
+	case ver > p.LatestVersion:
+		return "", errutil.UserError{Err: "requested version for decryption is higher than the latest key version"}
+	case p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion:
+		return "", errutil.UserError{Err: ErrTooOld}
+	}
+
+	plaintext, err := p.fpeTransform(ver, context, value, opts, false)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(plaintext)), nil
+}
+
+// fpeTransform encrypts or decrypts the characters of value that are part of
+// the alphabet of the format, leaving all other characters in place.
+func (p *Policy) fpeTransform(ver int, context []byte, value string, opts FPEOpts, encrypt bool) (string, error) {
+	if !utf8.ValidString(value) {
+		return "", errutil.UserError{Err: "value is not valid UTF-8"}
+	}
+
+	alphabet, err := p.FPEAlphabet(opts.Format)
+	if err != nil {
+		return "", err
+	}
+	chars := []rune(alphabet)
+	indexes := make(map[rune]int, len(chars))
+	for i, r := range chars {
+		indexes[r] = i
+	}
+
+	runes := []rune(value)
+	var positions []int
+	var numerals []int
+	for i, r := range runes {
+		if index, ok := indexes[r]; ok {
+			positions = append(positions, i)
+			numerals = append(numerals, index)
+		}
+	}
+
+	tweak, err := p.fpeTweak(ver, context, opts.Tweak)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := p.GetKey(context, ver, 32)
+	if err != nil {
+		return "", err
+	}
+	if len(key) != 32 {
+		return "", errutil.InternalError{Err: "could not derive fpe key, length not correct"}
+	}
+
+	var result []int
+	switch p.Type {
+	case KeyType_AES256_FF1:
+		ff1, err := newFF1(key, len(chars))
+		if err != nil {
+			return "", errutil.InternalError{Err: err.Error()}
+		}
+		if encrypt {
+			result, err = ff1.encrypt(numerals, tweak)
+		} else {
+			result, err = ff1.decrypt(numerals, tweak)
+		}
+		if err != nil {
+			return "", errutil.UserError{Err: err.Error()}
+		}
+	case KeyType_AES256_FF3_1:
+		ff3, err := newFF31(key, len(chars))
+		if err != nil {
+			return "", errutil.InternalError{Err: err.Error()}
+		}
+		if encrypt {
+			result, err = ff3.encrypt(numerals, tweak)
+		} else {
+			result, err = ff3.decrypt(numerals, tweak)
+		}
+		if err != nil {
+			return "", errutil.UserError{Err: err.Error()}
+		}
+	default:
+		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
+	}
+
+	for i, position := range positions {
+		runes[position] = chars[result[i]]
+	}
+	return string(runes), nil
+}
+
+// fpeTweak returns the tweak to use. As with nonces of convergent keys, the
+// tweak of convergent keys is derived from the context rather than supplied.
+func (p *Policy) fpeTweak(ver int, context, tweak []byte) ([]byte, error) {
+	if p.ConvergentEncryption {
+		if len(tweak) != 0 {
+			return nil, errutil.UserError{Err: "tweak provided when not allowed; convergent keys derive the tweak from the context"}
+		}
+		keyEntry, err := p.safeGetKeyEntry(ver)
+		if err != nil {
+			return nil, err
+		}
+		mac := hmac.New(sha256.New, keyEntry.HMACKey)
+		mac.Write(context)
+		sum := mac.Sum(nil)
+		if p.Type == KeyType_AES256_FF3_1 {
+			return sum[:ff31TweakLen], nil
+		}
+		return sum[:ff1DerivedTweakLen], nil
+	}
+
+	switch p.Type {
+	case KeyType_AES256_FF3_1:
+		if len(tweak) == 0 {
+			return make([]byte, ff31TweakLen), nil
+		}
+		if len(tweak) != ff31TweakLen {
+			return nil, errutil.UserError{Err: fmt.Sprintf("tweak must be exactly %d bytes long for key type %v", ff31TweakLen, p.Type)}
+		}
+	default:
+		if len(tweak) > ff1MaxTweakLen {
+			return nil, errutil.UserError{Err: fmt.Sprintf("tweak must be at most %d bytes long for key type %v", ff1MaxTweakLen, p.Type)}
+		}
+	}
+	return tweak, nil
+}
+
+// fpeMinLen returns the minimum length of numeral strings of the radix.
+func fpeMinLen(radix *big.Int) int {
+	minLen := 1
+	domain := new(big.Int).Set(radix)
+	for domain.Cmp(big.NewInt(fpeMinDomainSize)) < 0 {
+		domain.Mul(domain, radix)
+		minLen++
+	}
+	if minLen < 2 {
+		minLen = 2
+	}
+	return minLen
+}
+
+// fpeNum returns the number represented by the numeral string, most
+// significant numeral first.
+func fpeNum(x []int, radix *big.Int) *big.Int {
+	n := new(big.Int)
+	for _, numeral := range x {
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(numeral)))
+	}
+	return n
+}
+
+// fpeStr returns the m numerals representing n, most significant first.
+func fpeStr(n *big.Int, radix *big.Int, m int) []int {
+	out := make([]int, m)
+	n = new(big.Int).Set(n)
+	digit := new(big.Int)
+	for i := m - 1; i >= 0; i-- {
+		n.DivMod(n, radix, digit)
+		out[i] = int(digit.Int64())
+	}
+	return out
+}
+
+func fpeRev(x []int) []int {
+	out := make([]int, len(x))
+	for i, numeral := range x {
+		out[len(x)-1-i] = numeral
+	}
+	return out
+}
+
+func revBytes(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[len(b)-1-i] = c
+	}
+	return out
+}
+
+// ff1 implements the FF1 mode of NIST SP 800-38G.
+type ff1 struct {
+	block  cipher.Block
+	radix  *big.Int
+	minLen int
+}
+
+func newFF1(key []byte, radix int) (*ff1, error) {
+	if radix < 2 || radix > fpeMaxRadix {
+		return nil, fmt.Errorf("radix must be between 2 and %d", fpeMaxRadix)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	r := big.NewInt(int64(radix))
+	return &ff1{block: block, radix: r, minLen: fpeMinLen(r)}, nil
+}
+
+// prf is the CBC-MAC of the input, which must be a multiple of the block
+// size long.
+func (f *ff1) prf(input []byte) []byte {
+	y := make([]byte, aes.BlockSize)
+	for i := 0; i < len(input); i += aes.BlockSize {
+		for j := 0; j < aes.BlockSize; j++ {
+			y[j] ^= input[i+j]
+		}
+		f.block.Encrypt(y, y)
+	}
+	return y
+}
+
+func (f *ff1) encrypt(x []int, tweak []byte) ([]int, error) {
+	return f.cipher(x, tweak, true)
+}
+
+func (f *ff1) decrypt(x []int, tweak []byte) ([]int, error) {
+	return f.cipher(x, tweak, false)
+}
+
+func (f *ff1) cipher(x []int, tweak []byte, encrypt bool) ([]int, error) {
+	n := len(x)
+	if n < f.minLen {
+		return nil, fmt.Errorf("value must contain at least %d characters of the format", f.minLen)
+	}
+	if int64(n) > 1<<32-1 {
+		return nil, fmt.Errorf("value is too long")
+	}
+	t := len(tweak)
+	u := n / 2
+	v := n - u
+	a, b := x[:u], x[u:]
+
+	// b is the number of bytes needed for numbers of v numerals, and d the
+	// number of bytes of the pseudorandom output used in each round.
+	maxB := new(big.Int).Exp(f.radix, big.NewInt(int64(v)), nil)
+	maxB.Sub(maxB, big.NewInt(1))
+	numBytes := (maxB.BitLen() + 7) / 8
+	d := 4*((numBytes+3)/4) + 4
+
+	radix := f.radix.Int64()
+	p := []byte{
+		1, 2, 1,
+		byte(radix >> 16), byte(radix >> 8), byte(radix),
+		10,
+		byte(u),
+		byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n),
+		byte(t >> 24), byte(t >> 16), byte(t >> 8), byte(t),
+	}
+
+	pad := (16 - (t+numBytes+1)%16) % 16
+	q := make([]byte, t+pad+1+numBytes)
+	copy(q, tweak)
+
+	modU := new(big.Int).Exp(f.radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(f.radix, big.NewInt(int64(v)), nil)
+
+	round := func(i int, src []int) *big.Int {
+		q[t+pad] = byte(i)
+		numBytesOfSrc := fpeNum(src, f.radix).Bytes()
+		for j := t + pad + 1; j < len(q); j++ {
+			q[j] = 0
+		}
+		copy(q[len(q)-len(numBytesOfSrc):], numBytesOfSrc)
+
+		r := f.prf(append(append([]byte{}, p...), q...))
+		s := make([]byte, 0, ((d+15)/16)*16)
+		s = append(s, r...)
+		for j := 1; len(s) < d; j++ {
+			block := make([]byte, aes.BlockSize)
+			block[12], block[13], block[14], block[15] = byte(j>>24), byte(j>>16), byte(j>>8), byte(j)
+			for k := range block {
+				block[k] ^= r[k]
+			}
+			f.block.Encrypt(block, block)
+			s = append(s, block...)
+		}
+		return new(big.Int).SetBytes(s[:d])
+	}
+
+	if encrypt {
+		for i := 0; i < 10; i++ {
+			y := round(i, b)
+			m, mod := u, modU
+			if i%2 == 1 {
+				m, mod = v, modV
+			}
+			c := fpeNum(a, f.radix)
+			c.Add(c, y)
+			c.Mod(c, mod)
+			a, b = b, fpeStr(c, f.radix, m)
+		}
+	} else {
+		for i := 9; i >= 0; i-- {
+			y := round(i, a)
+			m, mod := u, modU
+			if i%2 == 1 {
+				m, mod = v, modV
+			}
+			c := fpeNum(b, f.radix)
+			c.Sub(c, y)
+			c.Mod(c, mod)
+			a, b = fpeStr(c, f.radix, m), a
+		}
+	}
+
+	return append(append([]int{}, a...), b...), nil
+}
+
+// ff31 implements the FF3-1 mode of NIST SP 800-38G Rev. 1.
+type ff31 struct {
+	block  cipher.Block
+	radix  *big.Int
+	minLen int
+	maxLen int
+}
+
+func newFF31(key []byte, radix int) (*ff31, error) {
+	if radix < 2 || radix > fpeMaxRadix {
+		return nil, fmt.Errorf("radix must be between 2 and %d", fpeMaxRadix)
+	}
+	// FF3 uses the key with its bytes reversed
+	block, err := aes.NewCipher(revBytes(key))
+	if err != nil {
+		return nil, err
+	}
+	r := big.NewInt(int64(radix))
+
+	// maxLen is 2*floor(log_radix(2^96))
+	limit := new(big.Int).Lsh(big.NewInt(1), 96)
+	half := 0
+	for domain := new(big.Int).Set(r); domain.Cmp(limit) <= 0; domain.Mul(domain, r) {
+		half++
+	}
+	return &ff31{block: block, radix: r, minLen: fpeMinLen(r), maxLen: 2 * half}, nil
+}
+
+func (f *ff31) encrypt(x []int, tweak []byte) ([]int, error) {
+	return f.cipher(x, ff31Tweak(tweak), true)
+}
+
+func (f *ff31) decrypt(x []int, tweak []byte) ([]int, error) {
+	return f.cipher(x, ff31Tweak(tweak), false)
+}
+
+// ff31Tweak expands a 56-bit FF3-1 tweak into the 64-bit tweak of FF3.
+func ff31Tweak(tweak []byte) []byte {
+	return []byte{
+		tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0,
+		tweak[4], tweak[5], tweak[6], tweak[3] << 4,
+	}
+}
+
+// cipher implements FF3 with a 64-bit tweak.
+func (f *ff31) cipher(x []int, tweak []byte, encrypt bool) ([]int, error) {
+	n := len(x)
+	if n < f.minLen {
+		return nil, fmt.Errorf("value must contain at least %d characters of the format", f.minLen)
+	}
+	if n > f.maxLen {
+		return nil, fmt.Errorf("value must contain at most %d characters of the format", f.maxLen)
+	}
+	u := (n + 1) / 2
+	v := n - u
+	a, b := x[:u], x[u:]
+	tl, tr := tweak[:4], tweak[4:]
+
+	modU := new(big.Int).Exp(f.radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(f.radix, big.NewInt(int64(v)), nil)
+
+	round := func(i int, src []int) *big.Int {
+		w := tr
+		if i%2 == 1 {
+			w = tl
+		}
+		p := make([]byte, aes.BlockSize)
+		copy(p, w)
+		p[3] ^= byte(i)
+		numBytes := fpeNum(fpeRev(src), f.radix).Bytes()
+		copy(p[aes.BlockSize-len(numBytes):], numBytes)
+
+		s := revBytes(p)
+		f.block.Encrypt(s, s)
+		return new(big.Int).SetBytes(revBytes(s))
+	}
+
+	if encrypt {
+		for i := 0; i < 8; i++ {
+			y := round(i, b)
+			m, mod := u, modU
+			if i%2 == 1 {
+				m, mod = v, modV
+			}
+			c := fpeNum(fpeRev(a), f.radix)
+			c.Add(c, y)
+			c.Mod(c, mod)
+			a, b = b, fpeRev(fpeStr(c, f.radix, m))
+		}
+	} else {
+		for i := 7; i >= 0; i-- {
+			y := round(i, a)
+			m, mod := u, modU
+			if i%2 == 1 {
+				m, mod = v, modV
+			}
+			c := fpeNum(fpeRev(b), f.radix)
+			c.Sub(c, y)
+			c.Mod(c, mod)
+			a, b = fpeRev(fpeStr(c, f.radix, m)), a
+		}
+	}
+
+	return append(append([]int{}, a...), b...), nil
+}
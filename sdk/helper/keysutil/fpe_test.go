@@ -0,0 +1,273 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const testFPEAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func testNumerals(t *testing.T, s string) []int {
+	t.Helper()
+	out := make([]int, len(s))
+	for i, c := range s {
+		index := strings.IndexRune(testFPEAlphabet, c)
+		if index < 0 {
+			t.Fatalf("bad numeral %q", c)
+		}
+		out[i] = index
+	}
+	return out
+}
+
+func testNumeralString(x []int) string {
+	var sb strings.Builder
+	for _, numeral := range x {
+		sb.WriteByte(testFPEAlphabet[numeral])
+	}
+	return sb.String()
+}
+
+func testHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Sample vectors of NIST SP 800-38G
+func TestFF1_NISTVectors(t *testing.T) {
+	tests := []struct {
+		key, tweak string
+		radix      int
+		pt, ct     string
+	}{
+		{"2B7E151628AED2A6ABF7158809CF4F3C", "", 10, "0123456789", "2433477484"},
+		{"2B7E151628AED2A6ABF7158809CF4F3C", "39383736353433323130", 10, "0123456789", "6124200773"},
+		{"2B7E151628AED2A6ABF7158809CF4F3C", "3737373770717273373737", 36, "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+		{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", "", 10, "0123456789", "6657667009"},
+		{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", "39383736353433323130", 10, "0123456789", "1001623463"},
+		{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", "3737373770717273373737", 36, "0123456789abcdefghi", "xs8a0azh2avyalyzuwd"},
+	}
+	for _, tt := range tests {
+		f, err := newFF1(testHex(t, tt.key), tt.radix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tweak := testHex(t, tt.tweak)
+
+		ct, err := f.encrypt(testNumerals(t, tt.pt), tweak)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := testNumeralString(ct); got != tt.ct {
+			t.Fatalf("encrypting %s: expected %s, got %s", tt.pt, tt.ct, got)
+		}
+
+		pt, err := f.decrypt(ct, tweak)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := testNumeralString(pt); got != tt.pt {
+			t.Fatalf("decrypting %s: expected %s, got %s", tt.ct, tt.pt, got)
+		}
+	}
+}
+
+// Sample vectors of NIST SP 800-38G for FF3, which FF3-1 uses with a
+// different tweak schedule
+func TestFF3_NISTVectors(t *testing.T) {
+	tests := []struct {
+		key, tweak string
+		radix      int
+		pt, ct     string
+	}{
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", "D8E7920AFA330A73", 10, "890121234567890000", "750918814058654607"},
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", "9A768A92F60E12D8", 10, "890121234567890000", "018989839189395384"},
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", "D8E7920AFA330A73", 10, "89012123456789000000789000000", "48598367162252569629397416226"},
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", "0000000000000000", 10, "89012123456789000000789000000", "34695224821734535122613701434"},
+		{"EF4359D8D580AA4F7F036D6F04FC6A94", "9A768A92F60E12D8", 26, "0123456789abcdefghi", "g2pk40i992fn20cjakb"},
+	}
+	for _, tt := range tests {
+		f, err := newFF31(testHex(t, tt.key), tt.radix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tweak := testHex(t, tt.tweak)
+
+		ct, err := f.cipher(testNumerals(t, tt.pt), tweak, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := testNumeralString(ct); got != tt.ct {
+			t.Fatalf("encrypting %s: expected %s, got %s", tt.pt, tt.ct, got)
+		}
+
+		pt, err := f.cipher(ct, tweak, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := testNumeralString(pt); got != tt.pt {
+			t.Fatalf("decrypting %s: expected %s, got %s", tt.ct, tt.pt, got)
+		}
+	}
+}
+
+func TestFF31_Tweak(t *testing.T) {
+	got := ff31Tweak(testHex(t, "D8E7920AFA330A"))
+	if expected := "D8E79200FA330AA0"; !strings.EqualFold(hex.EncodeToString(got), expected) {
+		t.Fatalf("expected %s, got %x", expected, got)
+	}
+}
+
+func TestPolicy_FPE(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+
+	for _, keyType := range []KeyType{KeyType_AES256_FF1, KeyType_AES256_FF3_1} {
+		t.Run(keyType.String(), func(t *testing.T) {
+			p := NewPolicy(PolicyConfig{
+				Name: "fpe-" + keyType.String(),
+				Type: keyType,
+			})
+			if err := p.Rotate(ctx, storage, rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+
+			plaintext := "4111-1111-1111-1111"
+			opts := FPEOpts{Format: "numeric"}
+			ct, ver, err := p.EncryptFPE(0, nil, base64.StdEncoding.EncodeToString([]byte(plaintext)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ver != 1 {
+				t.Fatalf("expected version 1, got %d", ver)
+			}
+			if len(ct) != len(plaintext) || ct == plaintext || strings.Count(ct, "-") != 3 || ct[4] != '-' {
+				t.Fatalf("format not preserved: %q", ct)
+			}
+
+			// Encryption is deterministic for a given tweak
+			again, _, err := p.EncryptFPE(0, nil, base64.StdEncoding.EncodeToString([]byte(plaintext)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != ct {
+				t.Fatalf("expected %q, got %q", ct, again)
+			}
+
+			pt, err := p.DecryptFPE(ver, nil, ct, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pt != base64.StdEncoding.EncodeToString([]byte(plaintext)) {
+				t.Fatalf("bad plaintext %q", pt)
+			}
+
+			// A different tweak gives a different ciphertext
+			tweaked, _, err := p.EncryptFPE(0, nil, base64.StdEncoding.EncodeToString([]byte(plaintext)), FPEOpts{Format: "numeric", Tweak: []byte("1234567")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tweaked == ct {
+				t.Fatal("expected tweak to change the ciphertext")
+			}
+
+			// Rotation keeps older versions decryptable
+			if err := p.Rotate(ctx, storage, rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+			rotated, ver, err := p.EncryptFPE(0, nil, base64.StdEncoding.EncodeToString([]byte(plaintext)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ver != 2 || rotated == ct {
+				t.Fatalf("expected a new ciphertext with version 2, got %q with version %d", rotated, ver)
+			}
+			pt, err = p.DecryptFPE(1, nil, ct, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pt != base64.StdEncoding.EncodeToString([]byte(plaintext)) {
+				t.Fatalf("bad plaintext %q", pt)
+			}
+
+			// Too few characters of the format
+			if _, _, err := p.EncryptFPE(0, nil, base64.StdEncoding.EncodeToString([]byte("12-34")), opts); err == nil {
+				t.Fatal("expected error for short value")
+			}
+			if _, _, err := p.EncryptFPE(0, nil, base64.StdEncoding.EncodeToString([]byte(plaintext)), FPEOpts{Format: "unknown"}); err == nil {
+				t.Fatal("expected error for unknown format")
+			}
+		})
+	}
+}
+
+func TestPolicy_FPEConvergent(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	lm, _ := NewLockManager(false, 0)
+
+	p, _, err := lm.GetPolicy(ctx, PolicyRequest{
+		Upsert:     true,
+		Storage:    storage,
+		KeyType:    KeyType_AES256_FF3_1,
+		Name:       "convergent",
+		Derived:    true,
+		Convergent: true,
+	}, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Unlock()
+
+	plaintext := base64.StdEncoding.EncodeToString([]byte("+1 (555) 010-9999"))
+	opts := FPEOpts{Format: "numeric"}
+	first, _, err := p.EncryptFPE(0, []byte("phones"), plaintext, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := p.EncryptFPE(0, []byte("phones"), plaintext, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatalf("expected convergent ciphertexts, got %q and %q", first, second)
+	}
+	other, _, err := p.EncryptFPE(0, []byte("other"), plaintext, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == first {
+		t.Fatal("expected the context to change the ciphertext")
+	}
+
+	if _, _, err := p.EncryptFPE(0, nil, plaintext, opts); err == nil {
+		t.Fatal("expected error without context")
+	}
+	if _, _, err := p.EncryptFPE(0, []byte("phones"), plaintext, FPEOpts{Format: "numeric", Tweak: []byte("1234567")}); err == nil {
+		t.Fatal("expected error for tweak with convergent key")
+	}
+
+	if _, err := p.DecryptFPE(0, []byte("phones"), first, opts); err == nil {
+		t.Fatal("expected error without key version")
+	}
+	decrypted, err := p.DecryptFPE(1, []byte("phones"), first, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted != plaintext {
+		t.Fatalf("bad plaintext %q", decrypted)
+	}
+}
@@ -70,6 +70,8 @@ const (
 	KeyType_HMAC
 	KeyType_AES128_CMAC
 	KeyType_AES256_CMAC
+	KeyType_AES256_FF1
+	KeyType_AES256_FF3_1
 	// If adding to this list please update allTestKeyTypes in policy_test.go
 )
 
@@ -157,7 +159,7 @@ func (kt KeyType) HashSignatureInput() bool {
 
 func (kt KeyType) DerivationSupported() bool {
 	switch kt {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_ED25519:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_ED25519, KeyType_AES256_FF1, KeyType_AES256_FF3_1:
 		return true
 	}
 	return false
@@ -171,6 +173,22 @@ func (kt KeyType) AssociatedDataSupported() bool {
 	return false
 }
 
+func (kt KeyType) FormatPreservingEncryptionSupported() bool {
+	switch kt {
+	case KeyType_AES256_FF1, KeyType_AES256_FF3_1:
+		return true
+	}
+	return false
+}
+
+func (kt KeyType) StreamingEncryptionSupported() bool {
+	switch kt {
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		return true
+	}
+	return false
+}
+
 func (kt KeyType) CMACSupported() bool {
 	switch kt {
 	case KeyType_AES128_CMAC, KeyType_AES256_CMAC:
@@ -229,6 +247,10 @@ func (kt KeyType) String() string {
 		return "aes128-cmac"
 	case KeyType_AES256_CMAC:
 		return "aes256-cmac"
+	case KeyType_AES256_FF1:
+		return "aes256-ff1"
+	case KeyType_AES256_FF3_1:
+		return "aes256-ff3-1"
 	}
 
 	return "[unknown]"
@@ -493,6 +515,10 @@ type Policy struct {
 
 	// AllowImportedKeyRotation indicates whether an imported key may be rotated by Vault
 	AllowImportedKeyRotation bool
+
+	// FPEAlphabets are the named alphabets usable as format with
+	// format-preserving encryption keys, in addition to the built-in ones.
+	FPEAlphabets map[string]string `json:"fpe_alphabets,omitempty"`
 }
 
 func (p *Policy) Lock(exclusive bool) {
@@ -876,7 +902,7 @@ func (p *Policy) DeriveKey(context, salt []byte, ver int, numBytes int) ([]byte,
 		}
 
 		switch p.Type {
-		case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_FF1, KeyType_AES256_FF3_1:
 			n, err := derBytes.ReadFrom(limReader)
 			if err != nil {
 				return nil, errutil.InternalError{Err: fmt.Sprintf("error reading returned derived bytes: %v", err)}
@@ -1671,7 +1697,7 @@ func (p *Policy) RotateInMemory(randReader io.Reader) (retErr error) {
 
 	var err error
 	switch p.Type {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_HMAC, KeyType_AES128_CMAC, KeyType_AES256_CMAC:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_HMAC, KeyType_AES128_CMAC, KeyType_AES256_CMAC, KeyType_AES256_FF1, KeyType_AES256_FF3_1:
 		// Default to 256 bit key
 		numBytes := 32
 		if p.Type == KeyType_AES128_GCM96 || p.Type == KeyType_AES128_CMAC {
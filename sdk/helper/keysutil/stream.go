@@ -0,0 +1,358 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Streaming ciphertexts use the STREAM construction of Hoang, Reyhanitabar,
+// Rogaway and Vizár, as also used by Tink and age. The plaintext is split
+// into fixed size segments which are sealed individually with a key unique
+// to the stream. The nonce of each segment carries its index and whether it
+// is the last one, so segments cannot be reordered, dropped or truncated
+// without authentication failing.
+//
+// A streaming ciphertext is laid out as
+//
+//	header  := magic (4) || key version (4) || segment size (4) || salt (32) || nonce prefix (7)
+//	segment := AEAD(stream key, nonce prefix || index (4) || last (1), plaintext segment)
+//
+// where the stream key is derived with HKDF-SHA256 from the key version's
+// (possibly context derived) key, the salt and the rest of the header.
+const (
+	// DefaultStreamSegmentSize is the plaintext size of the segments of a
+	// streaming ciphertext when none is requested.
+	DefaultStreamSegmentSize = 64 * 1024
+
+	// MinStreamSegmentSize and MaxStreamSegmentSize bound the plaintext size
+	// of the segments of a streaming ciphertext.
+	MinStreamSegmentSize = 1024
+	MaxStreamSegmentSize = 16 * 1024 * 1024
+
+	// StreamHeaderLen is the length of the header a streaming ciphertext
+	// starts with.
+	StreamHeaderLen = 12 + streamSaltLen + streamNoncePrefixLen
+
+	streamSaltLen        = 32
+	streamNoncePrefixLen = 7
+)
+
+var (
+	streamMagic = []byte("VTS1")
+	streamInfo  = []byte("vault transit stream")
+
+	errStreamInvalid = errutil.UserError{Err: "invalid streaming ciphertext"}
+)
+
+// StreamCipher encrypts or decrypts a single streaming ciphertext. It holds
+// the key derived for the stream, so unlike the policy it was created from it
+// can be used without holding the policy's lock.
+type StreamCipher struct {
+	aead        cipher.AEAD
+	header      []byte
+	segmentSize int
+	version     int
+	encrypt     bool
+}
+
+// NewStreamEncrypter returns a cipher encrypting a stream with the given key
+// version, or the latest one if zero, and plaintext segments of segmentSize
+// bytes, or DefaultStreamSegmentSize if zero.
+func (p *Policy) NewStreamEncrypter(ver int, context []byte, segmentSize int, randReader io.Reader) (*StreamCipher, error) {
+	if !p.Type.StreamingEncryptionSupported() {
+		return nil, errutil.UserError{Err: fmt.Sprintf("streaming encryption not supported for key type %v", p.Type)}
+	}
+	if p.ConvergentEncryption {
+		return nil, errutil.UserError{Err: "streaming encryption not supported for convergent keys"}
+	}
+
+	switch {
+	case ver == 0:
+		ver = p.LatestVersion
+	case ver < 0:
+		return nil, errutil.UserError{Err: "requested version for encryption is negative"}
+	case ver > p.LatestVersion:
+		return nil, errutil.UserError{Err: "requested version for encryption is higher than the latest key version"}
+	case ver < p.MinEncryptionVersion:
+		return nil, errutil.UserError{Err: "requested version for encryption is less than the minimum encryption key version"}
+	}
+
+	if segmentSize == 0 {
+		segmentSize = DefaultStreamSegmentSize
+	}
+	if segmentSize < MinStreamSegmentSize || segmentSize > MaxStreamSegmentSize {
+		return nil, errutil.UserError{Err: fmt.Sprintf("segment size must be between %d and %d bytes", MinStreamSegmentSize, MaxStreamSegmentSize)}
+	}
+
+	header := make([]byte, StreamHeaderLen)
+	copy(header, streamMagic)
+	binary.BigEndian.PutUint32(header[4:], uint32(ver))
+	binary.BigEndian.PutUint32(header[8:], uint32(segmentSize))
+	if _, err := io.ReadFull(randReader, header[12:]); err != nil {
+		return nil, errutil.InternalError{Err: err.Error()}
+	}
+
+	aead, err := p.streamAEAD(ver, context, header)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamCipher{
+		aead:        aead,
+		header:      header,
+		segmentSize: segmentSize,
+		version:     ver,
+		encrypt:     true,
+	}, nil
+}
+
+// NewStreamDecrypter returns a cipher decrypting the streaming ciphertext
+// starting with the given header, which is the first StreamHeaderLen bytes of
+// the ciphertext.
+func (p *Policy) NewStreamDecrypter(context, header []byte) (*StreamCipher, error) {
+	if !p.Type.StreamingEncryptionSupported() {
+		return nil, errutil.UserError{Err: fmt.Sprintf("streaming decryption not supported for key type %v", p.Type)}
+	}
+	if len(header) != StreamHeaderLen || !bytes.Equal(header[:4], streamMagic) {
+		return nil, errStreamInvalid
+	}
+
+	ver := int(binary.BigEndian.Uint32(header[4:]))
+	switch {
+	case ver == 0:
+		return nil, errStreamInvalid
+	case ver > p.LatestVersion:
+		return nil, errutil.UserError{Err: "invalid key version"}
+	case p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion:
+		return nil, errutil.UserError{Err: ErrTooOld}
+	}
+
+	segmentSize := int(binary.BigEndian.Uint32(header[8:]))
+	if segmentSize < MinStreamSegmentSize || segmentSize > MaxStreamSegmentSize {
+		return nil, errStreamInvalid
+	}
+
+	aead, err := p.streamAEAD(ver, context, header)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamCipher{
+		aead:        aead,
+		header:      append([]byte{}, header...),
+		segmentSize: segmentSize,
+		version:     ver,
+	}, nil
+}
+
+// Version returns the key version of the stream.
+func (s *StreamCipher) Version() int {
+	return s.version
+}
+
+// Stream encrypts everything read from src, or decrypts the rest of the
+// ciphertext following its header, writing the output to dst. It stops with
+// the error of ctx once ctx is done, which is checked before each segment.
+//
+// When encrypting, nothing is written to dst before the first segment has
+// been read from src, and the header goes out together with it. When
+// decrypting, each segment is authenticated before its plaintext is written,
+// but a stream that fails to decrypt may already have had part of its
+// plaintext written.
+func (s *StreamCipher) Stream(ctx context.Context, dst io.Writer, src io.Reader) error {
+	if s.encrypt {
+		return s.encryptStream(ctx, dst, src)
+	}
+	return s.decryptStream(ctx, dst, src)
+}
+
+func (s *StreamCipher) encryptStream(ctx context.Context, dst io.Writer, src io.Reader) error {
+	in := bufio.NewReaderSize(src, s.segmentSize)
+	plaintext := make([]byte, s.segmentSize)
+	out := make([]byte, 0, StreamHeaderLen+s.segmentSize+s.aead.Overhead())
+
+	// The header goes out together with the first segment
+	out = append(out, s.header...)
+	for index := uint32(0); ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, last, err := readStreamSegment(in, plaintext)
+		if err != nil {
+			return err
+		}
+		if !last && index == math.MaxUint32 {
+			return errutil.UserError{Err: "plaintext is too large for the segment size"}
+		}
+
+		out = s.aead.Seal(out, streamNonce(s.header, index, last), plaintext[:n], nil)
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		out = out[:0]
+	}
+}
+
+func (s *StreamCipher) decryptStream(ctx context.Context, dst io.Writer, src io.Reader) error {
+	in := bufio.NewReaderSize(src, s.segmentSize+s.aead.Overhead())
+	ciphertext := make([]byte, s.segmentSize+s.aead.Overhead())
+	out := make([]byte, 0, s.segmentSize)
+	for index := uint32(0); ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, last, err := readStreamSegment(in, ciphertext)
+		if err != nil {
+			return err
+		}
+		if n < s.aead.Overhead() || (!last && index == math.MaxUint32) {
+			return errStreamInvalid
+		}
+
+		out, err = s.aead.Open(out[:0], streamNonce(s.header, index, last), ciphertext[:n], nil)
+		if err != nil {
+			return errutil.UserError{Err: "invalid streaming ciphertext: message authentication failed"}
+		}
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// EncryptStream encrypts everything read from src as a streaming ciphertext
+// written to dst, using the given key version, or the latest one if zero, and
+// plaintext segments of segmentSize bytes, or DefaultStreamSegmentSize if
+// zero. The key version used is returned. Nothing is written to dst before
+// the first segment has been read from src.
+func (p *Policy) EncryptStream(ctx context.Context, ver int, context []byte, segmentSize int, dst io.Writer, src io.Reader, randReader io.Reader) (int, error) {
+	s, err := p.NewStreamEncrypter(ver, context, segmentSize, randReader)
+	if err != nil {
+		return 0, err
+	}
+	return s.version, s.Stream(ctx, dst, src)
+}
+
+// DecryptStream decrypts a streaming ciphertext read from src, writing the
+// plaintext to dst, and returns the key version it was encrypted with. Each
+// segment is authenticated before its plaintext is written, but a stream that
+// fails to decrypt may already have had part of its plaintext written.
+func (p *Policy) DecryptStream(ctx context.Context, context []byte, dst io.Writer, src io.Reader) (int, error) {
+	header, err := ReadStreamHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	s, err := p.NewStreamDecrypter(context, header)
+	if err != nil {
+		return 0, err
+	}
+	return s.version, s.Stream(ctx, dst, src)
+}
+
+// ReadStreamHeader reads the header of a streaming ciphertext from src.
+func ReadStreamHeader(src io.Reader) ([]byte, error) {
+	header := make([]byte, StreamHeaderLen)
+	if _, err := io.ReadFull(src, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errStreamInvalid
+		}
+		return nil, err
+	}
+	return header, nil
+}
+
+// streamAEAD returns the cipher sealing the segments of the stream with the
+// given header.
+func (p *Policy) streamAEAD(ver int, context, header []byte) (cipher.AEAD, error) {
+	numBytes := 32
+	if p.Type == KeyType_AES128_GCM96 {
+		numBytes = 16
+	}
+
+	key, err := p.GetKey(context, ver, numBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < numBytes {
+		return nil, errutil.InternalError{Err: "could not derive key, length too small"}
+	}
+
+	salt := header[12 : 12+streamSaltLen]
+	info := append(append([]byte{}, streamInfo...), header[:12]...)
+	streamKey := make([]byte, numBytes)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key[:numBytes], salt, info), streamKey); err != nil {
+		return nil, errutil.InternalError{Err: err.Error()}
+	}
+
+	switch p.Type {
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96:
+		block, err := aes.NewCipher(streamKey)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+		return aead, nil
+	case KeyType_ChaCha20_Poly1305:
+		aead, err := chacha20poly1305.New(streamKey)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+		return aead, nil
+	default:
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
+	}
+}
+
+// streamNonce returns the nonce of the segment with the given index.
+func streamNonce(header []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, streamNoncePrefixLen+5)
+	copy(nonce, header[StreamHeaderLen-streamNoncePrefixLen:])
+	binary.BigEndian.PutUint32(nonce[streamNoncePrefixLen:], index)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// readStreamSegment fills buf from in, returning the number of bytes read and
+// whether in has been exhausted.
+func readStreamSegment(in *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(in, buf)
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return n, true, nil
+	default:
+		return n, false, err
+	}
+
+	if _, err := in.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+	return n, false, nil
+}
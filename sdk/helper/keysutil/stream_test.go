@@ -0,0 +1,216 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPolicy_Stream(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+
+	for _, keyType := range []KeyType{KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305} {
+		t.Run(keyType.String(), func(t *testing.T) {
+			p := NewPolicy(PolicyConfig{
+				Name: "stream-" + keyType.String(),
+				Type: keyType,
+			})
+			if err := p.Rotate(ctx, storage, rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, size := range []int{0, 1, MinStreamSegmentSize - 1, MinStreamSegmentSize, MinStreamSegmentSize + 1, 3 * MinStreamSegmentSize} {
+				t.Run(fmt.Sprint(size), func(t *testing.T) {
+					plaintext := make([]byte, size)
+					if _, err := rand.Read(plaintext); err != nil {
+						t.Fatal(err)
+					}
+
+					var ciphertext bytes.Buffer
+					ver, err := p.EncryptStream(ctx, 0, nil, MinStreamSegmentSize, &ciphertext, bytes.NewReader(plaintext), rand.Reader)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if ver != 1 {
+						t.Fatalf("expected version 1, got %d", ver)
+					}
+
+					var decrypted bytes.Buffer
+					ver, err = p.DecryptStream(ctx, nil, &decrypted, bytes.NewReader(ciphertext.Bytes()))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if ver != 1 || !bytes.Equal(decrypted.Bytes(), plaintext) {
+						t.Fatalf("bad decryption with version %d", ver)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestPolicy_StreamTampering(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+
+	p := NewPolicy(PolicyConfig{
+		Name: "stream",
+		Type: KeyType_AES256_GCM96,
+	})
+	if err := p.Rotate(ctx, storage, rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := make([]byte, 3*MinStreamSegmentSize+100)
+	var buf bytes.Buffer
+	if _, err := p.EncryptStream(ctx, 0, nil, MinStreamSegmentSize, &buf, bytes.NewReader(plaintext), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := buf.Bytes()
+	segmentLen := MinStreamSegmentSize + 16
+	segment := func(i int) []byte {
+		start := StreamHeaderLen + i*segmentLen
+		end := start + segmentLen
+		if end > len(ciphertext) {
+			end = len(ciphertext)
+		}
+		return ciphertext[start:end]
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	flipped := append([]byte{}, ciphertext...)
+	flipped[StreamHeaderLen+segmentLen+1] ^= 1
+	badSegmentSize := append([]byte{}, ciphertext...)
+	badSegmentSize[11] ^= 1
+
+	tests := map[string][]byte{
+		"empty":              nil,
+		"header only":        ciphertext[:StreamHeaderLen],
+		"bit flip":           flipped,
+		"segment size":       badSegmentSize,
+		"truncated":          ciphertext[:len(ciphertext)-1],
+		"dropped segment":    join(ciphertext[:StreamHeaderLen], segment(0), segment(1), segment(2)),
+		"reordered segments": join(ciphertext[:StreamHeaderLen], segment(1), segment(0), segment(2), segment(3)),
+		"appended":           join(ciphertext, segment(3)),
+	}
+	for name, tampered := range tests {
+		if _, err := p.DecryptStream(ctx, nil, &bytes.Buffer{}, bytes.NewReader(tampered)); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPolicy_StreamVersions(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	lm, _ := NewLockManager(false, 0)
+
+	p, _, err := lm.GetPolicy(ctx, PolicyRequest{
+		Upsert:  true,
+		Storage: storage,
+		KeyType: KeyType_AES256_GCM96,
+		Name:    "derived",
+		Derived: true,
+	}, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Unlock()
+
+	plaintext := []byte("the quick brown fox")
+	var v1 bytes.Buffer
+	if _, err := p.EncryptStream(ctx, 0, []byte("backups"), 0, &v1, bytes.NewReader(plaintext), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Rotate(ctx, storage, rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	var decrypted bytes.Buffer
+	ver, err := p.DecryptStream(ctx, []byte("backups"), &decrypted, bytes.NewReader(v1.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ver != 1 || !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Fatalf("bad decryption with version %d", ver)
+	}
+
+	if _, err := p.DecryptStream(ctx, []byte("other"), &bytes.Buffer{}, bytes.NewReader(v1.Bytes())); err == nil {
+		t.Fatal("expected error for wrong context")
+	}
+
+	p.MinDecryptionVersion = 2
+	if _, err := p.DecryptStream(ctx, []byte("backups"), &bytes.Buffer{}, bytes.NewReader(v1.Bytes())); err == nil {
+		t.Fatal("expected error for version below min_decryption_version")
+	}
+
+	p.MinEncryptionVersion = 2
+	if _, err := p.EncryptStream(ctx, 1, []byte("backups"), 0, &bytes.Buffer{}, bytes.NewReader(plaintext), rand.Reader); err == nil {
+		t.Fatal("expected error for version below min_encryption_version")
+	}
+	if _, err := p.EncryptStream(ctx, 0, []byte("backups"), MinStreamSegmentSize-1, &bytes.Buffer{}, bytes.NewReader(plaintext), rand.Reader); err == nil {
+		t.Fatal("expected error for small segment size")
+	}
+}
+
+// cancelingReader cancels its context once it has been read from.
+type cancelingReader struct {
+	io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return r.Reader.Read(p)
+}
+
+func TestPolicy_StreamCanceled(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	p := NewPolicy(PolicyConfig{
+		Name: "stream",
+		Type: KeyType_AES256_GCM96,
+	})
+	if err := p.Rotate(context.Background(), storage, rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := make([]byte, 3*MinStreamSegmentSize)
+	var ciphertext bytes.Buffer
+	if _, err := p.EncryptStream(context.Background(), 0, nil, MinStreamSegmentSize, &ciphertext, bytes.NewReader(plaintext), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first segment is processed, but the stream stops before the next
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	_, err := p.EncryptStream(ctx, 0, nil, MinStreamSegmentSize, &out, &cancelingReader{Reader: bytes.NewReader(plaintext), cancel: cancel}, rand.Reader)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the stream to be canceled, got %v", err)
+	}
+	if out.Len() != StreamHeaderLen+MinStreamSegmentSize+16 {
+		t.Fatalf("expected a single segment, got %d bytes", out.Len())
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	out.Reset()
+	_, err = p.DecryptStream(ctx, nil, &out, &cancelingReader{Reader: bytes.NewReader(ciphertext.Bytes()), cancel: cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the stream to be canceled, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected no plaintext, got %d bytes", out.Len())
+	}
+}
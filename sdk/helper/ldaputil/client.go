@@ -27,74 +27,24 @@ import (
 type Client struct {
 	Logger hclog.Logger
 	LDAP   LDAP
+
+	// Pool optionally tracks the health of the servers, so that DialLDAP
+	// tries the servers which recently failed last.
+	Pool *ServerPool
 }
 
 func (c *Client) DialLDAP(cfg *ConfigEntry) (Connection, error) {
 	var retErr *multierror.Error
 	var conn Connection
 	urls := strings.Split(cfg.Url, ",")
+	if c.Pool != nil {
+		urls = c.Pool.Order(urls)
+	}
 
 	for _, uut := range urls {
-		u, err := url.Parse(uut)
-		if err != nil {
-			retErr = multierror.Append(retErr, fmt.Errorf(fmt.Sprintf("error parsing url %q: {{err}}", uut), err))
-			continue
-		}
-		host, port, err := net.SplitHostPort(u.Host)
-		if err != nil {
-			host = u.Host
-		}
-
-		var tlsConfig *tls.Config
-		dialer := net.Dialer{
-			Timeout: time.Duration(cfg.ConnectionTimeout) * time.Second,
-		}
-
-		switch u.Scheme {
-		case "ldap":
-			if port == "" {
-				port = "389"
-			}
-
-			fullAddr := fmt.Sprintf("%s://%s", u.Scheme, net.JoinHostPort(host, port))
-			opt := ldap.DialWithDialer(&dialer)
-
-			conn, err = c.LDAP.DialURL(fullAddr, opt)
-			if err != nil {
-				break
-			}
-			if conn == nil {
-				err = fmt.Errorf("empty connection after dialing")
-				break
-			}
-			if cfg.StartTLS {
-				tlsConfig, err = getTLSConfig(cfg, host)
-				if err != nil {
-					break
-				}
-				err = conn.StartTLS(tlsConfig)
-			}
-		case "ldaps":
-			if port == "" {
-				port = "636"
-			}
-			tlsConfig, err = getTLSConfig(cfg, host)
-			if err != nil {
-				break
-			}
-
-			fullAddr := fmt.Sprintf("%s://%s", u.Scheme, net.JoinHostPort(host, port))
-			opt := ldap.DialWithDialer(&dialer)
-			tls := ldap.DialWithTLSConfig(tlsConfig)
-
-			conn, err = c.LDAP.DialURL(fullAddr, opt, tls)
-			if err != nil {
-				break
-			}
-		default:
-			retErr = multierror.Append(retErr, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port)))
-			continue
-		}
+		start := time.Now()
+		var err error
+		conn, err = c.dialURL(cfg, uut)
 		if err == nil {
 			if retErr != nil {
 				if c.Logger.IsDebug() {
@@ -102,9 +52,15 @@ func (c *Client) DialLDAP(cfg *ConfigEntry) (Connection, error) {
 				}
 			}
 			retErr = nil
+			if c.Pool != nil {
+				c.Pool.RecordSuccess(uut, start)
+			}
 			break
 		}
-		retErr = multierror.Append(retErr, fmt.Errorf(fmt.Sprintf("error connecting to host %q: {{err}}", uut), err))
+		if c.Pool != nil {
+			c.Pool.RecordFailure(uut, err)
+		}
+		retErr = multierror.Append(retErr, err)
 	}
 	if retErr != nil {
 		return nil, retErr
@@ -115,6 +71,74 @@ func (c *Client) DialLDAP(cfg *ConfigEntry) (Connection, error) {
 	return conn, nil
 }
 
+// dialURL connects to the LDAP server at uut.
+func (c *Client) dialURL(cfg *ConfigEntry, uut string) (Connection, error) {
+	var conn Connection
+	u, err := url.Parse(uut)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url %q: %w", uut, err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+	}
+
+	var tlsConfig *tls.Config
+	dialer := net.Dialer{
+		Timeout: time.Duration(cfg.ConnectionTimeout) * time.Second,
+	}
+
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+
+		fullAddr := fmt.Sprintf("%s://%s", u.Scheme, net.JoinHostPort(host, port))
+		opt := ldap.DialWithDialer(&dialer)
+
+		conn, err = c.LDAP.DialURL(fullAddr, opt)
+		if err != nil {
+			break
+		}
+		if conn == nil {
+			err = fmt.Errorf("empty connection after dialing")
+			break
+		}
+		if cfg.StartTLS {
+			tlsConfig, err = getTLSConfig(cfg, host)
+			if err != nil {
+				break
+			}
+			err = conn.StartTLS(tlsConfig)
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+		tlsConfig, err = getTLSConfig(cfg, host)
+		if err != nil {
+			break
+		}
+
+		fullAddr := fmt.Sprintf("%s://%s", u.Scheme, net.JoinHostPort(host, port))
+		opt := ldap.DialWithDialer(&dialer)
+		tls := ldap.DialWithTLSConfig(tlsConfig)
+
+		conn, err = c.LDAP.DialURL(fullAddr, opt, tls)
+		if err != nil {
+			break
+		}
+	default:
+		return nil, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to host %q: %w", uut, err)
+	}
+
+	return conn, nil
+}
+
 /*
  * Searches for a username in the ldap server, returning a minimal subset of the
  * user's attributes (if found)
@@ -19,6 +19,10 @@ import (
 	"github.com/hashicorp/vault/sdk/framework"
 )
 
+// DefaultServerReprobeInterval is the server_reprobe_interval, in seconds, of
+// new configurations and of configurations stored before it was introduced.
+const DefaultServerReprobeInterval = 30
+
 var ldapDerefAliasMap = map[string]int{
 	"never":     ldap.NeverDerefAliases,
 	"finding":   ldap.DerefFindingBaseObj,
@@ -256,6 +260,12 @@ Default: ({{.UserAttr}}={{.Username}})`,
 			Description: "If set to a value greater than 0, the LDAP backend will use the LDAP server's paged search control to request pages of up to the given size. This can be used to avoid hitting the LDAP server's maximum result size limit. Otherwise, the LDAP backend will not use the paged search control.",
 			Default:     0,
 		},
+
+		"server_reprobe_interval": {
+			Type:        framework.TypeDurationSecond,
+			Description: "Duration for which a server that failed is only tried after the other servers of the URL list, before it is probed again. Set to 0 to always try the servers in order.",
+			Default:     DefaultServerReprobeInterval,
+		},
 	}
 }
 
@@ -434,6 +444,10 @@ func NewConfigEntry(existing *ConfigEntry, d *framework.FieldData) (*ConfigEntry
 		cfg.MaximumPageSize = d.Get("max_page_size").(int)
 	}
 
+	if _, ok := d.Raw["server_reprobe_interval"]; ok || !hadExisting {
+		cfg.ServerReprobeInterval = d.Get("server_reprobe_interval").(int)
+	}
+
 	return cfg, nil
 }
 
@@ -463,6 +477,7 @@ type ConfigEntry struct {
 	ConnectionTimeout        int    `json:"connection_timeout"` // deprecated: use RequestTimeout
 	DerefAliases             string `json:"dereference_aliases"`
 	MaximumPageSize          int    `json:"max_page_size"`
+	ServerReprobeInterval    int    `json:"server_reprobe_interval"`
 
 	// These json tags deviate from snake case because there was a past issue
 	// where the tag was being ignored, causing it to be jsonified as "CaseSensitiveNames", etc.
@@ -481,29 +496,30 @@ func (c *ConfigEntry) Map() map[string]interface{} {
 
 func (c *ConfigEntry) PasswordlessMap() map[string]interface{} {
 	m := map[string]interface{}{
-		"url":                    c.Url,
-		"userdn":                 c.UserDN,
-		"groupdn":                c.GroupDN,
-		"groupfilter":            c.GroupFilter,
-		"groupattr":              c.GroupAttr,
-		"userfilter":             c.UserFilter,
-		"upndomain":              c.UPNDomain,
-		"userattr":               c.UserAttr,
-		"certificate":            c.Certificate,
-		"insecure_tls":           c.InsecureTLS,
-		"starttls":               c.StartTLS,
-		"binddn":                 c.BindDN,
-		"deny_null_bind":         c.DenyNullBind,
-		"discoverdn":             c.DiscoverDN,
-		"tls_min_version":        c.TLSMinVersion,
-		"tls_max_version":        c.TLSMaxVersion,
-		"use_token_groups":       c.UseTokenGroups,
-		"anonymous_group_search": c.AnonymousGroupSearch,
-		"request_timeout":        c.RequestTimeout,
-		"connection_timeout":     c.ConnectionTimeout,
-		"username_as_alias":      c.UsernameAsAlias,
-		"dereference_aliases":    c.DerefAliases,
-		"max_page_size":          c.MaximumPageSize,
+		"url":                     c.Url,
+		"userdn":                  c.UserDN,
+		"groupdn":                 c.GroupDN,
+		"groupfilter":             c.GroupFilter,
+		"groupattr":               c.GroupAttr,
+		"userfilter":              c.UserFilter,
+		"upndomain":               c.UPNDomain,
+		"userattr":                c.UserAttr,
+		"certificate":             c.Certificate,
+		"insecure_tls":            c.InsecureTLS,
+		"starttls":                c.StartTLS,
+		"binddn":                  c.BindDN,
+		"deny_null_bind":          c.DenyNullBind,
+		"discoverdn":              c.DiscoverDN,
+		"tls_min_version":         c.TLSMinVersion,
+		"tls_max_version":         c.TLSMaxVersion,
+		"use_token_groups":        c.UseTokenGroups,
+		"anonymous_group_search":  c.AnonymousGroupSearch,
+		"request_timeout":         c.RequestTimeout,
+		"connection_timeout":      c.ConnectionTimeout,
+		"username_as_alias":       c.UsernameAsAlias,
+		"dereference_aliases":     c.DerefAliases,
+		"max_page_size":           c.MaximumPageSize,
+		"server_reprobe_interval": c.ServerReprobeInterval,
 	}
 	if c.CaseSensitiveNames != nil {
 		m["case_sensitive_names"] = *c.CaseSensitiveNames
@@ -176,6 +176,7 @@ var jsonConfigDefault = []byte(`
   "connection_timeout": 30,
   "dereference_aliases": "never",
   "max_page_size": 0,
+  "server_reprobe_interval": 30,
   "CaseSensitiveNames": false,
   "ClientTLSCert": "",
   "ClientTLSKey": ""
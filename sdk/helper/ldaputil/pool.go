@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package ldaputil
+
+import (
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// ServerPool tracks the health of the LDAP servers of a configuration, so that
+// servers which recently failed are only tried once the healthy ones failed
+// too, until they are probed again.
+//
+// A ServerPool is safe for concurrent use, and should be discarded when the
+// configuration of the servers changes.
+type ServerPool struct {
+	logger hclog.Logger
+
+	// reprobeInterval is how long servers are skipped after failing. Zero
+	// disables health tracking.
+	reprobeInterval time.Duration
+
+	l       sync.Mutex
+	servers map[string]*serverHealth
+}
+
+type serverHealth struct {
+	// failedAt is the time of the last failure of the server, or of the last
+	// time it was handed out to be probed again.
+	failedAt time.Time
+
+	// failures is the number of consecutive failures of the server.
+	failures int
+}
+
+// NewServerPool returns a ServerPool skipping servers for reprobeInterval
+// after they fail.
+func NewServerPool(logger hclog.Logger, reprobeInterval time.Duration) *ServerPool {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	return &ServerPool{
+		logger:          logger,
+		reprobeInterval: reprobeInterval,
+		servers:         make(map[string]*serverHealth),
+	}
+}
+
+// Order returns the urls with the servers which are not known to be failing
+// first, in their original order, followed by the servers which failed within
+// the re-probe interval, the oldest failure first. Once the re-probe interval
+// of a failed server elapsed, it is tried in its original position again by a
+// single caller, until that caller records whether it is healthy.
+func (p *ServerPool) Order(urls []string) []string {
+	if p.reprobeInterval <= 0 {
+		return urls
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	now := time.Now()
+	ordered := make([]string, 0, len(urls))
+	var failing []string
+	for _, url := range urls {
+		health, ok := p.servers[url]
+		switch {
+		case !ok || health.failures == 0:
+			ordered = append(ordered, url)
+		case now.Sub(health.failedAt) >= p.reprobeInterval:
+			// Other callers keep skipping the server while it is probed
+			health.failedAt = now
+			ordered = append(ordered, url)
+		default:
+			failing = append(failing, url)
+		}
+	}
+
+	// Insertion sort, since there are only a few servers
+	for i := 1; i < len(failing); i++ {
+		for j := i; j > 0 && p.servers[failing[j]].failedAt.Before(p.servers[failing[j-1]].failedAt); j-- {
+			failing[j], failing[j-1] = failing[j-1], failing[j]
+		}
+	}
+
+	return append(ordered, failing...)
+}
+
+// RecordSuccess marks the server as healthy, and records the latency of the
+// operation started at start.
+func (p *ServerPool) RecordSuccess(url string, start time.Time) {
+	metrics.MeasureSinceWithLabels([]string{"ldap", "server", "latency"}, start, []metrics.Label{{Name: "server", Value: url}})
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if health, ok := p.servers[url]; ok && health.failures > 0 {
+		p.logger.Info("ldap server is healthy again", "server", url)
+		delete(p.servers, url)
+	}
+}
+
+// RecordFailure marks the server as failing, so that it is skipped until the
+// re-probe interval elapses.
+func (p *ServerPool) RecordFailure(url string, err error) {
+	metrics.IncrCounterWithLabels([]string{"ldap", "server", "failure"}, 1, []metrics.Label{{Name: "server", Value: url}})
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	health, ok := p.servers[url]
+	if !ok {
+		health = &serverHealth{}
+		p.servers[url] = health
+	}
+	health.failedAt = time.Now()
+	health.failures++
+
+	if p.reprobeInterval > 0 {
+		p.logger.Warn("ldap server failed, skipping it until it is probed again", "server", url, "failures", health.failures, "reprobe_interval", p.reprobeInterval, "error", err)
+	}
+}
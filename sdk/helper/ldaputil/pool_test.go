@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package ldaputil
+
+import (
+	"crypto/tls"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLDAP dials fake connections, failing for the addresses in down.
+type fakeLDAP struct {
+	l     sync.Mutex
+	down  map[string]bool
+	dials map[string]int
+}
+
+func (f *fakeLDAP) DialURL(addr string, _ ...ldap.DialOpt) (Connection, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	f.dials[addr]++
+	if f.down[addr] {
+		return nil, ldap.NewError(ldap.ErrorNetwork, errors.New("connection refused"))
+	}
+	return &fakeConnection{}, nil
+}
+
+func (f *fakeLDAP) dialCount(addr string) int {
+	f.l.Lock()
+	defer f.l.Unlock()
+	return f.dials[addr]
+}
+
+type fakeConnection struct{}
+
+func (c *fakeConnection) Bind(string, string) error        { return nil }
+func (c *fakeConnection) UnauthenticatedBind(string) error { return nil }
+func (c *fakeConnection) Close() error                     { return nil }
+func (c *fakeConnection) Add(*ldap.AddRequest) error       { return nil }
+func (c *fakeConnection) Modify(*ldap.ModifyRequest) error { return nil }
+func (c *fakeConnection) Del(*ldap.DelRequest) error       { return nil }
+func (c *fakeConnection) StartTLS(*tls.Config) error       { return nil }
+func (c *fakeConnection) SetTimeout(time.Duration)         {}
+
+func (c *fakeConnection) Search(*ldap.SearchRequest) (*ldap.SearchResult, error) {
+	return &ldap.SearchResult{}, nil
+}
+
+func TestServerPool_Order(t *testing.T) {
+	pool := NewServerPool(hclog.NewNullLogger(), time.Hour)
+	urls := []string{"ldap://a", "ldap://b", "ldap://c"}
+
+	require.Equal(t, urls, pool.Order(urls))
+
+	pool.RecordFailure("ldap://b", errors.New("down"))
+	pool.RecordFailure("ldap://a", errors.New("down"))
+	require.Equal(t, []string{"ldap://c", "ldap://b", "ldap://a"}, pool.Order(urls))
+
+	// Once the re-probe interval elapsed, a single caller tries the server in
+	// its original position again
+	pool.servers["ldap://a"].failedAt = time.Now().Add(-2 * time.Hour)
+	require.Equal(t, []string{"ldap://a", "ldap://c", "ldap://b"}, pool.Order(urls))
+	require.Equal(t, []string{"ldap://c", "ldap://b", "ldap://a"}, pool.Order(urls))
+
+	pool.RecordSuccess("ldap://a", time.Now())
+	require.Equal(t, []string{"ldap://a", "ldap://c", "ldap://b"}, pool.Order(urls))
+
+	// Without a re-probe interval the order is unchanged
+	pool = NewServerPool(hclog.NewNullLogger(), 0)
+	pool.RecordFailure("ldap://a", errors.New("down"))
+	require.Equal(t, urls, pool.Order(urls))
+}
+
+func TestDialLDAP_ServerPool(t *testing.T) {
+	fake := &fakeLDAP{
+		down:  map[string]bool{"ldap://down:389": true},
+		dials: map[string]int{},
+	}
+	client := Client{
+		Logger: hclog.NewNullLogger(),
+		LDAP:   fake,
+		Pool:   NewServerPool(hclog.NewNullLogger(), time.Hour),
+	}
+	cfg := &ConfigEntry{
+		Url: "ldap://down,ldap://up",
+	}
+
+	conn, err := client.DialLDAP(cfg)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.Equal(t, 1, fake.dialCount("ldap://down:389"))
+	require.Equal(t, 1, fake.dialCount("ldap://up:389"))
+
+	// The failed server is skipped until it is probed again
+	conn, err = client.DialLDAP(cfg)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.Equal(t, 1, fake.dialCount("ldap://down:389"))
+	require.Equal(t, 2, fake.dialCount("ldap://up:389"))
+}
@@ -164,6 +164,13 @@ type Paths struct {
 	// be JSON encoded, and for which the backend will decode values for auditing
 	Binary []string
 
+	// Streaming paths are binary paths whose parameters are given in the
+	// query string and whose backend writes the response directly to the
+	// request's ResponseWriter, so it can be streamed. Requests to them are
+	// not bound by max_request_duration. Only builtin backends can stream
+	// responses, as the ResponseWriter is not passed to plugins.
+	Streaming []string
+
 	// Limited paths are storage paths that require special-cased request
 	// limiting.
 	//
@@ -283,6 +283,11 @@ func (r *Request) Clone() (*Request, error) {
 	// This needs to be overwritten as the internal connection state is not cloned properly
 	// mainly the big.Int serial numbers within the x509.Certificate objects get mangled.
 	req.Connection = r.Connection
+	// The HTTP request and response writer wrap live connection state, such
+	// as a request body which may not have been consumed yet, so they are
+	// shared rather than copied.
+	req.HTTPRequest = r.HTTPRequest
+	req.ResponseWriter = r.ResponseWriter
 
 	return req, nil
 }
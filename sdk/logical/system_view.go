@@ -107,6 +107,14 @@ type PasswordPolicy interface {
 	Generate(context.Context, io.Reader) (string, error)
 }
 
+// PasswordPolicyValidatorSystemView is implemented by system views able to
+// check user-provided passwords against a password policy.
+type PasswordPolicyValidatorSystemView interface {
+	// ValidatePasswordFromPolicy returns an error describing why the password
+	// does not satisfy the named password policy, if it does not.
+	ValidatePasswordFromPolicy(ctx context.Context, policyName, password string) error
+}
+
 type WellKnownSystemView interface {
 	// RequestWellKnownRedirect registers a redirect from .well-known/src
 	// to dest, where dest is a sub-path of the mount. An error
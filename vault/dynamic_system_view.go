@@ -449,6 +449,30 @@ func (d dynamicSystemView) GeneratePasswordFromPolicy(ctx context.Context, polic
 	return passPolicy.Generate(ctx, nil)
 }
 
+func (d dynamicSystemView) ValidatePasswordFromPolicy(ctx context.Context, policyName, password string) error {
+	if policyName == "" {
+		return fmt.Errorf("missing password policy name")
+	}
+
+	ctx = namespace.ContextWithNamespace(ctx, d.mountEntry.Namespace())
+
+	policyCfg, err := d.retrievePasswordPolicy(ctx, policyName)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve password policy: %w", err)
+	}
+
+	if policyCfg == nil {
+		return fmt.Errorf("no password policy found")
+	}
+
+	passPolicy, err := random.ParsePolicy(policyCfg.HCLPolicy)
+	if err != nil {
+		return fmt.Errorf("stored password policy is invalid: %w", err)
+	}
+
+	return passPolicy.Validate(password)
+}
+
 func (d dynamicSystemView) ClusterID(ctx context.Context) (string, error) {
 	clusterInfo, err := d.core.Cluster(ctx)
 	if err != nil || clusterInfo.ID == "" {
@@ -6,6 +6,7 @@ package vault
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
@@ -286,6 +287,98 @@ func TestDynamicSystemView_GeneratePasswordFromPolicy_failed(t *testing.T) {
 	}
 }
 
+func TestDynamicSystemView_ValidatePasswordFromPolicy(t *testing.T) {
+	rawPolicy := `
+length = 20
+rule "charset" {
+	charset = "abcdefghijklmnopqrstuvwxyz"
+	min-chars = 1
+}
+rule "charset" {
+	charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	min-chars = 1
+}
+rule "charset" {
+	charset = "0123456789"
+	min-chars = 1
+}`
+	policyJSON, err := json.Marshal(&passwordPolicyConfig{HCLPolicy: rawPolicy})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	type testCase struct {
+		policyName string
+		password   string
+		getEntry   *logical.StorageEntry
+		getErr     error
+		expectErr  bool
+	}
+
+	policyEntry := &logical.StorageEntry{
+		Key:   getPasswordPolicyKey("testpolicy"),
+		Value: policyJSON,
+	}
+
+	tests := map[string]testCase{
+		"valid password": {
+			policyName: "testpolicy",
+			password:   "abcdefghijKLMNOPQR12",
+			getEntry:   policyEntry,
+		},
+		"password too short": {
+			policyName: "testpolicy",
+			password:   "abcKLM12",
+			getEntry:   policyEntry,
+			expectErr:  true,
+		},
+		"password missing a charset": {
+			policyName: "testpolicy",
+			password:   "abcdefghijklmnopqrst12",
+			getEntry:   policyEntry,
+			expectErr:  true,
+		},
+		"no policy name": {
+			policyName: "",
+			password:   "abcdefghijKLMNOPQR12",
+			expectErr:  true,
+		},
+		"no policy found": {
+			policyName: "testpolicy",
+			password:   "abcdefghijKLMNOPQR12",
+			expectErr:  true,
+		},
+		"error retrieving policy": {
+			policyName: "testpolicy",
+			password:   "abcdefghijKLMNOPQR12",
+			getErr:     fmt.Errorf("a test error"),
+			expectErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			testStorage := fakeBarrier{
+				getEntry: test.getEntry,
+				getErr:   test.getErr,
+			}
+
+			core := &Core{
+				systemBarrierView: NewBarrierView(testStorage, "sys/"),
+			}
+			dsv := TestDynamicSystemView(core, nil).(logical.PasswordPolicyValidatorSystemView)
+
+			err := dsv.ValidatePasswordFromPolicy(context.Background(), test.policyName, test.password)
+			if test.expectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+		})
+	}
+}
+
 type runes []rune
 
 func (r runes) Len() int           { return len(r) }
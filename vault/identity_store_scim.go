@@ -0,0 +1,1534 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/pointerutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// Storage path constants
+	scimClientPath = "scim/client/"
+
+	// SCIM schema URNs, see RFC 7643 and RFC 7644
+	scimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	// scimContentType is the media type of every SCIM response body
+	scimContentType = "application/scim+json"
+
+	// Metadata keys used to track SCIM provisioned objects. The external ID
+	// is kept in the alias custom metadata for users and in the group
+	// metadata for groups. The client key marks the aliases and groups owned
+	// by a SCIM client, so that clients only ever see or modify what they
+	// provisioned, and the entities created for its users.
+	scimMetaExternalID = "scim_external_id"
+	scimMetaClient     = "scim_client"
+
+	// Error types from RFC 7644 section 3.12
+	scimErrInvalidFilter = "invalidFilter"
+	scimErrUniqueness    = "uniqueness"
+	scimErrInvalidValue  = "invalidValue"
+	scimErrInvalidPath   = "invalidPath"
+	scimErrNoTarget      = "noTarget"
+
+	scimDefaultCount = 100
+)
+
+// scimFilterRegex matches the single attribute equality filters sent by
+// identity providers when looking up an object before provisioning it,
+// e.g. `userName eq "alice"`.
+var scimFilterRegex = regexp.MustCompile(`^\s*(\w+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimMemberPathRegex matches a PATCH path selecting a single group member,
+// e.g. `members[value eq "<entity id>"]`.
+var scimMemberPathRegex = regexp.MustCompile(`^members\[\s*value\s+eq\s+"([^"]+)"\s*\]$`)
+
+// scimClient is the configuration of an identity provider allowed to push
+// users and groups through the SCIM endpoints.
+type scimClient struct {
+	// MountAccessor is the auth mount that provisioned users receive an
+	// entity alias on. Their userName becomes the alias name, so that logins
+	// through the mount resolve to the provisioned entity.
+	MountAccessor string `json:"mount_accessor"`
+
+	// name is the name the client is stored under
+	name string
+}
+
+func scimPaths(i *IdentityStore) []*framework.Path {
+	clientField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Name of the SCIM client.",
+	}
+	resourceFields := func() map[string]*framework.FieldSchema {
+		return map[string]*framework.FieldSchema{
+			"client": clientField,
+			"schemas": {
+				Type:        framework.TypeStringSlice,
+				Description: "SCIM schema URNs of the request body.",
+			},
+			"externalId": {
+				Type:        framework.TypeString,
+				Description: "Identifier of the resource in the identity provider.",
+			},
+			"userName": {
+				Type:        framework.TypeString,
+				Description: "User name; used as the entity alias name on the client's mount.",
+			},
+			"active": {
+				Type:        framework.TypeBool,
+				Description: "Whether the user is active. Inactive users have their entity disabled.",
+				Default:     true,
+			},
+			"displayName": {
+				Type:        framework.TypeString,
+				Description: "Display name of the group; used as the identity group name.",
+			},
+			"members": {
+				Type:        framework.TypeSlice,
+				Description: "Group members, as objects whose 'value' is a user ID.",
+			},
+			"Operations": {
+				Type:        framework.TypeSlice,
+				Description: "PATCH operations to apply to the resource.",
+			},
+			"filter": {
+				Type:        framework.TypeString,
+				Description: `Filter expression. Only equality on a single attribute is supported, e.g. 'userName eq "alice"'.`,
+			},
+			"startIndex": {
+				Type:        framework.TypeInt,
+				Description: "1-based index of the first result to return.",
+				Default:     1,
+			},
+			"count": {
+				Type:        framework.TypeInt,
+				Description: "Maximum number of results to return.",
+				Default:     scimDefaultCount,
+			},
+		}
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "scim/client/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "scim",
+				OperationSuffix: "client",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the SCIM client.",
+				},
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the auth mount that provisioned users are given an alias on.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: i.pathSCIMClientCreateUpdate,
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: i.pathSCIMClientCreateUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: i.pathSCIMClientRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: i.pathSCIMClientDelete,
+				},
+			},
+			ExistenceCheck:  i.pathSCIMClientExistenceCheck,
+			HelpSynopsis:    strings.TrimSpace(scimHelp["client"][0]),
+			HelpDescription: strings.TrimSpace(scimHelp["client"][1]),
+		},
+		{
+			Pattern: "scim/client/?$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "scim",
+				OperationSuffix: "clients",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: i.pathSCIMClientList,
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(scimHelp["client-list"][0]),
+			HelpDescription: strings.TrimSpace(scimHelp["client-list"][1]),
+		},
+		{
+			Pattern: "scim/" + framework.GenericNameRegex("client") + "/v2/ServiceProviderConfig$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "scim",
+				OperationSuffix: "service-provider-config",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"client": clientField,
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: i.pathSCIMServiceProviderConfig,
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(scimHelp["service-provider-config"][0]),
+			HelpDescription: strings.TrimSpace(scimHelp["service-provider-config"][1]),
+		},
+		{
+			Pattern: "scim/" + framework.GenericNameRegex("client") + "/v2/Users$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "scim",
+				OperationSuffix: "users",
+			},
+			Fields: resourceFields(),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: i.pathSCIMUsersSearch,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMUserCreate,
+					ForwardPerformanceStandby: true,
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(scimHelp["users"][0]),
+			HelpDescription: strings.TrimSpace(scimHelp["users"][1]),
+		},
+		{
+			Pattern: "scim/" + framework.GenericNameRegex("client") + "/v2/Users/" + framework.GenericNameRegex("id"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "scim",
+				OperationSuffix: "user",
+			},
+			Fields: withSCIMIDField(resourceFields(), "ID of the user, which is the ID of its entity."),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: i.pathSCIMUserRead,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMUserReplace,
+					ForwardPerformanceStandby: true,
+				},
+				logical.PatchOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMUserPatch,
+					ForwardPerformanceStandby: true,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMUserDelete,
+					ForwardPerformanceStandby: true,
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(scimHelp["user"][0]),
+			HelpDescription: strings.TrimSpace(scimHelp["user"][1]),
+		},
+		{
+			Pattern: "scim/" + framework.GenericNameRegex("client") + "/v2/Groups$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "scim",
+				OperationSuffix: "groups",
+			},
+			Fields: resourceFields(),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: i.pathSCIMGroupsSearch,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMGroupCreate,
+					ForwardPerformanceStandby: true,
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(scimHelp["groups"][0]),
+			HelpDescription: strings.TrimSpace(scimHelp["groups"][1]),
+		},
+		{
+			Pattern: "scim/" + framework.GenericNameRegex("client") + "/v2/Groups/" + framework.GenericNameRegex("id"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "scim",
+				OperationSuffix: "group",
+			},
+			Fields: withSCIMIDField(resourceFields(), "ID of the group, which is the ID of its identity group."),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: i.pathSCIMGroupRead,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMGroupReplace,
+					ForwardPerformanceStandby: true,
+				},
+				logical.PatchOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMGroupPatch,
+					ForwardPerformanceStandby: true,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback:                  i.pathSCIMGroupDelete,
+					ForwardPerformanceStandby: true,
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(scimHelp["group"][0]),
+			HelpDescription: strings.TrimSpace(scimHelp["group"][1]),
+		},
+	}
+}
+
+func withSCIMIDField(fields map[string]*framework.FieldSchema, description string) map[string]*framework.FieldSchema {
+	fields["id"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: description,
+	}
+	return fields
+}
+
+// pathSCIMClientCreateUpdate is used to create a new SCIM client or update an existing one
+func (i *IdentityStore) pathSCIMClientCreateUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := d.Get("name").(string)
+
+	var client scimClient
+	if req.Operation == logical.UpdateOperation {
+		existing, err := i.getSCIMClient(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			client = *existing
+		}
+	}
+
+	if mountAccessorRaw, ok := d.GetOk("mount_accessor"); ok {
+		client.MountAccessor = mountAccessorRaw.(string)
+	}
+	if client.MountAccessor == "" {
+		return logical.ErrorResponse("missing mount_accessor"), nil
+	}
+
+	mountEntry := i.router.MatchingMountByAccessor(client.MountAccessor)
+	if mountEntry == nil || mountEntry.Table != credentialTableType {
+		return logical.ErrorResponse("invalid auth mount accessor %q", client.MountAccessor), nil
+	}
+	if mountEntry.NamespaceID != ns.ID {
+		return logical.ErrorResponse("matching mount is in a different namespace than request"), logical.ErrPermissionDenied
+	}
+	if mountEntry.Local {
+		return logical.ErrorResponse("SCIM clients cannot provision users on local auth mounts"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(scimClientPath+name, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// pathSCIMClientRead is used to read an existing SCIM client
+func (i *IdentityStore) pathSCIMClientRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, err := i.getSCIMClient(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"mount_accessor": client.MountAccessor,
+		},
+	}, nil
+}
+
+// pathSCIMClientDelete is used to delete a SCIM client. Users and groups it
+// provisioned are left in place.
+func (i *IdentityStore) pathSCIMClientDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, scimClientPath+d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// pathSCIMClientList is used to list SCIM clients
+func (i *IdentityStore) pathSCIMClientList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	clients, err := req.Storage.List(ctx, scimClientPath)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(clients), nil
+}
+
+func (i *IdentityStore) pathSCIMClientExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	entry, err := req.Storage.Get(ctx, scimClientPath+d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+func (i *IdentityStore) getSCIMClient(ctx context.Context, s logical.Storage, name string) (*scimClient, error) {
+	entry, err := s.Get(ctx, scimClientPath+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var client scimClient
+	if err := entry.DecodeJSON(&client); err != nil {
+		return nil, err
+	}
+	client.name = name
+
+	return &client, nil
+}
+
+// scimClientFromRequest loads the client named in the request path. A nil
+// client is returned along with a SCIM error response if it does not exist.
+func (i *IdentityStore) scimClientFromRequest(ctx context.Context, req *logical.Request, d *framework.FieldData) (*scimClient, *logical.Response, error) {
+	client, err := i.getSCIMClient(ctx, req.Storage, d.Get("client").(string))
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil {
+		resp, err := scimErrorResponse(http.StatusNotFound, "", "unknown SCIM client")
+		return nil, resp, err
+	}
+	return client, nil, nil
+}
+
+func (i *IdentityStore) pathSCIMServiceProviderConfig(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if _, errResp, err := i.scimClientFromRequest(ctx, req, d); errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	return scimResponse(http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimSchemaServiceProviderConfig},
+		"patch":          map[string]interface{}{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimDefaultCount},
+		"changePassword": map[string]interface{}{"supported": false},
+		"sort":           map[string]interface{}{"supported": false},
+		"etag":           map[string]interface{}{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "Vault token",
+				"description": "A Vault token sent as an OAuth 2.0 bearer token.",
+				"primary":     true,
+			},
+		},
+	})
+}
+
+func (i *IdentityStore) pathSCIMUsersSearch(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	attr, value, err := parseSCIMFilter(d.Get("filter").(string))
+	if err != nil {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidFilter, err.Error())
+	}
+	switch attr {
+	case "", "userName", "externalId", "id":
+	default:
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidFilter, fmt.Sprintf("filtering on %q is not supported", attr))
+	}
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	aliases, err := i.scimClientAliases(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []map[string]interface{}
+	for _, alias := range aliases {
+		switch {
+		case attr == "userName" && !strings.EqualFold(alias.Name, value):
+			continue
+		case attr == "externalId" && alias.CustomMetadata[scimMetaExternalID] != value:
+			continue
+		case attr == "id" && alias.CanonicalID != value:
+			continue
+		}
+
+		entity, err := i.MemDBEntityByID(alias.CanonicalID, false)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			continue
+		}
+		user, err := i.scimUserFromEntity(ctx, entity, alias)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return scimListResponse(users, d)
+}
+
+func (i *IdentityStore) pathSCIMUserCreate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	userName := d.Get("userName").(string)
+	if userName == "" {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "missing userName")
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	existing, err := i.MemDBAliasByFactors(client.MountAccessor, userName, false, false)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// An alias created on the mount by a login is adopted by the
+		// client, so that users who logged in before being provisioned can
+		// be managed through SCIM.
+		if existing.CustomMetadata[scimMetaClient] != "" {
+			return scimErrorResponse(http.StatusConflict, scimErrUniqueness, "a user with this userName already exists")
+		}
+		return i.scimAdoptUser(ctx, client, existing, d)
+	}
+
+	entity := new(identity.Entity)
+
+	// Name the entity after the user where possible, falling back to a
+	// generated name if an unrelated entity already holds it.
+	entityByName, err := i.MemDBEntityByName(ctx, userName, false)
+	if err != nil {
+		return nil, err
+	}
+	if entityByName == nil {
+		entity.Name = userName
+	}
+	entity.Disabled = !d.Get("active").(bool)
+	entity.Metadata = map[string]string{
+		scimMetaClient: client.name,
+	}
+
+	if err := i.sanitizeEntity(ctx, entity); err != nil {
+		return nil, err
+	}
+
+	alias := &identity.Alias{
+		MountAccessor: client.MountAccessor,
+		Name:          userName,
+		CanonicalID:   entity.ID,
+		CustomMetadata: map[string]string{
+			scimMetaClient: client.name,
+		},
+	}
+	if externalID := d.Get("externalId").(string); externalID != "" {
+		alias.CustomMetadata[scimMetaExternalID] = externalID
+	}
+	if err := i.sanitizeAlias(ctx, alias); err != nil {
+		return nil, err
+	}
+	entity.UpsertAlias(alias)
+
+	if err := i.upsertEntity(ctx, entity, nil, true); err != nil {
+		return nil, err
+	}
+
+	user, err := i.scimUserFromEntity(ctx, entity, alias)
+	if err != nil {
+		return nil, err
+	}
+	return scimResponse(http.StatusCreated, user)
+}
+
+// scimAdoptUser marks the alias as provisioned by the client, applying the
+// attributes of the user creation request to it and its entity.
+func (i *IdentityStore) scimAdoptUser(ctx context.Context, client *scimClient, existing *identity.Alias, d *framework.FieldData) (*logical.Response, error) {
+	entity, err := i.MemDBEntityByID(existing.CanonicalID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, fmt.Errorf("entity %q of alias %q not found", existing.CanonicalID, existing.ID)
+	}
+
+	var alias *identity.Alias
+	for _, entityAlias := range entity.Aliases {
+		if entityAlias.ID == existing.ID {
+			alias = entityAlias
+			break
+		}
+	}
+	if alias == nil {
+		return nil, fmt.Errorf("alias %q not found on entity %q", existing.ID, entity.ID)
+	}
+
+	if alias.CustomMetadata == nil {
+		alias.CustomMetadata = make(map[string]string)
+	}
+	alias.CustomMetadata[scimMetaClient] = client.name
+	if externalID := d.Get("externalId").(string); externalID != "" {
+		alias.CustomMetadata[scimMetaExternalID] = externalID
+	}
+	alias.LastUpdateTime = timestamppb.Now()
+	entity.UpsertAlias(alias)
+	entity.Disabled = !d.Get("active").(bool)
+
+	if err := i.upsertEntity(ctx, entity, nil, true); err != nil {
+		return nil, err
+	}
+
+	user, err := i.scimUserFromEntity(ctx, entity, alias)
+	if err != nil {
+		return nil, err
+	}
+	return scimResponse(http.StatusCreated, user)
+}
+
+func (i *IdentityStore) pathSCIMUserRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	entity, alias, err := i.scimUserByID(ctx, client, d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "user not found")
+	}
+
+	user, err := i.scimUserFromEntity(ctx, entity, alias)
+	if err != nil {
+		return nil, err
+	}
+	return scimResponse(http.StatusOK, user)
+}
+
+// pathSCIMUserReplace handles PUT, which replaces all attributes of the user
+// that Vault tracks.
+func (i *IdentityStore) pathSCIMUserReplace(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	userName := d.Get("userName").(string)
+	if userName == "" {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "missing userName")
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, alias, err := i.scimUserByID(ctx, client, d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "user not found")
+	}
+
+	return i.scimUpdateUser(ctx, client, entity, alias, scimUserUpdate{
+		userName:   &userName,
+		externalID: pointerutil.StringPtr(d.Get("externalId").(string)),
+		active:     pointerutil.BoolPtr(d.Get("active").(bool)),
+	})
+}
+
+func (i *IdentityStore) pathSCIMUserPatch(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	ops, err := parseSCIMPatchOperations(d)
+	if err != nil {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, err.Error())
+	}
+
+	var update scimUserUpdate
+	for _, op := range ops {
+		if op.op == "remove" {
+			if op.path != "externalId" {
+				return scimErrorResponse(http.StatusBadRequest, scimErrInvalidPath, fmt.Sprintf("cannot remove %q", op.path))
+			}
+			update.externalID = pointerutil.StringPtr("")
+			continue
+		}
+
+		// Without a path the value holds the attributes to set
+		values := map[string]interface{}{op.path: op.value}
+		if op.path == "" {
+			var ok bool
+			if values, ok = op.value.(map[string]interface{}); !ok {
+				return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "operation without a path must have an object value")
+			}
+		}
+
+		for attr, value := range values {
+			switch attr {
+			case "active":
+				active, err := parseutil.ParseBool(value)
+				if err != nil {
+					return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, fmt.Sprintf("invalid active value: %v", err))
+				}
+				update.active = &active
+			case "userName":
+				userName, ok := value.(string)
+				if !ok || userName == "" {
+					return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "invalid userName")
+				}
+				update.userName = &userName
+			case "externalId":
+				externalID, ok := value.(string)
+				if !ok {
+					return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "invalid externalId")
+				}
+				update.externalID = &externalID
+			default:
+				// Attributes Vault does not track, such as name or emails,
+				// are accepted and ignored so that providers pushing full
+				// profiles are not rejected.
+			}
+		}
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, alias, err := i.scimUserByID(ctx, client, d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "user not found")
+	}
+
+	return i.scimUpdateUser(ctx, client, entity, alias, update)
+}
+
+// pathSCIMUserDelete deprovisions the user by removing its alias and its
+// memberships in the client's groups. The entity itself is only deleted if it
+// was created by the client and has no other aliases left, so that entities
+// in use through other mounts are preserved.
+func (i *IdentityStore) pathSCIMUserDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, alias, err := i.scimUserByID(ctx, client, d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "user not found")
+	}
+
+	txn := i.db.Txn(true)
+	defer txn.Abort()
+
+	if entity.Metadata[scimMetaClient] == client.name && len(entity.Aliases) == 1 {
+		if err := i.handleEntityDeleteCommon(ctx, txn, entity, true); err != nil {
+			return nil, err
+		}
+		txn.Commit()
+		return scimResponse(http.StatusNoContent, nil)
+	}
+
+	groups, err := i.MemDBGroupsByMemberEntityIDInTxn(txn, entity.ID, true, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.Metadata[scimMetaClient] != client.name {
+			continue
+		}
+		group.MemberEntityIDs = strutil.StrListDelete(group.MemberEntityIDs, entity.ID)
+		if err := i.UpsertGroupInTxn(ctx, txn, group, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.deleteAliasesInEntityInTxn(txn, entity, []*identity.Alias{alias}); err != nil {
+		return nil, err
+	}
+	if err := i.MemDBUpsertEntityInTxn(txn, entity); err != nil {
+		return nil, err
+	}
+	if err := i.persistEntity(ctx, entity); err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return scimResponse(http.StatusNoContent, nil)
+}
+
+// scimUserUpdate holds the user attributes to change; nil fields are left
+// untouched.
+type scimUserUpdate struct {
+	userName   *string
+	externalID *string
+	active     *bool
+}
+
+func (i *IdentityStore) scimUpdateUser(ctx context.Context, client *scimClient, entity *identity.Entity, alias *identity.Alias, update scimUserUpdate) (*logical.Response, error) {
+	if update.userName != nil && *update.userName != alias.Name {
+		existing, err := i.MemDBAliasByFactors(client.MountAccessor, *update.userName, false, false)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return scimErrorResponse(http.StatusConflict, scimErrUniqueness, "a user with this userName already exists")
+		}
+		alias.Name = *update.userName
+	}
+
+	if update.externalID != nil {
+		if alias.CustomMetadata == nil {
+			alias.CustomMetadata = make(map[string]string)
+		}
+		if *update.externalID == "" {
+			delete(alias.CustomMetadata, scimMetaExternalID)
+		} else {
+			alias.CustomMetadata[scimMetaExternalID] = *update.externalID
+		}
+	}
+
+	if update.active != nil {
+		entity.Disabled = !*update.active
+	}
+
+	alias.LastUpdateTime = timestamppb.Now()
+	entity.UpsertAlias(alias)
+
+	if err := i.sanitizeEntity(ctx, entity); err != nil {
+		return nil, err
+	}
+	if err := i.upsertEntity(ctx, entity, nil, true); err != nil {
+		return nil, err
+	}
+
+	user, err := i.scimUserFromEntity(ctx, entity, alias)
+	if err != nil {
+		return nil, err
+	}
+	return scimResponse(http.StatusOK, user)
+}
+
+// scimClientAliases returns the entity aliases provisioned by the client in
+// the request namespace, sorted by name for stable pagination.
+func (i *IdentityStore) scimClientAliases(ctx context.Context, client *scimClient) ([]*identity.Alias, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	txn := i.db.Txn(false)
+
+	iter, err := txn.Get(entityAliasesTable, "namespace_id", ns.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch iterator for aliases in memdb: %w", err)
+	}
+
+	var aliases []*identity.Alias
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		alias := raw.(*identity.Alias)
+		if scimClientOwnsAlias(client, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	sort.Slice(aliases, func(a, b int) bool {
+		return aliases[a].Name < aliases[b].Name
+	})
+
+	return aliases, nil
+}
+
+// scimUserByID returns a clone of the entity with the given ID along with the
+// alias the client provisioned for it. A nil entity is returned if the entity
+// does not exist or has no such alias, since it is then not visible to the
+// client.
+func (i *IdentityStore) scimUserByID(ctx context.Context, client *scimClient, entityID string) (*identity.Entity, *identity.Alias, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entity, err := i.MemDBEntityByID(entityID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entity == nil || entity.NamespaceID != ns.ID {
+		return nil, nil, nil
+	}
+
+	for _, alias := range entity.Aliases {
+		if scimClientOwnsAlias(client, alias) {
+			return entity, alias, nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// scimClientOwnsAlias reports whether the alias was provisioned by the
+// client. Aliases created on the mount by logins or by other clients are
+// never visible through SCIM.
+func scimClientOwnsAlias(client *scimClient, alias *identity.Alias) bool {
+	return alias.MountAccessor == client.MountAccessor && alias.CustomMetadata[scimMetaClient] == client.name
+}
+
+func (i *IdentityStore) scimUserFromEntity(ctx context.Context, entity *identity.Entity, alias *identity.Alias) (map[string]interface{}, error) {
+	user := map[string]interface{}{
+		"schemas":  []string{scimSchemaUser},
+		"id":       entity.ID,
+		"userName": alias.Name,
+		"active":   !entity.Disabled,
+		"meta":     scimMeta("User", entity.CreationTime, entity.LastUpdateTime),
+	}
+	if externalID := alias.CustomMetadata[scimMetaExternalID]; externalID != "" {
+		user["externalId"] = externalID
+	}
+
+	groups, err := i.MemDBGroupsByMemberEntityID(entity.ID, false, false)
+	if err != nil {
+		return nil, err
+	}
+	userGroups := []map[string]interface{}{}
+	for _, group := range groups {
+		userGroups = append(userGroups, map[string]interface{}{
+			"value":   group.ID,
+			"display": group.Name,
+		})
+	}
+	user["groups"] = userGroups
+
+	return user, nil
+}
+
+func (i *IdentityStore) pathSCIMGroupsSearch(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	clientName := d.Get("client").(string)
+	if _, errResp, err := i.scimClientFromRequest(ctx, req, d); errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	attr, value, err := parseSCIMFilter(d.Get("filter").(string))
+	if err != nil {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidFilter, err.Error())
+	}
+	switch attr {
+	case "", "displayName", "externalId", "id":
+	default:
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidFilter, fmt.Sprintf("filtering on %q is not supported", attr))
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	i.groupLock.RLock()
+	defer i.groupLock.RUnlock()
+
+	txn := i.db.Txn(false)
+
+	iter, err := txn.Get(groupsTable, "namespace_id", ns.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch iterator for groups in memdb: %w", err)
+	}
+
+	var groups []*identity.Group
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		group := raw.(*identity.Group)
+		if group.Metadata[scimMetaClient] != clientName {
+			continue
+		}
+		switch {
+		case attr == "displayName" && !strings.EqualFold(group.Name, value):
+			continue
+		case attr == "externalId" && group.Metadata[scimMetaExternalID] != value:
+			continue
+		case attr == "id" && group.ID != value:
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(a, b int) bool {
+		return groups[a].Name < groups[b].Name
+	})
+
+	scimGroups := make([]map[string]interface{}, 0, len(groups))
+	for _, group := range groups {
+		scimGroups = append(scimGroups, scimGroupFromGroup(group))
+	}
+
+	return scimListResponse(scimGroups, d)
+}
+
+func (i *IdentityStore) pathSCIMGroupCreate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	displayName := d.Get("displayName").(string)
+	if displayName == "" {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "missing displayName")
+	}
+
+	memberIDs, err := parseSCIMMembers(d.Get("members"))
+	if err != nil {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, err.Error())
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	groupByName, err := i.MemDBGroupByName(ctx, displayName, false)
+	if err != nil {
+		return nil, err
+	}
+	if groupByName != nil {
+		return scimErrorResponse(http.StatusConflict, scimErrUniqueness, "a group with this displayName already exists")
+	}
+
+	group := &identity.Group{
+		Name: displayName,
+		Type: groupTypeInternal,
+		Metadata: map[string]string{
+			scimMetaClient: d.Get("client").(string),
+		},
+		MemberEntityIDs: []string{},
+	}
+	if externalID := d.Get("externalId").(string); externalID != "" {
+		group.Metadata[scimMetaExternalID] = externalID
+	}
+
+	return i.scimUpsertGroup(ctx, client, group, memberIDs, http.StatusCreated)
+}
+
+func (i *IdentityStore) pathSCIMGroupRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if _, errResp, err := i.scimClientFromRequest(ctx, req, d); errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	i.groupLock.RLock()
+	defer i.groupLock.RUnlock()
+
+	group, err := i.scimGroupByID(ctx, d.Get("client").(string), d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "group not found")
+	}
+
+	return scimResponse(http.StatusOK, scimGroupFromGroup(group))
+}
+
+// pathSCIMGroupReplace handles PUT, which replaces the name and members of
+// the group.
+func (i *IdentityStore) pathSCIMGroupReplace(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	displayName := d.Get("displayName").(string)
+	if displayName == "" {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "missing displayName")
+	}
+
+	memberIDs, err := parseSCIMMembers(d.Get("members"))
+	if err != nil {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, err.Error())
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	group, err := i.scimGroupByID(ctx, d.Get("client").(string), d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "group not found")
+	}
+
+	if errResp, err := i.scimRenameGroup(ctx, group, displayName); errResp != nil || err != nil {
+		return errResp, err
+	}
+	if externalID := d.Get("externalId").(string); externalID != "" {
+		group.Metadata[scimMetaExternalID] = externalID
+	} else {
+		delete(group.Metadata, scimMetaExternalID)
+	}
+
+	return i.scimUpsertGroup(ctx, client, group, memberIDs, http.StatusOK)
+}
+
+func (i *IdentityStore) pathSCIMGroupPatch(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	client, errResp, err := i.scimClientFromRequest(ctx, req, d)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	ops, err := parseSCIMPatchOperations(d)
+	if err != nil {
+		return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, err.Error())
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	group, err := i.scimGroupByID(ctx, d.Get("client").(string), d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "group not found")
+	}
+
+	memberIDs := group.MemberEntityIDs
+	for _, op := range ops {
+		// A path selecting a single member is only meaningful for removal
+		if matches := scimMemberPathRegex.FindStringSubmatch(op.path); matches != nil {
+			if op.op != "remove" {
+				return scimErrorResponse(http.StatusBadRequest, scimErrInvalidPath, fmt.Sprintf("unsupported path %q for %s", op.path, op.op))
+			}
+			memberIDs = strutil.StrListDelete(memberIDs, matches[1])
+			continue
+		}
+
+		values := map[string]interface{}{op.path: op.value}
+		if op.path == "" {
+			var ok bool
+			if values, ok = op.value.(map[string]interface{}); !ok {
+				return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "operation without a path must have an object value")
+			}
+		}
+
+		for attr, value := range values {
+			switch attr {
+			case "members":
+				ids, err := parseSCIMMembers(value)
+				if err != nil {
+					return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, err.Error())
+				}
+				switch op.op {
+				case "add":
+					memberIDs = append(memberIDs, ids...)
+				case "replace":
+					memberIDs = ids
+				case "remove":
+					// Removing members without a value removes all of them
+					if value == nil {
+						memberIDs = []string{}
+					}
+					for _, id := range ids {
+						memberIDs = strutil.StrListDelete(memberIDs, id)
+					}
+				}
+			case "displayName":
+				displayName, ok := value.(string)
+				if !ok || displayName == "" || op.op == "remove" {
+					return scimErrorResponse(http.StatusBadRequest, scimErrInvalidValue, "invalid displayName")
+				}
+				if errResp, err := i.scimRenameGroup(ctx, group, displayName); errResp != nil || err != nil {
+					return errResp, err
+				}
+			case "externalId":
+				externalID, _ := value.(string)
+				if op.op == "remove" || externalID == "" {
+					delete(group.Metadata, scimMetaExternalID)
+				} else {
+					group.Metadata[scimMetaExternalID] = externalID
+				}
+			default:
+				return scimErrorResponse(http.StatusBadRequest, scimErrInvalidPath, fmt.Sprintf("unsupported path %q", attr))
+			}
+		}
+	}
+
+	return i.scimUpsertGroup(ctx, client, group, memberIDs, http.StatusOK)
+}
+
+func (i *IdentityStore) pathSCIMGroupDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if _, errResp, err := i.scimClientFromRequest(ctx, req, d); errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	// handleGroupDeleteCommon acquires the group lock itself, so only the
+	// lookup is done under the read lock.
+	i.groupLock.RLock()
+	group, err := i.scimGroupByID(ctx, d.Get("client").(string), d.Get("id").(string))
+	i.groupLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return scimErrorResponse(http.StatusNotFound, "", "group not found")
+	}
+
+	resp, err := i.handleGroupDeleteCommon(ctx, group.ID, true)
+	if err != nil || (resp != nil && resp.IsError()) {
+		return resp, err
+	}
+
+	return scimResponse(http.StatusNoContent, nil)
+}
+
+// scimGroupByID returns a clone of the group with the given ID if it is
+// owned by the named client.
+func (i *IdentityStore) scimGroupByID(ctx context.Context, clientName, groupID string) (*identity.Group, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := i.MemDBGroupByID(groupID, true)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil || group.NamespaceID != ns.ID || group.Metadata[scimMetaClient] != clientName {
+		return nil, nil
+	}
+
+	return group, nil
+}
+
+func (i *IdentityStore) scimRenameGroup(ctx context.Context, group *identity.Group, displayName string) (*logical.Response, error) {
+	if displayName == group.Name {
+		return nil, nil
+	}
+
+	groupByName, err := i.MemDBGroupByName(ctx, displayName, false)
+	if err != nil {
+		return nil, err
+	}
+	if groupByName != nil && groupByName.ID != group.ID {
+		return scimErrorResponse(http.StatusConflict, scimErrUniqueness, "a group with this displayName already exists")
+	}
+
+	group.Name = displayName
+	return nil, nil
+}
+
+// scimUpsertGroup sets the members of the group and persists it. Only users
+// visible to the client may be added as members.
+func (i *IdentityStore) scimUpsertGroup(ctx context.Context, client *scimClient, group *identity.Group, memberIDs []string, status int) (*logical.Response, error) {
+	memberIDs = strutil.RemoveDuplicates(memberIDs, false)
+	for _, memberID := range memberIDs {
+		entity, _, err := i.scimUserByID(ctx, client, memberID)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return scimErrorResponse(http.StatusBadRequest, scimErrNoTarget, fmt.Sprintf("unknown member %q", memberID))
+		}
+	}
+	group.MemberEntityIDs = memberIDs
+
+	if err := i.sanitizeAndUpsertGroup(ctx, group, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return scimResponse(status, scimGroupFromGroup(group))
+}
+
+func scimGroupFromGroup(group *identity.Group) map[string]interface{} {
+	members := make([]map[string]interface{}, 0, len(group.MemberEntityIDs))
+	for _, memberID := range group.MemberEntityIDs {
+		members = append(members, map[string]interface{}{
+			"value": memberID,
+			"type":  "User",
+		})
+	}
+
+	scimGroup := map[string]interface{}{
+		"schemas":     []string{scimSchemaGroup},
+		"id":          group.ID,
+		"displayName": group.Name,
+		"members":     members,
+		"meta":        scimMeta("Group", group.CreationTime, group.LastUpdateTime),
+	}
+	if externalID := group.Metadata[scimMetaExternalID]; externalID != "" {
+		scimGroup["externalId"] = externalID
+	}
+
+	return scimGroup
+}
+
+func scimMeta(resourceType string, created, lastModified *timestamppb.Timestamp) map[string]interface{} {
+	meta := map[string]interface{}{
+		"resourceType": resourceType,
+	}
+	if created != nil {
+		meta["created"] = created.AsTime().Format(time.RFC3339)
+	}
+	if lastModified != nil {
+		meta["lastModified"] = lastModified.AsTime().Format(time.RFC3339)
+	}
+	return meta
+}
+
+// scimPatchOperation is a single operation of a SCIM PATCH request, see
+// RFC 7644 section 3.5.2.
+type scimPatchOperation struct {
+	op    string
+	path  string
+	value interface{}
+}
+
+func parseSCIMPatchOperations(d *framework.FieldData) ([]scimPatchOperation, error) {
+	opsRaw := d.Get("Operations").([]interface{})
+	if len(opsRaw) == 0 {
+		return nil, fmt.Errorf("missing Operations")
+	}
+
+	ops := make([]scimPatchOperation, 0, len(opsRaw))
+	for _, opRaw := range opsRaw {
+		opMap, ok := opRaw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid operation")
+		}
+
+		var op scimPatchOperation
+		opName, _ := opMap["op"].(string)
+		op.op = strings.ToLower(opName)
+		switch op.op {
+		case "add", "replace", "remove":
+		default:
+			return nil, fmt.Errorf("invalid op %q", opName)
+		}
+		op.path, _ = opMap["path"].(string)
+		op.value = opMap["value"]
+
+		if op.op != "remove" && op.value == nil {
+			return nil, fmt.Errorf("missing value for %s", op.op)
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// parseSCIMMembers returns the IDs in a list of SCIM member objects.
+func parseSCIMMembers(raw interface{}) ([]string, error) {
+	if raw == nil {
+		return []string{}, nil
+	}
+
+	var list []interface{}
+	switch raw := raw.(type) {
+	case []interface{}:
+		list = raw
+	case map[string]interface{}:
+		list = []interface{}{raw}
+	default:
+		return nil, fmt.Errorf("invalid members")
+	}
+
+	ids := make([]string, 0, len(list))
+	for _, memberRaw := range list {
+		member, ok := memberRaw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid member")
+		}
+		id, ok := member["value"].(string)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("member is missing a value")
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// parseSCIMFilter parses an equality filter, returning the attribute and the
+// value it is compared to. An empty filter returns an empty attribute.
+func parseSCIMFilter(filter string) (string, string, error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", "", nil
+	}
+
+	matches := scimFilterRegex.FindStringSubmatch(filter)
+	if matches == nil {
+		return "", "", fmt.Errorf("unsupported filter %q; only 'attribute eq \"value\"' is supported", filter)
+	}
+
+	value, err := strconv.Unquote(`"` + matches[2] + `"`)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid filter value: %w", err)
+	}
+
+	return matches[1], value, nil
+}
+
+func scimListResponse(resources []map[string]interface{}, d *framework.FieldData) (*logical.Response, error) {
+	startIndex := d.Get("startIndex").(int)
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := d.Get("count").(int)
+	switch {
+	case count < 0:
+		count = 0
+	case count > scimDefaultCount:
+		// The service provider config advertises this as maxResults
+		count = scimDefaultCount
+	}
+
+	page := []map[string]interface{}{}
+	if startIndex <= len(resources) {
+		end := startIndex - 1 + count
+		if end > len(resources) {
+			end = len(resources)
+		}
+		page = resources[startIndex-1 : end]
+	}
+
+	return scimResponse(http.StatusOK, map[string]interface{}{
+		"schemas":      []string{scimSchemaListResponse},
+		"totalResults": len(resources),
+		"startIndex":   startIndex,
+		"itemsPerPage": len(page),
+		"Resources":    page,
+	})
+}
+
+func scimErrorResponse(status int, scimType, detail string) (*logical.Response, error) {
+	body := map[string]interface{}{
+		"schemas": []string{scimSchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	return scimResponse(status, body)
+}
+
+// scimResponse returns a raw response so that the body is returned as-is
+// rather than wrapped in Vault's response envelope.
+func scimResponse(status int, body map[string]interface{}) (*logical.Response, error) {
+	data := map[string]interface{}{
+		logical.HTTPStatusCode:  status,
+		logical.HTTPContentType: scimContentType,
+	}
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		data[logical.HTTPRawBody] = raw
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+var scimHelp = map[string][2]string{
+	"client": {
+		"Create, read, update or delete a SCIM client.",
+		`
+A SCIM client is an identity provider that provisions users and groups through
+the SCIM 2.0 endpoints at "identity/scim/<name>/v2". Provisioned users become
+entities with an alias on the auth mount given by "mount_accessor", so that
+logins through that mount resolve to the provisioned entity. Provisioned groups
+are internal groups whose members are provisioned users.
+
+The identity provider authenticates with a Vault token sent as a bearer token.
+The token's policies should only grant access to "identity/scim/<name>/*".
+		`,
+	},
+	"client-list": {
+		"List SCIM clients.",
+		"List the names of all configured SCIM clients.",
+	},
+	"service-provider-config": {
+		"SCIM service provider configuration.",
+		"Returns the features of the SCIM 2.0 service, as described in RFC 7643 section 5.",
+	},
+	"users": {
+		"Search for or create SCIM users.",
+		`
+GET lists the users provisioned by the client, optionally restricted by an
+equality filter on userName, externalId or id. POST creates an entity with an
+alias named after userName on the client's auth mount. If the mount already
+has an alias of that name which was created by a login rather than by a SCIM
+client, the client adopts that alias and its entity instead. Users created
+with "active" set to false have their entity disabled.
+		`,
+	},
+	"user": {
+		"Read, replace, patch or delete a SCIM user.",
+		`
+The user ID is the ID of its entity. Setting "active" to false disables the
+entity. Deleting the user removes the client's alias and the entity's
+memberships in the client's groups. The entity itself is only deleted if the
+client created it and it has no other aliases, so that entities adopted by the
+client or in use through other mounts are kept.
+		`,
+	},
+	"groups": {
+		"Search for or create SCIM groups.",
+		`
+GET lists the groups provisioned by the client, optionally restricted by an
+equality filter on displayName, externalId or id. POST creates an internal
+identity group named after displayName, whose members are provisioned users.
+		`,
+	},
+	"group": {
+		"Read, replace, patch or delete a SCIM group.",
+		"The group ID is the ID of its identity group.",
+	},
+}
@@ -0,0 +1,359 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func scimTestRequest(t *testing.T, is *IdentityStore, op logical.Operation, path string, data map[string]interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	ctx := namespace.RootContext(nil)
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Storage:   is.view,
+		Operation: op,
+		Path:      path,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("%s %s: err: %v", op, path, err)
+	}
+	if resp == nil {
+		t.Fatalf("%s %s: expected a response", op, path)
+	}
+
+	status := resp.Data[logical.HTTPStatusCode].(int)
+	var body map[string]interface{}
+	if raw, ok := resp.Data[logical.HTTPRawBody]; ok {
+		if err := json.Unmarshal(raw.([]byte), &body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return status, body
+}
+
+func TestIdentityStore_SCIM_Users(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, ghAccessor, _ := testIdentityStoreWithGithubAuth(ctx, t)
+
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Storage:   is.view,
+		Operation: logical.CreateOperation,
+		Path:      "scim/client/okta",
+		Data: map[string]interface{}{
+			"mount_accessor": ghAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	status, user := scimTestRequest(t, is, logical.UpdateOperation, "scim/okta/v2/Users", map[string]interface{}{
+		"schemas":    []interface{}{scimSchemaUser},
+		"userName":   "alice",
+		"externalId": "00u1",
+		"active":     true,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %v", status, user)
+	}
+	entityID := user["id"].(string)
+
+	// The provisioned entity must carry an alias on the client's mount so
+	// that logins resolve to it
+	alias, err := is.MemDBAliasByFactors(ghAccessor, "alice", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alias == nil || alias.CanonicalID != entityID {
+		t.Fatalf("expected alias for provisioned entity, got %#v", alias)
+	}
+
+	status, body := scimTestRequest(t, is, logical.UpdateOperation, "scim/okta/v2/Users", map[string]interface{}{
+		"userName": "alice",
+	})
+	if status != http.StatusConflict || body["scimType"] != scimErrUniqueness {
+		t.Fatalf("expected uniqueness conflict, got %d: %v", status, body)
+	}
+
+	status, body = scimTestRequest(t, is, logical.ReadOperation, "scim/okta/v2/Users", map[string]interface{}{
+		"filter": `userName eq "alice"`,
+	})
+	if status != http.StatusOK || body["totalResults"].(float64) != 1 {
+		t.Fatalf("expected one result, got %d: %v", status, body)
+	}
+
+	// Deactivating the user disables its entity
+	status, body = scimTestRequest(t, is, logical.PatchOperation, "scim/okta/v2/Users/"+entityID, map[string]interface{}{
+		"Operations": []interface{}{
+			map[string]interface{}{"op": "Replace", "path": "active", "value": "False"},
+		},
+	})
+	if status != http.StatusOK || body["active"] != false {
+		t.Fatalf("expected inactive user, got %d: %v", status, body)
+	}
+	entity, err := is.MemDBEntityByID(entityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entity.Disabled {
+		t.Fatal("expected entity to be disabled")
+	}
+
+	status, _ = scimTestRequest(t, is, logical.DeleteOperation, "scim/okta/v2/Users/"+entityID, nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", status)
+	}
+	status, _ = scimTestRequest(t, is, logical.ReadOperation, "scim/okta/v2/Users/"+entityID, nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+}
+
+func TestIdentityStore_SCIM_Groups(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, ghAccessor, _ := testIdentityStoreWithGithubAuth(ctx, t)
+
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Storage:   is.view,
+		Operation: logical.CreateOperation,
+		Path:      "scim/client/okta",
+		Data: map[string]interface{}{
+			"mount_accessor": ghAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	var userIDs []string
+	for _, userName := range []string{"alice", "bob"} {
+		status, user := scimTestRequest(t, is, logical.UpdateOperation, "scim/okta/v2/Users", map[string]interface{}{
+			"userName": userName,
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %v", status, user)
+		}
+		userIDs = append(userIDs, user["id"].(string))
+	}
+
+	status, group := scimTestRequest(t, is, logical.UpdateOperation, "scim/okta/v2/Groups", map[string]interface{}{
+		"displayName": "engineering",
+		"members": []interface{}{
+			map[string]interface{}{"value": userIDs[0]},
+		},
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %v", status, group)
+	}
+	groupID := group["id"].(string)
+
+	status, group = scimTestRequest(t, is, logical.PatchOperation, "scim/okta/v2/Groups/"+groupID, map[string]interface{}{
+		"Operations": []interface{}{
+			map[string]interface{}{
+				"op":    "add",
+				"path":  "members",
+				"value": []interface{}{map[string]interface{}{"value": userIDs[1]}},
+			},
+			map[string]interface{}{
+				"op":   "remove",
+				"path": `members[value eq "` + userIDs[0] + `"]`,
+			},
+		},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", status, group)
+	}
+
+	identityGroup, err := is.MemDBGroupByID(groupID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identityGroup.MemberEntityIDs) != 1 || identityGroup.MemberEntityIDs[0] != userIDs[1] {
+		t.Fatalf("unexpected members %v", identityGroup.MemberEntityIDs)
+	}
+
+	// Entities that are not visible to the client cannot be added
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	status, body := scimTestRequest(t, is, logical.PatchOperation, "scim/okta/v2/Groups/"+groupID, map[string]interface{}{
+		"Operations": []interface{}{
+			map[string]interface{}{
+				"op":    "add",
+				"path":  "members",
+				"value": []interface{}{map[string]interface{}{"value": resp.Data["id"]}},
+			},
+		},
+	})
+	if status != http.StatusBadRequest || body["scimType"] != scimErrNoTarget {
+		t.Fatalf("expected noTarget error, got %d: %v", status, body)
+	}
+
+	// Groups created outside of SCIM are not visible to the client
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"name": "unmanaged",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	status, body = scimTestRequest(t, is, logical.ReadOperation, "scim/okta/v2/Groups", nil)
+	if status != http.StatusOK || body["totalResults"].(float64) != 1 {
+		t.Fatalf("expected one group, got %d: %v", status, body)
+	}
+	status, _ = scimTestRequest(t, is, logical.ReadOperation, "scim/okta/v2/Groups/"+resp.Data["id"].(string), nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+
+	status, _ = scimTestRequest(t, is, logical.DeleteOperation, "scim/okta/v2/Groups/"+groupID, nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", status)
+	}
+}
+
+func TestIdentityStore_SCIM_UnprovisionedUsers(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, ghAccessor, upAccessor, _ := testIdentityStoreWithGithubUserpassAuth(ctx, t)
+
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Storage:   is.view,
+		Operation: logical.CreateOperation,
+		Path:      "scim/client/okta",
+		Data: map[string]interface{}{
+			"mount_accessor": ghAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// An entity with an alias on the client's mount that SCIM did not
+	// provision is not visible to the client
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity-alias",
+		Data: map[string]interface{}{
+			"name":           "mallory",
+			"mount_accessor": ghAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	unmanagedID := resp.Data["canonical_id"].(string)
+
+	status, body := scimTestRequest(t, is, logical.ReadOperation, "scim/okta/v2/Users", nil)
+	if status != http.StatusOK || body["totalResults"].(float64) != 0 {
+		t.Fatalf("expected no users, got %d: %v", status, body)
+	}
+	for _, op := range []logical.Operation{logical.ReadOperation, logical.DeleteOperation} {
+		status, _ = scimTestRequest(t, is, op, "scim/okta/v2/Users/"+unmanagedID, nil)
+		if status != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", op, status)
+		}
+	}
+
+	// Provisioning the user adopts the alias created on the mount by a
+	// login, which is then not available to other clients
+	status, user := scimTestRequest(t, is, logical.UpdateOperation, "scim/okta/v2/Users", map[string]interface{}{
+		"userName":   "mallory",
+		"externalId": "00u2",
+		"active":     true,
+	})
+	if status != http.StatusCreated || user["id"] != unmanagedID || user["externalId"] != "00u2" {
+		t.Fatalf("expected adopted user, got %d: %v", status, user)
+	}
+	status, body = scimTestRequest(t, is, logical.ReadOperation, "scim/okta/v2/Users/"+unmanagedID, nil)
+	if status != http.StatusOK || body["userName"] != "mallory" {
+		t.Fatalf("expected adopted user, got %d: %v", status, body)
+	}
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Storage:   is.view,
+		Operation: logical.CreateOperation,
+		Path:      "scim/client/other",
+		Data: map[string]interface{}{
+			"mount_accessor": ghAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	status, _ = scimTestRequest(t, is, logical.UpdateOperation, "scim/other/v2/Users", map[string]interface{}{
+		"userName": "mallory",
+	})
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", status)
+	}
+
+	// Deleting an adopted user removes its alias but keeps the entity,
+	// which the client did not create
+	status, _ = scimTestRequest(t, is, logical.DeleteOperation, "scim/okta/v2/Users/"+unmanagedID, nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", status)
+	}
+	entity, err := is.MemDBEntityByID(unmanagedID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entity == nil || len(entity.Aliases) != 0 {
+		t.Fatalf("expected entity without aliases, got %#v", entity)
+	}
+
+	// Deleting a provisioned user only removes its SCIM alias when the
+	// entity is also used through another mount
+	status, user = scimTestRequest(t, is, logical.UpdateOperation, "scim/okta/v2/Users", map[string]interface{}{
+		"userName": "alice",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %v", status, user)
+	}
+	entityID := user["id"].(string)
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity-alias",
+		Data: map[string]interface{}{
+			"name":           "alice",
+			"mount_accessor": upAccessor,
+			"canonical_id":   entityID,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	status, _ = scimTestRequest(t, is, logical.DeleteOperation, "scim/okta/v2/Users/"+entityID, nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", status)
+	}
+	entity, err = is.MemDBEntityByID(entityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entity == nil || len(entity.Aliases) != 1 || entity.Aliases[0].MountAccessor != upAccessor {
+		t.Fatalf("expected entity to keep its userpass alias, got %#v", entity)
+	}
+
+	// Counts above the advertised maxResults are clamped
+	status, body = scimTestRequest(t, is, logical.ReadOperation, "scim/okta/v2/Users", map[string]interface{}{
+		"count": scimDefaultCount + 1,
+	})
+	if status != http.StatusOK || body["itemsPerPage"].(float64) > scimDefaultCount {
+		t.Fatalf("unexpected response %d: %v", status, body)
+	}
+}
@@ -281,6 +281,11 @@ func (c *Core) reloadBackendCommon(ctx context.Context, entry *MountEntry, isAut
 				return err
 			}
 			re.binaryPaths.Store(binaryPathsEntry)
+			streamingPathsEntry, err := parseUnauthenticatedPaths(paths.Streaming)
+			if err != nil {
+				return err
+			}
+			re.streamingPaths.Store(streamingPathsEntry)
 		}
 	}
 
@@ -63,14 +63,15 @@ type routeEntry struct {
 	tainted atomic.Bool
 	// backend is the actual backend instance for this route entry; lock l must
 	// be held to access this field.
-	backend       logical.Backend
-	mountEntry    *MountEntry
-	storageView   logical.Storage
-	storagePrefix string
-	rootPaths     atomic.Value
-	loginPaths    atomic.Value
-	binaryPaths   atomic.Value
-	limitedPaths  atomic.Value
+	backend        logical.Backend
+	mountEntry     *MountEntry
+	storageView    logical.Storage
+	storagePrefix  string
+	rootPaths      atomic.Value
+	loginPaths     atomic.Value
+	binaryPaths    atomic.Value
+	streamingPaths atomic.Value
+	limitedPaths   atomic.Value
 	// l is the lock used to protect access to backend during reloads
 	l sync.RWMutex
 }
@@ -219,6 +220,12 @@ func (r *Router) Mount(backend logical.Backend, prefix string, mountEntry *Mount
 	}
 	re.binaryPaths.Store(binaryPathsEntry)
 
+	streamingPathsEntry, err := parseUnauthenticatedPaths(paths.Streaming)
+	if err != nil {
+		return err
+	}
+	re.streamingPaths.Store(streamingPathsEntry)
+
 	limitedPathsEntry, err := parseUnauthenticatedPaths(paths.Limited)
 	if err != nil {
 		return err
@@ -900,6 +907,14 @@ func (r *Router) BinaryPath(ctx context.Context, path string) bool {
 		})
 }
 
+// StreamingPath checks if the given path is used for streaming requests
+func (r *Router) StreamingPath(ctx context.Context, path string) bool {
+	return r.specialPath(ctx, path,
+		func(re *routeEntry) *specialPathsEntry {
+			return re.streamingPaths.Load().(*specialPathsEntry)
+		})
+}
+
 // LimitedPath checks if the given path uses limited requests
 func (r *Router) LimitedPath(ctx context.Context, path string) bool {
 	return r.specialPath(ctx, path,
@@ -909,8 +924,8 @@ func (r *Router) LimitedPath(ctx context.Context, path string) bool {
 }
 
 // specialPath is a common method for checking if the given path has a matching
-// PathsSpecial entry. This is used for Login, Binary, and Limited PathsSpecial
-// fields.
+// PathsSpecial entry. This is used for Login, Binary, Streaming, and Limited
+// PathsSpecial fields.
 // Matching Priority
 //  1. prefix
 //  2. exact